@@ -10,10 +10,14 @@ import (
 	"syscall"
 
 	"github.com/dr-duke/talmorGo/internal/api"
+	"github.com/dr-duke/talmorGo/internal/auth"
 	"github.com/dr-duke/talmorGo/internal/bot"
 	"github.com/dr-duke/talmorGo/internal/config"
 	"github.com/dr-duke/talmorGo/internal/db"
+	"github.com/dr-duke/talmorGo/internal/hls"
+	"github.com/dr-duke/talmorGo/internal/model"
 	"github.com/dr-duke/talmorGo/internal/ops"
+	"github.com/dr-duke/talmorGo/internal/playlist"
 	"github.com/dr-duke/talmorGo/internal/repo"
 	"github.com/dr-duke/talmorGo/internal/sse"
 	"github.com/dr-duke/talmorGo/internal/storage"
@@ -49,19 +53,42 @@ func main() {
 	settingsRepo := repo.NewSettingsRepo(database)
 	collectionRepo := repo.NewCollectionRepo(database)
 	operationRepo := repo.NewOperationRepo(database)
+	apiTokenRepo := repo.NewAPITokenRepo(database)
+	userRepo := repo.NewUserRepo(database)
+	subscriptionRepo := repo.NewSubscriptionRepo(database)
+	presetRepo := repo.NewPresetRepo(database)
+	ruleRepo := repo.NewDomainRuleRepo(database)
+
+	if err := seedAdmin(context.Background(), cfg, userRepo); err != nil {
+		slog.Error("seed admin", "err", err)
+		os.Exit(1)
+	}
 
 	hub := sse.New()
 
 	pool := worker.NewPool(cfg, jobRepo, itemRepo, tokenRepo, nil)
 	pool.SetHub(hub)
 	pool.SetSettingsRepo(settingsRepo)
+	pool.SetPresetRepo(presetRepo)
+	pool.SetDomainRuleRepo(ruleRepo)
+
+	transcoder := hls.New(cfg.HLSDir(), cfg.FfmpegBinary, int64(cfg.HLSCacheMB)<<20, hls.ParseRenditions(cfg.HLSRenditions), cfg.HLSMaxTranscodes)
+	defer transcoder.Close()
 
 	store := storage.New(cfg.YtDlpOutputDir)
+	store.SetItemDirs(cfg.ThumbDir(), cfg.SubsDir())
+	store.SetHLS(transcoder)
 	opsWorker := ops.NewWorker(operationRepo, tagRepo, jobRepo, itemRepo, store, cfg, hub)
+	opsWorker.InFlight = pool.InFlight()
+
+	subExpander := playlist.New(jobRepo, tagRepo)
+	subExpander.Hub = hub
+	subExpander.Rules = ruleRepo
+	poller := worker.NewSubscriptionPoller(subscriptionRepo, tagRepo, collectionRepo, subExpander, pool)
 
 	var tgBot *bot.Bot
 	if cfg.TelegramBotToken != "" {
-		tgBot, err = bot.New(cfg, jobRepo, itemRepo, tokenRepo, tagRepo, pool, settingsRepo)
+		tgBot, err = bot.New(cfg, jobRepo, itemRepo, tokenRepo, tagRepo, pool, settingsRepo, userRepo, subscriptionRepo, presetRepo, ruleRepo, store)
 		if err != nil {
 			slog.Warn("bot init failed, running without telegram", "err", err)
 		} else {
@@ -70,7 +97,7 @@ func main() {
 	} else {
 		slog.Info("TELEGRAM_BOT_TOKEN not set, running in web-only mode")
 	}
-	srv := api.New(cfg, jobRepo, itemRepo, tokenRepo, tagRepo, cookieRepo, settingsRepo, collectionRepo, operationRepo, store, pool, opsWorker, hub)
+	srv := api.New(cfg, jobRepo, itemRepo, tokenRepo, tagRepo, cookieRepo, settingsRepo, collectionRepo, operationRepo, apiTokenRepo, userRepo, subscriptionRepo, presetRepo, ruleRepo, store, transcoder, pool, opsWorker, poller, hub)
 	httpServer := &http.Server{
 		Addr:    cfg.HTTPHost + ":" + cfg.HTTPPort,
 		Handler: srv.Handler(),
@@ -78,6 +105,8 @@ func main() {
 
 	checker := worker.NewFileChecker(itemRepo, cfg.FileCheckInterval)
 	dirScanner := worker.NewDirScanner(jobRepo, itemRepo, cfg.YtDlpOutputDir, cfg.DirScanInterval, pool.InFlight())
+	dirScanner.SetOps(operationRepo, opsWorker.Enqueue)
+	dirScanner.SetFFprobe(cfg.FfprobeBinary)
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
@@ -96,8 +125,30 @@ func main() {
 	}
 	go checker.Start(ctx)
 	go dirScanner.Start(ctx)
+	go poller.Start(ctx)
 
 	<-ctx.Done()
 	slog.Info("shutting down…")
 	httpServer.Shutdown(context.Background()) //nolint:errcheck
 }
+
+// seedAdmin создаёт первого администратора из ADMIN_USERNAME/ADMIN_PASSWORD,
+// если пароль задан, а пользователей в базе ещё нет.
+func seedAdmin(ctx context.Context, cfg *config.Config, users repo.UserRepo) error {
+	if cfg.AdminPassword == "" {
+		return nil
+	}
+	n, err := users.Count(ctx)
+	if err != nil || n > 0 {
+		return err
+	}
+	hash, err := auth.HashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+	if err := users.Create(ctx, &model.User{Username: cfg.AdminUsername, PasswordHash: hash, Role: model.RoleAdmin}); err != nil {
+		return err
+	}
+	slog.Info("admin user created", "username", cfg.AdminUsername)
+	return nil
+}
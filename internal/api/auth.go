@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/api/handler"
+	"github.com/dr-duke/talmorGo/internal/auth"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/repo"
+)
+
+// authDeps — всё, что нужно middleware для опознания пользователя и проверки прав.
+type authDeps struct {
+	basePath  string
+	webToken  string
+	users     repo.UserRepo
+	apiTokens repo.APITokenRepo
+	jobs      repo.JobRepo
+	items     repo.ItemRepo
+}
+
+// authMiddleware определяет пользователя запроса и проверяет его права.
+// Порядок: cookie сессии → WEB_TOKEN (bearer или cookie _auth) → именованный API-токен.
+// Если не задан WEB_TOKEN и нет ни одного пользователя, авторизация выключена.
+func authMiddleware(d authDeps, next http.Handler) http.Handler {
+	deny := func(w http.ResponseWriter, r *http.Request, status int, code, msg string) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			handler.APIError(w, status, code, msg)
+			return
+		}
+		http.Error(w, msg, status)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		user, status := d.resolveUser(r)
+		switch status {
+		case http.StatusForbidden:
+			deny(w, r, status, "forbidden", "token scope does not allow this request")
+			return
+		case http.StatusUnauthorized:
+			// Браузер отправляем на форму входа, остальным — 401.
+			if r.Method == http.MethodGet && r.Header.Get("HX-Request") == "" && !strings.HasPrefix(r.URL.Path, "/api/") {
+				http.Redirect(w, r, d.basePath+"/login", http.StatusSeeOther)
+				return
+			}
+			deny(w, r, status, "unauthorized", "Unauthorized")
+			return
+		}
+		if !roleAllows(user, r) {
+			deny(w, r, http.StatusForbidden, "forbidden", "role does not allow this request")
+			return
+		}
+		if !d.ownsTarget(ctx, user, r.URL.Path) {
+			deny(w, r, http.StatusNotFound, "not_found", "not found")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithUser(ctx, user)))
+	})
+}
+
+// isPublicPath — пути, доступные без входа: presigned-ссылки, форма входа и статика.
+func isPublicPath(path string) bool {
+	return strings.HasPrefix(path, "/f/") || strings.HasPrefix(path, "/static/") || path == "/login"
+}
+
+// resolveUser возвращает пользователя запроса либо статус отказа (401/403).
+func (d authDeps) resolveUser(r *http.Request) (*model.User, int) {
+	ctx := r.Context()
+	if c, err := r.Cookie(handler.SessionCookie); err == nil {
+		if u, err := d.users.SessionUser(ctx, c.Value); err == nil {
+			return u, http.StatusOK
+		}
+	}
+	bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if d.webToken != "" {
+		if bearer == d.webToken {
+			return auth.Root, http.StatusOK
+		}
+		if c, err := r.Cookie("_auth"); err == nil && c.Value == d.webToken {
+			return auth.Root, http.StatusOK
+		}
+	}
+	// Именованные API-токены: проверяем срок и scope.
+	if bearer != "" && d.apiTokens != nil {
+		t, err := d.apiTokens.GetBySecret(ctx, bearer)
+		if err == nil && !t.Expired(time.Now()) {
+			if !scopeAllows(t.Scope, r) {
+				return nil, http.StatusForbidden
+			}
+			user := auth.Root
+			if t.UserID != "" {
+				if user, err = d.users.GetByID(ctx, t.UserID); err != nil {
+					return nil, http.StatusUnauthorized
+				}
+			}
+			if err := d.apiTokens.Touch(ctx, t.ID); err != nil {
+				slog.Warn("auth: touch api token", "id", t.ID, "err", err)
+			}
+			return user, http.StatusOK
+		}
+	}
+	if d.webToken == "" {
+		if n, err := d.users.Count(ctx); err == nil && n == 0 {
+			return auth.Root, http.StatusOK
+		}
+	}
+	return nil, http.StatusUnauthorized
+}
+
+// scopeAllows проверяет, разрешён ли запрос токену с данным scope.
+func scopeAllows(scope model.TokenScope, r *http.Request) bool {
+	switch scope {
+	case model.ScopeAdmin:
+		return true
+	case model.ScopeRead:
+		return r.Method == http.MethodGet || r.Method == http.MethodHead
+	case model.ScopeEnqueue:
+		return r.Method == http.MethodPost && (r.URL.Path == "/queue" || r.URL.Path == "/api/v1/jobs")
+	}
+	return false
+}
+
+// roleAllows проверяет ограничения роли, не зависящие от конкретного задания.
+func roleAllows(u *model.User, r *http.Request) bool {
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if !u.CanWrite() && !read && r.URL.Path != "/logout" && !isProgressPath(r.URL.Path) {
+		return false
+	}
+	if u.Role == model.RoleAdmin {
+		return true
+	}
+	p := r.URL.Path
+	switch {
+	case p == "/settings", p == "/settings/tokens", strings.HasPrefix(p, "/settings/tokens/"),
+		p == "/settings/subscriptions", strings.HasPrefix(p, "/settings/subscriptions/"):
+		// Страница настроек, собственные API-токены и подписки доступны всем.
+		return true
+	case strings.HasPrefix(p, "/settings/"), p == "/api/v1/settings",
+		p == "/queue/cancel-all", p == "/queue/pause", p == "/queue/resume", p == "/items/deleted":
+		return false
+	case !read && (strings.HasPrefix(p, "/operations/") || strings.HasPrefix(p, "/api/v1/operations/")):
+		// Завершённые фоновые операции убирает администратор.
+		return false
+	case !read && strings.HasPrefix(p, "/api/v1/tags/"):
+		// Теги общие для всех — переименовывает и удаляет их администратор.
+		return false
+	case !read && (strings.HasPrefix(p, "/collections/") || strings.HasPrefix(p, "/api/v1/collections/")):
+		// Коллекции тоже общие: участник может создать коллекцию и добавить в неё
+		// задания, а переименовывает и удаляет их администратор.
+		return r.Method == http.MethodPost && strings.HasSuffix(p, "/jobs")
+	}
+	return true
+}
+
+// isProgressPath — сохранение прогресса просмотра (/items/{id}/progress): у каждого
+// пользователя он свой, поэтому доступен и зрителю.
+func isProgressPath(p string) bool {
+	segs := strings.Split(strings.Trim(p, "/"), "/")
+	return len(segs) == 3 && segs[0] == "items" && segs[2] == "progress"
+}
+
+// ownsTarget для участника проверяет, что задание или item из пути принадлежит ему.
+// Чужие объекты выглядят как несуществующие (404).
+func (d authDeps) ownsTarget(ctx context.Context, u *model.User, path string) bool {
+	if u.SeesAll() {
+		return true
+	}
+	jobID, itemID := targetIDs(path)
+	if itemID != "" {
+		it, err := d.items.GetByID(ctx, itemID)
+		if err != nil {
+			return true // несуществующий item: 404 отдаст обработчик
+		}
+		jobID = it.JobID
+	}
+	if jobID == "" {
+		return true
+	}
+	j, err := d.jobs.GetByID(ctx, jobID)
+	if err != nil {
+		return true
+	}
+	return u.Owns(j)
+}
+
+// targetIDs извлекает ID задания или item из пути вида /jobs/{id}/…, /items/{id}/…,
+// /queue/{id}, /queue/jobs/{id} и их аналогов под /api/v1.
+func targetIDs(path string) (jobID, itemID string) {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segs) >= 2 && segs[0] == "api" && segs[1] == "v1" {
+		segs = segs[2:]
+	}
+	if len(segs) < 2 {
+		return "", ""
+	}
+	switch segs[0] {
+	case "jobs":
+		return segs[1], ""
+	case "items":
+		if segs[1] == "deleted" || segs[1] == "meta-bulk" || segs[1] == "transcode" {
+			return "", ""
+		}
+		return "", segs[1]
+	case "queue":
+		if segs[1] == "jobs" && len(segs) == 3 {
+			return segs[2], ""
+		}
+		if len(segs) == 2 && !slices.Contains([]string{"items", "cancel-all", "pause", "resume"}, segs[1]) {
+			return segs[1], ""
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+// TestRoleAllows_Collections проверяет, что участник создаёт коллекции и наполняет их,
+// но не переименовывает и не удаляет общие коллекции.
+func TestRoleAllows_Collections(t *testing.T) {
+	member := &model.User{ID: "u1", Role: model.RoleMember}
+	admin := &model.User{ID: "a1", Role: model.RoleAdmin}
+	cases := []struct {
+		method, path string
+		member       bool
+	}{
+		{http.MethodGet, "/collections", true},
+		{http.MethodPost, "/collections", true},
+		{http.MethodPost, "/collections/c1/jobs", true},
+		{http.MethodPatch, "/collections/c1", false},
+		{http.MethodDelete, "/collections/c1", false},
+		{http.MethodGet, "/api/v1/collections", true},
+		{http.MethodPost, "/api/v1/collections", true},
+		{http.MethodPost, "/api/v1/collections/c1/jobs", true},
+		{http.MethodPatch, "/api/v1/collections/c1", false},
+		{http.MethodDelete, "/api/v1/collections/c1", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, c.path, nil)
+		if got := roleAllows(member, r); got != c.member {
+			t.Errorf("member %s %s: got %v, want %v", c.method, c.path, got, c.member)
+		}
+		if !roleAllows(admin, r) {
+			t.Errorf("admin %s %s: denied", c.method, c.path)
+		}
+	}
+}
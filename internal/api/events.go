@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/internal/sse"
+)
+
+// maxCachedOwners ограничивает кэш владельцев одного SSE-соединения.
+const maxCachedOwners = 1024
+
+// eventFilter решает, отправлять ли событие клиенту: участник получает только
+// события своих заданий, элементов и операций. Владельцы запоминаются на время
+// соединения — события прогресса приходят часто.
+type eventFilter struct {
+	user   *model.User
+	jobs   repo.JobRepo
+	items  repo.ItemRepo
+	ops    repo.OperationRepo
+	owners map[string]string // "job:ID" | "op:ID" → ID владельца
+}
+
+func newEventFilter(u *model.User, jobs repo.JobRepo, items repo.ItemRepo, ops repo.OperationRepo) *eventFilter {
+	return &eventFilter{user: u, jobs: jobs, items: items, ops: ops, owners: make(map[string]string)}
+}
+
+func (f *eventFilter) visible(ctx context.Context, e sse.Event) bool {
+	if f.user.SeesAll() {
+		return true
+	}
+	switch d := e.Data.(type) {
+	case sse.JobStatusData:
+		if d.ID == "" {
+			return true // «отменить всё» — без данных заданий
+		}
+		// Удалённого задания уже нет в базе: владельца знаем только из кэша,
+		// а событие о неизвестном задании не отправляем.
+		return f.ownsJob(ctx, d.ID)
+	case sse.JobProgressData:
+		return f.ownsJob(ctx, d.ID)
+	case sse.ItemData:
+		jobID := d.JobID
+		if jobID == "" {
+			it, err := f.items.GetByID(ctx, d.ID)
+			if err != nil {
+				return false
+			}
+			jobID = it.JobID
+		}
+		return f.ownsJob(ctx, jobID)
+	case sse.OperationData:
+		return f.ownsOperation(ctx, d.ID)
+	case sse.OperationProgressData:
+		return f.ownsOperation(ctx, d.ID)
+	}
+	return true // коллекции общие
+}
+
+func (f *eventFilter) ownsJob(ctx context.Context, id string) bool {
+	owner, ok := f.jobOwner(ctx, id)
+	return ok && owner == f.user.ID
+}
+
+func (f *eventFilter) jobOwner(ctx context.Context, id string) (string, bool) {
+	return f.owner("job:"+id, func() (string, error) {
+		j, err := f.jobs.GetByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return j.OwnerID, nil
+	})
+}
+
+func (f *eventFilter) ownsOperation(ctx context.Context, id string) bool {
+	owner, ok := f.owner("op:"+id, func() (string, error) {
+		op, err := f.ops.GetByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return op.OwnerID, nil
+	})
+	return ok && owner == f.user.ID
+}
+
+// owner возвращает владельца из кэша или через load; false — объект не найден.
+func (f *eventFilter) owner(key string, load func() (string, error)) (string, bool) {
+	if owner, ok := f.owners[key]; ok {
+		return owner, true
+	}
+	owner, err := load()
+	if err != nil {
+		return "", false
+	}
+	if len(f.owners) >= maxCachedOwners {
+		clear(f.owners)
+	}
+	f.owners[key] = owner
+	return owner, true
+}
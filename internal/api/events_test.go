@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dr-duke/talmorGo/internal/db"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/internal/sse"
+)
+
+// TestEventFilter_DeletedJob проверяет, что участник получает событие об удалении
+// только своего задания, уже удалённого из базы, и не узнаёт ID чужих.
+func TestEventFilter_DeletedJob(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+	jobs := repo.NewJobRepo(database)
+
+	mine := &model.Job{URL: "https://example.com/1", Status: model.JobPending, Source: "web", OwnerID: "u1"}
+	foreign := &model.Job{URL: "https://example.com/2", Status: model.JobPending, Source: "web", OwnerID: "u2"}
+	for _, j := range []*model.Job{mine, foreign} {
+		if err := jobs.Create(ctx, j); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+	}
+
+	member := &model.User{ID: "u1", Role: model.RoleMember}
+	f := newEventFilter(member, jobs, repo.NewItemRepo(database), repo.NewOperationRepo(database))
+	status := func(id, st string) sse.Event {
+		return sse.Event{Type: sse.JobStatus, Data: sse.JobStatusData{ID: id, Status: st}}
+	}
+	if !f.visible(ctx, status(mine.ID, string(model.JobPending))) {
+		t.Fatal("own job status hidden")
+	}
+	if f.visible(ctx, status(foreign.ID, string(model.JobPending))) {
+		t.Fatal("foreign job status visible")
+	}
+
+	for _, j := range []*model.Job{mine, foreign} {
+		if err := jobs.Purge(ctx, j.ID); err != nil {
+			t.Fatalf("purge: %v", err)
+		}
+	}
+	if !f.visible(ctx, status(mine.ID, "deleted")) {
+		t.Error("own deleted job hidden")
+	}
+	if f.visible(ctx, status(foreign.ID, "deleted")) {
+		t.Error("foreign deleted job visible")
+	}
+	if f.visible(ctx, status("unknown", "deleted")) {
+		t.Error("deleted job with unknown owner visible")
+	}
+}
@@ -0,0 +1,1011 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/auth"
+	"github.com/dr-duke/talmorGo/internal/config"
+	"github.com/dr-duke/talmorGo/internal/hls"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/playlist"
+	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/internal/sse"
+	"github.com/dr-duke/talmorGo/internal/storage"
+)
+
+const (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 500
+)
+
+// APIHandler — версионированный JSON API (/api/v1) для скриптов и интеграций.
+// В отличие от HTMX-обработчиков всегда отвечает JSON, ошибки — объектом apiError.
+type APIHandler struct {
+	Jobs        repo.JobRepo
+	Items       repo.ItemRepo
+	Tags        repo.TagRepo
+	Collections repo.CollectionRepo
+	Presets     repo.PresetRepo
+	Ops         repo.OperationRepo
+	Settings    repo.SettingsRepo
+	Storage     *storage.Storage
+	HLS         *hls.Cache
+	Pool        Enqueuer
+	Cfg         *config.Config
+	Expander    *playlist.Expander
+	Hub         *sse.Hub
+}
+
+// ── JSON-представления ───────────────────────────────────────────────────────
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiJob struct {
+	ID          string     `json:"id"`
+	URL         string     `json:"url"`
+	Domain      string     `json:"domain"`
+	Status      string     `json:"status"`
+	Title       string     `json:"title"`
+	Error       string     `json:"error,omitempty"`
+	ErrorClass  string     `json:"error_class,omitempty"`
+	Source      string     `json:"source"`
+	Hidden      bool       `json:"hidden"`
+	RetryCount  int        `json:"retry_count"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Tags        []string   `json:"tags,omitempty"`
+	Items       []apiItem  `json:"items,omitempty"`
+
+	PresetID string            `json:"preset_id,omitempty"`
+	Options  *model.JobOptions `json:"options,omitempty"`
+	Priority int               `json:"priority"`
+
+	Extractor   string `json:"extractor,omitempty"`
+	VideoID     string `json:"video_id,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+type apiItem struct {
+	ID        string     `json:"id"`
+	JobID     string     `json:"job_id"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Size      int64      `json:"size"`
+	Duration  int        `json:"duration"`
+	Meta      apiMeta    `json:"meta"`
+	Info      *apiInfo   `json:"info,omitempty"`
+	Available bool       `json:"available"`
+	StreamURL string     `json:"stream_url,omitempty"`
+	ThumbURL  string     `json:"thumb_url,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	LostAt    *time.Time `json:"lost_at,omitempty"`
+}
+
+type apiMeta struct {
+	Title  string `json:"title,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	Year   string `json:"year,omitempty"`
+	Genre  string `json:"genre,omitempty"`
+}
+
+// apiInfo — метаданные из info.json yt-dlp и параметры файла по ffprobe.
+type apiInfo struct {
+	Uploader    string          `json:"uploader,omitempty"`
+	Channel     string          `json:"channel,omitempty"`
+	UploadDate  string          `json:"upload_date,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Width       int             `json:"width,omitempty"`
+	Height      int             `json:"height,omitempty"`
+	VCodec      string          `json:"vcodec,omitempty"`
+	ACodec      string          `json:"acodec,omitempty"`
+	ViewCount   int64           `json:"view_count,omitempty"`
+	WebpageURL  string          `json:"webpage_url,omitempty"`
+	Chapters    []model.Chapter `json:"chapters,omitempty"`
+	Bitrate     int64           `json:"bitrate,omitempty"`
+	FPS         float64         `json:"fps,omitempty"`
+	Channels    int             `json:"channels,omitempty"`
+}
+
+type apiMedia struct {
+	Job   apiJob    `json:"job"`
+	Item  *apiItem  `json:"item,omitempty"`
+	Tags  []string  `json:"tags"`
+	Watch *apiWatch `json:"watch,omitempty"`
+}
+
+type apiWatch struct {
+	Position int  `json:"position"`
+	Watched  bool `json:"watched"`
+}
+
+type apiTag struct {
+	Name       string `json:"name"`
+	Count      int    `json:"count"`
+	Collection bool   `json:"collection"`
+}
+
+type apiCollection struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ItemCount int       `json:"item_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// apiPreset не раскрывает прокси и дополнительные аргументы — в них бывают пароли.
+type apiPreset struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Format       string   `json:"format,omitempty"`
+	OutputFormat string   `json:"output_format,omitempty"`
+	PostProcess  []string `json:"post_process,omitempty"`
+	Subfolder    string   `json:"subfolder,omitempty"`
+	IsDefault    bool     `json:"is_default"`
+}
+
+type apiOperation struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status"`
+	Title      string     `json:"title"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type apiAttempt struct {
+	Number       int        `json:"number"`
+	Status       string     `json:"status"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	ExitCode     *int       `json:"exit_code,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	ErrorClass   string     `json:"error_class,omitempty"`
+	Files        []string   `json:"files,omitempty"`
+	YtDlpVersion string     `json:"ytdlp_version,omitempty"`
+	Args         string     `json:"args"`
+	Log          string     `json:"log"`
+}
+
+type apiList[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func toAPIJob(j *model.Job) apiJob {
+	out := apiJob{
+		ID: j.ID, URL: j.URL, Domain: j.Domain(), Status: string(j.Status),
+		Title: j.Title, Error: j.Error, ErrorClass: string(j.ErrorClass), Source: j.Source, Hidden: j.Hidden,
+		RetryCount: j.RetryCount, NextRetryAt: j.NextRetryAt,
+		CreatedAt: j.CreatedAt, UpdatedAt: j.UpdatedAt,
+		PresetID: j.PresetID, Priority: j.Priority,
+		Extractor: j.Extractor, VideoID: j.VideoID, DuplicateOf: j.DuplicateOf,
+	}
+	if !j.Options.IsZero() {
+		out.Options = &j.Options
+	}
+	return out
+}
+
+func (h *APIHandler) toAPIItem(it *model.Item) apiItem {
+	out := apiItem{
+		ID: it.ID, JobID: it.JobID, Kind: it.Kind, Name: it.Name,
+		Size: it.Size, Duration: it.Duration, Meta: apiMeta(it.Meta),
+		Available: it.IsAvailable(),
+		CreatedAt: it.CreatedAt, DeletedAt: it.DeletedAt, LostAt: it.LostAt,
+	}
+	if !it.Info.IsZero() {
+		info := apiInfo(it.Info)
+		out.Info = &info
+	}
+	if out.Available {
+		out.StreamURL = strings.TrimRight(h.Cfg.BasePath, "/") + "/items/" + it.ID + "/stream"
+	}
+	if it.Thumb != "" {
+		out.ThumbURL = strings.TrimRight(h.Cfg.BasePath, "/") + "/items/" + it.ID + "/thumb"
+	}
+	return out
+}
+
+func toAPIOperation(op *model.Operation) apiOperation {
+	return apiOperation{
+		ID: op.ID, Kind: op.Kind, Status: string(op.Status), Title: op.Title, Error: op.Error,
+		CreatedAt: op.CreatedAt, StartedAt: op.StartedAt, FinishedAt: op.FinishedAt,
+	}
+}
+
+// ── helpers ──────────────────────────────────────────────────────────────────
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, struct {
+		Error apiError `json:"error"`
+	}{apiError{Code: code, Message: msg}})
+}
+
+// APIError — ошибка в формате API для кода вне обработчиков (auth-middleware).
+func APIError(w http.ResponseWriter, status int, code, msg string) {
+	writeAPIError(w, status, code, msg)
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// encodeCursor / decodeCursor — непрозрачный для клиента курсор: base64url("sort_key|row_id").
+// row_id — UUID без «|», поэтому ключ (например, имя файла) режется по последнему разделителю.
+func encodeCursor(c model.MediaCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Key + "|" + c.RowID))
+}
+
+func decodeCursor(s string) (*model.MediaCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndexByte(string(raw), '|')
+	if i < 0 || i == len(raw)-1 {
+		return nil, errors.New("malformed cursor")
+	}
+	return &model.MediaCursor{Key: string(raw[:i]), RowID: string(raw[i+1:])}, nil
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return apiDefaultLimit, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return min(n, apiMaxLimit), nil
+}
+
+// ── OpenAPI ──────────────────────────────────────────────────────────────────
+
+// OpenAPI отдаёт описание API (встроено в бинарь вместе со статикой).
+func (h *APIHandler) OpenAPI(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(spec) //nolint:errcheck
+	}
+}
+
+// NotFound — JSON-ответ для неизвестных путей под /api/v1/.
+func (h *APIHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, "not_found", "unknown endpoint "+r.Method+" "+r.URL.Path)
+}
+
+// ── Media ────────────────────────────────────────────────────────────────────
+
+// ListMedia — лента медиатеки (как в веб-интерфейсе) с курсорной пагинацией.
+func (h *APIHandler) ListMedia(w http.ResponseWriter, r *http.Request) {
+	f := parseMediaFilter(r)
+	if f.Kind != "" && f.Kind != "video" && f.Kind != "audio" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "kind must be video or audio")
+		return
+	}
+	if !f.Sort.Valid() {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "sort must be date, name, size, duration, domain or relevance")
+		return
+	}
+	if !f.Status.Valid() {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "unknown status")
+		return
+	}
+	limit, err := parseLimit(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		if f.After, err = decodeCursor(c); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_argument", "invalid cursor")
+			return
+		}
+	}
+	// Берём на одну строку больше, чтобы понять, есть ли следующая страница.
+	f.Limit = limit + 1
+	rows, err := h.Jobs.FilterMedia(r.Context(), f)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	out := apiList[apiMedia]{Data: make([]apiMedia, 0, min(len(rows), limit))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		out.NextCursor = encodeCursor(rows[len(rows)-1].Pos)
+	}
+	for _, m := range rows {
+		am := apiMedia{Job: toAPIJob(m.Job), Tags: m.Tags}
+		if am.Tags == nil {
+			am.Tags = []string{}
+		}
+		if m.Item != nil {
+			it := h.toAPIItem(m.Item)
+			am.Item = &it
+			am.Watch = &apiWatch{Position: m.Watch.Position, Watched: m.Watch.Watched}
+		}
+		out.Data = append(out.Data, am)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// ── Jobs ─────────────────────────────────────────────────────────────────────
+
+// ListJobs — задания, опционально по статусам (?status=pending,running).
+func (h *APIHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	f := repo.JobFilter{OwnerID: auth.UserFrom(r.Context()).OwnerScope()}
+	if v := r.URL.Query().Get("status"); v != "" {
+		for s := range strings.SplitSeq(v, ",") {
+			f.Statuses = append(f.Statuses, model.JobStatus(strings.TrimSpace(s)))
+		}
+	}
+	jobs, err := h.Jobs.List(r.Context(), f)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	out := apiList[apiJob]{Data: make([]apiJob, 0, len(jobs))}
+	for _, j := range jobs {
+		out.Data = append(out.Data, toAPIJob(j))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// CreateJob ставит URL в очередь. Проверка на плейлист идёт асинхронно, как в веб-форме:
+// ответ содержит placeholder-задание в статусе checking.
+func (h *APIHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URL      string           `json:"url"`
+		Tags     []string         `json:"tags"`
+		PresetID string           `json:"preset_id"`
+		Options  model.JobOptions `json:"options"`
+		Priority int              `json:"priority"`
+	}
+	if err := decodeJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	if _, err := url.ParseRequestURI(body.URL); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "url must be an absolute URL")
+		return
+	}
+	if msg := body.Options.Validate(); msg != "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", msg)
+		return
+	}
+	ctx := r.Context()
+	if body.PresetID != "" {
+		if _, err := h.Presets.GetByID(ctx, body.PresetID); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_argument", "unknown preset_id")
+			return
+		}
+	}
+	job := &model.Job{
+		URL: body.URL, Status: model.JobChecking, Source: "api", OwnerID: auth.UserFrom(ctx).ID,
+		Options: body.Options, PresetID: body.PresetID, Priority: clampPriority(auth.UserFrom(ctx), body.Priority),
+	}
+	if err := h.Jobs.Create(ctx, job); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	// Теги вешаются на placeholder: у плейлиста задания создаются заново
+	// и получают тег с названием плейлиста.
+	for _, name := range body.Tags {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if tag, err := h.Tags.Upsert(ctx, name); err == nil {
+			h.Tags.AddToJob(ctx, job.ID, tag.ID) //nolint:errcheck
+		}
+	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusOf(job))
+
+	opts := resolveExpanderOpts(ctx, h.Cfg, h.Settings)
+	// API не спрашивает о дубликатах: клиент сам решает по extractor и video_id.
+	go func() {
+		h.Expander.ResolvePlaceholder(context.Background(), job, opts, true)
+		h.Pool.Enqueue()
+	}()
+
+	writeJSON(w, http.StatusAccepted, toAPIJob(job))
+}
+
+// GetJob — задание вместе с элементами и тегами.
+func (h *APIHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	h.writeJob(w, r, http.StatusOK, job)
+}
+
+// UpdateJob меняет изменяемые поля задания: hidden и priority.
+func (h *APIHandler) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Hidden   *bool `json:"hidden"`
+		Priority *int  `json:"priority"`
+	}
+	if err := decodeJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	ctx := r.Context()
+	if body.Hidden != nil && *body.Hidden != job.Hidden {
+		var err error
+		if *body.Hidden {
+			err = h.Jobs.Hide(ctx, job.ID)
+		} else {
+			err = h.Jobs.Unhide(ctx, job.ID)
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		job.Hidden = *body.Hidden
+	}
+	if body.Priority != nil {
+		if p := clampPriority(auth.UserFrom(ctx), *body.Priority); p != job.Priority {
+			if err := h.Jobs.SetPriority(ctx, job.ID, p); err != nil {
+				writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+				return
+			}
+			job.Priority = p
+		}
+	}
+	h.writeJob(w, r, http.StatusOK, job)
+}
+
+// clampPriority ограничивает приоритет, который задаёт не администратор, диапазоном
+// от плейлистов до срочных ссылок: так участник не обгонит очередь остальных.
+func clampPriority(u *model.User, p int) int {
+	if u.Role == model.RoleAdmin {
+		return p
+	}
+	return min(max(p, model.PriorityPlaylist), model.PriorityUrgent)
+}
+
+// DeleteJob отменяет активное задание; с ?purge=true безвозвратно удаляет его вместе с файлами.
+func (h *APIHandler) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if r.URL.Query().Get("purge") == "true" {
+		if job.Status == model.JobRunning {
+			h.Pool.CancelJob(job.ID)
+		}
+		if items, err := h.Items.ListByJobID(ctx, job.ID); err == nil {
+			for _, it := range items {
+				h.Storage.DeleteItem(it) //nolint:errcheck
+			}
+		}
+		if err := h.Jobs.Purge(ctx, job.ID); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: job.ID, Status: "deleted"})
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if h.Pool.CancelJob(job.ID) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := h.Jobs.Cancel(ctx, job.ID); err != nil {
+		writeAPIError(w, http.StatusConflict, "conflict", "job is not active; use ?purge=true to delete it")
+		return
+	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: job.ID, Status: string(model.JobCancelled)})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RetryJob переводит упавшее задание обратно в pending.
+func (h *APIHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Jobs.ResetFailed(r.Context(), job.ID); err != nil {
+		writeAPIError(w, http.StatusConflict, "conflict", err.Error())
+		return
+	}
+	h.Pool.Enqueue()
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: job.ID, Status: string(model.JobPending)})
+	job.Status = model.JobPending
+	h.writeJob(w, r, http.StatusOK, job)
+}
+
+// PauseJob приостанавливает задание; скачиваемое переходит в paused асинхронно.
+func (h *APIHandler) PauseJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	if !h.Pool.PauseJob(job.ID) {
+		if err := h.Jobs.Pause(r.Context(), job.ID); err != nil {
+			writeAPIError(w, http.StatusConflict, "conflict", err.Error())
+			return
+		}
+		h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: job.ID, Status: string(model.JobPaused)})
+		job.Status = model.JobPaused
+	}
+	h.writeJob(w, r, http.StatusOK, job)
+}
+
+func (h *APIHandler) ResumeJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Jobs.Resume(r.Context(), job.ID); err != nil {
+		writeAPIError(w, http.StatusConflict, "conflict", err.Error())
+		return
+	}
+	h.Pool.Enqueue()
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: job.ID, Status: string(model.JobPending)})
+	job.Status = model.JobPending
+	h.writeJob(w, r, http.StatusOK, job)
+}
+
+// JobLog — лог последней попытки скачивания.
+func (h *APIHandler) JobLog(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	log, err := h.Jobs.GetLog(r.Context(), job.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"job_id": job.ID, "log": log})
+}
+
+// JobAttempts — история попыток скачивания с логом каждой.
+func (h *APIHandler) JobAttempts(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	attempts, err := h.Jobs.ListAttempts(r.Context(), job.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	out := make([]apiAttempt, 0, len(attempts))
+	for _, a := range attempts {
+		out = append(out, apiAttempt{
+			Number: a.Number, Status: string(a.Status), StartedAt: a.StartedAt, FinishedAt: a.FinishedAt,
+			ExitCode: a.ExitCode, Error: a.Error, ErrorClass: string(a.ErrorClass), Files: a.Files,
+			YtDlpVersion: a.YtDlpVersion, Args: a.Args, Log: a.Log,
+		})
+	}
+	writeJSON(w, http.StatusOK, apiList[apiAttempt]{Data: out})
+}
+
+// AddJobTag вешает тег на задание.
+func (h *APIHandler) AddJobTag(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &body); err != nil || strings.TrimSpace(body.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "name is required")
+		return
+	}
+	ctx := r.Context()
+	tag, err := h.Tags.Upsert(ctx, strings.TrimSpace(body.Name))
+	if err == nil {
+		err = h.Tags.AddToJob(ctx, job.ID, tag.ID)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	h.writeJob(w, r, http.StatusOK, job)
+}
+
+// RemoveJobTag снимает тег с задания.
+func (h *APIHandler) RemoveJobTag(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.loadJob(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Tags.RemoveFromJob(r.Context(), job.ID, r.PathValue("tag")); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	h.writeJob(w, r, http.StatusOK, job)
+}
+
+func (h *APIHandler) loadJob(w http.ResponseWriter, r *http.Request) (*model.Job, bool) {
+	job, err := h.Jobs.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "job not found")
+		return nil, false
+	}
+	return job, true
+}
+
+// writeJob отдаёт задание с актуальными элементами и тегами.
+func (h *APIHandler) writeJob(w http.ResponseWriter, r *http.Request, status int, job *model.Job) {
+	ctx := r.Context()
+	out := toAPIJob(job)
+	if items, err := h.Items.ListByJobID(ctx, job.ID); err == nil {
+		for _, it := range items {
+			out.Items = append(out.Items, h.toAPIItem(it))
+		}
+	}
+	out.Tags, _ = h.Tags.ListByJob(ctx, job.ID)
+	writeJSON(w, status, out)
+}
+
+// ── Items ────────────────────────────────────────────────────────────────────
+
+func (h *APIHandler) GetItem(w http.ResponseWriter, r *http.Request) {
+	item, err := h.Items.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "item not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.toAPIItem(item))
+}
+
+// UpdateItem переименовывает файл и/или меняет аудио-теги в БД.
+func (h *APIHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	item, err := h.Items.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "item not found")
+		return
+	}
+	var body struct {
+		Name *string           `json:"name"`
+		Meta map[string]string `json:"meta"`
+	}
+	if err := decodeJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	if body.Name != nil && *body.Name != item.Name {
+		newPath, err := h.Storage.Rename(item.Path, *body.Name)
+		if errors.Is(err, storage.ErrInvalidName) {
+			writeAPIError(w, http.StatusBadRequest, "invalid_argument", "invalid name")
+			return
+		}
+		if err == nil {
+			err = h.Items.Rename(ctx, item.ID, *body.Name, newPath)
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+	}
+	if len(body.Meta) > 0 {
+		for k := range body.Meta {
+			if !slices.Contains([]string{"title", "artist", "album", "year", "genre"}, k) {
+				writeAPIError(w, http.StatusBadRequest, "invalid_argument", "unknown meta field "+k)
+				return
+			}
+		}
+		if err := h.Items.BulkUpdateMetaFields(ctx, []string{item.ID}, body.Meta); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+	}
+	if item, err = h.Items.GetByID(ctx, item.ID); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, h.toAPIItem(item))
+}
+
+// DeleteItem — мягкое удаление: файл удаляется с диска, запись остаётся.
+func (h *APIHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	item, err := h.Items.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "not_found", "item not found")
+		return
+	}
+	h.Storage.DeleteItem(item) //nolint:errcheck
+	if err := h.Items.SoftDelete(ctx, item.ID); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	h.Hub.Publish(sse.ItemDeleted, sse.ItemOf(item))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ── Tags ─────────────────────────────────────────────────────────────────────
+
+// ListTags — теги со счётчиками; участник видит только теги своих заданий.
+func (h *APIHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Tags.ListWithCountFiltered(r.Context(), model.MediaFilter{
+		OwnerID: auth.UserFrom(r.Context()).OwnerScope(),
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	out := apiList[apiTag]{Data: make([]apiTag, 0, len(tags))}
+	for _, t := range tags {
+		out.Data = append(out.Data, apiTag{Name: t.Name, Count: t.Count, Collection: t.IsCollection})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// CreateTag создаёт тег и вешает его на задания job_ids (чужие пропускаются).
+// Тег без заданий удаляется при пересчёте тегов.
+func (h *APIHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name   string   `json:"name"`
+		JobIDs []string `json:"job_ids"`
+	}
+	if err := decodeJSON(r, &body); err != nil || strings.TrimSpace(body.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "name is required")
+		return
+	}
+	ctx := r.Context()
+	tag, err := h.Tags.Upsert(ctx, strings.TrimSpace(body.Name))
+	if err == nil && len(body.JobIDs) > 0 {
+		err = h.Tags.BulkAddToJobs(ctx, tag.ID, ownedJobIDs(ctx, h.Jobs, body.JobIDs))
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiTag{Name: tag.Name, Collection: tag.Kind == "collection"})
+}
+
+// UpdateTag переименовывает тег (только администратор: теги общие для всех).
+func (h *APIHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &body); err != nil || strings.TrimSpace(body.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "name is required")
+		return
+	}
+	writeTagResult(w, h.Tags.Rename(r.Context(), r.PathValue("name"), strings.TrimSpace(body.Name)))
+}
+
+// DeleteTag удаляет тег со всех заданий (только администратор).
+func (h *APIHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	writeTagResult(w, h.Tags.Delete(r.Context(), r.PathValue("name")))
+}
+
+// writeTagResult — ответ на изменение тега: 204 или ошибка репозитория.
+func writeTagResult(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeAPIError(w, http.StatusNotFound, "not_found", "tag not found (collections are managed via /collections)")
+	case errors.Is(err, repo.ErrTagExists):
+		writeAPIError(w, http.StatusConflict, "conflict", err.Error())
+	case err != nil:
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ── Collections ──────────────────────────────────────────────────────────────
+
+func (h *APIHandler) ListCollections(w http.ResponseWriter, r *http.Request) {
+	cols, err := h.Collections.List(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	out := apiList[apiCollection]{Data: make([]apiCollection, 0, len(cols))}
+	for _, c := range cols {
+		out.Data = append(out.Data, apiCollection{ID: c.ID, Name: c.Name, ItemCount: c.ItemCount, CreatedAt: c.CreatedAt})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// ListPresets — пресеты загрузки для поля preset_id при создании задания.
+func (h *APIHandler) ListPresets(w http.ResponseWriter, r *http.Request) {
+	presets, err := h.Presets.List(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	out := apiList[apiPreset]{Data: make([]apiPreset, 0, len(presets))}
+	for _, p := range presets {
+		out.Data = append(out.Data, apiPreset{
+			ID: p.ID, Name: p.Name, Format: p.Format, OutputFormat: p.OutputFormat,
+			PostProcess: p.PostProcess, Subfolder: p.Subfolder, IsDefault: p.IsDefault,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *APIHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &body); err != nil || strings.TrimSpace(body.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "name is required")
+		return
+	}
+	col, err := h.Collections.Create(r.Context(), strings.TrimSpace(body.Name))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: col.ID, Action: "created"})
+	writeJSON(w, http.StatusCreated, apiCollection{ID: col.ID, Name: col.Name, ItemCount: col.ItemCount, CreatedAt: col.CreatedAt})
+}
+
+func (h *APIHandler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := decodeJSON(r, &body); err != nil || strings.TrimSpace(body.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "name is required")
+		return
+	}
+	if err := h.Collections.Rename(r.Context(), id, strings.TrimSpace(body.Name)); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: id, Action: "renamed"})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.Collections.Delete(r.Context(), id); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: id, Action: "deleted"})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIHandler) AddCollectionJobs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var body struct {
+		JobIDs []string `json:"job_ids"`
+	}
+	if err := decodeJSON(r, &body); err != nil || len(body.JobIDs) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "job_ids is required")
+		return
+	}
+	body.JobIDs = ownedJobIDs(r.Context(), h.Jobs, body.JobIDs)
+	if err := h.Collections.AddJobs(r.Context(), id, body.JobIDs); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: id, Action: "jobs_added"})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ── Operations ───────────────────────────────────────────────────────────────
+
+func (h *APIHandler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	var kinds []string
+	if v := r.URL.Query().Get("kind"); v != "" {
+		kinds = strings.Split(v, ",")
+	}
+	list, err := h.Ops.List(r.Context(), kinds, auth.UserFrom(r.Context()).OwnerScope())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	out := apiList[apiOperation]{Data: make([]apiOperation, 0, len(list))}
+	for _, op := range list {
+		out.Data = append(out.Data, toAPIOperation(op))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *APIHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	op, err := h.Ops.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || !auth.UserFrom(r.Context()).SeesOperation(op) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "operation not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIOperation(op))
+}
+
+func (h *APIHandler) DeleteOperation(w http.ResponseWriter, r *http.Request) {
+	op, err := h.Ops.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || !auth.UserFrom(r.Context()).SeesOperation(op) {
+		writeAPIError(w, http.StatusNotFound, "not_found", "operation not found")
+		return
+	}
+	if op.Status == model.OpPending || op.Status == model.OpRunning {
+		writeAPIError(w, http.StatusConflict, "conflict", "operation is still "+string(op.Status))
+		return
+	}
+	if err := h.Ops.Delete(r.Context(), op.ID); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ── Settings ─────────────────────────────────────────────────────────────────
+
+type apiSetting struct {
+	Value   string `json:"value"`
+	Default string `json:"default"`
+}
+
+// GetSettings — runtime-настройки: сохранённое значение и значение из конфига.
+func (h *APIHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	saved, err := h.Settings.All(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	defaults := runtimeDefaults(h.Cfg)
+	out := make(map[string]apiSetting, len(runtimeSettingKeys))
+	for _, k := range runtimeSettingKeys {
+		out[k] = apiSetting{Value: saved[k], Default: defaults[k]}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// UpdateSettings сохраняет переданные ключи; пустая строка сбрасывает к значению из конфига.
+func (h *APIHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var body map[string]string
+	if err := decodeJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	for k := range body {
+		if !slices.Contains(runtimeSettingKeys, k) {
+			writeAPIError(w, http.StatusBadRequest, "invalid_argument", "unknown setting "+k)
+			return
+		}
+	}
+	if _, err := model.ParseSchedule(body["download_schedule"]); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_argument", "download_schedule: "+err.Error())
+		return
+	}
+	for k, v := range body {
+		if err := h.Settings.Set(r.Context(), k, strings.TrimSpace(v)); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+	}
+	h.GetSettings(w, r)
+}
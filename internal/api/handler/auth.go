@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/auth"
+	"github.com/dr-duke/talmorGo/internal/config"
+	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/web/templates"
+)
+
+// SessionCookie — имя cookie с секретом сессии пользователя.
+const SessionCookie = "_session"
+
+// sessionTTL — срок жизни сессии после входа.
+const sessionTTL = 30 * 24 * time.Hour
+
+type AuthHandler struct {
+	Users    repo.UserRepo
+	Cfg      *config.Config
+	SiteName string
+}
+
+// LoginPage отдаёт форму входа.
+func (h *AuthHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	templ.Handler(templates.LoginPage(h.Cfg.BasePath, h.SiteName, "")).ServeHTTP(w, r)
+}
+
+// Login проверяет логин и пароль, открывает сессию и возвращает на главную.
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	u, err := h.Users.GetByUsername(ctx, strings.TrimSpace(r.FormValue("username")))
+	if err != nil || !auth.CheckPassword(u.PasswordHash, r.FormValue("password")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		templ.Handler(templates.LoginPage(h.Cfg.BasePath, h.SiteName, "Неверный логин или пароль")).ServeHTTP(w, r)
+		return
+	}
+	secret, err := h.Users.CreateSession(ctx, u.ID, sessionTTL)
+	if err != nil {
+		slog.Error("auth: create session", "user", u.Username, "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    secret,
+		Path:     h.cookiePath(),
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.cookiePath(), http.StatusSeeOther)
+}
+
+// Logout закрывает текущую сессию.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(SessionCookie); err == nil {
+		if err := h.Users.DeleteSession(r.Context(), c.Value); err != nil {
+			slog.Warn("auth: delete session", "err", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Path: h.cookiePath(), MaxAge: -1})
+	w.Header().Set("HX-Redirect", "login")
+	http.Redirect(w, r, "login", http.StatusSeeOther)
+}
+
+func (h *AuthHandler) cookiePath() string {
+	return strings.TrimRight(h.Cfg.BasePath, "/") + "/"
+}
+
+// ownedJobIDs оставляет из ids только задания, которыми управляет текущий пользователь.
+func ownedJobIDs(ctx context.Context, jobs repo.JobRepo, ids []string) []string {
+	u := auth.UserFrom(ctx)
+	if u.OwnerScope() == "" {
+		return ids
+	}
+	out := ids[:0:0]
+	for _, id := range ids {
+		if j, err := jobs.GetByID(ctx, id); err == nil && u.Owns(j) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// ownedItemIDs — то же для items: проверяется владелец задания, к которому относится item.
+func ownedItemIDs(ctx context.Context, jobs repo.JobRepo, items repo.ItemRepo, ids []string) []string {
+	u := auth.UserFrom(ctx)
+	if u.OwnerScope() == "" {
+		return ids
+	}
+	out := ids[:0:0]
+	for _, id := range ids {
+		it, err := items.GetByID(ctx, id)
+		if err != nil {
+			continue
+		}
+		if j, err := jobs.GetByID(ctx, it.JobID); err == nil && u.Owns(j) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -6,11 +6,14 @@ import (
 
 	"github.com/a-h/templ"
 	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/internal/sse"
 	"github.com/dr-duke/talmorGo/web/templates"
 )
 
 type CollectionHandler struct {
 	Collections repo.CollectionRepo
+	Jobs        repo.JobRepo
+	Hub         *sse.Hub
 }
 
 // List отдаёт JSON-список коллекций (для dropdown в action bar).
@@ -48,6 +51,7 @@ func (h *CollectionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: col.ID, Action: "created"})
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("HX-Trigger", "collectionsRefresh")
 	w.WriteHeader(http.StatusCreated)
@@ -61,6 +65,7 @@ func (h *CollectionHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: id, Action: "deleted"})
 	w.Header().Set("HX-Trigger", `{"collectionsRefresh":true,"tagsRefresh":true}`)
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -79,6 +84,7 @@ func (h *CollectionHandler) Rename(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: id, Action: "renamed"})
 	w.Header().Set("HX-Trigger", `{"collectionsRefresh":true,"tagsRefresh":true}`)
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -93,10 +99,12 @@ func (h *CollectionHandler) AddJobs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
+	body.JobIDs = ownedJobIDs(r.Context(), h.Jobs, body.JobIDs)
 	if err := h.Collections.AddJobs(r.Context(), id, body.JobIDs); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.Hub.Publish(sse.CollectionChanged, sse.CollectionData{ID: id, Action: "jobs_added"})
 	w.Header().Set("HX-Trigger", `{"collectionsRefresh":true,"tagsRefresh":true,"mediaRefresh":true,"showToast":"Добавлено в коллекцию"}`)
 	w.WriteHeader(http.StatusNoContent)
 }
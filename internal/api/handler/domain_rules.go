@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/web/templates"
+)
+
+// CreateDomainRule добавляет правило для домена.
+func (h *SettingsHandler) CreateDomainRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.domainRuleFromForm(w, r)
+	if !ok {
+		return
+	}
+	if err := h.DomainRules.Create(r.Context(), rule); err != nil {
+		slog.Warn("settings: create domain rule", "err", err)
+		h.renderDomainRules(w, r, "Правило для "+rule.Pattern+" уже есть")
+		return
+	}
+	h.renderDomainRules(w, r, "")
+}
+
+// UpdateDomainRule сохраняет изменённое правило.
+func (h *SettingsHandler) UpdateDomainRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.domainRuleFromForm(w, r)
+	if !ok {
+		return
+	}
+	rule.ID = r.PathValue("id")
+	if err := h.DomainRules.Update(r.Context(), rule); err != nil {
+		slog.Warn("settings: update domain rule", "id", rule.ID, "err", err)
+		h.renderDomainRules(w, r, "Не удалось сохранить правило для "+rule.Pattern)
+		return
+	}
+	h.renderDomainRules(w, r, "")
+}
+
+func (h *SettingsHandler) DeleteDomainRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.DomainRules.Delete(r.Context(), r.PathValue("id")); err != nil {
+		slog.Error("settings: delete domain rule", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderDomainRules(w, r, "")
+}
+
+// domainRuleFromForm читает правило из формы; при ошибке сам отвечает секцией с сообщением.
+func (h *SettingsHandler) domainRuleFromForm(w http.ResponseWriter, r *http.Request) (*model.DomainRule, bool) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return nil, false
+	}
+	rule := &model.DomainRule{
+		Pattern:  model.NormalizeDomainPattern(r.FormValue("pattern")),
+		Proxy:    strings.TrimSpace(r.FormValue("proxy")),
+		PresetID: r.FormValue("preset_id"),
+		Cookies:  r.FormValue("cookies") != "",
+	}
+	if rule.Pattern == "" || !strings.Contains(rule.Pattern, ".") {
+		h.renderDomainRules(w, r, "Укажите домен, например youtube.com")
+		return nil, false
+	}
+	var err error
+	if rule.MaxParallel, err = formInt(r, "max_parallel"); err != nil {
+		h.renderDomainRules(w, r, "Некорректное число параллельных загрузок")
+		return nil, false
+	}
+	delay, err := formInt(r, "min_delay")
+	if err != nil {
+		h.renderDomainRules(w, r, "Некорректная пауза между загрузками")
+		return nil, false
+	}
+	rule.MinDelay = time.Duration(delay) * time.Second
+	return rule, true
+}
+
+// formInt читает неотрицательное целое поле формы; пустое — 0.
+func formInt(r *http.Request, key string) (int, error) {
+	v := strings.TrimSpace(r.FormValue(key))
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err == nil && n < 0 {
+		err = strconv.ErrRange
+	}
+	return n, err
+}
+
+func (h *SettingsHandler) renderDomainRules(w http.ResponseWriter, r *http.Request, errMsg string) {
+	rules, err := h.DomainRules.List(r.Context())
+	if err != nil {
+		slog.Warn("settings: list domain rules", "err", err)
+	}
+	presets, _ := h.Presets.List(r.Context())
+	templ.Handler(templates.DomainRulesSection(rules, presets, errMsg)).ServeHTTP(w, r)
+}
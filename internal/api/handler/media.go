@@ -9,14 +9,19 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/auth"
 	"github.com/dr-duke/talmorGo/internal/config"
+	"github.com/dr-duke/talmorGo/internal/hls"
 	"github.com/dr-duke/talmorGo/internal/model"
 	"github.com/dr-duke/talmorGo/internal/ops"
 	"github.com/dr-duke/talmorGo/internal/playlist"
 	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/internal/sse"
 	"github.com/dr-duke/talmorGo/internal/storage"
+	"github.com/dr-duke/talmorGo/internal/transcode"
 	"github.com/dr-duke/talmorGo/web/templates"
 )
 
@@ -39,6 +44,8 @@ type MediaHandler struct {
 	Expander    *playlist.Expander
 	Ops         repo.OperationRepo
 	OpsWorker   OpsEnqueuer
+	Hub         *sse.Hub
+	HLS         *hls.Cache
 }
 
 // LibrarySidebar отдаёт HTML-фрагмент сайдбара с коллекциями (для обновления после изменения коллекций).
@@ -52,39 +59,60 @@ func (h *MediaHandler) LibrarySidebar(w http.ResponseWriter, r *http.Request) {
 }
 
 // LibraryItems — фрагмент списка элементов (HTMX, поддерживает фильтрацию).
+// С cursor отдаёт только следующую страницу строк — её подгружает последняя строка списка.
 func (h *MediaHandler) LibraryItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	f := parseMediaFilter(r)
-	f.Limit = h.resolvePageSize(ctx)
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		after, err := decodeCursor(c)
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		f.After = after
+	}
+	limit := h.resolvePageSize(ctx)
+	if limit > 0 {
+		// Берём на одну строку больше, чтобы понять, есть ли следующая страница.
+		f.Limit = limit + 1
+	}
 
 	items, err := h.Jobs.FilterMedia(ctx, f)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	next := ""
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+		next = encodeCursor(items[len(items)-1].Pos)
+	}
+	if f.After != nil {
+		templ.Handler(templates.ItemPage(items, next)).ServeHTTP(w, r)
+		return
+	}
 
 	total := len(items)
-	if len(items) >= f.Limit {
+	if next != "" {
 		total, _ = h.Jobs.CountMedia(ctx, f)
 	}
 
-	templ.Handler(templates.ItemList(items, total, f)).ServeHTTP(w, r)
+	templ.Handler(templates.ItemList(items, total, next)).ServeHTTP(w, r)
 }
 
 // TagsFragment отдаёт HTML-фрагмент облака тегов с учётом текущего фильтра.
 func (h *MediaHandler) TagsFragment(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	f := parseMediaFilter(r)
-	tagCounts, err := h.Tags.ListWithCountFiltered(ctx, f)
+	// Исключённые теги облако не учитывает — иначе их чипы пропали бы и исключение не снять.
+	counted := f
+	counted.NotTags = nil
+	tagCounts, err := h.Tags.ListWithCountFiltered(ctx, counted)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	activeTag := ""
-	if len(f.Tags) > 0 {
-		activeTag = f.Tags[0]
-	}
-	templ.Handler(templates.TagCloud(tagCounts, activeTag)).ServeHTTP(w, r)
+	templ.Handler(templates.TagCloud(tagCounts, f)).ServeHTTP(w, r)
 }
 
 // PlaylistItems возвращает JSON-список {stream, title} для всех видео, соответствующих
@@ -102,8 +130,11 @@ func (h *MediaHandler) PlaylistItems(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type entry struct {
-		Stream string `json:"stream"`
-		Title  string `json:"title"`
+		Stream   string   `json:"stream"`
+		Title    string   `json:"title"`
+		Subs     []string `json:"subs"`
+		HLS      string   `json:"hls,omitempty"`
+		Position int      `json:"position,omitempty"` // продолжить с этой секунды
 	}
 	basePath := strings.TrimRight(h.Cfg.BasePath, "/")
 	result := make([]entry, 0, len(items))
@@ -111,10 +142,16 @@ func (h *MediaHandler) PlaylistItems(w http.ResponseWriter, r *http.Request) {
 		if mi.Item == nil || !mi.Item.IsAvailable() {
 			continue
 		}
-		result = append(result, entry{
-			Stream: basePath + "/items/" + mi.Item.ID + "/stream",
-			Title:  mi.DisplayTitle(),
-		})
+		e := entry{
+			Stream:   basePath + "/items/" + mi.Item.ID + "/stream",
+			Title:    mi.DisplayTitle(),
+			Subs:     mi.Subtitles,
+			Position: mi.Watch.Position,
+		}
+		if !mi.Item.DirectPlay() {
+			e.HLS = basePath + "/items/" + mi.Item.ID + "/hls/master.m3u8"
+		}
+		result = append(result, e)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result) //nolint:errcheck
@@ -142,11 +179,13 @@ func (h *MediaHandler) BulkTag(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
+	body.JobIDs = ownedJobIDs(r.Context(), h.Jobs, body.JobIDs)
 	payload, _ := json.Marshal(body)
 	op := &model.Operation{
 		Kind:    ops.KindBulkTag,
 		Title:   fmt.Sprintf("Тег «%s» → %d заданий", body.TagName, len(body.JobIDs)),
 		Payload: string(payload),
+		OwnerID: auth.UserFrom(r.Context()).ID,
 	}
 	if err := h.Ops.Create(r.Context(), op); err != nil {
 		slog.Error("bulk tag: create op", "err", err)
@@ -167,11 +206,13 @@ func (h *MediaHandler) BulkHide(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
+	body.JobIDs = ownedJobIDs(r.Context(), h.Jobs, body.JobIDs)
 	payload, _ := json.Marshal(body)
 	op := &model.Operation{
 		Kind:    ops.KindBulkHide,
 		Title:   fmt.Sprintf("Скрыть %d заданий", len(body.JobIDs)),
 		Payload: string(payload),
+		OwnerID: auth.UserFrom(r.Context()).ID,
 	}
 	if err := h.Ops.Create(r.Context(), op); err != nil {
 		slog.Error("bulk hide: create op", "err", err)
@@ -184,17 +225,59 @@ func (h *MediaHandler) BulkHide(w http.ResponseWriter, r *http.Request) {
 }
 
 func parseMediaFilter(r *http.Request) model.MediaFilter {
-	var tags []string
-	for _, t := range r.URL.Query()["tag"] {
-		if t != "" {
-			tags = append(tags, t)
+	q := r.URL.Query()
+	watch := q.Get("watch")
+	f := model.MediaFilter{
+		Query:      q.Get("q"),
+		Kind:       q.Get("kind"),
+		Tags:       nonEmpty(q["tag"]),
+		AnyTags:    nonEmpty(q["any_tag"]),
+		NotTags:    nonEmpty(q["not_tag"]),
+		OwnerID:    auth.UserFrom(r.Context()).OwnerScope(),
+		ViewerID:   auth.UserFrom(r.Context()).ID,
+		Unwatched:  watch == "unwatched",
+		InProgress: watch == "in_progress",
+		Domain:     model.NormalizeDomainPattern(q.Get("domain")),
+		Source:     q.Get("source"),
+		Status:     model.MediaStatus(q.Get("status")),
+	}
+	// Без явной сортировки поиск выдаёт сначала самые подходящие строки.
+	switch sort := q.Get("sort"); sort {
+	case "":
+		if f.Query != "" {
+			f.Sort = model.SortRelevance
+		}
+	case "date":
+	default:
+		f.Sort = model.MediaSort(sort)
+	}
+	f.MinSize, _ = strconv.ParseInt(q.Get("min_size"), 10, 64)
+	f.MaxSize, _ = strconv.ParseInt(q.Get("max_size"), 10, 64)
+	// Даты — дни по местному времени сервера; «по» включает весь день.
+	if d, err := time.ParseInLocation(time.DateOnly, q.Get("from"), time.Local); err == nil {
+		f.From = d
+	}
+	if d, err := time.ParseInLocation(time.DateOnly, q.Get("to"), time.Local); err == nil {
+		f.To = d.AddDate(0, 0, 1)
+	}
+	switch q.Get("order") {
+	case "asc":
+		f.Asc = true
+	case "desc":
+	default:
+		f.Asc = f.Sort.DefaultAsc()
+	}
+	return f
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
 		}
 	}
-	return model.MediaFilter{
-		Query: r.URL.Query().Get("q"),
-		Kind:  r.URL.Query().Get("kind"),
-		Tags:  tags,
-	}
+	return out
 }
 
 // Stream отдаёт медиаэлемент (с Range-поддержкой).
@@ -215,6 +298,140 @@ func (h *MediaHandler) Stream(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, item.Path)
 }
 
+// Progress сохраняет позицию плеера (position, секунды): плеер шлёт её во время
+// просмотра и при закрытии. Отметка «просмотрено» ставится сама у конца файла;
+// параметр watched=true|false ставит или снимает её явно (пункт меню строки).
+// Прогресс у каждого пользователя свой.
+func (h *MediaHandler) Progress(w http.ResponseWriter, r *http.Request) {
+	item, err := h.Items.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	userID := auth.UserFrom(r.Context()).ID
+	if v := r.FormValue("watched"); v != "" {
+		if err := h.Items.SetWatched(r.Context(), userID, item.ID, v == "true"); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("HX-Trigger", "mediaRefresh")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	pos, err := strconv.ParseFloat(r.FormValue("position"), 64)
+	if err != nil || pos < 0 {
+		http.Error(w, "bad position", http.StatusBadRequest)
+		return
+	}
+	position := int(pos)
+	if err := h.Items.SaveWatchPosition(r.Context(), userID, item.ID, position, model.WatchedAt(position, item.Duration)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HLSMaster отдаёт мастер-плейлист для видео, которое браузер не воспроизводит сам.
+func (h *MediaHandler) HLSMaster(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.hlsItem(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(h.HLS.Master(item.Info.Width, item.Info.Height)) //nolint:errcheck
+}
+
+// HLSFile отдаёт плейлист варианта (index.m3u8) или его сегмент; первый запрос
+// варианта запускает перекодирование.
+func (h *MediaHandler) HLSFile(w http.ResponseWriter, r *http.Request) {
+	item, ok := h.hlsItem(w, r)
+	if !ok {
+		return
+	}
+	height, err := strconv.Atoi(r.PathValue("height"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var path string
+	if file := r.PathValue("file"); file == "index.m3u8" {
+		path, err = h.HLS.Playlist(r.Context(), item.ID, item.Path, height)
+		// Плейлист дописывается, пока идёт перекодирование.
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		path, err = h.HLS.Segment(r.Context(), item.ID, height, file)
+		w.Header().Set("Content-Type", "video/mp2t")
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+	}
+	if errors.Is(err, hls.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.Warn("hls: serve", "item_id", item.ID, "height", height, "err", err)
+		http.Error(w, "transcoding failed", http.StatusInternalServerError)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+func (h *MediaHandler) hlsItem(w http.ResponseWriter, r *http.Request) (*model.Item, bool) {
+	if h.HLS == nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	item, err := h.Items.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || !item.IsVideo() {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if !item.IsAvailable() {
+		http.Error(w, "item not available", http.StatusGone)
+		return nil, false
+	}
+	return item, true
+}
+
+// Thumb отдаёт превью элемента. Превью у элемента не меняется, поэтому браузер кеширует его на неделю.
+func (h *MediaHandler) Thumb(w http.ResponseWriter, r *http.Request) {
+	item, err := h.Items.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || item.Thumb == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=604800")
+	http.ServeFile(w, r, item.Thumb)
+}
+
+// Subtitles отдаёт субтитры элемента в WebVTT для плеера: /items/{id}/subtitles/{lang}.vtt.
+func (h *MediaHandler) Subtitles(w http.ResponseWriter, r *http.Request) {
+	lang, ok := strings.CutSuffix(r.PathValue("file"), ".vtt")
+	if !ok || !model.ValidSubLang(lang) {
+		http.NotFound(w, r)
+		return
+	}
+	sub, err := h.Items.GetSubtitle(r.Context(), r.PathValue("id"), lang)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeFile(w, r, sub.Path)
+}
+
+// Info отдаёт фрагмент с описанием, главами и ссылкой на источник для диалога плеера.
+func (h *MediaHandler) Info(w http.ResponseWriter, r *http.Request) {
+	item, err := h.Items.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	templ.Handler(templates.ItemInfo(item)).ServeHTTP(w, r)
+}
+
 // Delete — мягкое удаление: файл удаляется с диска, запись остаётся в БД.
 func (h *MediaHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -223,11 +440,12 @@ func (h *MediaHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "item not found", http.StatusNotFound)
 		return
 	}
-	h.Storage.Delete(item.Path) //nolint:errcheck
+	h.Storage.DeleteItem(item) //nolint:errcheck
 	if err := h.Items.SoftDelete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.Hub.Publish(sse.ItemDeleted, sse.ItemOf(item))
 	w.Header().Set("HX-Trigger", "mediaRefresh")
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -237,15 +455,14 @@ func (h *MediaHandler) PurgeJob(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
 	if items, err := h.Items.ListByJobID(r.Context(), jobID); err == nil {
 		for _, item := range items {
-			if item.IsAvailable() {
-				h.Storage.Delete(item.Path) //nolint:errcheck
-			}
+			h.Storage.DeleteItem(item) //nolint:errcheck
 		}
 	}
 	if err := h.Jobs.Purge(r.Context(), jobID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: jobID, Status: "deleted"})
 	w.Header().Set("HX-Trigger", "mediaRefresh")
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -329,6 +546,7 @@ func (h *MediaHandler) UpdateMeta(w http.ResponseWriter, r *http.Request) {
 		Kind:    ops.KindUpdateMeta,
 		Title:   "Теги аудио → 1 файл",
 		Payload: string(payload),
+		OwnerID: auth.UserFrom(r.Context()).ID,
 	}
 	if err := h.Ops.Create(r.Context(), op); err != nil {
 		slog.Error("update meta: create op", "err", err)
@@ -350,6 +568,7 @@ func (h *MediaHandler) BulkMeta(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
+	req.ItemIDs = ownedItemIDs(r.Context(), h.Jobs, h.Items, req.ItemIDs)
 	if len(req.ItemIDs) == 0 || len(req.Fields) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -359,6 +578,7 @@ func (h *MediaHandler) BulkMeta(w http.ResponseWriter, r *http.Request) {
 		Kind:    ops.KindBulkMeta,
 		Title:   fmt.Sprintf("Теги аудио → %d файлов", len(req.ItemIDs)),
 		Payload: string(payload),
+		OwnerID: auth.UserFrom(r.Context()).ID,
 	}
 	if err := h.Ops.Create(r.Context(), op); err != nil {
 		slog.Error("bulk meta: create op", "err", err)
@@ -370,6 +590,57 @@ func (h *MediaHandler) BulkMeta(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// Transcode ставит в очередь перекодирование видео по пресету — из меню строки
+// (один элемент) или для выделения.
+func (h *MediaHandler) Transcode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ItemIDs []string `json:"item_ids"`
+		Preset  string   `json:"preset"`
+		Replace bool     `json:"replace"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	preset, ok := transcode.Find(req.Preset)
+	if !ok {
+		http.Error(w, "unknown preset", http.StatusBadRequest)
+		return
+	}
+	var name string
+	ids := req.ItemIDs[:0:0]
+	for _, id := range ownedItemIDs(r.Context(), h.Jobs, h.Items, req.ItemIDs) {
+		if it, err := h.Items.GetByID(r.Context(), id); err == nil && it.IsAvailable() && it.IsVideo() {
+			ids, name = append(ids, id), it.Name
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "no video items", http.StatusBadRequest)
+		return
+	}
+	req.ItemIDs = ids
+	title := fmt.Sprintf("%s → %d файлов", preset.Title, len(ids))
+	if len(ids) == 1 {
+		title = preset.Title + ": " + name
+	}
+	payload, _ := json.Marshal(req)
+	op := &model.Operation{
+		Kind:    ops.KindTranscode,
+		Title:   title,
+		Payload: string(payload),
+		OwnerID: auth.UserFrom(r.Context()).ID,
+	}
+	if err := h.Ops.Create(r.Context(), op); err != nil {
+		slog.Error("transcode: create op", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	h.OpsWorker.Enqueue()
+	trigger, _ := json.Marshal(map[string]any{"showToast": "Перекодирование запущено"})
+	w.Header().Set("HX-Trigger", string(trigger))
+	w.WriteHeader(http.StatusAccepted)
+}
+
 // CreateLink создаёт или возвращает presigned-ссылку на элемент.
 func (h *MediaHandler) CreateLink(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -384,37 +655,116 @@ func (h *MediaHandler) CreateLink(w http.ResponseWriter, r *http.Request) {
 
 // Redownload сбрасывает задание, удаляет все элементы и инициирует повторную загрузку.
 func (h *MediaHandler) Redownload(w http.ResponseWriter, r *http.Request) {
-	jobID := r.PathValue("id")
-	job, err := h.Jobs.GetByID(r.Context(), jobID)
+	job, err := h.Jobs.GetByID(r.Context(), r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "job not found", http.StatusNotFound)
 		return
 	}
-	if items, err := h.Items.ListByJobID(r.Context(), jobID); err == nil {
+	if err := h.redownload(r.Context(), job); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Trigger", "mediaRefresh")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MediaHandler) redownload(ctx context.Context, job *model.Job) error {
+	if items, err := h.Items.ListByJobID(ctx, job.ID); err == nil {
 		for _, item := range items {
-			h.Storage.Delete(item.Path) //nolint:errcheck
+			h.Storage.DeleteItem(item) //nolint:errcheck
 		}
 	}
-	if err := h.Items.DeleteAllByJobID(r.Context(), jobID); err != nil {
-		slog.Warn("media: delete items for redownload", "job_id", jobID, "err", err)
+	if err := h.Items.DeleteAllByJobID(ctx, job.ID); err != nil {
+		slog.Warn("media: delete items for redownload", "job_id", job.ID, "err", err)
 	}
-	if err := h.Jobs.Redownload(r.Context(), jobID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if err := h.Jobs.Redownload(ctx, job.ID); err != nil {
+		return err
 	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: job.ID, Status: string(model.JobChecking)})
 
-	if h.Cfg != nil {
-		opts := resolveExpanderOpts(r.Context(), h.Cfg, h.Settings)
-		go func(id, rawURL string) {
-			h.Expander.ResolvePlaceholder(context.Background(), id, rawURL, opts, "web", 0)
-			h.Pool.Enqueue()
-		}(jobID, job.URL)
-	} else {
-		h.Jobs.ConfirmSingle(context.Background(), jobID) //nolint:errcheck
+	// Повтор инициирован из веба: уведомления в Telegram не нужны.
+	placeholder := *job
+	placeholder.Source, placeholder.ChatID = "web", 0
+	h.resolve(ctx, &placeholder)
+	return nil
+}
+
+// resolve в фоне проверяет placeholder на плейлист (без поиска дубликатов) и будит воркер.
+func (h *MediaHandler) resolve(ctx context.Context, placeholder *model.Job) {
+	if h.Cfg == nil {
+		h.Jobs.ConfirmSingle(context.Background(), placeholder.ID) //nolint:errcheck
 		h.Pool.Enqueue()
+		return
 	}
+	opts := resolveExpanderOpts(ctx, h.Cfg, h.Settings)
+	go func() {
+		h.Expander.ResolvePlaceholder(context.Background(), placeholder, opts, true)
+		h.Pool.Enqueue()
+	}()
+}
 
-	w.Header().Set("HX-Trigger", "mediaRefresh")
+// ResolveDuplicate выполняет выбор пользователя для placeholder-дубликата:
+//   - open       — убирает placeholder и показывает существующее задание (из корзины возвращает);
+//   - redownload — убирает placeholder и скачивает существующее задание заново;
+//   - add        — снимает пометку и добавляет видео ещё раз.
+func (h *MediaHandler) ResolveDuplicate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	job, err := h.Jobs.GetByID(ctx, r.PathValue("id"))
+	if err != nil || !job.IsDuplicate() {
+		http.Error(w, "not a pending duplicate", http.StatusNotFound)
+		return
+	}
+	action := r.PathValue("action")
+	if action == "add" {
+		if err := h.Jobs.SetDuplicate(ctx, job.ID, "", ""); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		job.DuplicateOf, job.Error = "", ""
+		h.Hub.Publish(sse.JobStatus, sse.JobStatusOf(job))
+		h.resolve(ctx, job)
+		w.Header().Set("HX-Trigger", "mediaRefresh")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if action != "open" && action != "redownload" {
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	existing, err := h.Jobs.GetByID(ctx, job.DuplicateOf)
+	if err != nil {
+		http.Error(w, "existing job not found", http.StatusNotFound)
+		return
+	}
+	if err := h.Jobs.DeleteChecking(ctx, job.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: job.ID, Status: "deleted"})
+
+	// Ожидающее задание и так скачается, а найденный сканером файл скачать нечем:
+	// для них «скачать заново» — просто показать задание.
+	if action == "redownload" && existing.Status == model.JobDone {
+		if err := h.redownload(ctx, existing); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("HX-Trigger", "mediaRefresh")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if existing.Hidden {
+		if err := h.Jobs.Unhide(ctx, existing.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	// openJob: браузер переходит к заданию — в очереди или поиском в библиотеке.
+	trigger, _ := json.Marshal(map[string]any{
+		"mediaRefresh": true,
+		"openJob":      map[string]any{"id": existing.ID, "title": existing.DisplayName(), "queue": inQueue(existing.Status)},
+	})
+	w.Header().Set("HX-Trigger", string(trigger))
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -453,10 +803,13 @@ func (h *MediaHandler) RemoveTag(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// Log возвращает plain-text лог последней попытки скачивания.
+// Log возвращает plain-text лог последней попытки скачивания или попытки ?attempt=N.
 func (h *MediaHandler) Log(w http.ResponseWriter, r *http.Request) {
 	jobID := r.PathValue("id")
 	log, err := h.Jobs.GetLog(r.Context(), jobID)
+	if n := r.URL.Query().Get("attempt"); n != "" {
+		log, err = h.attemptLog(r.Context(), jobID, n)
+	}
 	if err != nil || log == "" {
 		http.Error(w, "log not available", http.StatusNotFound)
 		return
@@ -465,6 +818,30 @@ func (h *MediaHandler) Log(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(log)) //nolint:errcheck
 }
 
+func (h *MediaHandler) attemptLog(ctx context.Context, jobID, number string) (string, error) {
+	attempts, err := h.Jobs.ListAttempts(ctx, jobID)
+	if err != nil {
+		return "", err
+	}
+	for _, a := range attempts {
+		if strconv.Itoa(a.Number) == number {
+			return a.Log, nil
+		}
+	}
+	return "", nil
+}
+
+// Attempts возвращает хронологию попыток скачивания для диалога лога.
+func (h *MediaHandler) Attempts(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	attempts, err := h.Jobs.ListAttempts(r.Context(), jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templ.Handler(templates.JobAttempts(jobID, attempts)).ServeHTTP(w, r)
+}
+
 // ListDeleted возвращает JSON-список soft-удалённых элементов.
 func (h *MediaHandler) ListDeleted(w http.ResponseWriter, r *http.Request) {
 	items, err := h.Items.ListDeleted(r.Context())
@@ -496,6 +873,7 @@ func (h *MediaHandler) ExtractAudio(w http.ResponseWriter, r *http.Request) {
 		Kind:    ops.KindExtractAudio,
 		Title:   "Извлечь аудио: " + srcItem.Name,
 		Payload: string(payload),
+		OwnerID: auth.UserFrom(r.Context()).ID,
 	}
 	if err := h.Ops.Create(r.Context(), op); err != nil {
 		slog.Error("extract audio: create op", "err", err)
@@ -507,3 +885,35 @@ func (h *MediaHandler) ExtractAudio(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("HX-Trigger", string(trigger))
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// ExtractSubtitles ставит в очередь извлечение встроенных дорожек субтитров (MKV) в WebVTT.
+func (h *MediaHandler) ExtractSubtitles(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	srcItem, err := h.Items.GetByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, "item not found", http.StatusNotFound)
+		return
+	}
+	if !srcItem.IsAvailable() {
+		http.Error(w, "item not available", http.StatusGone)
+		return
+	}
+	payload, _ := json.Marshal(struct {
+		ItemID string `json:"item_id"`
+	}{ItemID: id})
+	op := &model.Operation{
+		Kind:    ops.KindExtractSubs,
+		Title:   "Извлечь субтитры: " + srcItem.Name,
+		Payload: string(payload),
+		OwnerID: auth.UserFrom(r.Context()).ID,
+	}
+	if err := h.Ops.Create(r.Context(), op); err != nil {
+		slog.Error("extract subtitles: create op", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	h.OpsWorker.Enqueue()
+	trigger, _ := json.Marshal(map[string]any{"showToast": "Извлечение субтитров запущено"})
+	w.Header().Set("HX-Trigger", string(trigger))
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/web/templates"
+)
+
+// CreatePreset добавляет именованный пресет загрузки.
+func (h *SettingsHandler) CreatePreset(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.presetFromForm(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Presets.Create(r.Context(), p); err != nil {
+		slog.Warn("settings: create preset", "err", err)
+		h.renderPresets(w, r, "Пресет с таким названием уже есть")
+		return
+	}
+	h.renderPresets(w, r, "")
+}
+
+// UpdatePreset сохраняет изменённые поля пресета.
+func (h *SettingsHandler) UpdatePreset(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.presetFromForm(w, r)
+	if !ok {
+		return
+	}
+	p.ID = r.PathValue("id")
+	if err := h.Presets.Update(r.Context(), p); err != nil {
+		slog.Warn("settings: update preset", "id", p.ID, "err", err)
+		h.renderPresets(w, r, "Не удалось сохранить пресет «"+p.Name+"»")
+		return
+	}
+	h.renderPresets(w, r, "")
+}
+
+// DeletePreset удаляет пресет; задания с ним будут качаться пресетом по умолчанию.
+func (h *SettingsHandler) DeletePreset(w http.ResponseWriter, r *http.Request) {
+	if err := h.Presets.Delete(r.Context(), r.PathValue("id")); err != nil {
+		slog.Error("settings: delete preset", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderPresets(w, r, "")
+}
+
+// SetDefaultPreset делает пресет пресетом по умолчанию; повторный выбор снимает флаг.
+func (h *SettingsHandler) SetDefaultPreset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, err := h.Presets.GetByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if p.IsDefault {
+		id = ""
+	}
+	if err := h.Presets.SetDefault(r.Context(), id); err != nil {
+		slog.Error("settings: default preset", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderPresets(w, r, "")
+}
+
+// presetFromForm читает и проверяет пресет из формы; при ошибке сам отвечает секцией с сообщением.
+func (h *SettingsHandler) presetFromForm(w http.ResponseWriter, r *http.Request) (*model.Preset, bool) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return nil, false
+	}
+	p := &model.Preset{
+		Name:         strings.TrimSpace(r.FormValue("name")),
+		Format:       strings.TrimSpace(r.FormValue("format")),
+		OutputFormat: r.FormValue("output_format"),
+		ExtraArgs:    strings.Fields(r.FormValue("extra_args")),
+		Proxy:        strings.TrimSpace(r.FormValue("proxy")),
+		PostProcess:  r.Form["post_process"],
+		Subfolder:    strings.Trim(strings.TrimSpace(r.FormValue("subfolder")), "/"),
+		IsDefault:    r.FormValue("is_default") != "",
+	}
+	if msg := p.Validate(); msg != "" {
+		h.renderPresets(w, r, msg)
+		return nil, false
+	}
+	return p, true
+}
+
+func (h *SettingsHandler) renderPresets(w http.ResponseWriter, r *http.Request, errMsg string) {
+	presets, err := h.Presets.List(r.Context())
+	if err != nil {
+		slog.Warn("settings: list presets", "err", err)
+	}
+	templ.Handler(templates.PresetsSection(presets, errMsg)).ServeHTTP(w, r)
+}
@@ -1,18 +1,24 @@
 package handler
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 
 	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/auth"
 	"github.com/dr-duke/talmorGo/internal/config"
 	"github.com/dr-duke/talmorGo/internal/model"
 	"github.com/dr-duke/talmorGo/internal/ops"
 	"github.com/dr-duke/talmorGo/internal/playlist"
 	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/internal/sse"
 	"github.com/dr-duke/talmorGo/web/templates"
 )
 
@@ -20,6 +26,14 @@ type Enqueuer interface {
 	Enqueue()
 	// CancelJob прерывает активно скачиваемый job. Возвращает true если job был running.
 	CancelJob(jobID string) bool
+	// PauseJob приостанавливает скачиваемый job с сохранением недокачанного файла.
+	// Возвращает true если job был running.
+	PauseJob(jobID string) bool
+}
+
+// ProgressSource отдаёт прогресс скачиваемых сейчас job'ов (реализуется worker.Pool).
+type ProgressSource interface {
+	Progress() map[string]model.Progress
 }
 
 type QueueHandler struct {
@@ -27,28 +41,61 @@ type QueueHandler struct {
 	Tags     repo.TagRepo
 	Ops      repo.OperationRepo
 	Pool     Enqueuer
+	Progress ProgressSource // nil — прогресс не показывается
 	Cfg      *config.Config
 	Settings repo.SettingsRepo
+	Presets  repo.PresetRepo
 	Expander *playlist.Expander
+	Hub      *sse.Hub
+}
+
+// queueStatuses — статусы заданий, которые показываются во вкладке «Очередь».
+var queueStatuses = []model.JobStatus{
+	model.JobChecking, model.JobPending, model.JobRunning,
+	model.JobRetrying, model.JobPaused, model.JobFailed, model.JobCancelled,
+}
+
+func inQueue(s model.JobStatus) bool {
+	return slices.Contains(queueStatuses, s)
 }
 
 // Add добавляет URL в очередь немедленно, не блокируя ответ.
 // Если URL — плейлист, разворачивание в отдельные job'ы происходит асинхронно.
+// Если такое видео уже есть (force не задан), задание остаётся в очереди
+// с пометкой дубликата и ждёт решения пользователя (DuplicateAction).
 func (h *QueueHandler) Add(w http.ResponseWriter, r *http.Request) {
-	rawURL := ""
+	rawURL, presetID := "", ""
+	var jobOpts model.JobOptions
+	var force bool
 	ct := r.Header.Get("Content-Type")
 	if ct == "application/json" {
 		var body struct {
-			URL string `json:"url"`
+			URL      string           `json:"url"`
+			PresetID string           `json:"preset_id"`
+			Options  model.JobOptions `json:"options"`
+			Force    bool             `json:"force"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, "bad request", http.StatusBadRequest)
 			return
 		}
-		rawURL = body.URL
+		rawURL, presetID = body.URL, body.PresetID
+		jobOpts, force = body.Options, body.Force
 	} else {
 		r.ParseForm()
-		rawURL = r.FormValue("url")
+		rawURL, presetID = r.FormValue("url"), r.FormValue("preset")
+		jobOpts = jobOptionsFromForm(r)
+		force = r.FormValue("force") != ""
+	}
+	if msg := jobOpts.Validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if presetID != "" {
+		if _, err := h.Presets.GetByID(r.Context(), presetID); err != nil {
+			http.Error(w, "unknown preset", http.StatusBadRequest)
+			return
+		}
 	}
 
 	if rawURL == "" {
@@ -63,22 +110,55 @@ func (h *QueueHandler) Add(w http.ResponseWriter, r *http.Request) {
 	// Создаём placeholder в статусе "checking" — воркер игнорирует этот статус.
 	// Ответ отдаём немедленно; горутина проверяет плейлист и затем переводит
 	// placeholder в pending (одиночное видео) или удаляет + создаёт отдельные jobs (плейлист).
-	job := &model.Job{URL: rawURL, Status: model.JobChecking, Source: "web"}
+	job := &model.Job{URL: rawURL, Status: model.JobChecking, Source: "web", OwnerID: auth.UserFrom(r.Context()).ID, Options: jobOpts, PresetID: presetID}
+	// Ссылку, которую уже добавляли, узнаём сразу; другой URL того же видео —
+	// после проверки, когда yt-dlp сообщит id.
+	if !force {
+		if dup := h.Expander.FindDuplicate(r.Context(), job); dup != nil {
+			job.MarkDuplicate(dup)
+		}
+	}
 	if err := h.Jobs.Create(r.Context(), job); err != nil {
 		slog.Error("queue add", "err", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusOf(job))
 
+	if job.IsDuplicate() {
+		w.Header().Set("HX-Trigger", `{"mediaRefresh":true,"showToast":"Это видео уже есть — выберите действие в очереди"}`)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	w.Header().Set("HX-Trigger", "mediaRefresh")
 	w.WriteHeader(http.StatusNoContent)
 
 	opts := resolveExpanderOpts(r.Context(), h.Cfg, h.Settings)
 	// Асинхронно проверяем плейлист и сигналим воркеру; placeholder в статусе checking.
-	go func(id string) {
-		h.Expander.ResolvePlaceholder(context.Background(), id, rawURL, opts, "web", 0)
+	go func() {
+		h.Expander.ResolvePlaceholder(context.Background(), job, opts, force)
 		h.Pool.Enqueue()
-	}(job.ID)
+	}()
+}
+
+// jobOptionsFromForm читает опции скачивания из формы добавления:
+// quality — высота в пикселях или "audio", container, sub_langs, embed_thumbnail.
+func jobOptionsFromForm(r *http.Request) model.JobOptions {
+	var o model.JobOptions
+	switch q := r.FormValue("quality"); q {
+	case "":
+	case "audio":
+		o.AudioOnly = true
+	default:
+		o.MaxHeight, _ = strconv.Atoi(q)
+	}
+	o.Container = r.FormValue("container")
+	if slices.Contains(model.AudioFormats, o.Container) {
+		o.AudioOnly = true
+	}
+	o.SubLangs = model.SplitList(r.FormValue("sub_langs"))
+	o.EmbedThumbnail = r.FormValue("embed_thumbnail") != ""
+	return o
 }
 
 // Delete отменяет задачу в любом статусе:
@@ -99,6 +179,7 @@ func (h *QueueHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: id, Status: string(model.JobCancelled)})
 	w.Header().Set("HX-Trigger", "mediaRefresh")
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -109,6 +190,8 @@ func (h *QueueHandler) CancelAll(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// Без ID: затронуто сразу много заданий, клиенты перезапрашивают списки целиком.
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{Status: string(model.JobCancelled)})
 	w.Header().Set("HX-Trigger", "mediaRefresh")
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -116,21 +199,133 @@ func (h *QueueHandler) CancelAll(w http.ResponseWriter, r *http.Request) {
 // Items отдаёт HTMX-фрагмент со списком задач очереди и фоновых операций.
 func (h *QueueHandler) Items(w http.ResponseWriter, r *http.Request) {
 	jobs, err := h.Jobs.List(r.Context(), repo.JobFilter{
-		Statuses: []model.JobStatus{
-			model.JobChecking, model.JobPending, model.JobRunning,
-			model.JobRetrying, model.JobFailed, model.JobCancelled,
-		},
+		Statuses: queueStatuses,
+		OwnerID:  auth.UserFrom(r.Context()).OwnerScope(),
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	operations, err := h.Ops.List(r.Context(), ops.VisibleKinds())
+	operations, err := h.Ops.List(r.Context(), ops.VisibleKinds(), auth.UserFrom(r.Context()).OwnerScope())
 	if err != nil {
 		slog.Warn("queue: list ops", "err", err)
 		operations = nil
 	}
-	templ.Handler(templates.QueueItems(jobs, operations)).ServeHTTP(w, r)
+	var progress map[string]model.Progress
+	if h.Progress != nil {
+		progress = h.Progress.Progress()
+	}
+	sortQueue(jobs)
+	paused, _ := h.Settings.Get(r.Context(), "queue_paused")
+	templ.Handler(templates.QueueItems(jobs, operations, progress, paused != "")).ServeHTTP(w, r)
+}
+
+// sortQueue упорядочивает очередь так, как её будут разбирать воркеры: сначала
+// скачиваемые, затем ожидающие по приоритету и времени добавления, затем остальные.
+func sortQueue(jobs []*model.Job) {
+	rank := func(j *model.Job) int {
+		switch {
+		case j.Status == model.JobRunning:
+			return 0
+		case j.Waiting():
+			return 1
+		default:
+			return 2
+		}
+	}
+	slices.SortStableFunc(jobs, func(a, b *model.Job) int {
+		if c := cmp.Compare(rank(a), rank(b)); c != 0 || rank(a) != 1 {
+			return c
+		}
+		if c := cmp.Compare(b.Priority, a.Priority); c != 0 {
+			return c
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+// PauseJob приостанавливает задание: скачиваемое прерывается с сохранением
+// недокачанного файла, ожидающее просто не будет взято воркером.
+func (h *QueueHandler) PauseJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !h.Pool.PauseJob(id) {
+		if err := h.Jobs.Pause(r.Context(), id); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: id, Status: string(model.JobPaused)})
+	}
+	w.Header().Set("HX-Trigger", "mediaRefresh")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResumeJob возвращает приостановленное задание в очередь.
+func (h *QueueHandler) ResumeJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.Jobs.Resume(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: id, Status: string(model.JobPending)})
+	h.Pool.Enqueue()
+	w.Header().Set("HX-Trigger", "mediaRefresh")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PauseQueue ставит всю очередь на паузу: новые задания не начинаются,
+// уже скачиваемые докачиваются. Флаг хранится в настройках и переживает перезапуск.
+func (h *QueueHandler) PauseQueue(w http.ResponseWriter, r *http.Request) {
+	h.setQueuePaused(w, r, true)
+}
+
+func (h *QueueHandler) ResumeQueue(w http.ResponseWriter, r *http.Request) {
+	h.setQueuePaused(w, r, false)
+}
+
+func (h *QueueHandler) setQueuePaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	v := ""
+	if paused {
+		v = "1"
+	}
+	if err := h.Settings.Set(r.Context(), "queue_paused", v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !paused {
+		h.Pool.Enqueue()
+	}
+	h.Hub.Publish(sse.QueueState, sse.QueueData{Paused: paused})
+	w.Header().Set("HX-Trigger", "mediaRefresh")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveToTop ставит ожидающее задание первым в очереди. Участник поднимает задание
+// не выше срочных — как и при явном выборе приоритета (clampPriority).
+func (h *QueueHandler) MoveToTop(w http.ResponseWriter, r *http.Request) {
+	ceiling := clampPriority(auth.UserFrom(r.Context()), math.MaxInt)
+	if _, err := h.Jobs.MoveToTop(r.Context(), r.PathValue("id"), ceiling); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("HX-Trigger", "mediaRefresh")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Row отдаёт одну строку очереди; для заданий вне очереди — пустой ответ,
+// чтобы клиент убрал строку при outerHTML-замене.
+func (h *QueueHandler) Row(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Jobs.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || !inQueue(job.Status) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	var pr *model.Progress
+	if h.Progress != nil {
+		if p, ok := h.Progress.Progress()[job.ID]; ok && job.Status == model.JobRunning {
+			pr = &p
+		}
+	}
+	templ.Handler(templates.QueueRow(job, pr)).ServeHTTP(w, r)
 }
 
 // DismissOp удаляет завершённую или упавшую операцию из очереди.
@@ -151,6 +346,7 @@ func (h *QueueHandler) Retry(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: id, Status: string(model.JobPending)})
 	h.Pool.Enqueue()
 	w.Header().Set("HX-Trigger", "mediaRefresh")
 	w.WriteHeader(http.StatusNoContent)
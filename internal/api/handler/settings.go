@@ -6,9 +6,13 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/auth"
 	"github.com/dr-duke/talmorGo/internal/config"
 	"github.com/dr-duke/talmorGo/internal/model"
 	"github.com/dr-duke/talmorGo/internal/ops"
@@ -18,20 +22,28 @@ import (
 )
 
 type SettingsHandler struct {
-	Cookies   repo.CookieRepo
-	Settings  repo.SettingsRepo
-	Jobs      repo.JobRepo
-	Items     repo.ItemRepo
-	Tags      repo.TagRepo
-	Storage   *storage.Storage
-	Cfg       *config.Config
-	SiteName  string
-	Ops       repo.OperationRepo
-	OpsWorker OpsEnqueuer
+	Cookies       repo.CookieRepo
+	Settings      repo.SettingsRepo
+	Jobs          repo.JobRepo
+	Items         repo.ItemRepo
+	Tags          repo.TagRepo
+	Storage       *storage.Storage
+	Cfg           *config.Config
+	SiteName      string
+	Ops           repo.OperationRepo
+	OpsWorker     OpsEnqueuer
+	APITokens     repo.APITokenRepo
+	Users         repo.UserRepo
+	Subscriptions repo.SubscriptionRepo
+	Collections   repo.CollectionRepo
+	Presets       repo.PresetRepo
+	DomainRules   repo.DomainRuleRepo
+	Poller        SubscriptionPoller // nil — опрос только по расписанию
 }
 
 func (h *SettingsHandler) Page(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	user := auth.UserFrom(ctx)
 	records, err := h.Cookies.List(ctx)
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
@@ -40,7 +52,18 @@ func (h *SettingsHandler) Page(w http.ResponseWriter, r *http.Request) {
 	cf := h.Cfg.CookiesFilePath()
 	fileStatus := cookieFileStatus(cf)
 	rtSettings := h.loadRuntimeSettings(ctx)
-	templ.Handler(templates.SettingsPage(h.Cfg.BasePath, h.SiteName, records, fileStatus, rtSettings, h.runtimeDefaults())).ServeHTTP(w, r)
+	tokens := h.listAPITokens(ctx)
+	var users []*model.User
+	var presets []*model.Preset
+	var rules []*model.DomainRule
+	if user.Role == model.RoleAdmin {
+		users, _ = h.Users.List(ctx)
+		presets, _ = h.Presets.List(ctx)
+		rules, _ = h.DomainRules.List(ctx)
+	}
+	subs := h.listSubscriptions(ctx)
+	cols, _ := h.Collections.List(ctx)
+	templ.Handler(templates.SettingsPage(h.Cfg.BasePath, h.SiteName, records, fileStatus, rtSettings, h.runtimeDefaults(), tokens, h.authEnabled(ctx), user, users, subs, cols, presets, rules)).ServeHTTP(w, r)
 }
 
 // SaveRuntimeSettings сохраняет настройки загрузчика из формы.
@@ -50,15 +73,23 @@ func (h *SettingsHandler) SaveRuntimeSettings(w http.ResponseWriter, r *http.Req
 		return
 	}
 	ctx := r.Context()
-	keys := []string{"yt_dlp_proxy", "yt_dlp_extra_args", "yt_dlp_output_format", "yt_dlp_max_files", "yt_dlp_timeout", "lib_page_size"}
-	for _, k := range keys {
+	if _, err := model.ParseSchedule(r.FormValue("download_schedule")); err != nil {
+		// Ничего не сохраняем и возвращаем форму с введёнными значениями.
+		form := make(map[string]string, len(runtimeSettingKeys))
+		for _, k := range runtimeSettingKeys {
+			form[k] = strings.TrimSpace(r.FormValue(k))
+		}
+		templ.Handler(templates.RuntimeSettingsSection(h.Cfg.BasePath, form, h.runtimeDefaults(), "Расписание: "+err.Error())).ServeHTTP(w, r)
+		return
+	}
+	for _, k := range runtimeSettingKeys {
 		val := strings.TrimSpace(r.FormValue(k))
 		if err := h.Settings.Set(ctx, k, val); err != nil {
 			slog.Error("settings: save runtime setting", "key", k, "err", err)
 		}
 	}
 	rtSettings := h.loadRuntimeSettings(ctx)
-	templ.Handler(templates.RuntimeSettingsSection(h.Cfg.BasePath, rtSettings, h.runtimeDefaults())).ServeHTTP(w, r)
+	templ.Handler(templates.RuntimeSettingsSection(h.Cfg.BasePath, rtSettings, h.runtimeDefaults(), "")).ServeHTTP(w, r)
 }
 
 func (h *SettingsHandler) loadRuntimeSettings(ctx context.Context) map[string]string {
@@ -72,15 +103,25 @@ func (h *SettingsHandler) loadRuntimeSettings(ctx context.Context) map[string]st
 	return m
 }
 
+// runtimeSettingKeys — ключи настроек, редактируемых из UI и API без перезапуска.
+var runtimeSettingKeys = []string{"yt_dlp_proxy", "yt_dlp_geo_proxies", "yt_dlp_extra_args", "yt_dlp_output_format", "yt_dlp_sub_langs", "yt_dlp_max_files", "yt_dlp_timeout", "lib_page_size", "download_schedule"}
+
 // runtimeDefaults возвращает значения из конфига — показываются как placeholder в форме.
 func (h *SettingsHandler) runtimeDefaults() map[string]string {
+	return runtimeDefaults(h.Cfg)
+}
+
+func runtimeDefaults(cfg *config.Config) map[string]string {
 	return map[string]string{
-		"yt_dlp_proxy":         h.Cfg.YtDlpProxy,
-		"yt_dlp_extra_args":    h.Cfg.YtDlpExtraArgs,
-		"yt_dlp_output_format": h.Cfg.YtDlpOutputFormat,
-		"yt_dlp_max_files":     fmt.Sprintf("%d", h.Cfg.YtDlpMaxFilesPerRequest),
-		"yt_dlp_timeout":       fmt.Sprintf("%d", h.Cfg.YtDlpTimeout),
-		"lib_page_size":        fmt.Sprintf("%d", h.Cfg.LibPageSize),
+		"yt_dlp_proxy":         cfg.YtDlpProxy,
+		"yt_dlp_geo_proxies":   strings.Join(cfg.YtDlpGeoProxies, " "),
+		"yt_dlp_extra_args":    cfg.YtDlpExtraArgs,
+		"yt_dlp_output_format": cfg.YtDlpOutputFormat,
+		"yt_dlp_sub_langs":     cfg.YtDlpSubLangs,
+		"yt_dlp_max_files":     fmt.Sprintf("%d", cfg.YtDlpMaxFilesPerRequest),
+		"yt_dlp_timeout":       fmt.Sprintf("%d", cfg.YtDlpTimeout),
+		"lib_page_size":        fmt.Sprintf("%d", cfg.LibPageSize),
+		"download_schedule":    cfg.DownloadSchedule,
 	}
 }
 
@@ -151,6 +192,83 @@ func (h *SettingsHandler) DeleteDomain(w http.ResponseWriter, r *http.Request) {
 	templ.Handler(templates.CookieDomainList(records)).ServeHTTP(w, r)
 }
 
+// CreateAPIToken создаёт именованный токен и один раз показывает его секрет.
+func (h *SettingsHandler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	scope := model.TokenScope(r.FormValue("scope"))
+	if name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	switch scope {
+	case model.ScopeRead, model.ScopeEnqueue, model.ScopeAdmin:
+	default:
+		http.Error(w, "invalid scope", http.StatusBadRequest)
+		return
+	}
+	t := &model.APIToken{Name: name, Scope: scope, UserID: auth.UserFrom(r.Context()).ID}
+	if v := r.FormValue("expires_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			http.Error(w, "invalid expires_days", http.StatusBadRequest)
+			return
+		}
+		exp := time.Now().UTC().AddDate(0, 0, days)
+		t.ExpiresAt = &exp
+	}
+	ctx := r.Context()
+	secret, err := h.APITokens.Create(ctx, t)
+	if err != nil {
+		slog.Error("settings: create api token", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	templ.Handler(templates.APITokensSection(h.listAPITokens(ctx), secret, h.authEnabled(ctx))).ServeHTTP(w, r)
+}
+
+// DeleteAPIToken отзывает токен. Не-администратор может отозвать только свой.
+func (h *SettingsHandler) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	if !slices.ContainsFunc(h.listAPITokens(ctx), func(t *model.APIToken) bool { return t.ID == id }) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err := h.APITokens.Delete(ctx, id); err != nil {
+		slog.Error("settings: delete api token", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	templ.Handler(templates.APITokensSection(h.listAPITokens(ctx), "", h.authEnabled(ctx))).ServeHTTP(w, r)
+}
+
+// listAPITokens — все токены для администратора, свои для остальных.
+func (h *SettingsHandler) listAPITokens(ctx context.Context) []*model.APIToken {
+	user := auth.UserFrom(ctx)
+	userID := user.ID
+	if user.Role == model.RoleAdmin {
+		userID = ""
+	}
+	tokens, err := h.APITokens.List(ctx, userID)
+	if err != nil {
+		slog.Warn("settings: list api tokens", "err", err)
+	}
+	return tokens
+}
+
+// authEnabled сообщает, проверяется ли авторизация: задан WEB_TOKEN или есть пользователи.
+func (h *SettingsHandler) authEnabled(ctx context.Context) bool {
+	if h.Cfg.WebToken != "" {
+		return true
+	}
+	n, _ := h.Users.Count(ctx)
+	return n > 0
+}
+
 // rewriteFile пересоздаёт объединённый cookies.txt на диске.
 func (h *SettingsHandler) rewriteFile(ctx context.Context) error {
 	merged, err := h.Cookies.MergeAll(ctx)
@@ -182,6 +300,23 @@ func (h *SettingsHandler) Cleanup(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, `<p class="cleanup-result">Операция запущена…</p>`)
 }
 
+// Probe перечитывает через ffprobe длительность, разрешение и кодеки всех файлов.
+func (h *SettingsHandler) Probe(w http.ResponseWriter, r *http.Request) {
+	op := &model.Operation{
+		Kind:    ops.KindProbe,
+		Title:   "Чтение параметров файлов",
+		Payload: "{}",
+	}
+	if err := h.Ops.Create(r.Context(), op); err != nil {
+		slog.Error("settings: create probe op", "err", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	h.OpsWorker.Enqueue()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, `<p class="cleanup-result">Операция запущена…</p>`)
+}
+
 // parseCookiesByDomain группирует строки Netscape-файла по домену (первая колонка).
 // Комментарии и пустые строки пропускаются.
 func parseCookiesByDomain(raw string) map[string][]string {
@@ -204,7 +339,8 @@ func parseCookiesByDomain(raw string) map[string][]string {
 	return out
 }
 
-// Reindex очищает осиротевшие теги/коллекции и проверяет доступность файлов на диске.
+// Reindex очищает осиротевшие теги/коллекции, проверяет доступность файлов на диске
+// и перестраивает поисковый индекс.
 func (h *SettingsHandler) Reindex(w http.ResponseWriter, r *http.Request) {
 	op := &model.Operation{
 		Kind:    ops.KindReindex,
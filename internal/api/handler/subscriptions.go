@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/auth"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/web/templates"
+)
+
+// SubscriptionPoller опрашивает подписку вне расписания (реализуется worker.SubscriptionPoller).
+type SubscriptionPoller interface {
+	Poll(ctx context.Context, s *model.Subscription) int
+}
+
+// CreateSubscription добавляет подписку на канал или плейлист.
+func (h *SettingsHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return
+	}
+	rawURL := strings.TrimSpace(r.FormValue("url"))
+	if _, err := url.ParseRequestURI(rawURL); err != nil {
+		h.renderSubscriptions(w, r, "Некорректная ссылка")
+		return
+	}
+	minutes, err := strconv.Atoi(r.FormValue("interval"))
+	if err != nil || minutes < 5 {
+		h.renderSubscriptions(w, r, "Интервал — не меньше 5 минут")
+		return
+	}
+	maxAge := 0
+	if v := strings.TrimSpace(r.FormValue("max_age_days")); v != "" {
+		if maxAge, err = strconv.Atoi(v); err != nil || maxAge < 0 {
+			h.renderSubscriptions(w, r, "Некорректный возраст видео")
+			return
+		}
+	}
+	s := &model.Subscription{
+		URL:          rawURL,
+		Interval:     time.Duration(minutes) * time.Minute,
+		Tags:         splitTags(r.FormValue("tags")),
+		CollectionID: r.FormValue("collection_id"),
+		MaxAgeDays:   maxAge,
+		OwnerID:      auth.UserFrom(r.Context()).ID,
+		Enabled:      true,
+	}
+	if err := h.Subscriptions.Create(r.Context(), s); err != nil {
+		slog.Error("settings: create subscription", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.pollAsync(s)
+	h.renderSubscriptions(w, r, "")
+}
+
+// DeleteSubscription удаляет подписку; уже скачанное остаётся.
+func (h *SettingsHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Subscriptions.Delete(r.Context(), s.ID); err != nil {
+		slog.Error("settings: delete subscription", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderSubscriptions(w, r, "")
+}
+
+// ToggleSubscription ставит подписку на паузу или снимает с неё.
+func (h *SettingsHandler) ToggleSubscription(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Subscriptions.SetEnabled(r.Context(), s.ID, !s.Enabled); err != nil {
+		slog.Error("settings: toggle subscription", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderSubscriptions(w, r, "")
+}
+
+// PollSubscription запускает внеочередной опрос подписки.
+func (h *SettingsHandler) PollSubscription(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	h.pollAsync(s)
+	w.Header().Set("HX-Trigger", `{"showToast":"Проверка запущена"}`)
+	h.renderSubscriptions(w, r, "")
+}
+
+func (h *SettingsHandler) pollAsync(s *model.Subscription) {
+	if h.Poller == nil {
+		return
+	}
+	go h.Poller.Poll(context.Background(), s)
+}
+
+// ownSubscription загружает подписку из пути; чужие подписки участнику не видны.
+func (h *SettingsHandler) ownSubscription(w http.ResponseWriter, r *http.Request) (*model.Subscription, bool) {
+	s, err := h.Subscriptions.GetByID(r.Context(), r.PathValue("id"))
+	user := auth.UserFrom(r.Context())
+	if err != nil || (user.Role != model.RoleAdmin && s.OwnerID != user.ID) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil, false
+	}
+	return s, true
+}
+
+// listSubscriptions — все подписки для администратора, свои для остальных.
+func (h *SettingsHandler) listSubscriptions(ctx context.Context) []*model.Subscription {
+	user := auth.UserFrom(ctx)
+	ownerID := user.ID
+	if user.Role == model.RoleAdmin {
+		ownerID = ""
+	}
+	subs, err := h.Subscriptions.List(ctx, ownerID)
+	if err != nil {
+		slog.Warn("settings: list subscriptions", "err", err)
+	}
+	return subs
+}
+
+func (h *SettingsHandler) renderSubscriptions(w http.ResponseWriter, r *http.Request, errMsg string) {
+	cols, _ := h.Collections.List(r.Context())
+	templ.Handler(templates.SubscriptionsSection(h.listSubscriptions(r.Context()), cols, errMsg)).ServeHTTP(w, r)
+}
+
+// splitTags разбирает список тегов через запятую.
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
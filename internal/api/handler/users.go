@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/dr-duke/talmorGo/internal/auth"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/web/templates"
+)
+
+// CreateUser заводит пользователя из формы в настройках.
+func (h *SettingsHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return
+	}
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+	role := model.Role(r.FormValue("role"))
+	if username == "" || password == "" || !validRole(role) {
+		h.renderUsers(w, r, "Укажите логин, пароль и роль")
+		return
+	}
+	ctx := r.Context()
+	if _, err := h.Users.GetByUsername(ctx, username); err == nil {
+		h.renderUsers(w, r, "Пользователь «"+username+"» уже существует")
+		return
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.Users.Create(ctx, &model.User{Username: username, PasswordHash: hash, Role: role}); err != nil {
+		slog.Error("settings: create user", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderUsers(w, r, "")
+}
+
+// DeleteUser удаляет пользователя; его задания становятся общими.
+func (h *SettingsHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == auth.UserFrom(r.Context()).ID {
+		h.renderUsers(w, r, "Нельзя удалить себя")
+		return
+	}
+	if err := h.Users.Delete(r.Context(), id); err != nil {
+		slog.Error("settings: delete user", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderUsers(w, r, "")
+}
+
+// SetUserRole меняет роль пользователя. Свою роль менять нельзя, чтобы не остаться без администратора.
+func (h *SettingsHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return
+	}
+	id := r.PathValue("id")
+	role := model.Role(r.FormValue("role"))
+	if !validRole(role) || id == auth.UserFrom(r.Context()).ID {
+		h.renderUsers(w, r, "Недопустимая смена роли")
+		return
+	}
+	if err := h.Users.SetRole(r.Context(), id, role); err != nil {
+		slog.Error("settings: set user role", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderUsers(w, r, "")
+}
+
+// LinkChat привязывает Telegram-чат к пользователю.
+func (h *SettingsHandler) LinkChat(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "parse form", http.StatusBadRequest)
+		return
+	}
+	chatID, err := strconv.ParseInt(strings.TrimSpace(r.FormValue("chat_id")), 10, 64)
+	if err != nil || chatID == 0 {
+		h.renderUsers(w, r, "Некорректный chat ID")
+		return
+	}
+	if err := h.Users.LinkChat(r.Context(), r.PathValue("id"), chatID); err != nil {
+		slog.Error("settings: link chat", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderUsers(w, r, "")
+}
+
+// UnlinkChat отвязывает Telegram-чат.
+func (h *SettingsHandler) UnlinkChat(w http.ResponseWriter, r *http.Request) {
+	chatID, err := strconv.ParseInt(r.PathValue("chat"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad chat id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Users.UnlinkChat(r.Context(), chatID); err != nil {
+		slog.Error("settings: unlink chat", "err", err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	h.renderUsers(w, r, "")
+}
+
+func (h *SettingsHandler) renderUsers(w http.ResponseWriter, r *http.Request, errMsg string) {
+	users, err := h.Users.List(r.Context())
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	templ.Handler(templates.UsersSection(users, auth.UserFrom(r.Context()), errMsg)).ServeHTTP(w, r)
+}
+
+func validRole(r model.Role) bool {
+	switch r {
+	case model.RoleAdmin, model.RoleMember, model.RoleViewer:
+		return true
+	}
+	return false
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/a-h/templ"
 	"github.com/dr-duke/talmorGo/internal/api/handler"
+	"github.com/dr-duke/talmorGo/internal/auth"
 	"github.com/dr-duke/talmorGo/internal/config"
+	"github.com/dr-duke/talmorGo/internal/hls"
 	"github.com/dr-duke/talmorGo/internal/playlist"
 	"github.com/dr-duke/talmorGo/internal/repo"
 	"github.com/dr-duke/talmorGo/internal/sse"
@@ -32,9 +36,16 @@ func New(
 	settings repo.SettingsRepo,
 	collections repo.CollectionRepo,
 	operations repo.OperationRepo,
+	apiTokens repo.APITokenRepo,
+	users repo.UserRepo,
+	subscriptions repo.SubscriptionRepo,
+	presets repo.PresetRepo,
+	rules repo.DomainRuleRepo,
 	store *storage.Storage,
+	transcoder *hls.Cache,
 	pool handler.Enqueuer,
 	opsWorker handler.OpsEnqueuer,
+	poller handler.SubscriptionPoller,
 	hub *sse.Hub,
 ) *Server {
 	basePath := strings.TrimRight(cfg.BasePath, "/")
@@ -44,18 +55,28 @@ func New(
 
 	expander := playlist.New(jobs, tags)
 	expander.Hub = hub
+	expander.Rules = rules
 
-	qh := &handler.QueueHandler{Jobs: jobs, Tags: tags, Ops: operations, Pool: pool, Cfg: cfg, Settings: settings, Expander: expander}
+	qh := &handler.QueueHandler{Jobs: jobs, Tags: tags, Ops: operations, Pool: pool, Cfg: cfg, Settings: settings, Presets: presets, Expander: expander, Hub: hub}
+	if ps, ok := pool.(handler.ProgressSource); ok {
+		qh.Progress = ps
+	}
 	mh := &handler.MediaHandler{
 		Jobs: jobs, Items: items, Tags: tags,
 		Tokens: tokens, Storage: store,
 		BaseURL: cfg.BaseURL, Pool: pool, Cfg: cfg, Settings: settings,
 		Collections: collections, Expander: expander,
-		Ops: operations, OpsWorker: opsWorker,
+		Ops: operations, OpsWorker: opsWorker, Hub: hub, HLS: transcoder,
 	}
-	ch := &handler.CollectionHandler{Collections: collections}
+	ch := &handler.CollectionHandler{Collections: collections, Jobs: jobs, Hub: hub}
 	lh := &handler.LinkHandler{Tokens: tokens, Items: items}
-	sh := &handler.SettingsHandler{Cookies: cookies, Settings: settings, Jobs: jobs, Items: items, Tags: tags, Storage: store, Cfg: cfg, SiteName: siteName, Ops: operations, OpsWorker: opsWorker}
+	ah := &handler.APIHandler{
+		Jobs: jobs, Items: items, Tags: tags, Collections: collections, Presets: presets,
+		Ops: operations, Settings: settings, Storage: store, HLS: transcoder,
+		Pool: pool, Cfg: cfg, Expander: expander, Hub: hub,
+	}
+	sh := &handler.SettingsHandler{Cookies: cookies, Settings: settings, Jobs: jobs, Items: items, Tags: tags, Storage: store, Cfg: cfg, SiteName: siteName, Ops: operations, OpsWorker: opsWorker, APITokens: apiTokens, Users: users, Subscriptions: subscriptions, Collections: collections, Presets: presets, DomainRules: rules, Poller: poller}
+	auh := &handler.AuthHandler{Users: users, Cfg: cfg, SiteName: siteName}
 
 	// Статика.
 	staticSub, _ := fs.Sub(web.StaticFiles, "static")
@@ -68,9 +89,15 @@ func New(
 			return
 		}
 		cols, _ := collections.List(r.Context())
-		templ.Handler(templates.Index(basePath, siteName, cols)).ServeHTTP(w, r)
+		ps, _ := presets.List(r.Context())
+		templ.Handler(templates.Index(basePath, siteName, cols, auth.UserFrom(r.Context()), ps)).ServeHTTP(w, r)
 	})
 
+	// Вход и выход.
+	mux.HandleFunc("GET /login", auh.LoginPage)
+	mux.HandleFunc("POST /login", auh.Login)
+	mux.HandleFunc("POST /logout", auh.Logout)
+
 	// Медиатека: сайдбар, items, теги, плейлист.
 	mux.HandleFunc("GET /library/sidebar", mh.LibrarySidebar)
 	mux.HandleFunc("GET /library/items", mh.LibraryItems)
@@ -79,22 +106,32 @@ func New(
 
 	// Items: стриминг, удаление, переименование, ссылки, аудио.
 	mux.HandleFunc("GET /items/{id}/stream", mh.Stream)
+	mux.HandleFunc("GET /items/{id}/hls/master.m3u8", mh.HLSMaster)
+	mux.HandleFunc("GET /items/{id}/hls/{height}/{file}", mh.HLSFile)
+	mux.HandleFunc("GET /items/{id}/info", mh.Info)
+	mux.HandleFunc("GET /items/{id}/thumb", mh.Thumb)
+	mux.HandleFunc("GET /items/{id}/subtitles/{file}", mh.Subtitles)
 	mux.HandleFunc("DELETE /items/{id}", mh.Delete)
 	mux.HandleFunc("PATCH /items/{id}", mh.Rename)
 	mux.HandleFunc("PATCH /items/{id}/meta", mh.UpdateMeta)
 	mux.HandleFunc("POST /items/meta-bulk", mh.BulkMeta)
+	mux.HandleFunc("POST /items/transcode", mh.Transcode)
 	mux.HandleFunc("POST /items/{id}/link", mh.CreateLink)
 	mux.HandleFunc("POST /items/{id}/extract-audio", mh.ExtractAudio)
+	mux.HandleFunc("POST /items/{id}/extract-subtitles", mh.ExtractSubtitles)
+	mux.HandleFunc("POST /items/{id}/progress", mh.Progress)
 	mux.HandleFunc("GET /items/deleted", mh.ListDeleted)
 
 	// Jobs: управление заданиями.
 	mux.HandleFunc("POST /jobs/{id}/redownload", mh.Redownload)
+	mux.HandleFunc("POST /jobs/{id}/duplicate/{action}", mh.ResolveDuplicate)
 	mux.HandleFunc("POST /jobs/{id}/hide", mh.Hide)
 	mux.HandleFunc("POST /jobs/{id}/unhide", mh.Unhide)
 	mux.HandleFunc("DELETE /jobs/{id}", mh.PurgeJob)
 	mux.HandleFunc("POST /jobs/{id}/tags", mh.AddTag)
 	mux.HandleFunc("DELETE /jobs/{id}/tags/{tag}", mh.RemoveTag)
 	mux.HandleFunc("GET /jobs/{id}/log", mh.Log)
+	mux.HandleFunc("GET /jobs/{id}/attempts", mh.Attempts)
 	mux.HandleFunc("POST /media/bulk-tag", mh.BulkTag)
 	mux.HandleFunc("POST /media/bulk-hide", mh.BulkHide)
 
@@ -110,8 +147,14 @@ func New(
 	mux.HandleFunc("POST /queue", qh.Add)
 	mux.HandleFunc("DELETE /queue/{id}", qh.Delete)
 	mux.HandleFunc("POST /queue/cancel-all", qh.CancelAll)
+	mux.HandleFunc("POST /queue/pause", qh.PauseQueue)
+	mux.HandleFunc("POST /queue/resume", qh.ResumeQueue)
 	mux.HandleFunc("GET /queue/items", qh.Items)
+	mux.HandleFunc("GET /queue/jobs/{id}", qh.Row)
 	mux.HandleFunc("POST /jobs/{id}/retry", qh.Retry)
+	mux.HandleFunc("POST /jobs/{id}/top", qh.MoveToTop)
+	mux.HandleFunc("POST /jobs/{id}/pause", qh.PauseJob)
+	mux.HandleFunc("POST /jobs/{id}/resume", qh.ResumeJob)
 	mux.HandleFunc("DELETE /operations/{id}", qh.DismissOp)
 
 	// Настройки.
@@ -120,7 +163,65 @@ func New(
 	mux.HandleFunc("DELETE /settings/cookies/{domain}", sh.DeleteDomain)
 	mux.HandleFunc("POST /settings/cleanup", sh.Cleanup)
 	mux.HandleFunc("POST /settings/reindex", sh.Reindex)
+	mux.HandleFunc("POST /settings/probe", sh.Probe)
 	mux.HandleFunc("POST /settings/runtime", sh.SaveRuntimeSettings)
+	mux.HandleFunc("POST /settings/tokens", sh.CreateAPIToken)
+	mux.HandleFunc("DELETE /settings/tokens/{id}", sh.DeleteAPIToken)
+	mux.HandleFunc("POST /settings/subscriptions", sh.CreateSubscription)
+	mux.HandleFunc("DELETE /settings/subscriptions/{id}", sh.DeleteSubscription)
+	mux.HandleFunc("POST /settings/subscriptions/{id}/toggle", sh.ToggleSubscription)
+	mux.HandleFunc("POST /settings/subscriptions/{id}/poll", sh.PollSubscription)
+	mux.HandleFunc("POST /settings/presets", sh.CreatePreset)
+	mux.HandleFunc("PUT /settings/presets/{id}", sh.UpdatePreset)
+	mux.HandleFunc("DELETE /settings/presets/{id}", sh.DeletePreset)
+	mux.HandleFunc("POST /settings/presets/{id}/default", sh.SetDefaultPreset)
+	mux.HandleFunc("POST /settings/domains", sh.CreateDomainRule)
+	mux.HandleFunc("PUT /settings/domains/{id}", sh.UpdateDomainRule)
+	mux.HandleFunc("DELETE /settings/domains/{id}", sh.DeleteDomainRule)
+	mux.HandleFunc("POST /settings/users", sh.CreateUser)
+	mux.HandleFunc("DELETE /settings/users/{id}", sh.DeleteUser)
+	mux.HandleFunc("POST /settings/users/{id}/role", sh.SetUserRole)
+	mux.HandleFunc("POST /settings/users/{id}/chats", sh.LinkChat)
+	mux.HandleFunc("DELETE /settings/users/{id}/chats/{chat}", sh.UnlinkChat)
+
+	// JSON API v1.
+	spec, _ := fs.ReadFile(web.StaticFiles, "static/openapi.json")
+	mux.HandleFunc("GET /api/v1/openapi.json", ah.OpenAPI(spec))
+	mux.HandleFunc("GET /api/v1/media", ah.ListMedia)
+	mux.HandleFunc("GET /api/v1/jobs", ah.ListJobs)
+	mux.HandleFunc("POST /api/v1/jobs", ah.CreateJob)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", ah.GetJob)
+	mux.HandleFunc("PATCH /api/v1/jobs/{id}", ah.UpdateJob)
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", ah.DeleteJob)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/retry", ah.RetryJob)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/pause", ah.PauseJob)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/resume", ah.ResumeJob)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/log", ah.JobLog)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/attempts", ah.JobAttempts)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/tags", ah.AddJobTag)
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}/tags/{tag}", ah.RemoveJobTag)
+	mux.HandleFunc("GET /api/v1/items/{id}", ah.GetItem)
+	mux.HandleFunc("PATCH /api/v1/items/{id}", ah.UpdateItem)
+	mux.HandleFunc("DELETE /api/v1/items/{id}", ah.DeleteItem)
+	mux.HandleFunc("GET /api/v1/tags", ah.ListTags)
+	mux.HandleFunc("POST /api/v1/tags", ah.CreateTag)
+	mux.HandleFunc("PATCH /api/v1/tags/{name}", ah.UpdateTag)
+	mux.HandleFunc("DELETE /api/v1/tags/{name}", ah.DeleteTag)
+	mux.HandleFunc("GET /api/v1/presets", ah.ListPresets)
+	mux.HandleFunc("GET /api/v1/collections", ah.ListCollections)
+	mux.HandleFunc("POST /api/v1/collections", ah.CreateCollection)
+	mux.HandleFunc("PATCH /api/v1/collections/{id}", ah.UpdateCollection)
+	mux.HandleFunc("DELETE /api/v1/collections/{id}", ah.DeleteCollection)
+	mux.HandleFunc("POST /api/v1/collections/{id}/jobs", ah.AddCollectionJobs)
+	mux.HandleFunc("GET /api/v1/operations", ah.ListOperations)
+	mux.HandleFunc("GET /api/v1/operations/{id}", ah.GetOperation)
+	mux.HandleFunc("DELETE /api/v1/operations/{id}", ah.DeleteOperation)
+	mux.HandleFunc("GET /api/v1/settings", ah.GetSettings)
+	mux.HandleFunc("PATCH /api/v1/settings", ah.UpdateSettings)
+	// Без метода паттерн конфликтует с "GET /", поэтому заглушки — по методам.
+	for _, m := range []string{"GET", "POST", "PATCH", "DELETE"} {
+		mux.HandleFunc(m+" /api/v1/", ah.NotFound)
+	}
 
 	// SSE.
 	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
@@ -135,8 +236,14 @@ func New(
 			return
 		}
 
-		ch, unsub := hub.Subscribe()
+		// ?topics=job,collection.changed — подписка только на нужные события.
+		var topics []string
+		if t := r.URL.Query().Get("topics"); t != "" {
+			topics = strings.Split(t, ",")
+		}
+		ch, unsub := hub.Subscribe(topics...)
 		defer unsub()
+		filter := newEventFilter(auth.UserFrom(r.Context()), jobs, items, operations)
 
 		fmt.Fprint(w, "event: ping\ndata: ok\n\n")
 		flusher.Flush()
@@ -145,8 +252,16 @@ func New(
 			select {
 			case <-r.Context().Done():
 				return
-			case <-ch:
-				fmt.Fprint(w, "event: update\ndata: 1\n\n")
+			case e := <-ch:
+				if !filter.visible(r.Context(), e) {
+					continue
+				}
+				data, err := json.Marshal(e.Data)
+				if err != nil {
+					slog.Warn("sse: marshal event", "type", e.Type, "err", err)
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
 				flusher.Flush()
 			}
 		}
@@ -160,10 +275,10 @@ func New(
 		mux.HandleFunc("GET "+cfg.HealthEndpoint, handler.Health)
 	}
 
-	var h http.Handler = mux
-	if cfg.WebToken != "" {
-		h = authMiddleware(cfg.WebToken, mux)
-	}
+	h := authMiddleware(authDeps{
+		basePath: basePath, webToken: cfg.WebToken,
+		users: users, apiTokens: apiTokens, jobs: jobs, items: items,
+	}, mux)
 
 	if basePath != "" {
 		outer := http.NewServeMux()
@@ -183,21 +298,3 @@ func New(
 func (s *Server) Handler() http.Handler {
 	return s.handler
 }
-
-func authMiddleware(token string, next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasPrefix(r.URL.Path, "/f/") {
-			next.ServeHTTP(w, r)
-			return
-		}
-		if r.Header.Get("Authorization") == "Bearer "+token {
-			next.ServeHTTP(w, r)
-			return
-		}
-		if c, err := r.Cookie("_auth"); err == nil && c.Value == token {
-			next.ServeHTTP(w, r)
-			return
-		}
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-	})
-}
@@ -0,0 +1,78 @@
+// Package auth — хэширование паролей и передача текущего пользователя через context.
+package auth
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+// pbkdf2Iterations — рекомендация OWASP для PBKDF2-HMAC-SHA256.
+const pbkdf2Iterations = 600_000
+
+// HashPassword возвращает строку вида pbkdf2-sha256$<итерации>$<соль>$<хэш>.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", pbkdf2Iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword сверяет пароль с хэшем из HashPassword.
+func CheckPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+type ctxKey struct{}
+
+// Root — пользователь без учётной записи с правами администратора:
+// вход по WEB_TOKEN, токен без владельца или выключенная авторизация.
+var Root = &model.User{Username: "admin", Role: model.RoleAdmin}
+
+// WithUser кладёт пользователя в context запроса.
+func WithUser(ctx context.Context, u *model.User) context.Context {
+	return context.WithValue(ctx, ctxKey{}, u)
+}
+
+// UserFrom возвращает пользователя запроса; если его нет (фоновые задачи, тесты) — Root.
+func UserFrom(ctx context.Context) *model.User {
+	if u, ok := ctx.Value(ctxKey{}).(*model.User); ok && u != nil {
+		return u
+	}
+	return Root
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !CheckPassword(hash, "s3cret") {
+		t.Error("correct password rejected")
+	}
+	if CheckPassword(hash, "S3cret") {
+		t.Error("wrong password accepted")
+	}
+	if CheckPassword("garbage", "s3cret") {
+		t.Error("malformed hash accepted")
+	}
+	other, _ := HashPassword("s3cret")
+	if other == hash {
+		t.Error("same hash for two calls — salt not applied")
+	}
+}
+
+func TestUserFrom(t *testing.T) {
+	if u := UserFrom(context.Background()); u != Root {
+		t.Errorf("empty context: got %+v, want Root", u)
+	}
+	member := &model.User{ID: "u1", Role: model.RoleMember}
+	if u := UserFrom(WithUser(context.Background(), member)); u != member {
+		t.Errorf("got %+v, want member", u)
+	}
+}
@@ -11,8 +11,10 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/dr-duke/talmorGo/internal/config"
+	"github.com/dr-duke/talmorGo/internal/model"
 	"github.com/dr-duke/talmorGo/internal/playlist"
 	"github.com/dr-duke/talmorGo/internal/repo"
+	"github.com/dr-duke/talmorGo/internal/storage"
 	"github.com/dr-duke/talmorGo/internal/worker"
 )
 
@@ -28,11 +30,15 @@ type Bot struct {
 	items    repo.ItemRepo
 	tags     repo.TagRepo
 	settings repo.SettingsRepo
+	users    repo.UserRepo
+	subs     repo.SubscriptionRepo
+	presets  repo.PresetRepo
 	pool     Enqueuer
 	expander *playlist.Expander
+	store    *storage.Storage
 }
 
-func New(cfg *config.Config, jobs repo.JobRepo, items repo.ItemRepo, tokens repo.TokenRepo, tags repo.TagRepo, pool Enqueuer, settings repo.SettingsRepo) (*Bot, error) {
+func New(cfg *config.Config, jobs repo.JobRepo, items repo.ItemRepo, tokens repo.TokenRepo, tags repo.TagRepo, pool Enqueuer, settings repo.SettingsRepo, users repo.UserRepo, subs repo.SubscriptionRepo, presets repo.PresetRepo, rules repo.DomainRuleRepo, store *storage.Storage) (*Bot, error) {
 	var httpClient *http.Client
 	if cfg.TelegramProxy != "" {
 		proxyURL, err := url.Parse(cfg.TelegramProxy)
@@ -60,9 +66,10 @@ func New(cfg *config.Config, jobs repo.JobRepo, items repo.ItemRepo, tokens repo
 
 	b := &Bot{
 		cfg: cfg, api: api, jobs: jobs, items: items, tokens: tokens, tags: tags,
-		settings: settings, pool: pool,
-		expander: playlist.New(jobs, tags),
+		settings: settings, users: users, subs: subs, presets: presets, pool: pool,
+		expander: playlist.New(jobs, tags), store: store,
 	}
+	b.expander.Rules = rules
 	b.setCommands()
 	return b, nil
 }
@@ -72,8 +79,11 @@ func (b *Bot) setCommands() {
 		tgbotapi.BotCommand{Command: "start", Description: "Начало работы"},
 		tgbotapi.BotCommand{Command: "status", Description: "Статус очереди"},
 		tgbotapi.BotCommand{Command: "queue", Description: "Активные задачи"},
-		tgbotapi.BotCommand{Command: "last", Description: "Последние файлы (/last N, по умолчанию 5)"},
+		tgbotapi.BotCommand{Command: "last", Description: "Последние файлы (/last N, /last new — непросмотренные)"},
 		tgbotapi.BotCommand{Command: "search", Description: "Поиск по файлам (/search запрос)"},
+		tgbotapi.BotCommand{Command: "subscribe", Description: "Подписаться на канал (/subscribe ссылка)"},
+		tgbotapi.BotCommand{Command: "unsubscribe", Description: "Отписаться (/unsubscribe номер)"},
+		tgbotapi.BotCommand{Command: "urgent", Description: "Скачать раньше остальных (/urgent ссылка)"},
 		tgbotapi.BotCommand{Command: "web", Description: "Перейти на сайт"},
 		tgbotapi.BotCommand{Command: "help", Description: "Помощь"},
 	)
@@ -100,6 +110,20 @@ func (b *Bot) Notify(ctx context.Context, n worker.Notification) {
 			),
 		)
 
+	case worker.NotifJobProgress:
+		// Обновляем «скачивается» строкой прогресса (воркер ограничивает частоту).
+		if n.MessageID == 0 || n.Progress == nil {
+			return
+		}
+		b.editMsg(n.ChatID, int(n.MessageID),
+			"⬇️ <b>Скачивается…</b> "+progressLine(n.Progress)+"\n"+escapeHTML(shortenMsg(n.JobURL)),
+			tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("🛑 Отменить", "stop:"+n.JobID),
+				),
+			),
+		)
+
 	case worker.NotifFileDone:
 		// Новое сообщение-карточка на каждый файл.
 		b.sendFileCard(n.ChatID, n.FileName, n.Token)
@@ -120,7 +144,7 @@ func (b *Bot) Notify(ctx context.Context, n worker.Notification) {
 			errShort = string([]rune(errShort)[:197]) + "…"
 		}
 		b.editMsg(n.ChatID, int(n.MessageID),
-			"❌ <b>Ошибка скачивания</b>\n"+escapeHTML(shortenMsg(n.JobURL))+"\n\n<code>"+escapeHTML(errShort)+"</code>",
+			"❌ <b>Ошибка скачивания</b>\n"+escapeHTML(shortenMsg(n.JobURL))+errClassText(n.ErrClass)+"\n\n<code>"+escapeHTML(errShort)+"</code>",
 			tgbotapi.NewInlineKeyboardMarkup(
 				tgbotapi.NewInlineKeyboardRow(
 					tgbotapi.NewInlineKeyboardButtonData("↩ Повторить", "retry:"+n.JobID),
@@ -134,7 +158,7 @@ func (b *Bot) Notify(ctx context.Context, n worker.Notification) {
 			return
 		}
 		b.editMsg(n.ChatID, int(n.MessageID),
-			"🔄 <b>Повтор "+n.RetryAt+"</b>\n"+escapeHTML(shortenMsg(n.JobURL)),
+			"🔄 <b>Повтор "+n.RetryAt+"</b>\n"+escapeHTML(shortenMsg(n.JobURL))+errClassText(n.ErrClass),
 			tgbotapi.NewInlineKeyboardMarkup(
 				tgbotapi.NewInlineKeyboardRow(
 					tgbotapi.NewInlineKeyboardButtonData("🛑 Отменить", "stop:"+n.JobID),
@@ -283,3 +307,30 @@ func shortenMsg(s string) string {
 	}
 	return s
 }
+
+// errClassText — строка с объяснением причины ошибки; пусто, если класс не распознан.
+func errClassText(c model.ErrorClass) string {
+	if c == model.ErrUnknown {
+		return ""
+	}
+	return "\n<i>" + escapeHTML(c.Explain()) + "</i>"
+}
+
+// progressLine форматирует прогресс: «45% · 12.3/120.0 МБ · 2.1 МБ/с · ~1:23».
+func progressLine(p *model.Progress) string {
+	const mb = 1 << 20
+	parts := []string{}
+	if p.Total > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f%%", p.Percent),
+			fmt.Sprintf("%.1f/%.1f МБ", float64(p.Downloaded)/mb, float64(p.Total)/mb))
+	} else {
+		parts = append(parts, fmt.Sprintf("%.1f МБ", float64(p.Downloaded)/mb))
+	}
+	if p.Speed > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f МБ/с", p.Speed/mb))
+	}
+	if p.ETA >= 0 {
+		parts = append(parts, fmt.Sprintf("~%d:%02d", p.ETA/60, p.ETA%60))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -11,20 +11,24 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/dr-duke/talmorGo/internal/auth"
 	"github.com/dr-duke/talmorGo/internal/downloader"
 	"github.com/dr-duke/talmorGo/internal/model"
 	"github.com/dr-duke/talmorGo/internal/repo"
 )
 
 func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
-	if !b.isAllowed(msg.Chat.ID) {
+	if !b.isAllowed(ctx, msg.Chat.ID) {
 		b.send(msg.Chat.ID, "🛑 This bot is private")
 		return
 	}
 	if msg.IsCommand() {
 		b.handleCommand(ctx, msg)
+	} else if text, ok := strings.CutPrefix(strings.TrimSpace(msg.Text), "!"); ok {
+		// «!ссылка» — то же, что /urgent.
+		b.handleURL(ctx, msg.Chat.ID, text, model.PriorityUrgent)
 	} else {
-		b.handleURL(ctx, msg)
+		b.handleURL(ctx, msg.Chat.ID, msg.Text, model.PriorityNormal)
 	}
 }
 
@@ -39,10 +43,14 @@ func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 			"📋 <b>Команды:</b>\n"+
 				"/status — статус очереди\n"+
 				"/queue — активные задачи\n"+
-				"/last [N] — последние N файлов (по умолчанию 5)\n"+
-				"/search запрос — поиск по файлам, URL и тегам\n\n"+
+				"/last [N] [new] — последние N файлов (по умолчанию 5), new — только непросмотренные\n"+
+				"/search запрос — поиск по названиям, тегам, описаниям и субтитрам; \"фраза\", tag:, domain:, kind:\n"+
+				"/subscribe [ссылка] — подписаться на канал или плейлист (без ссылки — список подписок)\n"+
+				"/unsubscribe номер|ссылка — отписаться\n"+
+				"/urgent ссылка — скачать раньше остальных (или «!» перед ссылкой)\n\n"+
 				"Просто отправь ссылку, чтобы поставить в очередь.\n"+
-				"Можно отправить несколько ссылок через пробел.")
+				"Можно отправить несколько ссылок через пробел.\n"+
+				"После ссылки можно указать опции: 720p, audio, mp3, mkv, subs=en,ru, thumb.")
 	case "status":
 		b.handleStatus(ctx, msg.Chat.ID)
 	case "queue":
@@ -51,6 +59,16 @@ func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 		b.handleSearch(ctx, msg.Chat.ID, msg.CommandArguments())
 	case "last":
 		b.handleLast(ctx, msg.Chat.ID, msg.CommandArguments())
+	case "subscribe":
+		b.handleSubscribe(ctx, msg.Chat.ID, msg.CommandArguments())
+	case "unsubscribe":
+		b.handleUnsubscribe(ctx, msg.Chat.ID, msg.CommandArguments())
+	case "urgent":
+		if strings.TrimSpace(msg.CommandArguments()) == "" {
+			b.send(msg.Chat.ID, "Использование: /urgent <ссылка>")
+			return
+		}
+		b.handleURL(ctx, msg.Chat.ID, msg.CommandArguments(), model.PriorityUrgent)
 	case "web":
 		b.send(msg.Chat.ID, "🌐 "+b.cfg.BaseURL)
 	default:
@@ -59,7 +77,7 @@ func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 }
 
 func (b *Bot) handleStatus(ctx context.Context, chatID int64) {
-	all, err := b.jobs.List(ctx, repo.JobFilter{})
+	all, err := b.jobs.List(ctx, repo.JobFilter{OwnerID: b.chatUser(ctx, chatID).OwnerScope()})
 	if err != nil {
 		b.send(chatID, "Ошибка получения статуса")
 		return
@@ -68,16 +86,21 @@ func (b *Bot) handleStatus(ctx context.Context, chatID int64) {
 	for _, j := range all {
 		counts[j.Status]++
 	}
-	b.send(chatID, fmt.Sprintf(
-		"📊 <b>Статус очереди:</b>\n⏳ Ожидание: %d\n▶️ В работе: %d\n🔄 Повтор: %d\n✅ Готово: %d\n❌ Ошибка: %d",
+	text := fmt.Sprintf(
+		"📊 <b>Статус очереди:</b>\n⏳ Ожидание: %d\n▶️ В работе: %d\n🔄 Повтор: %d\n⏸ На паузе: %d\n✅ Готово: %d\n❌ Ошибка: %d",
 		counts[model.JobPending], counts[model.JobRunning], counts[model.JobRetrying],
-		counts[model.JobDone], counts[model.JobFailed],
-	))
+		counts[model.JobPaused], counts[model.JobDone], counts[model.JobFailed],
+	)
+	if v, _ := b.settings.Get(ctx, "queue_paused"); v != "" {
+		text += "\n\n⏸ Очередь на паузе: новые загрузки не начинаются"
+	}
+	b.send(chatID, text)
 }
 
 func (b *Bot) handleQueue(ctx context.Context, chatID int64) {
 	jobs, err := b.jobs.List(ctx, repo.JobFilter{
-		Statuses: []model.JobStatus{model.JobPending, model.JobRunning, model.JobRetrying},
+		Statuses: []model.JobStatus{model.JobPending, model.JobRunning, model.JobRetrying, model.JobPaused},
+		OwnerID:  b.chatUser(ctx, chatID).OwnerScope(),
 	})
 	if err != nil {
 		b.send(chatID, "Ошибка получения очереди")
@@ -96,6 +119,8 @@ func (b *Bot) handleQueue(ctx context.Context, chatID int64) {
 			status = "▶️"
 		case model.JobRetrying:
 			status = "🔄"
+		case model.JobPaused:
+			status = "⏸"
 		}
 		shortID := j.ID
 		if len(shortID) > 8 {
@@ -139,46 +164,72 @@ func (b *Bot) resolveDownloaderOpts(ctx context.Context) downloader.Options {
 	}
 }
 
-func (b *Bot) handleURL(ctx context.Context, msg *tgbotapi.Message) {
-	text := strings.TrimSpace(msg.Text)
+// handleURL ставит в очередь ссылки из текста сообщения с заданным приоритетом.
+func (b *Bot) handleURL(ctx context.Context, chatID int64, text string, priority int) {
+	text = strings.TrimSpace(text)
 	if text == "" {
 		return
 	}
+	user := b.chatUser(ctx, chatID)
+	if !user.CanWrite() {
+		b.send(chatID, "🛑 Роль «viewer» не позволяет добавлять загрузки")
+		return
+	}
 
+	// Опции («720p», «mp3», «subs=en»…) относятся ко всем ссылкам сообщения.
+	jobOpts, parts := model.ParseOptionTokens(strings.Fields(text))
+	if errMsg := jobOpts.Validate(); errMsg != "" {
+		b.send(chatID, "❌ "+errMsg)
+		return
+	}
+	proto := model.Job{Source: "telegram", ChatID: chatID, OwnerID: user.ID, Options: jobOpts, Priority: priority}
 	dlOpts := b.resolveDownloaderOpts(ctx)
+	presets, err := b.presets.List(ctx)
+	if err != nil {
+		slog.Warn("bot: list presets", "err", err)
+	}
 
 	var added, invalid int
-	for _, part := range strings.Fields(text) {
+	for _, part := range parts {
 		if _, err := url.ParseRequestURI(part); err != nil {
 			invalid++
 			continue
 		}
 
-		if info := downloader.FetchPlaylist(ctx, part, dlOpts); info != nil {
+		if info, single := downloader.FetchPlaylist(ctx, part, b.expander.OptsFor(ctx, part, dlOpts)); info != nil {
 			// Плейлист — создаём отдельный job на каждое видео.
-			n := b.createPlaylistJobs(ctx, msg.Chat.ID, part, info)
+			n := b.createPlaylistJobs(ctx, proto, part, info)
 			added += n
 		} else {
 			// Одиночное видео — текущее поведение с анимированным сообщением.
 			job := &model.Job{
-				URL:    part,
-				Status: model.JobPending,
-				Source: "telegram",
-				ChatID: msg.Chat.ID,
+				URL:       part,
+				Status:    model.JobPending,
+				Source:    "telegram",
+				ChatID:    chatID,
+				OwnerID:   user.ID,
+				Options:   jobOpts,
+				Priority:  priority,
+				Extractor: single.Extractor,
+				VideoID:   single.ID,
+			}
+			// Такое видео уже есть — задание ждёт в checking, пока пользователь не выберет.
+			if dup := b.expander.FindDuplicate(ctx, job); dup != nil {
+				job.Status = model.JobChecking
+				job.MarkDuplicate(dup)
 			}
 			if err := b.jobs.Create(ctx, job); err != nil {
 				slog.Error("bot: create job", "err", err)
 				continue
 			}
-			stopKb := tgbotapi.NewInlineKeyboardMarkup(
-				tgbotapi.NewInlineKeyboardRow(
-					tgbotapi.NewInlineKeyboardButtonData("🛑 Отменить", "stop:"+job.ID),
-				),
-			)
-			msgID := b.sendMarkup(msg.Chat.ID,
-				"⏳ <b>В очереди</b>\n"+escapeHTML(shortenMsg(part)),
-				&stopKb,
-			)
+			if job.IsDuplicate() {
+				kb := duplicateKeyboard(job)
+				b.sendMarkup(chatID, duplicateText(job), &kb)
+				added++
+				continue
+			}
+			kb := queuedKeyboard(job, presets)
+			msgID := b.sendMarkup(chatID, queuedText(job, presets), &kb)
 			if msgID != 0 {
 				b.jobs.SetTgMessageID(ctx, job.ID, msgID) //nolint:errcheck
 			}
@@ -188,17 +239,21 @@ func (b *Bot) handleURL(ctx context.Context, msg *tgbotapi.Message) {
 	}
 
 	if added == 0 {
-		b.send(msg.Chat.ID, "❌ Не найдено корректных ссылок")
+		b.send(chatID, "❌ Не найдено корректных ссылок")
 	} else if invalid > 0 {
-		b.send(msg.Chat.ID, fmt.Sprintf("⚠️ Пропущено (не URL): %d", invalid))
+		b.send(chatID, fmt.Sprintf("⚠️ Пропущено (не URL): %d", invalid))
 	}
 }
 
 // createPlaylistJobs разворачивает плейлист в отдельные задания (через общий Expander)
-// и отправляет одно сводное сообщение. Возвращает число созданных заданий.
-func (b *Bot) createPlaylistJobs(ctx context.Context, chatID int64, originalURL string, info *downloader.PlaylistInfo) int {
-	created := b.expander.CreateJobs(ctx, info, "telegram", chatID)
-	if created == 0 {
+// и отправляет одно сводное сообщение. Видео, которые уже есть, Expander пропускает.
+// Возвращает число обработанных видео: созданных и пропущенных.
+func (b *Bot) createPlaylistJobs(ctx context.Context, proto model.Job, originalURL string, info *downloader.PlaylistInfo) int {
+	chatID := proto.ChatID
+	created := len(b.expander.CreateJobs(ctx, info, proto))
+	// Остаток — в основном уже известные видео (ошибки записи в БД редки и есть в логе).
+	skipped := len(info.Entries) - created
+	if created == 0 && skipped == 0 {
 		return 0
 	}
 
@@ -208,14 +263,223 @@ func (b *Bot) createPlaylistJobs(ctx context.Context, chatID int64, originalURL
 		title = shortenMsg(originalURL)
 	}
 	text := fmt.Sprintf("📋 <b>%s</b>\n⏳ Добавлено в очередь: <b>%d</b> видео",
-		escapeHTML(title), created)
+		escapeHTML(title), created) + optionsLine(proto.Options)
+	if skipped > 0 {
+		text += fmt.Sprintf("\n📂 Уже есть, пропущено: <b>%d</b>", skipped)
+	}
 	b.send(chatID, text)
-	return created
+	return created + skipped
+}
+
+// duplicateText — сообщение о том, что видео уже добавляли.
+func duplicateText(job *model.Job) string {
+	return "⚠️ <b>Это видео уже есть</b>\n" + escapeHTML(job.Error) + "\n" + escapeHTML(shortenMsg(job.URL))
+}
+
+// duplicateKeyboard — выбор для задания-дубликата; в callback — ID нового задания.
+func duplicateKeyboard(job *model.Job) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📂 Открыть", "dup:open:"+job.ID),
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Скачать заново", "dup:re:"+job.ID),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Всё равно добавить", "dup:add:"+job.ID),
+		),
+	)
+}
+
+// handleDuplicateCallback выполняет выбор пользователя для задания-дубликата:
+// open — показать уже добавленное, re — скачать его заново, add — добавить копию.
+func (b *Bot) handleDuplicateCallback(ctx context.Context, cq *tgbotapi.CallbackQuery, arg string) {
+	chatID := cq.Message.Chat.ID
+	action, jobID, _ := strings.Cut(arg, ":")
+	if !b.canManage(ctx, chatID, jobID) {
+		b.answerCallback(cq.ID, "🛑 Доступ запрещён")
+		return
+	}
+	job, err := b.jobs.GetByID(ctx, jobID)
+	if err != nil || !job.IsDuplicate() {
+		b.answerCallback(cq.ID, "⚠️ Уже решено")
+		b.deleteMsg(chatID, cq.Message.MessageID)
+		return
+	}
+
+	if action == "add" {
+		if err := b.jobs.SetDuplicate(ctx, jobID, "", ""); err != nil {
+			b.answerCallback(cq.ID, "Ошибка: "+err.Error())
+			return
+		}
+		if err := b.jobs.ConfirmSingle(ctx, jobID); err != nil {
+			b.answerCallback(cq.ID, "Ошибка: "+err.Error())
+			return
+		}
+		b.pool.Enqueue()
+		b.answerCallback(cq.ID, "⏳ Добавлено в очередь")
+		job.Status, job.Error, job.DuplicateOf = model.JobPending, "", ""
+		presets, _ := b.presets.List(ctx)
+		b.editMsg(chatID, cq.Message.MessageID, queuedText(job, presets), queuedKeyboard(job, presets))
+		b.jobs.SetTgMessageID(ctx, jobID, int64(cq.Message.MessageID)) //nolint:errcheck
+		return
+	}
+
+	existing, err := b.jobs.GetByID(ctx, job.DuplicateOf)
+	if err != nil {
+		b.answerCallback(cq.ID, "Ошибка: задание не найдено")
+		return
+	}
+	if err := b.jobs.DeleteChecking(ctx, jobID); err != nil {
+		b.answerCallback(cq.ID, "Ошибка: "+err.Error())
+		return
+	}
+	b.deleteMsg(chatID, cq.Message.MessageID)
+
+	// Заново скачивается только готовое задание: ожидающее и так скачается,
+	// а у найденного сканером файла нет ссылки.
+	if action == "re" && existing.Status == model.JobDone {
+		if err := b.redownload(ctx, existing); err != nil {
+			b.answerCallback(cq.ID, "Ошибка: "+err.Error())
+			return
+		}
+		b.answerCallback(cq.ID, "🔁 Скачиваю заново")
+		stopKb := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🛑 Отменить", "stop:"+existing.ID),
+			),
+		)
+		b.sendMarkup(chatID, "⏳ <b>В очереди</b> (заново)\n"+escapeHTML(shortenMsg(existing.URL)), &stopKb)
+		return
+	}
+
+	b.answerCallback(cq.ID, "")
+	if existing.Hidden {
+		b.jobs.Unhide(ctx, existing.ID) //nolint:errcheck
+	}
+	if existing.Status != model.JobDone && existing.Status != model.JobImported {
+		b.send(chatID, "⏳ <b>Уже в очереди</b>\n"+escapeHTML(existing.DisplayName()))
+		return
+	}
+	items, err := b.items.ListByJobID(ctx, existing.ID)
+	if err != nil {
+		slog.Warn("bot: list items", "job", existing.ID, "err", err)
+	}
+	media := make([]*model.MediaItem, 0, len(items))
+	for _, it := range items {
+		media = append(media, &model.MediaItem{Job: existing, Item: it})
+	}
+	if len(media) == 0 {
+		media = append(media, &model.MediaItem{Job: existing})
+	}
+	b.sendMediaList(ctx, chatID, "📂 Уже в библиотеке:", media)
+}
+
+// redownload удаляет файлы готового задания и ставит его в очередь заново.
+func (b *Bot) redownload(ctx context.Context, job *model.Job) error {
+	if items, err := b.items.ListByJobID(ctx, job.ID); err == nil {
+		for _, it := range items {
+			b.store.DeleteItem(it) //nolint:errcheck
+		}
+	}
+	if err := b.items.DeleteAllByJobID(ctx, job.ID); err != nil {
+		slog.Warn("bot: delete items for redownload", "job", job.ID, "err", err)
+	}
+	if err := b.jobs.Redownload(ctx, job.ID); err != nil {
+		return err
+	}
+	if err := b.jobs.ConfirmSingle(ctx, job.ID); err != nil {
+		return err
+	}
+	b.pool.Enqueue()
+	return nil
+}
+
+// queuedText — сообщение «В очереди» с опциями и пресетом задания.
+func queuedText(job *model.Job, presets []*model.Preset) string {
+	header := "⏳ <b>В очереди</b>\n"
+	if job.Priority > model.PriorityNormal {
+		header = "⚡ <b>В очереди, срочно</b>\n"
+	}
+	text := header + escapeHTML(shortenMsg(job.URL)) + optionsLine(job.Options)
+	if p := effectivePreset(job, presets); p != nil {
+		text += "\n🎛 " + escapeHTML(p.Name)
+	}
+	return text
+}
+
+// queuedKeyboard — кнопка отмены и, если пресеты заведены, выбор пресета
+// (по два в ряд; выбранный отмечен галочкой). В callback — индекс в списке пресетов.
+func queuedKeyboard(job *model.Job, presets []*model.Preset) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🛑 Отменить", "stop:"+job.ID)),
+	}
+	current := effectivePreset(job, presets)
+	var row []tgbotapi.InlineKeyboardButton
+	for i, p := range presets {
+		label := p.Name
+		if p == current {
+			label = "✅ " + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("preset:%s:%d", job.ID, i)))
+		if len(row) == 2 || i == len(presets)-1 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// effectivePreset — пресет, которым будет скачано задание: выбранный или по умолчанию.
+func effectivePreset(job *model.Job, presets []*model.Preset) *model.Preset {
+	var def *model.Preset
+	for _, p := range presets {
+		if p.ID == job.PresetID {
+			return p
+		}
+		if p.IsDefault {
+			def = p
+		}
+	}
+	return def
+}
+
+// handlePresetCallback меняет пресет задания, пока оно ждёт в очереди.
+func (b *Bot) handlePresetCallback(ctx context.Context, cq *tgbotapi.CallbackQuery, arg string) {
+	chatID := cq.Message.Chat.ID
+	jobID, idxStr, _ := strings.Cut(arg, ":")
+	if !b.canManage(ctx, chatID, jobID) {
+		b.answerCallback(cq.ID, "🛑 Доступ запрещён")
+		return
+	}
+	presets, err := b.presets.List(ctx)
+	idx, convErr := strconv.Atoi(idxStr)
+	if err != nil || convErr != nil || idx < 0 || idx >= len(presets) {
+		b.answerCallback(cq.ID, "⚠️ Пресет не найден — список изменился")
+		return
+	}
+	if err := b.jobs.SetPreset(ctx, jobID, presets[idx].ID); err != nil {
+		b.answerCallback(cq.ID, "⚠️ Поздно — задание уже скачивается")
+		return
+	}
+	job, err := b.jobs.GetByID(ctx, jobID)
+	if err != nil {
+		b.answerCallback(cq.ID, "Ошибка: задание не найдено")
+		return
+	}
+	b.answerCallback(cq.ID, "🎛 "+presets[idx].Name)
+	b.editMsg(chatID, cq.Message.MessageID, queuedText(job, presets), queuedKeyboard(job, presets))
+}
+
+// optionsLine — строка с опциями задания для сообщения «В очереди»; пусто для глобальных.
+func optionsLine(o model.JobOptions) string {
+	if o.IsZero() {
+		return ""
+	}
+	return "\n⚙️ " + escapeHTML(o.Summary())
 }
 
 // handleCallback обрабатывает нажатие inline-кнопок.
 func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
-	if !b.isAllowed(cq.From.ID) {
+	if !b.isAllowed(ctx, cq.From.ID) {
 		b.answerCallback(cq.ID, "🛑 Доступ запрещён")
 		return
 	}
@@ -242,6 +506,10 @@ func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
 	case strings.HasPrefix(data, "stop:"):
 		// Мягкая отмена: статус cancelled, URL сохраняется в БД.
 		jobID := strings.TrimPrefix(data, "stop:")
+		if !b.canManage(ctx, chatID, jobID) {
+			b.answerCallback(cq.ID, "🛑 Доступ запрещён")
+			return
+		}
 		if err := b.jobs.Cancel(ctx, jobID); err != nil {
 			b.answerCallback(cq.ID, "⚠️ Нельзя отменить — задание уже выполняется")
 			return
@@ -249,6 +517,12 @@ func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
 		b.answerCallback(cq.ID, "🛑 Задача отменена")
 		b.deleteMsg(chatID, cq.Message.MessageID)
 
+	case strings.HasPrefix(data, "dup:"):
+		b.handleDuplicateCallback(ctx, cq, strings.TrimPrefix(data, "dup:"))
+
+	case strings.HasPrefix(data, "preset:"):
+		b.handlePresetCallback(ctx, cq, strings.TrimPrefix(data, "preset:"))
+
 	case strings.HasPrefix(data, "retry:"):
 		// Сбрасываем failed-задачу в pending, редактируем сообщение в «очередь».
 		jobID := strings.TrimPrefix(data, "retry:")
@@ -257,6 +531,10 @@ func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
 			b.answerCallback(cq.ID, "Ошибка: задание не найдено")
 			return
 		}
+		if !b.chatUser(ctx, chatID).Owns(job) {
+			b.answerCallback(cq.ID, "🛑 Доступ запрещён")
+			return
+		}
 		if err := b.jobs.ResetFailed(ctx, jobID); err != nil {
 			b.answerCallback(cq.ID, "Ошибка: "+err.Error())
 			return
@@ -281,10 +559,11 @@ func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
 func (b *Bot) handleSearch(ctx context.Context, chatID int64, args string) {
 	q := strings.TrimSpace(args)
 	if q == "" {
-		b.send(chatID, "Использование: /search <запрос>")
+		b.send(chatID, "Использование: /search <запрос>\n"+
+			"Слова ищутся по началу, \"фраза в кавычках\" — целиком; фильтры: tag:имя, domain:сайт, kind:video|audio")
 		return
 	}
-	items, err := b.jobs.SearchMedia(ctx, q)
+	items, err := b.jobs.SearchMedia(ctx, q, b.chatUser(ctx, chatID).OwnerScope())
 	if err != nil {
 		b.send(chatID, "Ошибка поиска")
 		return
@@ -300,26 +579,36 @@ func (b *Bot) handleSearch(ctx context.Context, chatID int64, args string) {
 
 func (b *Bot) handleLast(ctx context.Context, chatID int64, args string) {
 	n := 5
-	if s := strings.TrimSpace(args); s != "" {
+	unwatched := false // «/last new» — только непросмотренные
+	for _, s := range strings.Fields(args) {
 		if v, err := strconv.Atoi(s); err == nil && v > 0 {
 			n = v
+		} else if s == "new" {
+			unwatched = true
 		}
 	}
 	if n > 20 {
 		n = 20
 	}
-	items, err := b.jobs.LastMedia(ctx, n)
+	user := b.chatUser(ctx, chatID)
+	items, err := b.jobs.LastMedia(ctx, n, user.OwnerScope(), user.ID, unwatched)
 	if err != nil {
 		b.send(chatID, "Ошибка получения списка")
 		return
 	}
+	if len(items) == 0 && unwatched {
+		b.send(chatID, "Непросмотренных файлов нет")
+		return
+	}
 	if len(items) == 0 {
 		b.send(chatID, "Скачанных файлов пока нет")
 		return
 	}
-	b.sendMediaList(ctx, chatID,
-		fmt.Sprintf("📼 Последние %d файлов:", len(items)),
-		items)
+	header := fmt.Sprintf("📼 Последние %d файлов:", len(items))
+	if unwatched {
+		header = fmt.Sprintf("📼 Последние %d непросмотренных файлов:", len(items))
+	}
+	b.sendMediaList(ctx, chatID, header, items)
 }
 
 // sendMediaList отправляет нумерованный список с inline-кнопками для каждого доступного файла.
@@ -388,12 +677,40 @@ func escapeHTML(s string) string {
 	return s
 }
 
-func (b *Bot) isAllowed(chatID int64) bool {
+// isAllowed пускает чаты из TELEGRAM_ALLOWED_IDS и чаты, привязанные к пользователям.
+func (b *Bot) isAllowed(ctx context.Context, chatID int64) bool {
 	if len(b.cfg.TelegramAllowedIDs) == 0 {
 		slog.Warn("bot: TELEGRAM_ALLOWED_IDS not set — all users allowed")
 		return true
 	}
-	return slices.Contains(b.cfg.TelegramAllowedIDs, chatID)
+	if slices.Contains(b.cfg.TelegramAllowedIDs, chatID) {
+		return true
+	}
+	if b.users == nil {
+		return false
+	}
+	_, err := b.users.GetByChatID(ctx, chatID)
+	return err == nil
+}
+
+// chatUser возвращает пользователя, к которому привязан чат.
+// Непривязанные разрешённые чаты работают как администратор с общей библиотекой.
+func (b *Bot) chatUser(ctx context.Context, chatID int64) *model.User {
+	if b.users != nil {
+		if u, err := b.users.GetByChatID(ctx, chatID); err == nil {
+			return u
+		}
+	}
+	return auth.Root
+}
+
+// canManage проверяет, может ли пользователь чата управлять заданием.
+func (b *Bot) canManage(ctx context.Context, chatID int64, jobID string) bool {
+	job, err := b.jobs.GetByID(ctx, jobID)
+	if err != nil {
+		return false
+	}
+	return b.chatUser(ctx, chatID).Owns(job)
 }
 
 func shortenURL(u string) string {
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+// defaultSubscriptionInterval — период опроса подписок, созданных из Telegram.
+const defaultSubscriptionInterval = time.Hour
+
+// handleSubscribe подписывает чат на канал или плейлист; без аргументов — показывает подписки.
+func (b *Bot) handleSubscribe(ctx context.Context, chatID int64, args string) {
+	rawURL := strings.TrimSpace(args)
+	if rawURL == "" {
+		b.sendSubscriptions(ctx, chatID)
+		return
+	}
+	user := b.chatUser(ctx, chatID)
+	if !user.CanWrite() {
+		b.send(chatID, "🛑 Роль «viewer» не позволяет подписываться")
+		return
+	}
+	if _, err := url.ParseRequestURI(rawURL); err != nil {
+		b.send(chatID, "Использование: /subscribe <ссылка на канал или плейлист>")
+		return
+	}
+	for _, s := range b.chatSubscriptions(ctx, chatID) {
+		if s.URL == rawURL {
+			b.send(chatID, "Уже подписаны на эту ссылку")
+			return
+		}
+	}
+	s := &model.Subscription{
+		URL:      rawURL,
+		Interval: defaultSubscriptionInterval,
+		OwnerID:  user.ID,
+		ChatID:   chatID,
+		Enabled:  true,
+	}
+	if err := b.subs.Create(ctx, s); err != nil {
+		slog.Error("bot: create subscription", "err", err)
+		b.send(chatID, "Ошибка создания подписки")
+		return
+	}
+	b.send(chatID, "🔔 Подписка создана: новые видео будут ставиться в очередь автоматически.\n"+
+		"Уже опубликованные видео не скачиваются. Проверка — раз в час.")
+}
+
+// handleUnsubscribe удаляет подписку по номеру из /subscribe или по ссылке.
+func (b *Bot) handleUnsubscribe(ctx context.Context, chatID int64, args string) {
+	arg := strings.TrimSpace(args)
+	subs := b.chatSubscriptions(ctx, chatID)
+	if arg == "" || len(subs) == 0 {
+		b.sendSubscriptions(ctx, chatID)
+		return
+	}
+	var target *model.Subscription
+	if n, err := strconv.Atoi(arg); err == nil && n >= 1 && n <= len(subs) {
+		target = subs[n-1]
+	} else {
+		for _, s := range subs {
+			if s.URL == arg {
+				target = s
+				break
+			}
+		}
+	}
+	if target == nil {
+		b.send(chatID, "Подписка не найдена. Список — /subscribe")
+		return
+	}
+	if err := b.subs.Delete(ctx, target.ID); err != nil {
+		slog.Error("bot: delete subscription", "err", err)
+		b.send(chatID, "Ошибка удаления подписки")
+		return
+	}
+	b.send(chatID, "🔕 Отписались от "+escapeHTML(subscriptionLabel(target)))
+}
+
+func (b *Bot) sendSubscriptions(ctx context.Context, chatID int64) {
+	subs := b.chatSubscriptions(ctx, chatID)
+	if len(subs) == 0 {
+		b.send(chatID, "Подписок нет. Подписаться: /subscribe <ссылка>")
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("🔔 <b>Подписки:</b>\n")
+	for i, s := range subs {
+		state := ""
+		if !s.Enabled {
+			state = " (пауза)"
+		} else if s.LastError != "" {
+			state = " ⚠️"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s%s\n", i+1, escapeHTML(shortenURL(subscriptionLabel(s))), state))
+	}
+	sb.WriteString("\nОтписаться: /unsubscribe номер")
+	b.send(chatID, sb.String())
+}
+
+// chatSubscriptions — подписки пользователя, к которому привязан чат;
+// для непривязанного чата — созданные из него.
+func (b *Bot) chatSubscriptions(ctx context.Context, chatID int64) []*model.Subscription {
+	user := b.chatUser(ctx, chatID)
+	subs, err := b.subs.List(ctx, user.ID)
+	if err != nil {
+		slog.Error("bot: list subscriptions", "err", err)
+		return nil
+	}
+	if user.ID != "" {
+		return subs
+	}
+	var own []*model.Subscription
+	for _, s := range subs {
+		if s.ChatID == chatID {
+			own = append(own, s)
+		}
+	}
+	return own
+}
+
+func subscriptionLabel(s *model.Subscription) string {
+	if s.Title != "" {
+		return s.Title
+	}
+	return s.URL
+}
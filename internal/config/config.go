@@ -12,15 +12,18 @@ type Config struct {
 	DBPath string `long:"db-path" env:"DB_PATH" default:"/data/talmor.db"`
 
 	// HTTP server
-	HTTPPort       string `long:"http-port" env:"HTTP_PORT" default:"8080"`
-	HTTPHost       string `long:"http-host" env:"HTTP_HOST" default:""`
-	BaseURL        string `long:"base-url" env:"BASE_URL"`
-	SiteName       string `long:"site-name" env:"SITE_NAME" default:"TalmorGo"`
+	HTTPPort string `long:"http-port" env:"HTTP_PORT" default:"8080"`
+	HTTPHost string `long:"http-host" env:"HTTP_HOST" default:""`
+	BaseURL  string `long:"base-url" env:"BASE_URL"`
+	SiteName string `long:"site-name" env:"SITE_NAME" default:"TalmorGo"`
 	// BasePath — префикс пути, если приложение смонтировано не в корне (напр. /talmor).
 	// Ingress передаёт запросы с полным путём; приложение само снимает префикс.
 	BasePath       string `long:"base-path" env:"BASE_PATH" default:""`
 	HealthEndpoint string `long:"health-endpoint" env:"HEALTH_ENDPOINT" default:"/health"`
 	WebToken       string `long:"web-token" env:"WEB_TOKEN"`
+	// Первый администратор создаётся при старте, если пользователей ещё нет.
+	AdminUsername string `long:"admin-username" env:"ADMIN_USERNAME" default:"admin"`
+	AdminPassword string `long:"admin-password" env:"ADMIN_PASSWORD"`
 
 	// Telegram bot
 	TelegramBotToken   string  `long:"telegram-bot-token" env:"TELEGRAM_BOT_TOKEN"`
@@ -36,14 +39,24 @@ type Config struct {
 	YtDlpTimeout      int    `long:"yt-dlp-timeout" env:"YT_DLP_TIMEOUT" default:"300"`
 	YtDlpExtraArgs    string `long:"yt-dlp-extra-args" env:"YT_DLP_EXTRA_ARGS"`
 	// Каталог незавершённых загрузок (вне зоны сканирования). Пусто → <output>/.talmor-tmp.
-	YtDlpStagingDir   string `long:"yt-dlp-staging-dir" env:"YT_DLP_STAGING_DIR" default:""`
+	YtDlpStagingDir string `long:"yt-dlp-staging-dir" env:"YT_DLP_STAGING_DIR" default:""`
+
+	// Субтитры для плеера: языки через запятую ("en,ru"), пусто — не скачивать;
+	// YT_DLP_AUTO_SUBS добавляет автоматически созданные субтитры.
+	YtDlpSubLangs string `long:"yt-dlp-sub-langs" env:"YT_DLP_SUB_LANGS"`
+	YtDlpAutoSubs bool   `long:"yt-dlp-auto-subs" env:"YT_DLP_AUTO_SUBS"`
+
+	// Запасные прокси для повторов после гео-блокировки, по очереди.
+	YtDlpGeoProxies []string `long:"yt-dlp-geo-proxies" env:"YT_DLP_GEO_PROXIES" env-delim:";"`
 
 	// Worker pool
 	WorkerCount int `long:"worker-count" env:"WORKER_COUNT" default:"2"`
+	// Окна загрузок, например "01:00-07:00;07:00-01:00 2M" (см. model.ParseSchedule). Пусто — качать всегда.
+	DownloadSchedule string `long:"download-schedule" env:"DOWNLOAD_SCHEDULE"`
 
 	// Retry backoff
-	RetryBackoffBase    int `long:"retry-backoff-base" env:"RETRY_BACKOFF_BASE" default:"30"`
-	RetryMaxDuration    int `long:"retry-max-duration" env:"RETRY_MAX_DURATION" default:"86400"`
+	RetryBackoffBase int `long:"retry-backoff-base" env:"RETRY_BACKOFF_BASE" default:"30"`
+	RetryMaxDuration int `long:"retry-max-duration" env:"RETRY_MAX_DURATION" default:"86400"`
 
 	// File health check (секунды между проверками)
 	FileCheckInterval int `long:"file-check-interval" env:"FILE_CHECK_INTERVAL" default:"300"`
@@ -54,10 +67,18 @@ type Config struct {
 	// Сканирование директории скачивания (секунды между сканами, 0 — выключено)
 	DirScanInterval int `long:"dir-scan-interval" env:"DIR_SCAN_INTERVAL" default:"0"`
 
-	// Извлечение аудио
+	// Извлечение аудио, превью и параметры файлов
 	FfmpegBinary   string `long:"ffmpeg-binary" env:"FFMPEG_BINARY" default:"ffmpeg"`
+	FfprobeBinary  string `long:"ffprobe-binary" env:"FFPROBE_BINARY" default:"ffprobe"`
 	AudioOutputDir string `long:"audio-output-dir" env:"AUDIO_OUTPUT_DIR" default:""`
 
+	// Перекодирование в HLS файлов, которые браузер не воспроизводит: высоты
+	// вариантов, предел кеша сегментов в МБ (0 — без предела) и число
+	// одновременных перекодирований.
+	HLSRenditions    string `long:"hls-renditions" env:"HLS_RENDITIONS" default:"720,480"`
+	HLSCacheMB       int    `long:"hls-cache-mb" env:"HLS_CACHE_MB" default:"4096"`
+	HLSMaxTranscodes int    `long:"hls-max-transcodes" env:"HLS_MAX_TRANSCODES" default:"2"`
+
 	// Медиатека
 	LibPageSize int `long:"lib-page-size" env:"LIB_PAGE_SIZE" default:"200"`
 }
@@ -71,9 +92,13 @@ func (c *Config) LinkBase() string {
 	return base + prefix
 }
 
-// StagingDir — каталог для незавершённых загрузок, вне зоны работы DirScanner.
-// По умолчанию — поддиректория OutputDir с точкой в имени: сканер пропускает dot-каталоги,
-// а нахождение на том же ФС гарантирует атомарный rename готового файла в OutputDir.
+// Служебные каталоги и файлы (staging, куки, превью, субтитры, HLS) лежат в OutputDir
+// под именами с точкой: DirScanner пропускает dot-каталоги и dot-файлы, поэтому
+// они не попадают в медиатеку.
+
+// StagingDir — каталог для незавершённых загрузок.
+// По умолчанию — поддиректория OutputDir: нахождение на том же ФС гарантирует
+// атомарный rename готового файла в OutputDir.
 func (c *Config) StagingDir() string {
 	if c.YtDlpStagingDir != "" {
 		return c.YtDlpStagingDir
@@ -90,11 +115,25 @@ func (c *Config) AudioDir() string {
 }
 
 // CookiesFilePath — путь к объединённому Netscape-файлу кук в зоне OutputDir.
-// Начинается с точки, поэтому DirScanner его пропускает.
 func (c *Config) CookiesFilePath() string {
 	return filepath.Join(c.YtDlpOutputDir, ".talmor-cookies.txt")
 }
 
+// ThumbDir — каталог превью элементов.
+func (c *Config) ThumbDir() string {
+	return filepath.Join(c.YtDlpOutputDir, ".talmor-thumbs")
+}
+
+// SubsDir — каталог субтитров в WebVTT.
+func (c *Config) SubsDir() string {
+	return filepath.Join(c.YtDlpOutputDir, ".talmor-subs")
+}
+
+// HLSDir — кеш сегментов HLS.
+func (c *Config) HLSDir() string {
+	return filepath.Join(c.YtDlpOutputDir, ".talmor-hls")
+}
+
 // ExtraArgsList возвращает YT_DLP_EXTRA_ARGS как слайс строк.
 func (c *Config) ExtraArgsList() []string {
 	s := strings.TrimSpace(c.YtDlpExtraArgs)
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            TEXT PRIMARY KEY,
+    username      TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role          TEXT NOT NULL DEFAULT 'member',
+    created_at    TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TEXT NOT NULL,
+    expires_at TEXT NOT NULL
+);
+
+-- Привязка Telegram-чатов к пользователям.
+CREATE TABLE IF NOT EXISTS user_chats (
+    chat_id INTEGER PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- NULL — общее задание (создано до появления пользователей, сканером или непривязанным чатом).
+ALTER TABLE jobs ADD COLUMN owner_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_jobs_owner ON jobs(owner_id);
+
+ALTER TABLE api_tokens ADD COLUMN user_id TEXT;
+
+-- Автор операции: участник видит только свои операции. NULL — системная.
+ALTER TABLE operations ADD COLUMN owner_id TEXT;
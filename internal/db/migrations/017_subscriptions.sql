@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id             TEXT PRIMARY KEY,
+    url            TEXT NOT NULL,
+    title          TEXT NOT NULL DEFAULT '',
+    interval_sec   INTEGER NOT NULL DEFAULT 3600,
+    tags           TEXT NOT NULL DEFAULT '[]', -- JSON-массив имён тегов
+    collection_id  TEXT,
+    max_age_days   INTEGER NOT NULL DEFAULT 0,
+    owner_id       TEXT,
+    chat_id        INTEGER NOT NULL DEFAULT 0,
+    enabled        INTEGER NOT NULL DEFAULT 1,
+    created_at     TEXT NOT NULL,
+    last_polled_at TEXT,
+    synced         INTEGER NOT NULL DEFAULT 0, -- 1 — был успешный опрос, видео канала запомнены
+    last_error     TEXT NOT NULL DEFAULT ''
+);
+
+-- Уже встречавшиеся видео подписки: новые определяются разницей с этим списком.
+CREATE TABLE IF NOT EXISTS subscription_entries (
+    subscription_id TEXT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    url             TEXT NOT NULL,
+    seen_at         TEXT NOT NULL,
+    PRIMARY KEY (subscription_id, url)
+);
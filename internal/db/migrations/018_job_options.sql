@@ -0,0 +1,2 @@
+-- JSON-профиль скачивания задания (model.JobOptions); пустая строка — глобальные настройки.
+ALTER TABLE jobs ADD COLUMN options TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- Именованные профили загрузки. Не больше одного пресета с is_default=1.
+CREATE TABLE presets (
+    id            TEXT PRIMARY KEY,
+    name          TEXT NOT NULL UNIQUE,
+    format        TEXT NOT NULL DEFAULT '',
+    output_format TEXT NOT NULL DEFAULT '',
+    extra_args    TEXT NOT NULL DEFAULT '[]',
+    proxy         TEXT NOT NULL DEFAULT '',
+    post_process  TEXT NOT NULL DEFAULT '[]',
+    subfolder     TEXT NOT NULL DEFAULT '',
+    is_default    INTEGER NOT NULL DEFAULT 0,
+    created_at    TEXT NOT NULL
+);
+
+ALTER TABLE jobs ADD COLUMN preset_id TEXT REFERENCES presets(id) ON DELETE SET NULL;
@@ -0,0 +1,11 @@
+-- Правила для доменов: pattern совпадает с хостом задания и его поддоменами.
+CREATE TABLE domain_rules (
+    id            TEXT PRIMARY KEY,
+    pattern       TEXT NOT NULL UNIQUE,
+    proxy         TEXT NOT NULL DEFAULT '',
+    max_parallel  INTEGER NOT NULL DEFAULT 0,
+    min_delay_sec INTEGER NOT NULL DEFAULT 0,
+    preset_id     TEXT REFERENCES presets(id) ON DELETE SET NULL,
+    cookies       INTEGER NOT NULL DEFAULT 1,
+    created_at    TEXT NOT NULL
+);
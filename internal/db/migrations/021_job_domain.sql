@@ -0,0 +1,14 @@
+-- Хост задания и время последнего запуска: по ним воркеры чередуют домены
+-- и соблюдают лимиты правил доменов.
+ALTER TABLE jobs ADD COLUMN domain TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN started_at TEXT;
+
+-- Хост существующих заданий: между «://» и первым «/», «?» или «:».
+UPDATE jobs SET domain = lower(substr(url, instr(url, '://') + 3)) WHERE instr(url, '://') > 0;
+UPDATE jobs SET domain = substr(domain, 1, instr(domain, '/') - 1) WHERE instr(domain, '/') > 0;
+UPDATE jobs SET domain = substr(domain, 1, instr(domain, '?') - 1) WHERE instr(domain, '?') > 0;
+UPDATE jobs SET domain = substr(domain, 1, instr(domain, ':') - 1) WHERE instr(domain, ':') > 0;
+
+CREATE INDEX idx_jobs_domain ON jobs(domain, started_at);
+-- ClaimNext смотрит только на недавние запуски.
+CREATE INDEX idx_jobs_started ON jobs(started_at);
@@ -0,0 +1,2 @@
+-- Приоритет задания: больше — раньше. Видео из плейлистов получают отрицательный.
+ALTER TABLE jobs ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
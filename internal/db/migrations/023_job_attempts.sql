@@ -0,0 +1,19 @@
+-- История попыток скачивания: по строке на каждый запуск yt-dlp.
+-- jobs.last_log остаётся для заданий, скачанных до появления истории.
+CREATE TABLE job_attempts (
+    id            TEXT PRIMARY KEY,
+    job_id        TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    number        INTEGER NOT NULL,
+    started_at    TEXT NOT NULL,
+    finished_at   TEXT,
+    status        TEXT NOT NULL DEFAULT 'running',
+    exit_code     INTEGER,
+    error         TEXT NOT NULL DEFAULT '',
+    error_class   TEXT NOT NULL DEFAULT '',
+    files         TEXT NOT NULL DEFAULT '',
+    ytdlp_version TEXT NOT NULL DEFAULT '',
+    args          TEXT NOT NULL DEFAULT '',
+    log           TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_job_attempts_job ON job_attempts(job_id, number);
@@ -0,0 +1,8 @@
+-- Класс последней ошибки задания (model.ErrorClass).
+ALTER TABLE jobs ADD COLUMN error_class TEXT NOT NULL DEFAULT '';
+
+-- Домены, приостановленные после ответа HTTP 429: воркеры не берут их задания до until.
+CREATE TABLE domain_pauses (
+    domain TEXT PRIMARY KEY,
+    until  TEXT NOT NULL
+);
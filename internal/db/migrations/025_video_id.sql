@@ -0,0 +1,9 @@
+-- Видео у источника (extractor_key и id из yt-dlp): по ним ищутся дубликаты при добавлении.
+ALTER TABLE jobs ADD COLUMN extractor TEXT NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN video_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_jobs_video ON jobs(video_id);
+CREATE INDEX idx_jobs_url ON jobs(url);
+
+-- Задание, которое уже есть для того же видео: placeholder в статусе checking
+-- ждёт решения пользователя (открыть, скачать заново или добавить всё равно).
+ALTER TABLE jobs ADD COLUMN duplicate_of TEXT NOT NULL DEFAULT '';
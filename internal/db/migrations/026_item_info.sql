@@ -0,0 +1,12 @@
+-- Сведения о видео из info.json yt-dlp (model.VideoInfo); главы — JSON-массив.
+ALTER TABLE items ADD COLUMN uploader TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN channel TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN upload_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN vcodec TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN acodec TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN view_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN webpage_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN chapters TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Путь к превью элемента в Config.ThumbDir; пусто — превью нет.
+ALTER TABLE items ADD COLUMN thumb TEXT NOT NULL DEFAULT '';
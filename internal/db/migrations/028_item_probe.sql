@@ -0,0 +1,4 @@
+-- Параметры файла по ffprobe; ширина, высота и кодеки — в колонках из 026.
+ALTER TABLE items ADD COLUMN bitrate INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN fps REAL NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN channels INTEGER NOT NULL DEFAULT 0;
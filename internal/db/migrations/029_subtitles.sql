@@ -0,0 +1,10 @@
+-- Субтитры элемента в WebVTT: скачанные yt-dlp или извлечённые из файла.
+CREATE TABLE subtitles (
+    id         TEXT PRIMARY KEY,
+    item_id    TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    lang       TEXT NOT NULL,
+    path       TEXT NOT NULL,
+    source     TEXT NOT NULL DEFAULT 'download',
+    created_at TEXT NOT NULL,
+    UNIQUE (item_id, lang)
+);
@@ -0,0 +1,11 @@
+-- Прогресс просмотра элемента: позиция плеера и отметка «просмотрено».
+-- У каждого пользователя он свой; user_id '' — вход по WEB_TOKEN или без авторизации.
+CREATE TABLE watch_state (
+    user_id    TEXT NOT NULL DEFAULT '',
+    item_id    TEXT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL DEFAULT 0,
+    watched    INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (user_id, item_id)
+);
+CREATE INDEX idx_watch_state_item ON watch_state(item_id);
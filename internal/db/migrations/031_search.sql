@@ -0,0 +1,94 @@
+-- Полнотекстовый поиск по медиатеке. Документ — строка медиатеки: элемент или
+-- задание без элементов. Индекс не хранит тексты: они собираются из jobs, items,
+-- tags и subtitles, и документы задания пересобираются триггерами целиком.
+ALTER TABLE subtitles ADD COLUMN text TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE search_docs (
+    id     INTEGER PRIMARY KEY,
+    row_id TEXT NOT NULL UNIQUE, -- ID элемента или задания без элементов
+    job_id TEXT NOT NULL
+);
+CREATE INDEX idx_search_docs_job ON search_docs(job_id);
+
+CREATE VIRTUAL TABLE search_fts USING fts5(
+    title, name, tags, channel, description, subtitles, site,
+    content='', contentless_delete=1,
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+-- Словарь индекса — для исправления опечаток.
+CREATE VIRTUAL TABLE search_vocab USING fts5vocab(search_fts, 'row');
+
+-- Вставка ID задания в search_reindex пересобирает его документы.
+CREATE VIEW search_reindex AS SELECT id AS job_id FROM jobs WHERE 0;
+
+CREATE TRIGGER search_reindex_job INSTEAD OF INSERT ON search_reindex BEGIN
+    DELETE FROM search_fts WHERE rowid IN (SELECT id FROM search_docs WHERE job_id = NEW.job_id);
+    DELETE FROM search_docs WHERE job_id = NEW.job_id;
+    INSERT INTO search_docs (row_id, job_id)
+        SELECT COALESCE(i.id, j.id), j.id
+        FROM jobs j LEFT JOIN items i ON i.job_id = j.id
+        WHERE j.id = NEW.job_id;
+    INSERT INTO search_fts (rowid, title, name, tags, channel, description, subtitles, site)
+        SELECT d.id,
+               j.title,
+               COALESCE(i.name, '') || ' ' || COALESCE(i.title, '') || ' ' ||
+                   COALESCE(i.artist, '') || ' ' || COALESCE(i.album, ''),
+               COALESCE((SELECT GROUP_CONCAT(t.name, ' ') FROM job_tags jt
+                         JOIN tags t ON t.id = jt.tag_id WHERE jt.job_id = j.id), ''),
+               COALESCE(i.uploader, '') || ' ' || COALESCE(i.channel, ''),
+               COALESCE(i.description, ''),
+               COALESCE((SELECT GROUP_CONCAT(s.text, ' ') FROM subtitles s WHERE s.item_id = i.id), ''),
+               j.domain || ' ' || j.url
+        FROM search_docs d
+        JOIN jobs j ON j.id = d.job_id
+        LEFT JOIN items i ON i.id = d.row_id
+        WHERE d.job_id = NEW.job_id;
+END;
+
+CREATE TRIGGER search_jobs_insert AFTER INSERT ON jobs BEGIN
+    INSERT INTO search_reindex VALUES (NEW.id);
+END;
+-- jobRepo.Update всегда перезаписывает title: без WHEN документы пересобирались бы
+-- при каждой смене статуса.
+CREATE TRIGGER search_jobs_update AFTER UPDATE OF url, title, domain ON jobs
+WHEN OLD.title IS NOT NEW.title OR OLD.url IS NOT NEW.url OR OLD.domain IS NOT NEW.domain BEGIN
+    INSERT INTO search_reindex VALUES (NEW.id);
+END;
+CREATE TRIGGER search_jobs_delete AFTER DELETE ON jobs BEGIN
+    INSERT INTO search_reindex VALUES (OLD.id);
+END;
+
+CREATE TRIGGER search_items_insert AFTER INSERT ON items BEGIN
+    INSERT INTO search_reindex VALUES (NEW.job_id);
+END;
+CREATE TRIGGER search_items_update
+AFTER UPDATE OF job_id, name, title, artist, album, uploader, channel, description ON items BEGIN
+    INSERT INTO search_reindex VALUES (NEW.job_id);
+    INSERT INTO search_reindex SELECT OLD.job_id WHERE OLD.job_id <> NEW.job_id;
+END;
+CREATE TRIGGER search_items_delete AFTER DELETE ON items BEGIN
+    INSERT INTO search_reindex VALUES (OLD.job_id);
+END;
+
+CREATE TRIGGER search_job_tags_insert AFTER INSERT ON job_tags BEGIN
+    INSERT INTO search_reindex VALUES (NEW.job_id);
+END;
+CREATE TRIGGER search_job_tags_delete AFTER DELETE ON job_tags BEGIN
+    INSERT INTO search_reindex VALUES (OLD.job_id);
+END;
+CREATE TRIGGER search_tags_update AFTER UPDATE OF name ON tags BEGIN
+    INSERT INTO search_reindex SELECT job_id FROM job_tags WHERE tag_id = NEW.id;
+END;
+
+CREATE TRIGGER search_subtitles_insert AFTER INSERT ON subtitles BEGIN
+    INSERT INTO search_reindex SELECT job_id FROM items WHERE id = NEW.item_id;
+END;
+CREATE TRIGGER search_subtitles_update AFTER UPDATE OF item_id, text ON subtitles BEGIN
+    INSERT INTO search_reindex SELECT job_id FROM items WHERE id = NEW.item_id;
+END;
+CREATE TRIGGER search_subtitles_delete AFTER DELETE ON subtitles BEGIN
+    INSERT INTO search_reindex SELECT job_id FROM items WHERE id = OLD.item_id;
+END;
+
+INSERT INTO search_reindex SELECT id FROM jobs;
@@ -0,0 +1,121 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+// infoJSON — поля info.json yt-dlp, которые сохраняем в БД.
+type infoJSON struct {
+	Uploader    string  `json:"uploader"`
+	Channel     string  `json:"channel"`
+	UploadDate  string  `json:"upload_date"`
+	Description string  `json:"description"`
+	Duration    float64 `json:"duration"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	VCodec      string  `json:"vcodec"`
+	ACodec      string  `json:"acodec"`
+	ViewCount   int64   `json:"view_count"`
+	WebpageURL  string  `json:"webpage_url"`
+	Chapters    []struct {
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+		Title     string  `json:"title"`
+	} `json:"chapters"`
+}
+
+// thumbnailExts — форматы обложек yt-dlp; jpg — после --convert-thumbnails.
+var thumbnailExts = []string{".jpg", ".webp", ".png"}
+
+// findThumbnail ищет обложку рядом с файлом по тому же правилу, что и info.json.
+func findThumbnail(mediaPath string) string {
+	base := strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath))
+	for _, ext := range thumbnailExts {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// Subtitle — файл субтитров, записанный yt-dlp рядом со скачанным файлом.
+type Subtitle struct {
+	Lang string
+	Path string
+}
+
+// findSubtitles ищет субтитры вида <основа>.<язык>.vtt|srt. Язык без точек —
+// так не спутать «clip.en.vtt» с субтитрами другого файла «clip.en».
+func findSubtitles(mediaPath string) []Subtitle {
+	base := strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath))
+	entries, err := os.ReadDir(filepath.Dir(mediaPath))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(base) + "."
+	var subs []Subtitle
+	for _, e := range entries {
+		name := e.Name()
+		ext := filepath.Ext(name)
+		if e.IsDir() || (ext != ".vtt" && ext != ".srt") || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		lang := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if lang == "" || strings.Contains(lang, ".") {
+			continue
+		}
+		subs = append(subs, Subtitle{Lang: lang, Path: filepath.Join(filepath.Dir(mediaPath), name)})
+	}
+	return subs
+}
+
+// infoPath — info.json рядом с файлом: yt-dlp заменяет расширение шаблона -o на .info.json,
+// поэтому после слияния или извлечения аудио имя совпадает по основе.
+func infoPath(mediaPath string) string {
+	return strings.TrimSuffix(mediaPath, filepath.Ext(mediaPath)) + ".info.json"
+}
+
+// readInfo читает и удаляет info.json скачанного файла. Возвращает nil, если
+// файла нет (старый yt-dlp, --no-write-info-json в доп. аргументах).
+func readInfo(mediaPath string) (*model.VideoInfo, int) {
+	path := infoPath(mediaPath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0
+	}
+	os.Remove(path) //nolint:errcheck
+	var raw infoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0
+	}
+	var chapters []model.Chapter
+	for _, c := range raw.Chapters {
+		chapters = append(chapters, model.Chapter{Start: c.StartTime, End: c.EndTime, Title: c.Title})
+	}
+	return &model.VideoInfo{
+		Uploader:    raw.Uploader,
+		Channel:     raw.Channel,
+		UploadDate:  raw.UploadDate,
+		Description: raw.Description,
+		Width:       raw.Width,
+		Height:      raw.Height,
+		VCodec:      codec(raw.VCodec),
+		ACodec:      codec(raw.ACodec),
+		ViewCount:   raw.ViewCount,
+		WebpageURL:  raw.WebpageURL,
+		Chapters:    chapters,
+	}, int(raw.Duration)
+}
+
+// codec: yt-dlp пишет "none" для отсутствующей дорожки.
+func codec(s string) string {
+	if s == "none" {
+		return ""
+	}
+	return s
+}
@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"slices"
 	"strings"
 	"time"
 )
 
 // PlaylistEntry — одно видео из плейлиста.
 type PlaylistEntry struct {
-	URL   string
-	Title string
+	URL       string
+	Title     string
+	Uploaded  time.Time // дата публикации; нулевая, если yt-dlp её не отдал
+	ID        string    // id видео у источника; "" — неизвестен
+	Extractor string    // extractor_key («Youtube»)
 }
 
 // PlaylistInfo — результат разворачивания плейлиста.
@@ -24,16 +28,60 @@ type PlaylistInfo struct {
 // FetchPlaylist извлекает список видео из URL без скачивания (--flat-playlist).
 // Возвращает nil если URL — одиночное видео, плейлист недоступен или запрос завершился с ошибкой;
 // вызывающий должен обработать nil как «создать один job с оригинальным URL».
-func FetchPlaylist(ctx context.Context, url string, opts Options) *PlaylistInfo {
+// Для одиночного видео вторым значением отдаётся его запись с оригинальным URL:
+// ID и Extractor заполнены, если yt-dlp их сообщил.
+func FetchPlaylist(ctx context.Context, url string, opts Options) (*PlaylistInfo, PlaylistEntry) {
+	single := PlaylistEntry{URL: url}
+	info, err := fetchEntries(ctx, url, opts)
+	if err != nil {
+		slog.Debug("downloader: flat-playlist failed", "url", url, "err", err)
+		return nil, single
+	}
+	if len(info.Entries) <= 1 {
+		// Одиночное видео → fallback; у него URL бывает NA, а id есть.
+		if len(info.Entries) == 1 {
+			e := info.Entries[0]
+			single.Title, single.ID, single.Extractor = e.Title, e.ID, e.Extractor
+		}
+		return nil, single
+	}
+	info.Entries = withURL(info.Entries)
+
+	slog.Info("downloader: playlist expanded", "url", url, "entries", len(info.Entries), "title", info.PlaylistTitle)
+	return info, PlaylistEntry{}
+}
+
+// ListEntries возвращает все видео канала или плейлиста (--flat-playlist), в том числе
+// единственное. В отличие от FetchPlaylist сообщает об ошибке — нужно подпискам.
+func ListEntries(ctx context.Context, url string, opts Options) (*PlaylistInfo, error) {
+	info, err := fetchEntries(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	info.Entries = withURL(info.Entries)
+	return info, nil
+}
+
+// withURL отбрасывает записи без URL: скачать по ним нечего.
+func withURL(entries []PlaylistEntry) []PlaylistEntry {
+	return slices.DeleteFunc(entries, func(e PlaylistEntry) bool { return e.URL == "" })
+}
+
+// fetchEntries запускает yt-dlp --flat-playlist и разбирает вывод.
+func fetchEntries(ctx context.Context, url string, opts Options) (*PlaylistInfo, error) {
 	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
 	defer cancel()
 
 	args := []string{
 		"--flat-playlist", "--simulate",
-		// Три строки на каждое видео: url, title, playlist_title
+		// Шесть строк на каждое видео: url, title, playlist_title, upload_date, id, extractor
 		"--print", "%(url)s",
 		"--print", "%(title)s",
 		"--print", "%(playlist_title)s",
+		"--print", "%(upload_date)s",
+		"--print", "%(id)s",
+		// У записей плейлиста экстрактор в ie_key, у одиночного видео — в extractor_key.
+		"--print", "%(ie_key,extractor_key)s",
 	}
 	if opts.MaxFiles > 0 {
 		args = append(args, "--playlist-items", fmt.Sprintf("1:%d", opts.MaxFiles))
@@ -49,44 +97,46 @@ func FetchPlaylist(ctx context.Context, url string, opts Options) *PlaylistInfo
 	cmd := exec.CommandContext(ctx, opts.Binary, args...)
 	out, err := cmd.Output()
 	if err != nil {
-		slog.Debug("downloader: flat-playlist failed", "url", url, "err", err)
-		return nil
+		return nil, fmt.Errorf("yt-dlp --flat-playlist: %w", err)
 	}
+	return parseFlatPlaylist(string(out)), nil
+}
 
-	raw := strings.TrimRight(string(out), "\n")
+// parseFlatPlaylist разбирает вывод fetchEntries шестёрками строк.
+// Запись без URL (одиночное видео без JS-runtime) остаётся ради id.
+func parseFlatPlaylist(out string) *PlaylistInfo {
+	info := &PlaylistInfo{}
+	raw := strings.TrimRight(out, "\n")
 	if raw == "" {
-		return nil
+		return info
 	}
 	lines := strings.Split(raw, "\n")
 
-	var entries []PlaylistEntry
-	var playlistTitle string
-
-	// Парсим тройками: [url, title, playlist_title]
-	for i := 0; i+2 < len(lines); i += 3 {
-		entryURL := strings.TrimSpace(lines[i])
-		entryTitle := strings.TrimSpace(lines[i+1])
+	// Парсим шестёрками: [url, title, playlist_title, upload_date, id, extractor]
+	field := func(s string) string {
+		if s = strings.TrimSpace(s); s == "NA" {
+			return ""
+		}
+		return s
+	}
+	for i := 0; i+5 < len(lines); i += 6 {
+		entryURL := field(lines[i])
+		entryTitle := field(lines[i+1])
 		pTitle := strings.TrimSpace(lines[i+2])
+		uploadDate := strings.TrimSpace(lines[i+3])
+		entryID := field(lines[i+4])
 
-		if entryURL == "" || entryURL == "NA" {
+		if entryURL == "" && entryID == "" {
 			continue // одиночное видео без JS-runtime вернёт NA
 		}
-		if entryTitle == "NA" {
-			entryTitle = ""
+		entry := PlaylistEntry{URL: entryURL, Title: entryTitle, ID: entryID, Extractor: field(lines[i+5])}
+		if t, err := time.Parse("20060102", uploadDate); err == nil {
+			entry.Uploaded = t
 		}
-		entries = append(entries, PlaylistEntry{URL: entryURL, Title: entryTitle})
-		if playlistTitle == "" && pTitle != "" && pTitle != "NA" {
-			playlistTitle = pTitle
+		info.Entries = append(info.Entries, entry)
+		if info.PlaylistTitle == "" && pTitle != "" && pTitle != "NA" {
+			info.PlaylistTitle = pTitle
 		}
 	}
-
-	if len(entries) <= 1 {
-		return nil // одиночное видео → fallback
-	}
-
-	slog.Info("downloader: playlist expanded", "url", url, "entries", len(entries), "title", playlistTitle)
-	return &PlaylistInfo{
-		Entries:       entries,
-		PlaylistTitle: playlistTitle,
-	}
+	return info
 }
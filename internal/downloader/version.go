@@ -0,0 +1,47 @@
+package downloader
+
+import (
+	"context"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version возвращает версию yt-dlp (вывод --version).
+func Version(ctx context.Context, binary string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, binary, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// secretFlags — аргументы yt-dlp, значения которых не попадают в историю попыток.
+var secretFlags = map[string]bool{"-p": true, "--password": true, "--video-password": true, "--ap-password": true}
+
+// DisplayArgs — командная строка yt-dlp для истории попыток: пароли и логин
+// прокси заменены на «***».
+func DisplayArgs(rawURL string, opts Options) string {
+	args := buildArgs(rawURL, opts)
+	parts := make([]string, len(args))
+	for i, a := range args {
+		switch {
+		case i > 0 && secretFlags[args[i-1]]:
+			a = "***"
+		case i > 0 && args[i-1] == "--proxy":
+			if u, err := url.Parse(a); err == nil && u.User != nil {
+				u.User = nil
+				a = strings.Replace(u.String(), "://", "://***@", 1)
+			}
+		}
+		if a == "" || strings.ContainsAny(a, " \t\"'") {
+			a = strconv.Quote(a)
+		}
+		parts[i] = a
+	}
+	return strings.Join(parts, " ")
+}
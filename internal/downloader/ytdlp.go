@@ -8,11 +8,25 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
 )
 
+// progressPrefix помечает строки прогресса, печатаемые через --progress-template.
+const progressPrefix = "[talmor-progress]"
+
+// progressTemplate — downloaded total total_estimate speed eta; отсутствующие поля yt-dlp печатает как NA.
+const progressTemplate = "download:" + progressPrefix +
+	" %(progress.downloaded_bytes)s %(progress.total_bytes)s %(progress.total_bytes_estimate)s %(progress.speed)s %(progress.eta)s"
+
+// idPrefix помечает строку «экстрактор id» видео, печатаемую перед скачиванием.
+const idPrefix = "[talmor-id]"
+
 type Event struct {
 	// FileName содержит имя файла после успешного скачивания.
 	FileName string
@@ -21,6 +35,22 @@ type Event struct {
 	Err  error
 	// Log — полный вывод stderr + нераспознанные строки stdout (финальное событие после завершения процесса).
 	Log string
+	// Done помечает финальное событие: в нём Log и ExitCode.
+	Done bool
+	// ExitCode — код выхода yt-dlp; -1, если процесс убит (отмена, пауза, таймаут).
+	ExitCode int
+	// Progress — промежуточный прогресс текущего файла; такие события могут теряться.
+	Progress *model.Progress
+	// VideoID и Extractor — видео, которое начинает скачиваться (событие без файла).
+	VideoID   string
+	Extractor string
+	// Info и Duration — из info.json файла; nil, если yt-dlp его не записал.
+	Info     *model.VideoInfo
+	Duration int
+	// Thumbnail — обложка файла, записанная yt-dlp в OutputDir; удаляет её получатель.
+	Thumbnail string
+	// Subtitles — файлы субтитров рядом с файлом (.vtt/.srt); удаляет их получатель.
+	Subtitles []Subtitle
 }
 
 type Options struct {
@@ -31,7 +61,119 @@ type Options struct {
 	Timeout      time.Duration
 	MaxFiles     int // передаётся в --playlist-items "1:N"; 0 — без лимита
 	ExtraArgs    []string
-	CookiesFile  string // путь к Netscape cookies.txt; пусто — куки не используются
+	CookiesFile  string   // путь к Netscape cookies.txt; пусто — куки не используются
+	Continue     bool     // докачать .part-файлы, оставшиеся в OutputDir после паузы
+	LimitRate    string   // --limit-rate, например "2M"; пусто — без ограничения
+	SaveSubs     []string // сохранить субтитры на этих языках для плеера
+	AutoSubs     bool     // брать и автоматически созданные субтитры
+
+	// Пресет (model.Preset) и опции конкретного задания (model.JobOptions), см. WithPreset и WithJob.
+	Format         string // селектор -f; пусто — лучшее видео+аудио
+	MaxHeight      int    // ограничение высоты видео; 0 — лучшее доступное
+	AudioOnly      bool
+	AudioFormat    string   // для AudioOnly: mp3/m4a/opus; пусто — как есть
+	SubLangs       []string // встроить субтитры на этих языках
+	EmbedThumbnail bool
+	PostProcess    []string // шаги из model.PostProcessSteps
+}
+
+// postProcessArgs — аргументы yt-dlp для шагов постобработки пресета.
+var postProcessArgs = map[string][]string{
+	"metadata":     {"--embed-metadata"},
+	"chapters":     {"--embed-chapters"},
+	"sponsorblock": {"--sponsorblock-remove", "sponsor"},
+}
+
+// WithPreset накладывает пресет поверх глобальных настроек: непустые поля
+// заменяют их, дополнительные аргументы идут после глобальных.
+func (o Options) WithPreset(p *model.Preset) Options {
+	if p == nil {
+		return o
+	}
+	if p.Format != "" {
+		o.Format = p.Format
+	}
+	if slices.Contains(model.AudioFormats, p.OutputFormat) {
+		o.AudioOnly, o.AudioFormat = true, p.OutputFormat
+	} else if p.OutputFormat != "" {
+		o.OutputFormat = p.OutputFormat
+	}
+	if p.Proxy != "" {
+		o.Proxy = p.Proxy
+	}
+	if len(p.ExtraArgs) > 0 {
+		o.ExtraArgs = append(slices.Clip(o.ExtraArgs), p.ExtraArgs...)
+	}
+	for _, step := range p.PostProcess {
+		if step == "thumbnail" {
+			o.EmbedThumbnail = true
+		} else {
+			o.PostProcess = append(o.PostProcess, step)
+		}
+	}
+	return o
+}
+
+// WithDomainRule применяет правило домена: его прокси важнее глобального и пресета —
+// некоторые сайты доступны только через него; куки можно отключить.
+func (o Options) WithDomainRule(r *model.DomainRule) Options {
+	if r == nil {
+		return o
+	}
+	if r.Proxy != "" {
+		o.Proxy = r.Proxy
+	}
+	if !r.Cookies {
+		o.CookiesFile = ""
+	}
+	return o
+}
+
+// WithJob накладывает опции задания поверх глобальных и пресета: заданные
+// поля побеждают.
+func (o Options) WithJob(j model.JobOptions) Options {
+	switch {
+	case j.AudioOnly:
+		o.AudioOnly = true
+		if j.Container != "" {
+			o.AudioFormat = j.Container
+		}
+	case j.MaxHeight > 0 || j.Container != "":
+		// Явно выбранное видео отменяет аудио-пресет.
+		o.AudioOnly = false
+		if j.Container != "" {
+			o.OutputFormat = j.Container
+		}
+	}
+	if j.MaxHeight > 0 {
+		o.MaxHeight = j.MaxHeight
+	}
+	if len(j.SubLangs) > 0 {
+		o.SubLangs = j.SubLangs
+	}
+	o.EmbedThumbnail = o.EmbedThumbnail || j.EmbedThumbnail
+	return o
+}
+
+// formatArgs выбирает -f и параметры слияния/извлечения аудио.
+func formatArgs(opts Options) []string {
+	if opts.AudioOnly {
+		args := []string{"-f", "ba/b", "-x"}
+		if opts.AudioFormat != "" {
+			args = append(args, "--audio-format", opts.AudioFormat)
+		}
+		return args
+	}
+	// Выбираем лучший видео+аудио; фолбек на best combined если раздельных треков нет.
+	format := "bv*+ba/b"
+	if opts.Format != "" {
+		format = opts.Format
+	}
+	if opts.MaxHeight > 0 {
+		h := strconv.Itoa(opts.MaxHeight)
+		format = "bv*[height<=" + h + "]+ba/b[height<=" + h + "]/b"
+	}
+	return []string{"-f", format, "--merge-output-format", opts.OutputFormat}
 }
 
 // Run запускает yt-dlp для указанного URL и возвращает канал событий.
@@ -84,21 +226,39 @@ func Run(ctx context.Context, url string, opts Options) <-chan Event {
 
 		wg.Add(2)
 
-		// stdout содержит только --print-вывод (пути к готовым файлам).
+		// sendProgress не блокирует чтение вывода: если потребитель не успевает,
+		// промежуточный прогресс просто отбрасывается.
+		sendProgress := func(p model.Progress) {
+			select {
+			case ch <- Event{Progress: &p}:
+			default:
+			}
+		}
+
+		// stdout содержит --print-вывод (пути к готовым файлам) и строки прогресса.
 		// Отправляем событие немедленно — не ждём завершения всего процесса.
 		go func() {
 			defer wg.Done()
 			s := bufio.NewScanner(stdout)
 			for s.Scan() {
 				text := s.Text()
-				if filePattern.MatchString(text) {
+				if p, ok := ParseProgress(text); ok {
+					sendProgress(p)
+				} else if extractor, id, ok := parseVideoID(text); ok {
+					ch <- Event{Extractor: extractor, VideoID: id}
+				} else if filePattern.MatchString(text) {
 					mu.Lock()
 					fileCount++
 					if len(logLines) < maxLogLines {
 						logLines = append(logLines, "[file] "+filepath.Base(text))
 					}
 					mu.Unlock()
-					ch <- Event{FileName: filepath.Base(text), Path: text}
+					info, duration := readInfo(text)
+					ch <- Event{
+						FileName: filepath.Base(text), Path: text,
+						Info: info, Duration: duration, Thumbnail: findThumbnail(text),
+						Subtitles: findSubtitles(text),
+					}
 				} else {
 					slog.Debug("yt-dlp stdout", "line", text)
 					mu.Lock()
@@ -116,6 +276,10 @@ func Run(ctx context.Context, url string, opts Options) <-chan Event {
 			s := bufio.NewScanner(stderr)
 			for s.Scan() {
 				text := s.Text()
+				if p, ok := ParseProgress(text); ok {
+					sendProgress(p)
+					continue
+				}
 				slog.Info("yt-dlp stderr", "line", text)
 				mu.Lock()
 				if len(logLines) < maxLogLines {
@@ -134,10 +298,12 @@ func Run(ctx context.Context, url string, opts Options) <-chan Event {
 		errMsg := lastErrorLine(logLines)
 		mu.Unlock()
 
-		// Отправляем накопленный лог для сохранения в БД.
-		if fullLog != "" {
-			ch <- Event{Log: fullLog}
+		// Отправляем накопленный лог и код выхода для истории попыток.
+		exitCode := -1
+		if cmd.ProcessState != nil {
+			exitCode = cmd.ProcessState.ExitCode()
 		}
+		ch <- Event{Log: fullLog, Done: true, ExitCode: exitCode}
 
 		// Сигнализируем ошибку только если не скачали ни одного файла.
 		// При частичном успехе (--no-abort-on-error) файлы уже отправлены выше.
@@ -164,17 +330,56 @@ func lastErrorLine(lines []string) string {
 	return ""
 }
 
+// subLangs — языки для --sub-langs: встраиваемые и сохраняемые, без повторов.
+func subLangs(opts Options) []string {
+	langs := slices.Clone(opts.SubLangs)
+	for _, l := range opts.SaveSubs {
+		if !slices.Contains(langs, l) {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
+
 func buildArgs(url string, opts Options) []string {
 	args := []string{
 		"-o", "%(title)s.%(ext)s",
 		"--print", "after_move:filename",
+		"--print", "before_dl:" + idPrefix + " %(extractor_key)s %(id)s",
 		"--no-simulate",
-		// Выбираем лучший видео+аудио; фолбек на best combined если раздельных треков нет.
-		"-f", "bv*+ba/b",
-		"--merge-output-format", opts.OutputFormat,
+	}
+	args = append(args, formatArgs(opts)...)
+	args = append(args,
 		"-P", opts.OutputDir,
 		// Не прерывать весь job при ошибке одного видео в плейлисте.
 		"--no-abort-on-error",
+		// Метаданные видео (канал, дата, главы) — читаются после каждого файла и удаляются.
+		"--write-info-json", "--no-write-playlist-metafiles",
+		// Обложка для превью в медиатеке; без ffmpeg останется в исходном формате (webp).
+		"--write-thumbnail", "--convert-thumbnails", "jpg",
+		// Прогресс построчно в машиночитаемом виде (--print иначе его глушит).
+		"--progress", "--newline",
+		"--progress-template", progressTemplate,
+	)
+	if langs := subLangs(opts); len(langs) > 0 {
+		args = append(args, "--write-subs")
+		if len(opts.SubLangs) > 0 {
+			args = append(args, "--embed-subs")
+		}
+		if opts.AutoSubs {
+			args = append(args, "--write-auto-subs")
+		}
+		args = append(args, "--sub-langs", strings.Join(langs, ","))
+		if len(opts.SaveSubs) > 0 {
+			// Плеер понимает только WebVTT; srt конвертируется при сохранении.
+			args = append(args, "--sub-format", "vtt/srt/best")
+		}
+	}
+	if opts.EmbedThumbnail {
+		args = append(args, "--embed-thumbnail")
+	}
+	for _, step := range opts.PostProcess {
+		args = append(args, postProcessArgs[step]...)
 	}
 	if opts.MaxFiles > 0 {
 		// Ограничиваем на стороне yt-dlp, а не только в Go — экономит трафик.
@@ -186,11 +391,70 @@ func buildArgs(url string, opts Options) []string {
 	if opts.CookiesFile != "" {
 		args = append(args, "--cookies", opts.CookiesFile)
 	}
+	if opts.Continue {
+		args = append(args, "--continue")
+	}
+	if opts.LimitRate != "" {
+		args = append(args, "--limit-rate", opts.LimitRate)
+	}
 	args = append(args, opts.ExtraArgs...)
 	args = append(args, url)
 	return args
 }
 
+// ParseProgress разбирает строку, напечатанную по progressTemplate.
+// Возвращает false, если строка не является строкой прогресса.
+func ParseProgress(line string) (model.Progress, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), progressPrefix)
+	if !ok {
+		return model.Progress{}, false
+	}
+	f := strings.Fields(rest)
+	if len(f) != 5 {
+		return model.Progress{}, false
+	}
+	num := func(s string) float64 {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0
+		}
+		return v
+	}
+	p := model.Progress{
+		Downloaded: int64(num(f[0])),
+		Total:      int64(num(f[1])),
+		Speed:      num(f[3]),
+		ETA:        -1,
+		UpdatedAt:  time.Now(),
+	}
+	if p.Total == 0 {
+		p.Total = int64(num(f[2]))
+	}
+	if eta, err := strconv.ParseFloat(f[4], 64); err == nil {
+		p.ETA = int(eta)
+	}
+	if p.Total > 0 {
+		p.Percent = min(float64(p.Downloaded)*100/float64(p.Total), 100)
+	}
+	return p, true
+}
+
+// parseVideoID разбирает строку idPrefix: «[talmor-id] Youtube dQw4w9WgXcQ».
+func parseVideoID(line string) (extractor, id string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), idPrefix)
+	if !ok {
+		return "", "", false
+	}
+	f := strings.Fields(rest)
+	if len(f) != 2 || f[1] == "NA" {
+		return "", "", false
+	}
+	if f[0] == "NA" {
+		f[0] = ""
+	}
+	return f[0], f[1], true
+}
+
 // buildFilePattern строит regexp для распознавания строк с путём к файлу.
 func buildFilePattern(outputDir string) *regexp.Regexp {
 	escaped := regexp.QuoteMeta(strings.TrimRight(outputDir, "/") + "/")
@@ -0,0 +1,426 @@
+// Package hls перекодирует файлы, которые браузер не воспроизводит, в HLS через
+// ffmpeg по запросу плеера. Готовые варианты хранятся в кеше с вытеснением LRU;
+// одновременно идёт не больше заданного числа перекодирований, а брошенные
+// зрителем останавливаются.
+package hls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	playlistName = "index.m3u8"
+	segmentTime  = 6   // длительность сегмента, с
+	audioKbps    = 128 // битрейт звука во всех вариантах
+	// waitTimeout — сколько запрос ждёт плейлист или сегмент, пока ffmpeg их пишет.
+	waitTimeout = 60 * time.Second
+	// idleTimeout — перекодирование, к которому столько не обращались, останавливается.
+	idleTimeout = 5 * time.Minute
+)
+
+// ErrNotFound — варианта или сегмента нет и перекодирование не идёт.
+var ErrNotFound = errors.New("hls: not found")
+
+// bitrates — битрейт видео (кбит/с) для поддерживаемых высот вариантов.
+var bitrates = map[int]int{2160: 16000, 1440: 9000, 1080: 5000, 720: 2800, 480: 1400, 360: 800, 240: 400}
+
+var segmentRe = regexp.MustCompile(`^seg\d{5}\.ts$`)
+
+// ParseRenditions разбирает высоты вариантов ("720,480"), от большей к меньшей;
+// неподдерживаемые отбрасываются, без единой подходящей — 720.
+func ParseRenditions(s string) []int {
+	var out []int
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		h, err := strconv.Atoi(strings.TrimSuffix(f, "p"))
+		if err == nil && bitrates[h] > 0 && !slices.Contains(out, h) {
+			out = append(out, h)
+		}
+	}
+	if len(out) == 0 {
+		return []int{720}
+	}
+	slices.Sort(out)
+	slices.Reverse(out)
+	return out
+}
+
+// Cache запускает ffmpeg для каждого варианта (элемент + высота) один раз и
+// хранит сегменты в dir. Когда кеш больше maxBytes, удаляются варианты, которые
+// дольше всех не открывали.
+type Cache struct {
+	dir      string
+	ffmpeg   string
+	maxBytes int64
+	heights  []int
+	slots    chan struct{} // семафор одновременных перекодирований
+	idle     time.Duration // см. idleTimeout
+
+	mu      sync.Mutex
+	running map[string]*transcode // ключ — "<itemID>/<высота>"
+	used    map[string]time.Time  // последнее обращение к варианту
+}
+
+type transcode struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// New создаёт кеш; maxRunning — предел одновременных перекодирований (не меньше 1),
+// остальные ждут очереди.
+func New(dir, ffmpegBin string, maxBytes int64, heights []int, maxRunning int) *Cache {
+	c := &Cache{
+		dir: dir, ffmpeg: ffmpegBin, maxBytes: maxBytes, heights: heights,
+		slots:   make(chan struct{}, max(maxRunning, 1)),
+		idle:    idleTimeout,
+		running: make(map[string]*transcode),
+		used:    make(map[string]time.Time),
+	}
+	c.loadUsed()
+	return c
+}
+
+// loadUsed восстанавливает порядок LRU после перезапуска по времени изменения вариантов.
+func (c *Cache) loadUsed() {
+	items, _ := os.ReadDir(c.dir)
+	for _, it := range items {
+		if !it.IsDir() {
+			continue
+		}
+		variants, _ := os.ReadDir(filepath.Join(c.dir, it.Name()))
+		for _, v := range variants {
+			if info, err := v.Info(); err == nil && v.IsDir() {
+				c.used[it.Name()+"/"+v.Name()] = info.ModTime()
+			}
+		}
+	}
+}
+
+// Renditions — варианты для видео высотой srcHeight: не выше исходника, а если
+// он ниже всех — самый низкий. Высота неизвестна — все варианты.
+func (c *Cache) Renditions(srcHeight int) []int {
+	if srcHeight <= 0 {
+		return c.heights
+	}
+	var out []int
+	for _, h := range c.heights {
+		if h <= srcHeight {
+			out = append(out, h)
+		}
+	}
+	if len(out) == 0 {
+		return c.heights[len(c.heights)-1:]
+	}
+	return out
+}
+
+// Master — мастер-плейлист с вариантами для файла; пути к ним относительные.
+func (c *Cache) Master(srcWidth, srcHeight int) []byte {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, h := range c.Renditions(srcHeight) {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d", (bitrates[h]+audioKbps)*1000)
+		if srcWidth > 0 && srcHeight > 0 {
+			eh := min(h, srcHeight)
+			fmt.Fprintf(&b, ",RESOLUTION=%dx%d", srcWidth*eh/srcHeight/2*2, eh)
+		}
+		fmt.Fprintf(&b, "\n%d/%s\n", h, playlistName)
+	}
+	return []byte(b.String())
+}
+
+// Playlist запускает перекодирование варианта, если готового нет, и ждёт первый
+// плейлист: плеер начинает играть, пока ffmpeg пишет следующие сегменты.
+func (c *Cache) Playlist(ctx context.Context, itemID, src string, height int) (string, error) {
+	if bitrates[height] == 0 || !slices.Contains(c.heights, height) {
+		return "", ErrNotFound
+	}
+	key := variantKey(itemID, height)
+	path := filepath.Join(c.dir, key, playlistName)
+	return path, c.wait(ctx, path, c.start(key, src, height))
+}
+
+// Segment возвращает путь к сегменту варианта; если он ещё пишется — ждёт.
+func (c *Cache) Segment(ctx context.Context, itemID string, height int, name string) (string, error) {
+	if !segmentRe.MatchString(name) || !slices.Contains(c.heights, height) {
+		return "", ErrNotFound
+	}
+	key := variantKey(itemID, height)
+	c.mu.Lock()
+	c.used[key] = time.Now()
+	t := c.running[key]
+	c.mu.Unlock()
+	path := filepath.Join(c.dir, key, name)
+	return path, c.wait(ctx, path, t)
+}
+
+// Remove останавливает перекодирование и удаляет все варианты элемента.
+// На nil-кеше ничего не делает — удобно, когда перекодирование не настроено.
+func (c *Cache) Remove(itemID string) {
+	if c == nil || itemID == "" {
+		return
+	}
+	c.mu.Lock()
+	var stopped []*transcode
+	for key, t := range c.running {
+		if strings.HasPrefix(key, itemID+"/") {
+			t.cancel()
+			stopped = append(stopped, t)
+		}
+	}
+	for key := range c.used {
+		if strings.HasPrefix(key, itemID+"/") {
+			delete(c.used, key)
+		}
+	}
+	c.mu.Unlock()
+	for _, t := range stopped {
+		<-t.done
+	}
+	os.RemoveAll(filepath.Join(c.dir, itemID)) //nolint:errcheck
+}
+
+// Close останавливает все перекодирования; недописанные варианты удаляются.
+func (c *Cache) Close() {
+	c.mu.Lock()
+	running := make([]*transcode, 0, len(c.running))
+	for _, t := range c.running {
+		t.cancel()
+		running = append(running, t)
+	}
+	c.mu.Unlock()
+	for _, t := range running {
+		<-t.done
+	}
+}
+
+func variantKey(itemID string, height int) string {
+	return itemID + "/" + strconv.Itoa(height)
+}
+
+// start возвращает идущее перекодирование варианта или запускает новое;
+// nil — вариант уже готов.
+func (c *Cache) start(key, src string, height int) *transcode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used[key] = time.Now()
+	if t, ok := c.running[key]; ok {
+		return t
+	}
+	dir := filepath.Join(c.dir, key)
+	if complete(dir) {
+		return nil
+	}
+	// Недописанный вариант (ffmpeg прервали перезапуском) начинаем заново.
+	os.RemoveAll(dir) //nolint:errcheck
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &transcode{cancel: cancel, done: make(chan struct{})}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		cancel()
+		t.err = fmt.Errorf("create hls dir: %w", err)
+		close(t.done)
+		return t
+	}
+	c.running[key] = t
+	go c.run(ctx, t, key, src, height)
+	return t
+}
+
+func (c *Cache) run(ctx context.Context, t *transcode, key, src string, height int) {
+	defer t.cancel()
+	go c.watchIdle(ctx, t, key)
+	dir := filepath.Join(c.dir, key)
+	var out []byte
+	var err error
+	var started time.Time
+	select {
+	case c.slots <- struct{}{}:
+		started = time.Now()
+		out, err = exec.CommandContext(ctx, c.ffmpeg, args(src, dir, height)...).CombinedOutput()
+		<-c.slots
+	case <-ctx.Done():
+	}
+	switch {
+	case ctx.Err() != nil:
+		t.err = ctx.Err()
+	case err != nil:
+		t.err = fmt.Errorf("ffmpeg hls: %w: %s", err, tail(string(out), 300))
+		slog.Warn("hls: transcode failed", "src", src, "height", height, "err", t.err)
+	default:
+		slog.Info("hls: transcoded", "src", src, "height", height, "took", time.Since(started).Round(time.Second))
+	}
+	if t.err != nil {
+		os.RemoveAll(dir) //nolint:errcheck
+	}
+	c.mu.Lock()
+	delete(c.running, key)
+	if t.err != nil {
+		delete(c.used, key)
+	}
+	c.mu.Unlock()
+	close(t.done)
+	c.evict()
+}
+
+// watchIdle останавливает перекодирование (и ожидание очереди), если к варианту
+// дольше c.idle не обращались: зритель закрыл плеер, дописывать вариант незачем.
+func (c *Cache) watchIdle(ctx context.Context, t *transcode, key string) {
+	tick := time.NewTicker(c.idle / 4)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			c.mu.Lock()
+			idle := time.Since(c.used[key])
+			c.mu.Unlock()
+			if idle > c.idle {
+				slog.Info("hls: idle transcode stopped", "variant", key)
+				t.cancel()
+				return
+			}
+		}
+	}
+}
+
+// args — H.264/AAC, ключевой кадр в начале каждого сегмента, чтобы перемотка
+// попадала точно; плейлист типа event дописывается по мере готовности сегментов.
+func args(src, dir string, height int) []string {
+	kbps := bitrates[height]
+	return []string{
+		"-hide_banner", "-loglevel", "error", "-nostdin",
+		"-i", src,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale=-2:'trunc(min(%d,ih)/2)*2'", height),
+		"-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", "yuv420p",
+		"-b:v", fmt.Sprintf("%dk", kbps), "-maxrate", fmt.Sprintf("%dk", kbps*3/2), "-bufsize", fmt.Sprintf("%dk", kbps*2),
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentTime), "-sc_threshold", "0",
+		"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", audioKbps), "-ac", "2",
+		"-f", "hls", "-hls_time", strconv.Itoa(segmentTime), "-hls_playlist_type", "event",
+		"-hls_flags", "temp_file+independent_segments",
+		"-hls_segment_filename", filepath.Join(dir, "seg%05d.ts"),
+		filepath.Join(dir, playlistName),
+	}
+}
+
+// wait ждёт появления path, пока идёт перекодирование t.
+func (c *Cache) wait(ctx context.Context, path string, t *transcode) error {
+	deadline := time.NewTimer(waitTimeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(200 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if fileExists(path) {
+			return nil
+		}
+		if t == nil {
+			return ErrNotFound
+		}
+		select {
+		case <-t.done:
+			if fileExists(path) {
+				return nil
+			}
+			if t.err != nil {
+				return t.err
+			}
+			return ErrNotFound
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return fmt.Errorf("hls: %s not ready after %s", filepath.Base(path), waitTimeout)
+		case <-tick.C:
+		}
+	}
+}
+
+// evict удаляет варианты, которые дольше всех не открывали, пока кеш больше
+// предела. Идущие перекодирования и последний открытый вариант не трогаются.
+func (c *Cache) evict() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	type entry struct {
+		key  string
+		size int64
+		used time.Time
+	}
+	var entries []entry
+	var total int64
+	for key, used := range c.used {
+		size := dirSize(filepath.Join(c.dir, key))
+		total += size
+		if _, ok := c.running[key]; !ok {
+			entries = append(entries, entry{key, size, used})
+		}
+	}
+	if total <= c.maxBytes {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	if len(c.running) == 0 && len(entries) > 0 {
+		entries = entries[:len(entries)-1]
+	}
+	for _, e := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		dir := filepath.Join(c.dir, e.key)
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("hls: evict", "dir", dir, "err", err)
+			continue
+		}
+		os.Remove(filepath.Dir(dir)) //nolint:errcheck // каталог элемента, если он опустел
+		delete(c.used, e.key)
+		total -= e.size
+		slog.Info("hls: evicted", "variant", e.key, "size", e.size)
+	}
+}
+
+// complete — ffmpeg дописал плейлист до конца.
+func complete(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, playlistName))
+	return err == nil && strings.Contains(string(data), "#EXT-X-ENDLIST")
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error { //nolint:errcheck
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
@@ -0,0 +1,154 @@
+package hls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFFmpeg пишет сегмент и законченный плейлист в каталог варианта (последний
+// аргумент — путь плейлиста) и считает вызовы; для файла "bad.mkv" падает,
+// а на "slow.mkv" зависает.
+func fakeFFmpeg(t *testing.T, dir string) (bin, log string) {
+	t.Helper()
+	bin = filepath.Join(dir, "ffmpeg")
+	log = filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+echo "$@" >> ` + log + `
+case "$*" in
+*bad.mkv*) echo "invalid data" >&2; exit 1 ;;
+*slow.mkv*) exec sleep 30 ;;
+esac
+for a; do out="$a"; done
+d=$(dirname "$out")
+head -c 1000 /dev/zero > "$d/seg00000.ts"
+printf '#EXTM3U\n#EXTINF:6.0,\nseg00000.ts\n#EXT-X-ENDLIST\n' > "$out"
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return bin, log
+}
+
+func calls(t *testing.T, log string) int {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	bin, log := fakeFFmpeg(t, dir)
+	ctx := context.Background()
+	c := New(filepath.Join(dir, "hls"), bin, 1500, []int{720, 480}, 2)
+	defer c.Close()
+
+	path, err := c.Playlist(ctx, "a", "/v/a.mkv", 720)
+	if err != nil {
+		t.Fatalf("playlist: %v", err)
+	}
+	if want := filepath.Join(dir, "hls", "a", "720", "index.m3u8"); path != want {
+		t.Errorf("playlist path %q, want %q", path, want)
+	}
+	seg, err := c.Segment(ctx, "a", 720, "seg00000.ts")
+	if err != nil || filepath.Base(seg) != "seg00000.ts" {
+		t.Fatalf("segment: %q, %v", seg, err)
+	}
+	if _, err := c.Segment(ctx, "a", 720, "../../etc/passwd"); err != ErrNotFound {
+		t.Errorf("bad segment name: %v", err)
+	}
+	if _, err := c.Playlist(ctx, "a", "/v/a.mkv", 1080); err != ErrNotFound {
+		t.Errorf("unknown rendition: %v", err)
+	}
+
+	// Готовый вариант не перекодируется повторно.
+	if _, err := c.Playlist(ctx, "a", "/v/a.mkv", 720); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls(t, log); n != 1 {
+		t.Errorf("ffmpeg calls: got %d, want 1", n)
+	}
+
+	// Кеш на 1500 байт вмещает один вариант: новый вытесняет старый.
+	c.Playlist(ctx, "b", "/v/b.mkv", 480) //nolint:errcheck
+	c.evict()
+	if _, err := os.Stat(filepath.Join(dir, "hls", "a")); !os.IsNotExist(err) {
+		t.Errorf("variant a not evicted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hls", "b", "480", "index.m3u8")); err != nil {
+		t.Errorf("variant b evicted: %v", err)
+	}
+
+	c.Remove("b")
+	if _, err := os.Stat(filepath.Join(dir, "hls", "b")); !os.IsNotExist(err) {
+		t.Errorf("variant b not removed: %v", err)
+	}
+
+	if _, err := c.Playlist(ctx, "c", "/v/bad.mkv", 480); err == nil || !strings.Contains(err.Error(), "invalid data") {
+		t.Errorf("failed transcode: %v", err)
+	}
+}
+
+func TestCacheLimits(t *testing.T) {
+	dir := t.TempDir()
+	bin, log := fakeFFmpeg(t, dir)
+	c := New(filepath.Join(dir, "hls"), bin, 0, []int{720}, 1)
+	c.idle = 200 * time.Millisecond
+	defer c.Close()
+
+	// Единственный слот занят зависшим ffmpeg — следующее перекодирование ждёт очереди.
+	slow := make(chan error, 1)
+	go func() {
+		_, err := c.Playlist(context.Background(), "s", "/v/slow.mkv", 720)
+		slow <- err
+	}()
+	for i := 0; calls(t, log) == 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.Playlist(ctx, "a", "/v/a.mkv", 720); err == nil {
+		t.Error("second transcode ran without a free slot")
+	}
+	c.Remove("a") // из очереди
+
+	// К зависшему варианту никто не обращается — его останавливают, и очередь идёт дальше.
+	select {
+	case err := <-slow:
+		if err == nil {
+			t.Error("idle transcode finished")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle transcode not stopped")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hls", "s", "720")); !os.IsNotExist(err) {
+		t.Errorf("idle variant left: %v", err)
+	}
+	if _, err := c.Playlist(context.Background(), "a", "/v/a.mkv", 720); err != nil {
+		t.Fatalf("queued transcode: %v", err)
+	}
+	if n := calls(t, log); n != 2 {
+		t.Errorf("ffmpeg calls: got %d, want 2", n)
+	}
+}
+
+func TestMaster(t *testing.T) {
+	c := New(t.TempDir(), "ffmpeg", 0, ParseRenditions("480, 1080p,720,999"), 1)
+	got := string(c.Master(1280, 720))
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2928000,RESOLUTION=1280x720\n720/index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1528000,RESOLUTION=852x480\n480/index.m3u8\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	// Исходник ниже всех вариантов — самый низкий, без увеличения кадра.
+	if got := string(c.Master(426, 240)); !strings.Contains(got, "RESOLUTION=426x240\n480/index.m3u8") {
+		t.Errorf("small source:\n%s", got)
+	}
+}
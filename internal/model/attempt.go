@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// JobAttempt — одна попытка скачать задание: запуск yt-dlp и её итог.
+type JobAttempt struct {
+	ID           string
+	JobID        string
+	Number       int // 1, 2, … в пределах задания
+	StartedAt    time.Time
+	FinishedAt   *time.Time // nil — попытка ещё идёт
+	Status       JobStatus  // статус задания после попытки; running — ещё идёт
+	ExitCode     *int       // nil — yt-dlp не запускался или был остановлен
+	Error        string
+	ErrorClass   ErrorClass
+	Files        []string // имена сохранённых файлов
+	YtDlpVersion string
+	Args         string // аргументы yt-dlp без секретов прокси
+	Log          string
+}
+
+// Duration — длительность попытки; для идущей — от старта до now.
+func (a *JobAttempt) Duration(now time.Time) time.Duration {
+	end := now
+	if a.FinishedAt != nil {
+		end = *a.FinishedAt
+	}
+	return end.Sub(a.StartedAt).Round(time.Second)
+}
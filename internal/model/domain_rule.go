@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// DomainRule — настройки загрузки для сайта: прокси, куки, пресет и ограничения нагрузки.
+type DomainRule struct {
+	ID          string
+	Pattern     string // youtube.com — сам домен и все поддомены
+	Proxy       string // пусто — глобальный прокси
+	MaxParallel int    // одновременных загрузок с домена; 0 — без ограничения
+	MinDelay    time.Duration
+	PresetID    string // пресет по умолчанию для домена; "" — общий
+	Cookies     bool   // передавать ли yt-dlp куки
+	CreatedAt   time.Time
+}
+
+// NormalizeDomainPattern приводит шаблон к виду «example.com»: без схемы, «*.», «www.» и пути.
+func NormalizeDomainPattern(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, rest, ok := strings.Cut(s, "://"); ok {
+		s = rest
+	}
+	s, _, _ = strings.Cut(s, "/")
+	s = strings.TrimPrefix(s, "*.")
+	s = strings.TrimPrefix(s, "www.")
+	return strings.Trim(s, ".")
+}
+
+// Matches сообщает, относится ли хост к правилу.
+func (r *DomainRule) Matches(host string) bool {
+	host = strings.ToLower(host)
+	return host == r.Pattern || strings.HasSuffix(host, "."+r.Pattern)
+}
+
+// MatchDomainRule выбирает самое конкретное правило для хоста (с самым длинным шаблоном) или nil.
+func MatchDomainRule(rules []*DomainRule, host string) *DomainRule {
+	var best *DomainRule
+	for _, r := range rules {
+		if r.Matches(host) && (best == nil || len(r.Pattern) > len(best.Pattern)) {
+			best = r
+		}
+	}
+	return best
+}
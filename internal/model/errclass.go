@@ -0,0 +1,125 @@
+package model
+
+import "strings"
+
+// ErrorClass — причина неудачной загрузки, распознанная по сообщению yt-dlp.
+type ErrorClass string
+
+const (
+	ErrUnknown       ErrorClass = ""
+	ErrUnavailable   ErrorClass = "unavailable"    // приватное или удалённое видео
+	ErrGeoBlocked    ErrorClass = "geo_blocked"    // недоступно в стране сервера
+	ErrLoginRequired ErrorClass = "login_required" // нужен вход или подтверждение возраста
+	ErrRateLimited   ErrorClass = "rate_limited"   // HTTP 429
+	ErrNetwork       ErrorClass = "network"
+	ErrDiskFull      ErrorClass = "disk_full"
+	ErrUnsupported   ErrorClass = "unsupported" // yt-dlp не знает такой ссылки
+)
+
+// RetryPolicy — что делать с заданием после ошибки данного класса.
+type RetryPolicy uint8
+
+const (
+	RetryBackoff     RetryPolicy = iota // обычный повтор с экспоненциальной паузой
+	RetryNever                          // сразу ошибка: повтор не поможет
+	RetryLong                           // повтор через долгую паузу
+	RetryOtherProxy                     // повтор через следующий запасной прокси
+	RetryPauseDomain                    // пауза всех загрузок с домена, затем повтор
+)
+
+// errorPatterns — фрагменты сообщений yt-dlp в нижнем регистре. Порядок важен:
+// «Private video. Sign in…» — это недоступное видео, а не требование входа.
+var errorPatterns = []struct {
+	class    ErrorClass
+	patterns []string
+}{
+	{ErrDiskFull, []string{"no space left on device", "disk quota exceeded", "errno 28"}},
+	{ErrRateLimited, []string{"http error 429", "too many requests", "rate-limit", "rate limit"}},
+	{ErrGeoBlocked, []string{"in your country", "geo restrict", "geo-restrict", "from your location", "geoblock"}},
+	{ErrUnavailable, []string{
+		"private video", "video is private", "video unavailable", "has been removed", "no longer available",
+		"account associated with this video has been terminated", "http error 404", "http error 410",
+		// Не просто «does not exist»: так заканчиваются и локальные ошибки файлов.
+		"video does not exist", "playlist does not exist", "channel does not exist",
+	}},
+	{ErrLoginRequired, []string{
+		"sign in to confirm", "login required", "requires authentication", "use --cookies",
+		"members-only", "age-restricted", "http error 401",
+	}},
+	{ErrUnsupported, []string{"unsupported url", "is not a valid url", "no suitable extractor"}},
+	{ErrNetwork, []string{
+		"timed out", "connection reset", "connection refused", "connection aborted", "network is unreachable",
+		"name or service not known", "temporary failure in name resolution", "unable to connect",
+		"remote end closed connection", "urlopen error", "incompleteread", "ssl:",
+	}},
+}
+
+// ClassifyError определяет класс ошибки по сообщению yt-dlp.
+func ClassifyError(msg string) ErrorClass {
+	msg = strings.ToLower(msg)
+	for _, c := range errorPatterns {
+		for _, p := range c.patterns {
+			if strings.Contains(msg, p) {
+				return c.class
+			}
+		}
+	}
+	return ErrUnknown
+}
+
+// Policy возвращает политику повтора для класса.
+func (c ErrorClass) Policy() RetryPolicy {
+	switch c {
+	case ErrUnavailable, ErrLoginRequired, ErrUnsupported:
+		return RetryNever
+	case ErrDiskFull:
+		return RetryLong
+	case ErrGeoBlocked:
+		return RetryOtherProxy
+	case ErrRateLimited:
+		return RetryPauseDomain
+	}
+	return RetryBackoff
+}
+
+// Title — короткое название класса для значка в списке.
+func (c ErrorClass) Title() string {
+	switch c {
+	case ErrUnavailable:
+		return "недоступно"
+	case ErrGeoBlocked:
+		return "гео-блок"
+	case ErrLoginRequired:
+		return "нужен вход"
+	case ErrRateLimited:
+		return "лимит запросов"
+	case ErrNetwork:
+		return "сеть"
+	case ErrDiskFull:
+		return "нет места"
+	case ErrUnsupported:
+		return "не поддерживается"
+	}
+	return ""
+}
+
+// Explain — объяснение причины и того, что с ней делать.
+func (c ErrorClass) Explain() string {
+	switch c {
+	case ErrUnavailable:
+		return "Видео приватное или удалено — повтор не поможет."
+	case ErrGeoBlocked:
+		return "Видео недоступно в стране сервера — нужен прокси другой страны."
+	case ErrLoginRequired:
+		return "Сайт требует входа или подтверждения возраста — добавьте куки авторизованной сессии."
+	case ErrRateLimited:
+		return "Сайт ограничил частоту запросов — загрузки с него приостановлены на время."
+	case ErrNetwork:
+		return "Сетевая ошибка — загрузка повторится."
+	case ErrDiskFull:
+		return "Закончилось место на диске — загрузка повторится не скоро, освободите место."
+	case ErrUnsupported:
+		return "yt-dlp не поддерживает эту ссылку."
+	}
+	return ""
+}
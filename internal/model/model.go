@@ -13,6 +13,16 @@ var ytdlpID = regexp.MustCompile(`\s*\[[A-Za-z0-9_-]{6,15}\](\.[^.]+)$`)
 // ytdlpFmt matches yt-dlp format codes in filenames, e.g. ".f140" before the extension.
 var ytdlpFmt = regexp.MustCompile(`\.f\d{3,4}(\.[^.]+)$`)
 
+// VideoIDFromName возвращает id видео yt-dlp из имени файла («Title [CRbLJq6Pgew].mp4») или "".
+func VideoIDFromName(name string) string {
+	m := ytdlpID.FindString(name)
+	if m == "" {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimSpace(m)[1:], "]")
+	return id
+}
+
 // cleanFileName strips yt-dlp artifacts (video IDs, format codes) from file names.
 func cleanFileName(name string) string {
 	strip := func(s string, m []int) string {
@@ -39,16 +49,26 @@ const (
 	JobDone      JobStatus = "done"
 	JobFailed    JobStatus = "failed"
 	JobCancelled JobStatus = "cancelled"
+	JobPaused    JobStatus = "paused"   // приостановлено; недокачанный файл ждёт в staging
 	JobImported  JobStatus = "imported" // файл найден сканером, не скачан ботом
 )
 
+// Приоритет задания: воркеры берут сначала задания с большим приоритетом,
+// при равном — по очереди доменов и времени добавления.
+const (
+	PriorityPlaylist = -10 // сдвиг для видео из плейлистов и подписок
+	PriorityNormal   = 0
+	PriorityUrgent   = 10 // «!» перед ссылкой или /urgent в боте
+)
+
 type Job struct {
 	ID            string
 	URL           string
 	Status        JobStatus
 	Title         string
 	Error         string
-	Source        string // "web" | "telegram"
+	ErrorClass    ErrorClass // класс последней ошибки
+	Source        string     // "web" | "telegram" | "api" | "subscription"
 	ChatID        int64
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
@@ -57,6 +77,41 @@ type Job struct {
 	FirstFailedAt *time.Time
 	TgMessageID   int64
 	Hidden        bool
+	OwnerID       string // "" — общее задание (видно только admin/viewer)
+	Options       JobOptions
+	PresetID      string // "" — пресет по умолчанию
+	Priority      int
+	Extractor     string // extractor_key yt-dlp («Youtube»); "" — неизвестен
+	VideoID       string // id видео у источника; "" — неизвестен
+	DuplicateOf   string // задание с тем же видео, если placeholder ждёт решения пользователя
+}
+
+// IsDuplicate сообщает, что placeholder ждёт решения: такое видео уже есть.
+func (j *Job) IsDuplicate() bool {
+	return j.Status == JobChecking && j.DuplicateOf != ""
+}
+
+// MarkDuplicate помечает placeholder дубликатом существующего задания.
+func (j *Job) MarkDuplicate(existing *Job) {
+	j.DuplicateOf = existing.ID
+	j.Error = existing.DuplicateNote()
+}
+
+// DuplicateNote — чем уже является видео: «Уже скачано: …», «Уже в очереди: …».
+func (j *Job) DuplicateNote() string {
+	switch {
+	case j.Hidden:
+		return "Удалено из библиотеки: " + j.DisplayName()
+	case j.Status == JobDone || j.Status == JobImported:
+		return "Уже скачано: " + j.DisplayName()
+	default:
+		return "Уже в очереди: " + j.DisplayName()
+	}
+}
+
+// Waiting сообщает, ждёт ли задание своей очереди на скачивание.
+func (j *Job) Waiting() bool {
+	return j.Status == JobChecking || j.Status == JobPending || j.Status == JobRetrying
 }
 
 func (j *Job) DisplayName() string {
@@ -74,6 +129,16 @@ func (j *Job) Domain() string {
 	return u.Hostname()
 }
 
+// Progress — текущий прогресс скачивания job'а (хранится только в памяти воркера).
+type Progress struct {
+	Percent    float64 // 0..100
+	Downloaded int64   // байт
+	Total      int64   // байт; 0 — неизвестно
+	Speed      float64 // байт/с; 0 — неизвестно
+	ETA        int     // секунды; -1 — неизвестно
+	UpdatedAt  time.Time
+}
+
 // OpStatus — статус фоновой операции.
 type OpStatus string
 
@@ -91,6 +156,7 @@ type Operation struct {
 	Status     OpStatus
 	Title      string
 	Payload    string // JSON-блоб с параметрами операции
+	OwnerID    string // "" — системная операция (видна только admin/viewer)
 	CreatedAt  time.Time
 	StartedAt  *time.Time
 	FinishedAt *time.Time
@@ -116,6 +182,8 @@ type Item struct {
 	Size      int64
 	Duration  int // секунды
 	Meta      AudioMeta
+	Info      VideoInfo // из info.json yt-dlp; пусто у найденных сканером файлов
+	Thumb     string    // путь к превью; пусто — превью нет
 	CreatedAt time.Time
 	DeletedAt *time.Time
 	LostAt    *time.Time
@@ -132,6 +200,11 @@ type MediaItem struct {
 	Job  *Job
 	Item *Item  // nil пока файл не скачан
 	Tags []string
+	// Subtitles — языки субтитров элемента для плеера.
+	Subtitles []string
+	Watch     WatchState
+	// Pos — позиция строки в выдаче, из которой она получена (для курсора следующей страницы).
+	Pos MediaCursor
 }
 
 // EffectiveStatus возвращает статус с учётом состояния элемента и флага скрытия.
@@ -178,6 +251,103 @@ type Token struct {
 	CreatedAt time.Time
 }
 
+// Role — роль пользователя.
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"  // вся медиатека, настройки, пользователи
+	RoleMember Role = "member" // своя медиатека: добавление и управление своими заданиями
+	RoleViewer Role = "viewer" // вся медиатека, только просмотр
+)
+
+// User — учётная запись веб-интерфейса и бота.
+type User struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	Role         Role
+	CreatedAt    time.Time
+	ChatIDs      []int64 // привязанные Telegram-чаты; заполняется репозиторием
+}
+
+// SeesAll сообщает, видит ли пользователь задания всех пользователей.
+func (u *User) SeesAll() bool { return u.Role != RoleMember }
+
+// CanWrite сообщает, может ли пользователь что-либо менять.
+func (u *User) CanWrite() bool { return u.Role != RoleViewer }
+
+// Owns сообщает, может ли пользователь управлять заданием.
+func (u *User) Owns(j *Job) bool {
+	return u.Role == RoleAdmin || (u.Role == RoleMember && j.OwnerID == u.ID)
+}
+
+// SeesOperation сообщает, видна ли пользователю фоновая операция.
+func (u *User) SeesOperation(op *Operation) bool {
+	return u.SeesAll() || (op.OwnerID != "" && op.OwnerID == u.ID)
+}
+
+// OwnerScope возвращает фильтр владельца для выборок: ID для member, "" (все) для остальных.
+func (u *User) OwnerScope() string {
+	if u.SeesAll() {
+		return ""
+	}
+	return u.ID
+}
+
+// TokenScope — права API-токена.
+type TokenScope string
+
+const (
+	ScopeRead    TokenScope = "read"    // только чтение (GET)
+	ScopeEnqueue TokenScope = "enqueue" // только постановка URL в очередь
+	ScopeAdmin   TokenScope = "admin"   // всё
+)
+
+// APIToken — именованный токен доступа к API. Сам секрет не хранится — только его хэш.
+type APIToken struct {
+	ID         string
+	UserID     string // "" — токен без владельца (действует от имени администратора)
+	Name       string
+	Scope      TokenScope
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// Expired сообщает, истёк ли срок действия токена к моменту now.
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// Subscription — подписка на канал или плейлист: новые видео ставятся в очередь автоматически.
+type Subscription struct {
+	ID           string
+	URL          string
+	Title        string // название канала/плейлиста; заполняется при опросе
+	Interval     time.Duration
+	Tags         []string // теги, которые получают новые задания
+	CollectionID string   // "" — без коллекции
+	MaxAgeDays   int      // 0 — без ограничения по дате публикации
+	OwnerID      string
+	ChatID       int64 // подписка из Telegram: уведомления о загрузках уходят в этот чат
+	Enabled      bool
+	CreatedAt    time.Time
+	LastPolledAt *time.Time // nil — ещё не опрашивалась
+	Synced       bool       // был успешный опрос: видео, найденные позже, — новые
+	LastError    string
+}
+
+// Due сообщает, пора ли опрашивать подписку.
+func (s *Subscription) Due(now time.Time) bool {
+	return s.Enabled && (s.LastPolledAt == nil || !now.Before(s.LastPolledAt.Add(s.Interval)))
+}
+
+// TooOld сообщает, что видео опубликовано раньше допустимого MaxAgeDays.
+// Видео с неизвестной датой не отсекаются.
+func (s *Subscription) TooOld(uploaded, now time.Time) bool {
+	return s.MaxAgeDays > 0 && !uploaded.IsZero() && uploaded.Before(now.AddDate(0, 0, -s.MaxAgeDays))
+}
+
 type Tag struct {
 	ID   string
 	Name string
@@ -201,10 +371,89 @@ type TagWithCount struct {
 
 // MediaFilter — параметры серверной фильтрации медиатеки.
 type MediaFilter struct {
-	Query string   // текстовый поиск (имя файла, URL, заголовок)
-	Kind  string   // "" | "video" | "audio"
-	Tags  []string // AND-пересечение тегов (включая коллекции)
-	Limit int      // максимум строк; 0 = без ограничений
+	Query   string       // строка поиска, см. ParseSearch
+	Kind    string       // "" | "video" | "audio"
+	Tags    []string     // AND-пересечение тегов (включая коллекции)
+	AnyTags []string     // хотя бы один из тегов (OR)
+	NotTags []string     // ни одного из тегов
+	Limit   int          // максимум строк; 0 = без ограничений
+	After   *MediaCursor // продолжить выдачу после этой строки (курсорная пагинация)
+	OwnerID string       // только задания этого пользователя; "" — все
+	// ViewerID — чей прогресс просмотра показывать и учитывать в фильтрах.
+	ViewerID string
+	// Unwatched и InProgress оставляют только непросмотренные и начатые элементы
+	// (строки без элемента при этом не показываются, как и с Kind).
+	Unwatched  bool
+	InProgress bool
+
+	Domain string      // домен ссылки вместе с поддоменами: "youtube.com"
+	Source string      // источник задания: "web" | "telegram" | "api" | "subscription" | "filesystem"
+	Status MediaStatus // "" — любые строки
+	// MinSize и MaxSize — размер файла в байтах (0 — без границы), тоже только строки с элементом.
+	MinSize int64
+	MaxSize int64
+	// From и To — диапазон даты добавления; To не включается. Нулевые — без границы.
+	From time.Time
+	To   time.Time
+
+	Sort MediaSort
+	Asc  bool // по возрастанию ключа; по умолчанию — по убыванию
+}
+
+// MediaSort — ключ сортировки медиатеки.
+type MediaSort string
+
+const (
+	SortDate     MediaSort = ""         // дата добавления
+	SortName     MediaSort = "name"     // имя файла, для строк без файла — заголовок или URL
+	SortSize     MediaSort = "size"     // размер файла
+	SortDuration MediaSort = "duration" // длительность
+	SortDomain   MediaSort = "domain"   // домен ссылки
+	// SortRelevance — сначала лучше подходящие под Query; без текста в поиске — как SortDate.
+	SortRelevance MediaSort = "relevance"
+)
+
+// Valid сообщает, известен ли ключ сортировки.
+func (s MediaSort) Valid() bool {
+	switch s {
+	case SortDate, SortName, SortSize, SortDuration, SortDomain, SortRelevance:
+		return true
+	}
+	return false
+}
+
+// DefaultAsc — естественное направление для ключа: имена и домены по алфавиту,
+// остальное — сначала новые, большие и длинные.
+func (s MediaSort) DefaultAsc() bool {
+	return s == SortName || s == SortDomain
+}
+
+// MediaStatus — состояние строки медиатеки для фильтра.
+type MediaStatus string
+
+const (
+	StatusAvailable MediaStatus = "available" // файл на месте
+	StatusDeleted   MediaStatus = "deleted"   // файл удалён из медиатеки
+	StatusMissing   MediaStatus = "missing"   // файл пропал с диска
+	StatusActive    MediaStatus = "active"    // задание ещё в очереди или скачивается
+	StatusFailed    MediaStatus = "failed"    // задание завершилось ошибкой или отменено
+)
+
+// Valid сообщает, известно ли состояние ("" — любое).
+func (s MediaStatus) Valid() bool {
+	switch s {
+	case "", StatusAvailable, StatusDeleted, StatusMissing, StatusActive, StatusFailed:
+		return true
+	}
+	return false
+}
+
+// MediaCursor — позиция строки в выдаче FilterMedia: ключ сортировки + ID строки
+// (ID элемента или, для строк без элемента, ID задания). Ключ вычисляет сама база,
+// поэтому курсор годится только для выдачи с той же сортировкой.
+type MediaCursor struct {
+	Key   string
+	RowID string
 }
 
 // CookieRecord — куки одного домена (Netscape-формат).
@@ -1,6 +1,10 @@
 package model
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+	"time"
+)
 
 func TestCleanFileName(t *testing.T) {
 	cases := []struct {
@@ -25,3 +29,304 @@ func TestCleanFileName(t *testing.T) {
 		}
 	}
 }
+
+func TestVideoIDFromName(t *testing.T) {
+	for in, want := range map[string]string{
+		"хвост машет котом [CRbLJq6Pgew].mp4": "CRbLJq6Pgew",
+		"clip [a-b_c1].webm": "a-b_c1",
+		"video [abc].mp4":    "",
+		"normal_video.mp4":   "",
+	} {
+		if got := VideoIDFromName(in); got != want {
+			t.Errorf("VideoIDFromName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAPIToken_Expired(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	cases := []struct {
+		exp  *time.Time
+		want bool
+	}{
+		{nil, false},
+		{&past, true},
+		{&future, false},
+	}
+	for _, c := range cases {
+		tok := &APIToken{ExpiresAt: c.exp}
+		if got := tok.Expired(now); got != c.want {
+			t.Errorf("Expired(%v) = %v, want %v", c.exp, got, c.want)
+		}
+	}
+}
+
+func TestUser_Owns(t *testing.T) {
+	mine := &Job{OwnerID: "u1"}
+	foreign := &Job{OwnerID: "u2"}
+	shared := &Job{}
+	cases := []struct {
+		role  Role
+		job   *Job
+		want  bool
+		scope string
+	}{
+		{RoleAdmin, foreign, true, ""},
+		{RoleAdmin, shared, true, ""},
+		{RoleMember, mine, true, "u1"},
+		{RoleMember, foreign, false, "u1"},
+		{RoleMember, shared, false, "u1"},
+		{RoleViewer, mine, false, ""},
+	}
+	for _, c := range cases {
+		u := &User{ID: "u1", Role: c.role}
+		if got := u.Owns(c.job); got != c.want {
+			t.Errorf("%s.Owns(owner=%q) = %v, want %v", c.role, c.job.OwnerID, got, c.want)
+		}
+		if got := u.OwnerScope(); got != c.scope {
+			t.Errorf("%s.OwnerScope() = %q, want %q", c.role, got, c.scope)
+		}
+	}
+}
+
+func TestSubscription_DueAndTooOld(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-10 * time.Minute)
+	s := &Subscription{Interval: time.Hour, Enabled: true}
+	if !s.Due(now) {
+		t.Error("never polled subscription must be due")
+	}
+	s.LastPolledAt = &recent
+	if s.Due(now) || !s.Due(now.Add(time.Hour)) {
+		t.Error("due must follow interval")
+	}
+	s.Enabled = false
+	if s.Due(now.Add(2 * time.Hour)) {
+		t.Error("paused subscription must not be due")
+	}
+
+	if s.TooOld(now.AddDate(-1, 0, 0), now) {
+		t.Error("no max age: nothing is too old")
+	}
+	s.MaxAgeDays = 7
+	if !s.TooOld(now.AddDate(0, 0, -8), now) || s.TooOld(now.AddDate(0, 0, -6), now) || s.TooOld(time.Time{}, now) {
+		t.Error("max age filter")
+	}
+}
+
+func TestParseOptionTokens(t *testing.T) {
+	o, rest := ParseOptionTokens([]string{"https://a/1", "720p", "MKV", "subs=en,ru", "thumb", "https://a/2", "foo"})
+	if o.MaxHeight != 720 || o.Container != "mkv" || o.AudioOnly || !o.EmbedThumbnail {
+		t.Errorf("options: %+v", o)
+	}
+	if len(o.SubLangs) != 2 || o.SubLangs[1] != "ru" {
+		t.Errorf("sub langs: %v", o.SubLangs)
+	}
+	if len(rest) != 3 || rest[0] != "https://a/1" || rest[2] != "foo" {
+		t.Errorf("rest: %v", rest)
+	}
+	if msg := o.Validate(); msg != "" {
+		t.Errorf("validate: %s", msg)
+	}
+
+	o, _ = ParseOptionTokens([]string{"mp3"})
+	if !o.AudioOnly || o.Container != "mp3" || o.Validate() != "" {
+		t.Errorf("mp3: %+v", o)
+	}
+	if (JobOptions{AudioOnly: true, Container: "mkv"}).Validate() == "" {
+		t.Error("video container with audio-only must be rejected")
+	}
+	if !(JobOptions{}).IsZero() || o.IsZero() {
+		t.Error("IsZero")
+	}
+}
+
+func TestMatchDomainRule(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://www.YouTube.com/watch?v=1": "youtube.com",
+		"*.vk.com":                          "vk.com",
+		" example.org. ":                    "example.org",
+	} {
+		if got := NormalizeDomainPattern(in); got != want {
+			t.Errorf("NormalizeDomainPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	rules := []*DomainRule{{Pattern: "youtube.com"}, {Pattern: "music.youtube.com"}, {Pattern: "vk.com"}}
+	cases := []struct {
+		host string
+		want string
+	}{
+		{"youtube.com", "youtube.com"},
+		{"m.youtube.com", "youtube.com"},
+		{"music.youtube.com", "music.youtube.com"},
+		{"notyoutube.com", ""},
+		{"example.com", ""},
+	}
+	for _, c := range cases {
+		got := MatchDomainRule(rules, c.host)
+		if (got == nil && c.want != "") || (got != nil && got.Pattern != c.want) {
+			t.Errorf("MatchDomainRule(%q) = %v, want %q", c.host, got, c.want)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	sched, err := ParseSchedule("01:00-07:00\n07:00-01:00 2m # днём медленно")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(clock string) time.Time {
+		tm, _ := time.Parse("15:04", clock)
+		return tm
+	}
+	cases := []struct {
+		clock string
+		rate  string
+	}{
+		{"00:30", "2M"},
+		{"01:00", ""},
+		{"06:59", ""},
+		{"07:00", "2M"},
+		{"23:59", "2M"},
+	}
+	for _, c := range cases {
+		w, ok := sched.Active(at(c.clock))
+		if !ok || w == nil || w.RateLimit != c.rate {
+			t.Errorf("Active(%s) = %v, %v, want rate %q", c.clock, w, ok, c.rate)
+		}
+	}
+
+	night, _ := ParseSchedule("23:00-06:00")
+	if _, ok := night.Active(at("12:00")); ok {
+		t.Error("12:00 must be outside 23:00-06:00")
+	}
+	if _, ok := night.Active(at("02:00")); !ok {
+		t.Error("02:00 must be inside 23:00-06:00")
+	}
+	if w, ok := Schedule(nil).Active(at("12:00")); !ok || w != nil {
+		t.Error("empty schedule must allow downloads at full speed")
+	}
+
+	for _, bad := range []string{"1:00", "25:00-07:00", "01:00-07:00 fast", "01:00-07:00 2M extra"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Errorf("ParseSchedule(%q) must fail", bad)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := map[string]ErrorClass{
+		"ERROR: [youtube] abc: Private video. Sign in if you've been granted access to this video": ErrUnavailable,
+		"ERROR: [youtube] abc: Video unavailable. This video has been removed by the uploader":     ErrUnavailable,
+		"ERROR: [youtube] abc: The uploader has not made this video available in your country":     ErrGeoBlocked,
+		"ERROR: [youtube] abc: Sign in to confirm your age":                                        ErrLoginRequired,
+		"ERROR: unable to download video data: HTTP Error 429: Too Many Requests":                  ErrRateLimited,
+		"ERROR: <urlopen error [Errno -3] Temporary failure in name resolution>":                   ErrNetwork,
+		"ERROR: unable to write data: [Errno 28] No space left on device":                          ErrDiskFull,
+		"ERROR: Unsupported URL: https://example.com/":                                             ErrUnsupported,
+		"ERROR: [vimeo] 123: This video does not exist":                                            ErrUnavailable,
+		"ERROR: something new": ErrUnknown,
+		// Локальная ошибка — не повод навсегда бросать задание.
+		"open /data/cookies.txt: file does not exist": ErrUnknown,
+	}
+	for msg, want := range cases {
+		if got := ClassifyError(msg); got != want {
+			t.Errorf("ClassifyError(%q) = %q, want %q", msg, got, want)
+		}
+	}
+	if ErrUnavailable.Policy() != RetryNever || ErrRateLimited.Policy() != RetryPauseDomain ||
+		ErrGeoBlocked.Policy() != RetryOtherProxy || ErrUnknown.Policy() != RetryBackoff {
+		t.Error("unexpected retry policies")
+	}
+	if p := ClassifyError("mkdir /media/out: file does not exist").Policy(); p != RetryBackoff {
+		t.Errorf("local file error policy = %v, want RetryBackoff", p)
+	}
+}
+
+func TestVideoInfoQuality(t *testing.T) {
+	cases := []struct {
+		w, h int
+		want string
+	}{
+		{3840, 2160, "4K"}, {1920, 1080, "1080p"}, {1080, 1920, "1080p"}, {1920, 800, "1080p"},
+		{1280, 718, "720p"}, {640, 360, "360p"}, {0, 0, ""},
+	}
+	for _, c := range cases {
+		if got := (VideoInfo{Width: c.w, Height: c.h}).Quality(); got != c.want {
+			t.Errorf("Quality(%dx%d) = %q, want %q", c.w, c.h, got, c.want)
+		}
+	}
+}
+
+func TestProbeApply(t *testing.T) {
+	it := &Item{Duration: 60, Info: VideoInfo{Width: 1920, Height: 1080, VCodec: "avc1", ACodec: "mp4a", FPS: 30}}
+	Probe{Duration: 61, ACodec: "opus", Bitrate: 128000, Channels: 2}.Apply(it)
+	want := VideoInfo{Width: 1920, Height: 1080, VCodec: "avc1", ACodec: "opus", FPS: 30, Bitrate: 128000, Channels: 2}
+	if it.Duration != 61 || !reflect.DeepEqual(it.Info, want) {
+		t.Errorf("after Apply: %d %+v", it.Duration, it.Info)
+	}
+}
+
+func TestItemDirectPlay(t *testing.T) {
+	cases := []struct {
+		path, vcodec, acodec string
+		want                 bool
+	}{
+		{"/d/a.mp4", "h264", "aac", true},
+		{"/d/a.mp4", "avc1.640028", "mp4a.40.2", true},
+		{"/d/a.webm", "vp9", "opus", true},
+		{"/d/a.mp4", "", "", true},
+		{"/d/a.mp4", "hevc", "aac", false},
+		{"/d/a.mp4", "av01.0.08M.08", "opus", false},
+		{"/d/a.mp4", "h264", "ac3", false},
+		{"/d/a.MKV", "h264", "aac", false},
+		{"/d/a.avi", "", "", false},
+	}
+	for _, c := range cases {
+		it := &Item{Path: c.path, Info: VideoInfo{VCodec: c.vcodec, ACodec: c.acodec}}
+		if got := it.DirectPlay(); got != c.want {
+			t.Errorf("DirectPlay(%s, %s/%s) = %v, want %v", c.path, c.vcodec, c.acodec, got, c.want)
+		}
+	}
+}
+
+func TestWatchedAt(t *testing.T) {
+	cases := []struct {
+		position, duration int
+		want               bool
+	}{
+		{0, 100, false},
+		{89, 100, false},
+		{90, 100, true},
+		{120, 100, true},
+		{500, 0, false}, // длительность неизвестна — отметку ставят только вручную
+	}
+	for _, c := range cases {
+		if got := WatchedAt(c.position, c.duration); got != c.want {
+			t.Errorf("WatchedAt(%d, %d) = %v, want %v", c.position, c.duration, got, c.want)
+		}
+	}
+}
+
+func TestParseSearch(t *testing.T) {
+	cases := []struct {
+		in   string
+		want SearchQuery
+	}{
+		{"", SearchQuery{}},
+		{"  go  tutorial ", SearchQuery{Words: []string{"go", "tutorial"}}},
+		{`cat "funny  dog" video`, SearchQuery{Words: []string{"cat", "video"}, Phrases: []string{"funny dog"}}},
+		{`"open quote`, SearchQuery{Phrases: []string{"open quote"}}},
+		{`tag:music Tag:"live set" domain:https://www.YouTube.com/x kind:Audio`, SearchQuery{
+			Tags: []string{"music", "live set"}, Domain: "youtube.com", Kind: "audio",
+		}},
+		{"kind:image tag: 10:30", SearchQuery{Words: []string{"kind:image", "10:30"}}},
+	}
+	for _, c := range cases {
+		if got := ParseSearch(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseSearch(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+}
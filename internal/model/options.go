@@ -0,0 +1,163 @@
+package model
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JobOptions — профиль скачивания конкретного задания. Нулевое значение —
+// глобальные настройки (лучшее видео+аудио в формате YT_DLP_OUTPUT_FORMAT).
+// Хранится в jobs.options и переживает повторы и перекачивание. Произвольные
+// аргументы yt-dlp сюда не входят: их задаёт только администратор в пресетах.
+type JobOptions struct {
+	MaxHeight      int      `json:"max_height,omitempty"` // 0 — без ограничения
+	Container      string   `json:"container,omitempty"`  // mp4/mkv/webm; для AudioOnly — mp3/m4a/opus
+	AudioOnly      bool     `json:"audio_only,omitempty"`
+	SubLangs       []string `json:"sub_langs,omitempty"` // встроить субтитры на этих языках
+	EmbedThumbnail bool     `json:"embed_thumbnail,omitempty"`
+}
+
+// IsZero сообщает, что задание качается с глобальными настройками.
+func (o JobOptions) IsZero() bool {
+	return o.MaxHeight == 0 && o.Container == "" && !o.AudioOnly &&
+		len(o.SubLangs) == 0 && !o.EmbedThumbnail
+}
+
+// VideoContainers и AudioFormats — допустимые значения Container.
+var (
+	VideoContainers = []string{"mp4", "mkv", "webm"}
+	AudioFormats    = []string{"mp3", "m4a", "opus"}
+)
+
+// Validate проверяет согласованность опций; возвращает текст ошибки или "".
+func (o JobOptions) Validate() string {
+	switch {
+	case o.MaxHeight < 0:
+		return "max_height must be positive"
+	case o.Container == "":
+	case o.AudioOnly && !slices.Contains(AudioFormats, o.Container):
+		return "audio container must be one of " + strings.Join(AudioFormats, ", ")
+	case !o.AudioOnly && !slices.Contains(VideoContainers, o.Container):
+		return "container must be one of " + strings.Join(VideoContainers, ", ")
+	}
+	return ""
+}
+
+// Summary — краткое описание для списков и уведомлений, например «720p mkv, subs: en».
+func (o JobOptions) Summary() string {
+	var parts []string
+	if o.AudioOnly {
+		parts = append(parts, "аудио")
+	} else if o.MaxHeight > 0 {
+		parts = append(parts, strconv.Itoa(o.MaxHeight)+"p")
+	}
+	if o.Container != "" {
+		parts = append(parts, o.Container)
+	}
+	if len(o.SubLangs) > 0 {
+		parts = append(parts, "subs: "+strings.Join(o.SubLangs, ","))
+	}
+	if o.EmbedThumbnail {
+		parts = append(parts, "обложка")
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseOptionTokens разбирает короткие опции из сообщения бота:
+// 720p, audio, mp3/m4a/opus, mp4/mkv/webm, subs или subs=en,ru, thumb.
+// Возвращает опции и токены, которые опциями не являются.
+func ParseOptionTokens(tokens []string) (JobOptions, []string) {
+	var o JobOptions
+	var rest []string
+	for _, tok := range tokens {
+		t := strings.ToLower(tok)
+		switch {
+		case strings.HasSuffix(t, "p") && isDigits(t[:len(t)-1]):
+			o.MaxHeight, _ = strconv.Atoi(t[:len(t)-1])
+		case t == "audio":
+			o.AudioOnly = true
+		case slices.Contains(AudioFormats, t):
+			o.AudioOnly = true
+			o.Container = t
+		case slices.Contains(VideoContainers, t):
+			o.Container = t
+		case t == "subs":
+			o.SubLangs = []string{"en", "ru"}
+		case strings.HasPrefix(t, "subs="):
+			o.SubLangs = SplitList(t[len("subs="):])
+		case t == "thumb":
+			o.EmbedThumbnail = true
+		default:
+			rest = append(rest, tok)
+		}
+	}
+	return o, rest
+}
+
+// SplitList разбирает список через запятую или пробел, отбрасывая пустые элементы.
+func SplitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Preset — именованный профиль загрузки («Телефон 480p», «Подкаст»).
+// Пустые поля означают глобальные настройки.
+type Preset struct {
+	ID           string
+	Name         string
+	Format       string   // селектор -f; пусто — лучшее видео+аудио
+	OutputFormat string   // контейнер; mp3/m4a/opus — извлечь аудио
+	ExtraArgs    []string // добавляются после глобальных
+	Proxy        string
+	PostProcess  []string // шаги из PostProcessSteps
+	Subfolder    string   // подпапка медиатеки для готовых файлов
+	IsDefault    bool
+	CreatedAt    time.Time
+}
+
+// PostProcessSteps — шаги постобработки, которые можно включить в пресете.
+var PostProcessSteps = []string{"metadata", "thumbnail", "chapters", "sponsorblock"}
+
+// Validate проверяет пресет; возвращает текст ошибки или "".
+func (p *Preset) Validate() string {
+	switch {
+	case strings.TrimSpace(p.Name) == "":
+		return "Укажите название"
+	case p.OutputFormat != "" && !slices.Contains(VideoContainers, p.OutputFormat) && !slices.Contains(AudioFormats, p.OutputFormat):
+		return "Неизвестный формат " + p.OutputFormat
+	case !validSubfolder(p.Subfolder):
+		return "Подпапка должна быть относительным путём внутри медиатеки"
+	}
+	for _, s := range p.PostProcess {
+		if !slices.Contains(PostProcessSteps, s) {
+			return "Неизвестный шаг постобработки " + s
+		}
+	}
+	return ""
+}
+
+// validSubfolder не пускает абсолютные пути и выход за пределы медиатеки.
+func validSubfolder(s string) bool {
+	if s == "" {
+		return true
+	}
+	if filepath.IsAbs(s) {
+		return false
+	}
+	c := filepath.Clean(s)
+	return c != "." && c != ".." && !strings.HasPrefix(c, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ScheduleWindow — интервал суток, в который разрешено начинать загрузки.
+type ScheduleWindow struct {
+	Start     int    // минуты от полуночи
+	End       int    // End <= Start — окно переходит через полночь
+	RateLimit string // --limit-rate yt-dlp, например "2M"; пусто — полная скорость
+}
+
+// Schedule — окна загрузок по местному времени. Пустое расписание — качать всегда.
+type Schedule []ScheduleWindow
+
+var rateLimitRe = regexp.MustCompile(`^\d+(\.\d+)?[KMG]?$`)
+
+// ParseSchedule разбирает окна вида «01:00-07:00» или «07:00-01:00 2M»,
+// разделённые переводом строки или «;». Строки с «#» — комментарии.
+func ParseSchedule(s string) (Schedule, error) {
+	var sched Schedule
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ';' }) {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("окно %q: ожидается «ЧЧ:ММ-ЧЧ:ММ [скорость]»", strings.TrimSpace(line))
+		}
+		from, to, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			return nil, fmt.Errorf("окно %q: ожидается «ЧЧ:ММ-ЧЧ:ММ»", fields[0])
+		}
+		var w ScheduleWindow
+		var err error
+		if w.Start, err = parseClock(from); err != nil {
+			return nil, err
+		}
+		if w.End, err = parseClock(to); err != nil {
+			return nil, err
+		}
+		if len(fields) == 2 {
+			w.RateLimit = strings.ToUpper(fields[1])
+			if !rateLimitRe.MatchString(w.RateLimit) {
+				return nil, fmt.Errorf("скорость %q: ожидается число с K, M или G, например 2M", fields[1])
+			}
+		}
+		sched = append(sched, w)
+	}
+	return sched, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("время %q: ожидается ЧЧ:ММ", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains сообщает, попадает ли минута суток в окно.
+func (w ScheduleWindow) Contains(minute int) bool {
+	if w.End <= w.Start {
+		return minute >= w.Start || minute < w.End
+	}
+	return minute >= w.Start && minute < w.End
+}
+
+func (w ScheduleWindow) String() string {
+	s := fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+	if w.RateLimit != "" {
+		s += " " + w.RateLimit
+	}
+	return s
+}
+
+// Active возвращает первое окно, в которое попадает t, и false, если t вне всех окон.
+// Пустое расписание разрешает загрузки всегда: nil, true.
+func (s Schedule) Active(t time.Time) (*ScheduleWindow, bool) {
+	if len(s) == 0 {
+		return nil, true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for i := range s {
+		if s[i].Contains(minute) {
+			return &s[i], true
+		}
+	}
+	return nil, false
+}
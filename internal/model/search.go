@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchQuery — разобранная строка поиска медиатеки.
+type SearchQuery struct {
+	Words   []string // ищутся по началу слова
+	Phrases []string // из кавычек: слова подряд и целиком
+	Tags    []string // tag:имя — нужны все теги сразу
+	Domain  string   // domain:сайт — вместе с поддоменами
+	Kind    string   // kind:video | kind:audio
+}
+
+// Empty — в строке нечего искать по тексту.
+func (q SearchQuery) Empty() bool {
+	return len(q.Words) == 0 && len(q.Phrases) == 0
+}
+
+// ParseSearch разбирает строку поиска: слова, фразы в кавычках и операторы
+// tag:, domain: и kind:. Значение оператора тоже можно взять в кавычки —
+// tag:"live music". Незакрытая кавычка действует до конца строки.
+func ParseSearch(s string) SearchQuery {
+	var q SearchQuery
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		switch {
+		case unicode.IsSpace(rs[i]):
+			i++
+		case rs[i] == '"':
+			var phrase string
+			phrase, i = quoted(rs, i)
+			if phrase = strings.Join(strings.Fields(phrase), " "); phrase != "" {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+		default:
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && rs[i] != '"' {
+				i++
+			}
+			word := string(rs[start:i])
+			key, val, ok := strings.Cut(word, ":")
+			key = strings.ToLower(key)
+			if !ok || (key != "tag" && key != "domain" && key != "kind") {
+				q.Words = append(q.Words, word)
+				continue
+			}
+			if val == "" && i < len(rs) && rs[i] == '"' {
+				val, i = quoted(rs, i)
+			}
+			if !q.apply(key, strings.TrimSpace(val)) {
+				q.Words = append(q.Words, word)
+			}
+		}
+	}
+	return q
+}
+
+// apply применяет оператор key:val. Пустое значение пропускается, а неизвестный
+// kind возвращает false — такое слово ищется как текст.
+func (q *SearchQuery) apply(key, val string) bool {
+	switch {
+	case val == "":
+	case key == "tag":
+		q.Tags = append(q.Tags, val)
+	case key == "domain":
+		q.Domain = NormalizeDomainPattern(val)
+	case strings.EqualFold(val, "video"), strings.EqualFold(val, "audio"):
+		q.Kind = strings.ToLower(val)
+	default:
+		return false
+	}
+	return true
+}
+
+// quoted читает текст от открывающей кавычки rs[i] до закрывающей и
+// возвращает его вместе с позицией после неё.
+func quoted(rs []rune, i int) (string, int) {
+	end := i + 1
+	for end < len(rs) && rs[end] != '"' {
+		end++
+	}
+	if end < len(rs) {
+		return string(rs[i+1 : end]), end + 1
+	}
+	return string(rs[i+1:]), end
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Источники субтитров.
+const (
+	SubsDownloaded = "download" // yt-dlp скачал вместе с видео
+	SubsEmbedded   = "embedded" // извлечены ffmpeg из дорожки файла
+)
+
+// Subtitle — субтитры элемента в WebVTT; на язык не больше одной записи.
+type Subtitle struct {
+	ID        string
+	ItemID    string
+	Lang      string // код языка, как у источника: en, ru, en-US, eng
+	Path      string
+	Source    string
+	Text      string // текст реплик для поиска; при чтении из репозитория не заполняется
+	CreatedAt time.Time
+}
+
+var subLangRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,35}$`)
+
+// ValidSubLang — код языка годится для имени файла и URL субтитров.
+func ValidSubLang(lang string) bool {
+	return subLangRe.MatchString(lang)
+}
+
+// ParseSubLangs разбирает список языков через запятую или пробел ("en,ru"),
+// отбрасывая недопустимые коды и повторы.
+func ParseSubLangs(s string) []string {
+	var out []string
+	for _, l := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if ValidSubLang(l) && !slices.Contains(out, l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VideoInfo — сведения о скачанном видео из info.json yt-dlp; технические
+// параметры (размер кадра, кодеки, битрейт) уточняет ffprobe, см. Probe.
+type VideoInfo struct {
+	Uploader    string
+	Channel     string
+	UploadDate  string // YYYYMMDD, как отдаёт yt-dlp
+	Description string
+	Width       int
+	Height      int
+	VCodec      string
+	ACodec      string
+	ViewCount   int64
+	WebpageURL  string // исходная страница видео
+	Chapters    []Chapter
+	Bitrate     int64   // бит/с, весь файл
+	FPS         float64 // кадров в секунду; 0 у аудио
+	Channels    int     // аудиоканалов
+}
+
+// Probe — параметры файла по ffprobe; нулевые поля — ffprobe их не сообщил.
+type Probe struct {
+	Duration int // секунды
+	Width    int
+	Height   int
+	VCodec   string
+	ACodec   string
+	Bitrate  int64
+	FPS      float64
+	Channels int
+}
+
+// Apply переносит параметры в элемент: данные самого файла точнее info.json,
+// который описывает формат до слияния и конвертации. Чего ffprobe не сообщил,
+// остаётся из info.json.
+func (p Probe) Apply(it *Item) {
+	if p.Duration > 0 {
+		it.Duration = p.Duration
+	}
+	if p.Width > 0 && p.Height > 0 {
+		it.Info.Width, it.Info.Height = p.Width, p.Height
+	}
+	if p.VCodec != "" {
+		it.Info.VCodec = p.VCodec
+	}
+	if p.ACodec != "" {
+		it.Info.ACodec = p.ACodec
+	}
+	if p.Bitrate > 0 {
+		it.Info.Bitrate = p.Bitrate
+	}
+	if p.FPS > 0 {
+		it.Info.FPS = p.FPS
+	}
+	if p.Channels > 0 {
+		it.Info.Channels = p.Channels
+	}
+}
+
+// Chapter — глава видео; время в секундах от начала.
+type Chapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title"`
+}
+
+// IsZero — info.json не было (файл найден сканером или скачан до миграции).
+func (v VideoInfo) IsZero() bool {
+	return v.Uploader == "" && v.Channel == "" && v.UploadDate == "" && v.Description == "" &&
+		v.Width == 0 && v.Height == 0 && v.VCodec == "" && v.ACodec == "" &&
+		v.ViewCount == 0 && v.WebpageURL == "" && len(v.Chapters) == 0 &&
+		v.Bitrate == 0 && v.FPS == 0 && v.Channels == 0
+}
+
+// Uploaded — дата публикации; нулевая, если неизвестна.
+func (v VideoInfo) Uploaded() time.Time {
+	t, _ := time.Parse("20060102", v.UploadDate)
+	return t
+}
+
+// Author — канал, а если его нет — загрузивший.
+func (v VideoInfo) Author() string {
+	if v.Channel != "" {
+		return v.Channel
+	}
+	return v.Uploader
+}
+
+// Resolution — «1920×1080»; пусто у аудио.
+func (v VideoInfo) Resolution() string {
+	if v.Width == 0 || v.Height == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d×%d", v.Width, v.Height)
+}
+
+// Quality — значок качества по высоте кадра: «4K», «1080p»; пусто у аудио.
+func (v VideoInfo) Quality() string {
+	// Меряем по короткой стороне (вертикальные видео) и не занижаем кадр,
+	// обрезанный под кино: 1920×800 — это 1080p.
+	h := max(min(v.Width, v.Height), max(v.Width, v.Height)*9/16)
+	switch {
+	case h == 0:
+		return ""
+	case h >= 2160:
+		return "4K"
+	case h >= 1440:
+		return "1440p"
+	}
+	for _, q := range []int{1080, 720, 480, 360, 240} {
+		if h >= q {
+			return fmt.Sprintf("%dp", q)
+		}
+	}
+	return fmt.Sprintf("%dp", h)
+}
+
+// Technical — «30 fps · 2.1 Мбит/с · стерео» для плеера.
+func (v VideoInfo) Technical() string {
+	var parts []string
+	if v.FPS > 0 {
+		parts = append(parts, strings.TrimSuffix(fmt.Sprintf("%.2f", v.FPS), ".00")+" fps")
+	}
+	if v.Bitrate > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f Мбит/с", float64(v.Bitrate)/1_000_000))
+	}
+	switch v.Channels {
+	case 0:
+	case 1:
+		parts = append(parts, "моно")
+	case 2:
+		parts = append(parts, "стерео")
+	default:
+		parts = append(parts, fmt.Sprintf("%d канала", v.Channels))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Codecs — «avc1 / mp4a»: видео- и аудиокодек без профиля.
+func (v VideoInfo) Codecs() string {
+	var parts []string
+	for _, c := range []string{v.VCodec, v.ACodec} {
+		if c != "" {
+			name, _, _ := strings.Cut(c, ".")
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+// Summary — «Канал · 31.01.2024 · 1.2K просмотров» для строки медиатеки;
+// разрешение там показывает значок Quality.
+func (v VideoInfo) Summary() string {
+	var parts []string
+	if a := v.Author(); a != "" {
+		parts = append(parts, a)
+	}
+	if t := v.Uploaded(); !t.IsZero() {
+		parts = append(parts, t.Format("02.01.2006"))
+	}
+	if v.ViewCount > 0 {
+		parts = append(parts, shortCount(v.ViewCount)+" просмотров")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// shortCount — 950, 1.2K, 3.4M.
+func shortCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1_000_000), ".0") + "M"
+	case n >= 1_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1_000), ".0") + "K"
+	}
+	return fmt.Sprint(n)
+}
+
+// directContainers — контейнеры, которые браузеры открывают сами.
+var directContainers = map[string]bool{
+	".mp4": true, ".m4v": true, ".webm": true, ".mov": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".wav": true, ".flac": true,
+}
+
+// directVideoCodecs и directAudioCodecs — префиксы кодеков в записи ffprobe (h264)
+// и yt-dlp (avc1.640028). HEVC и AV1 поддерживают не все браузеры — их перекодируем.
+var (
+	directVideoCodecs = []string{"h264", "avc", "vp8", "vp9", "vp09"}
+	directAudioCodecs = []string{"aac", "mp4a", "mp3", "opus", "vorbis", "flac"}
+)
+
+// DirectPlay — браузер воспроизведёт файл как есть; иначе плеер берёт HLS.
+// Неизвестный кодек (файл не проверен ffprobe) не мешает: решает контейнер.
+func (i *Item) DirectPlay() bool {
+	if !directContainers[strings.ToLower(filepath.Ext(i.Path))] {
+		return false
+	}
+	return knownCodec(i.Info.VCodec, directVideoCodecs) && knownCodec(i.Info.ACodec, directAudioCodecs)
+}
+
+func knownCodec(codec string, prefixes []string) bool {
+	if codec == "" {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(codec, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package model
+
+// WatchedShare — доля длительности, после которой элемент считается просмотренным:
+// финальные титры обычно не досматривают.
+const WatchedShare = 0.9
+
+// WatchState — где остановился просмотр элемента; у каждого пользователя своё.
+type WatchState struct {
+	Position int // секунды от начала; у просмотренного — 0, следующий просмотр с начала
+	Watched  bool
+}
+
+// WatchedAt — позиция position досмотрена до конца элемента длительностью duration.
+func WatchedAt(position, duration int) bool {
+	return duration > 0 && float64(position) >= float64(duration)*WatchedShare
+}
+
+// InProgress — просмотр начат, но не закончен.
+func (w WatchState) InProgress() bool {
+	return !w.Watched && w.Position > 0
+}
+
+// WatchPercent — досмотренная доля в процентах для полосы в медиатеке; 0 — полосы нет.
+func (m *MediaItem) WatchPercent() int {
+	if m.Item == nil || m.Item.Duration <= 0 || !m.Watch.InProgress() {
+		return 0
+	}
+	return min(100, max(1, m.Watch.Position*100/m.Item.Duration))
+}
@@ -6,9 +6,13 @@ const (
 	KindBulkHide     = "bulk_hide"
 	KindBulkMeta     = "bulk_meta"
 	KindExtractAudio = "extract_audio"
+	KindExtractSubs  = "extract_subtitles"
+	KindTranscode    = "transcode"
 	KindUpdateMeta   = "update_meta"
 	KindReindex      = "reindex"
 	KindCleanup      = "cleanup"
+	KindThumbnails   = "thumbnails"
+	KindProbe        = "probe"
 )
 
 // ShowInQueue управляет тем, отображается ли каждый вид операций в UI очереди.
@@ -18,9 +22,13 @@ var ShowInQueue = map[string]bool{
 	KindBulkHide:     true,
 	KindBulkMeta:     true,
 	KindExtractAudio: true,
+	KindExtractSubs:  true,
+	KindTranscode:    true,
 	KindUpdateMeta:   true,
+	KindProbe:        true,
 	KindReindex:      false, // системные операции — не отображаем в очереди
 	KindCleanup:      false,
+	KindThumbnails:   false,
 }
 
 // VisibleKinds возвращает виды операций, включённые для отображения в очереди.
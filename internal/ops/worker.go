@@ -3,17 +3,25 @@ package ops
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/dr-duke/talmorGo/internal/audio"
 	"github.com/dr-duke/talmorGo/internal/config"
 	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/probe"
 	"github.com/dr-duke/talmorGo/internal/repo"
 	"github.com/dr-duke/talmorGo/internal/sse"
 	"github.com/dr-duke/talmorGo/internal/storage"
+	"github.com/dr-duke/talmorGo/internal/subtitle"
+	"github.com/dr-duke/talmorGo/internal/thumb"
+	"github.com/dr-duke/talmorGo/internal/transcode"
 )
 
 // Worker исполняет пакетные операции в фоне, по одной за раз.
@@ -25,7 +33,16 @@ type Worker struct {
 	Storage *storage.Storage
 	Cfg     *config.Config
 	Hub     *sse.Hub
-	ch      chan struct{}
+	// InFlight — пути, которые DirScanner не должен импортировать (*worker.InFlightPaths):
+	// результат перекодирования ещё не записан в БД.
+	InFlight InFlightPaths
+	ch       chan struct{}
+}
+
+// InFlightPaths — набор путей файлов, которые пишутся прямо сейчас.
+type InFlightPaths interface {
+	Add(path string)
+	Remove(path string)
 }
 
 func NewWorker(
@@ -78,7 +95,7 @@ func (w *Worker) drainPending(ctx context.Context) {
 		if op == nil {
 			return
 		}
-		w.Hub.Broadcast() // уведомить UI: статус running
+		w.Hub.Publish(sse.OperationStarted, sse.OperationData{ID: op.ID, Kind: op.Kind, Status: string(model.OpRunning)})
 
 		var execErr error
 		switch op.Kind {
@@ -90,27 +107,37 @@ func (w *Worker) drainPending(ctx context.Context) {
 			execErr = w.execBulkMeta(ctx, op)
 		case KindExtractAudio:
 			execErr = w.execExtractAudio(ctx, op)
+		case KindExtractSubs:
+			execErr = w.execExtractSubs(ctx, op)
+		case KindTranscode:
+			execErr = w.execTranscode(ctx, op)
 		case KindUpdateMeta:
 			execErr = w.execUpdateMeta(ctx, op)
 		case KindReindex:
 			execErr = w.execReindex(ctx, op)
 		case KindCleanup:
 			execErr = w.execCleanup(ctx, op)
+		case KindThumbnails:
+			execErr = w.execThumbnails(ctx, op)
+		case KindProbe:
+			execErr = w.execProbe(ctx, op)
 		default:
 			slog.Warn("ops: unknown kind", "kind", op.Kind)
 		}
 
+		finished := sse.OperationData{ID: op.ID, Kind: op.Kind, Status: string(model.OpDone)}
 		if execErr != nil {
 			slog.Error("ops: exec failed", "kind", op.Kind, "id", op.ID, "err", execErr)
 			if err := w.Ops.SetFailed(ctx, op.ID, execErr.Error()); err != nil {
 				slog.Error("ops: set failed", "err", err)
 			}
+			finished.Status, finished.Error = string(model.OpFailed), execErr.Error()
 		} else {
 			if err := w.Ops.SetDone(ctx, op.ID); err != nil {
 				slog.Error("ops: set done", "err", err)
 			}
 		}
-		w.Hub.Broadcast() // уведомить UI: операция завершена
+		w.Hub.Publish(sse.OperationFinished, finished)
 
 		// Невидимые операции удаляем сразу — они не нужны в очереди и не имеют кнопки dismiss.
 		if !ShowInQueue[op.Kind] {
@@ -225,13 +252,239 @@ func (w *Worker) execExtractAudio(ctx context.Context, op *model.Operation) erro
 		Size:  size,
 		Meta:  meta,
 	}
+	if pr, err := probe.File(ctx, w.Cfg.FfprobeBinary, outPath); err == nil {
+		pr.Apply(audioItem)
+	}
 	if err := w.Items.Create(ctx, audioItem); err != nil {
 		return fmt.Errorf("save audio item: %w", err)
 	}
+	// Обложки у извлечённой дорожки нет — копия превью видео: своё превью видео
+	// удаляется вместе с ним.
+	if src.Thumb != "" {
+		th := thumb.Path(w.Cfg.ThumbDir(), audioItem.ID)
+		if err := thumb.FromImage(ctx, w.Cfg.FfmpegBinary, src.Thumb, th); err != nil {
+			slog.Warn("ops: audio thumbnail", "item_id", audioItem.ID, "err", err)
+		} else if err := w.Items.SetThumb(ctx, audioItem.ID, th); err == nil {
+			audioItem.Thumb = th
+		}
+	}
 	slog.Info("ops: audio extracted", "src", src.Path, "dst", outPath)
+	w.Hub.Publish(sse.ItemCreated, sse.ItemOf(audioItem))
+	return nil
+}
+
+// ── ExtractSubs ──────────────────────────────────────────────────────────────
+
+type extractSubsPayload struct {
+	ItemID string `json:"item_id"`
+}
+
+// execExtractSubs извлекает текстовые дорожки субтитров файла в WebVTT для плеера.
+func (w *Worker) execExtractSubs(ctx context.Context, op *model.Operation) error {
+	var p extractSubsPayload
+	if err := json.Unmarshal([]byte(op.Payload), &p); err != nil {
+		return err
+	}
+	item, err := w.Items.GetByID(ctx, p.ItemID)
+	if err != nil {
+		return fmt.Errorf("get item: %w", err)
+	}
+	if !item.IsAvailable() {
+		return fmt.Errorf("item not available")
+	}
+	streams, err := probe.Subtitles(ctx, w.Cfg.FfprobeBinary, item.Path)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return fmt.Errorf("no text subtitle tracks")
+	}
+	extracted := 0
+	for _, s := range streams {
+		dst := subtitle.Path(w.Cfg.SubsDir(), item.ID, s.Lang)
+		if err := subtitle.Extract(ctx, w.Cfg.FfmpegBinary, item.Path, s.Index, dst); err != nil {
+			slog.Warn("ops: extract subtitles", "item_id", item.ID, "stream", s.Index, "err", err)
+			continue
+		}
+		sub := &model.Subtitle{ItemID: item.ID, Lang: s.Lang, Path: dst, Source: model.SubsEmbedded, Text: subtitle.ReadText(dst)}
+		if err := w.Items.AddSubtitle(ctx, sub); err != nil {
+			return fmt.Errorf("save subtitles: %w", err)
+		}
+		extracted++
+	}
+	slog.Info("ops: subtitles extracted", "item_id", item.ID, "tracks", len(streams), "extracted", extracted)
+	if extracted == 0 {
+		return fmt.Errorf("ffmpeg failed for all %d tracks", len(streams))
+	}
+	return nil
+}
+
+// ── Transcode ────────────────────────────────────────────────────────────────
+
+type transcodePayload struct {
+	ItemIDs []string `json:"item_ids"`
+	Preset  string   `json:"preset"`
+	Replace bool     `json:"replace"`
+}
+
+// execTranscode перекодирует видео по пресету. Результат — новый элемент того же
+// задания рядом с исходным файлом; с Replace исходный элемент удаляется.
+func (w *Worker) execTranscode(ctx context.Context, op *model.Operation) error {
+	var p transcodePayload
+	if err := json.Unmarshal([]byte(op.Payload), &p); err != nil {
+		return err
+	}
+	preset, ok := transcode.Find(p.Preset)
+	if !ok {
+		return fmt.Errorf("unknown preset %q", p.Preset)
+	}
+	done := 0
+	var lastErr error
+	for i, id := range p.ItemIDs {
+		if err := w.transcodeItem(ctx, id, preset, p.Replace, w.progress(op.ID, i, len(p.ItemIDs))); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.Warn("ops: transcode", "item_id", id, "preset", preset.ID, "err", err)
+			lastErr = err
+			continue
+		}
+		done++
+	}
+	slog.Info("ops: transcode done", "preset", preset.ID, "items", len(p.ItemIDs), "done", done)
+	if done == 0 && lastErr != nil {
+		return lastErr
+	}
+	return nil
+}
+
+func (w *Worker) transcodeItem(ctx context.Context, id string, preset transcode.Preset, replace bool, onProgress func(float64)) error {
+	src, err := w.Items.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get item: %w", err)
+	}
+	if !src.IsAvailable() {
+		return fmt.Errorf("item not available")
+	}
+	if !src.IsVideo() {
+		return fmt.Errorf("not a video")
+	}
+
+	dir := filepath.Dir(src.Path)
+	stem := strings.TrimSuffix(filepath.Base(src.Path), filepath.Ext(src.Path))
+	dst := transcode.FreePath(dir, stem+" ("+preset.Suffix+")", preset.Ext)
+	// Результат пишется прямо в медиатеку: сканер не должен принять его за новый файл.
+	w.inFlight(dst)
+	defer w.landed(dst)
+	if err := transcode.Run(ctx, w.Cfg.FfmpegBinary, src.Path, dst, preset, src.Duration, onProgress); err != nil {
+		return err
+	}
+
+	if replace {
+		if err := w.Storage.Delete(src.Path); err != nil {
+			slog.Warn("ops: transcode delete source", "path", src.Path, "err", err)
+		}
+		if err := w.Items.SoftDelete(ctx, src.ID); err != nil {
+			return fmt.Errorf("delete source item: %w", err)
+		}
+		w.Hub.Publish(sse.ItemDeleted, sse.ItemOf(src))
+		// Без приписки, если контейнер сменился: путь исходника остаётся за удалённым элементом.
+		if name := stem + preset.Ext; filepath.Join(dir, name) != src.Path {
+			if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+				w.inFlight(filepath.Join(dir, name))
+				defer w.landed(filepath.Join(dir, name))
+				if renamed, err := w.Storage.Rename(dst, name); err == nil {
+					dst = renamed
+				}
+			}
+		}
+	}
+
+	item := &model.Item{
+		JobID:    src.JobID,
+		Kind:     src.Kind,
+		Path:     dst,
+		Name:     filepath.Base(dst),
+		Duration: src.Duration,
+		Meta:     src.Meta,
+		Info:     src.Info,
+	}
+	if st, err := os.Stat(dst); err == nil {
+		item.Size = st.Size()
+	}
+	if pr, err := probe.File(ctx, w.Cfg.FfprobeBinary, dst); err == nil {
+		pr.Apply(item)
+	}
+	if err := w.Items.Create(ctx, item); err != nil {
+		return fmt.Errorf("save transcoded item: %w", err)
+	}
+	// Своё превью: общее с исходником пропало бы вместе с ним.
+	if th := thumb.Path(w.Cfg.ThumbDir(), item.ID); thumb.Make(ctx, w.Cfg.FfmpegBinary, item, th) == nil {
+		if err := w.Items.SetThumb(ctx, item.ID, th); err != nil {
+			slog.Warn("ops: transcode thumbnail", "item_id", item.ID, "err", err)
+		} else {
+			item.Thumb = th
+		}
+	}
+	w.copySubtitles(ctx, src.ID, item.ID)
+	if replace {
+		w.Storage.DeleteDerived(src.ID)
+	}
+	slog.Info("ops: transcoded", "src", src.Path, "dst", dst, "preset", preset.ID)
+	w.Hub.Publish(sse.ItemCreated, sse.ItemOf(item))
 	return nil
 }
 
+func (w *Worker) inFlight(path string) {
+	if w.InFlight != nil {
+		w.InFlight.Add(path)
+	}
+}
+
+func (w *Worker) landed(path string) {
+	if w.InFlight != nil {
+		w.InFlight.Remove(path)
+	}
+}
+
+// copySubtitles копирует субтитры плеера исходного элемента новому.
+func (w *Worker) copySubtitles(ctx context.Context, fromID, toID string) {
+	subs, err := w.Items.ListSubtitles(ctx, fromID)
+	if err != nil {
+		slog.Warn("ops: list subtitles", "item_id", fromID, "err", err)
+		return
+	}
+	for _, s := range subs {
+		dst := subtitle.Path(w.Cfg.SubsDir(), toID, s.Lang)
+		if err := subtitle.Save(s.Path, dst); err != nil {
+			slog.Warn("ops: copy subtitles", "item_id", fromID, "lang", s.Lang, "err", err)
+			continue
+		}
+		sub := &model.Subtitle{ItemID: toID, Lang: s.Lang, Path: dst, Source: s.Source, Text: subtitle.ReadText(dst)}
+		if err := w.Items.AddSubtitle(ctx, sub); err != nil {
+			slog.Warn("ops: save subtitles", "item_id", toID, "err", err)
+		}
+	}
+}
+
+// progress возвращает колбэк прогресса i-го из n файлов операции; operation.progress
+// публикуется, только когда общий процент вырос хотя бы на единицу.
+func (w *Worker) progress(opID string, i, n int) func(float64) {
+	var text string
+	if n > 1 {
+		text = fmt.Sprintf("%d из %d", i+1, n)
+	}
+	last := -1.0
+	return func(frac float64) {
+		pct := math.Floor((float64(i) + frac) / float64(n) * 100)
+		if pct <= last {
+			return
+		}
+		last = pct
+		w.Hub.Publish(sse.OperationProgress, sse.OperationProgressData{ID: opID, Percent: pct, Text: text})
+	}
+}
+
 // ── UpdateMeta ───────────────────────────────────────────────────────────────
 
 type updateMetaPayload struct {
@@ -292,22 +545,38 @@ func (w *Worker) execReindex(ctx context.Context, op *model.Operation) error {
 			}
 		}
 	}
+	// Текст субтитров, сохранённых до появления поиска, и сам поисковый индекс.
+	subs, err := w.Items.ListSubtitlesWithoutText(ctx)
+	if err != nil {
+		slog.Error("ops: reindex list subtitles", "err", err)
+	}
+	subsIndexed := 0
+	for _, s := range subs {
+		if text := subtitle.ReadText(s.Path); text != "" {
+			if e := w.Items.SetSubtitleText(ctx, s.ID, text); e == nil {
+				subsIndexed++
+			}
+		}
+	}
+	if err := w.Jobs.RebuildSearch(ctx); err != nil {
+		return fmt.Errorf("rebuild search index: %w", err)
+	}
 	slog.Info("ops: reindex done",
 		"job_tags_pruned", nJobTags, "tags_pruned", nTags, "collections_pruned", nCollections,
-		"files_lost", lost, "files_found", found)
+		"files_lost", lost, "files_found", found, "subtitles_indexed", subsIndexed)
 	return nil
 }
 
 // ── Cleanup ──────────────────────────────────────────────────────────────────
 
 func (w *Worker) execCleanup(ctx context.Context, op *model.Operation) error {
-	paths, err := w.Items.PathsForCleanup(ctx)
+	items, err := w.Items.ItemsForCleanup(ctx)
 	if err != nil {
-		slog.Error("ops: cleanup paths", "err", err)
+		slog.Error("ops: cleanup items", "err", err)
 	}
-	for _, p := range paths {
-		if delErr := w.Storage.Delete(p); delErr != nil {
-			slog.Warn("ops: cleanup delete file", "path", p, "err", delErr)
+	for _, it := range items {
+		if delErr := w.Storage.DeleteItem(it); delErr != nil {
+			slog.Warn("ops: cleanup delete file", "path", it.Path, "err", delErr)
 		}
 	}
 	nJobs, err := w.Jobs.CleanupDead(ctx)
@@ -319,6 +588,72 @@ func (w *Worker) execCleanup(ctx context.Context, op *model.Operation) error {
 		slog.Error("ops: prune lost", "err", err)
 	}
 	slog.Info("ops: cleanup done",
-		"files_deleted", len(paths), "jobs_deleted", nJobs, "lost_pruned", nFiles)
+		"files_deleted", len(items), "jobs_deleted", nJobs, "lost_pruned", nFiles)
+	return nil
+}
+
+// ── Thumbnails ───────────────────────────────────────────────────────────────
+
+type thumbnailsPayload struct {
+	ItemIDs []string `json:"item_ids"`
+}
+
+// execThumbnails делает превью файлам, найденным DirScanner: у них нет обложки от yt-dlp.
+func (w *Worker) execThumbnails(ctx context.Context, op *model.Operation) error {
+	var p thumbnailsPayload
+	if err := json.Unmarshal([]byte(op.Payload), &p); err != nil {
+		return err
+	}
+	made := 0
+	for _, id := range p.ItemIDs {
+		item, err := w.Items.GetByID(ctx, id)
+		if err != nil || !item.IsAvailable() || item.Thumb != "" {
+			continue
+		}
+		dst := thumb.Path(w.Cfg.ThumbDir(), item.ID)
+		if err := thumb.Make(ctx, w.Cfg.FfmpegBinary, item, dst); err != nil {
+			slog.Warn("ops: thumbnail", "item_id", id, "err", err)
+			continue
+		}
+		if err := w.Items.SetThumb(ctx, item.ID, dst); err != nil {
+			return fmt.Errorf("save thumbnail: %w", err)
+		}
+		made++
+	}
+	slog.Info("ops: thumbnails done", "items", len(p.ItemIDs), "made", made)
+	return nil
+}
+
+// ── Probe ────────────────────────────────────────────────────────────────────
+
+// execProbe перечитывает параметры всех доступных файлов — для скачанных до ffprobe.
+func (w *Worker) execProbe(ctx context.Context, op *model.Operation) error {
+	items, err := w.Items.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("list items: %w", err)
+	}
+	probed, failed := 0, 0
+	for _, item := range items {
+		if !item.IsAvailable() {
+			continue
+		}
+		pr, err := probe.File(ctx, w.Cfg.FfprobeBinary, item.Path)
+		if errors.Is(err, exec.ErrNotFound) {
+			return err
+		}
+		if err != nil {
+			slog.Warn("ops: probe", "path", item.Path, "err", err)
+			failed++
+			continue
+		}
+		if err := w.Items.SetProbe(ctx, item.ID, pr); err != nil {
+			return fmt.Errorf("save probe: %w", err)
+		}
+		probed++
+	}
+	slog.Info("ops: probe done", "probed", probed, "failed", failed)
+	if probed == 0 && failed > 0 {
+		return fmt.Errorf("ffprobe failed for all %d files", failed)
+	}
 	return nil
 }
@@ -15,18 +15,47 @@ import (
 
 // Expander разворачивает плейлисты в отдельные задания.
 type Expander struct {
-	Jobs repo.JobRepo
-	Tags repo.TagRepo
-	Hub  *sse.Hub // опционально: уведомляет браузер после разворачивания
+	Jobs  repo.JobRepo
+	Tags  repo.TagRepo
+	Rules repo.DomainRuleRepo // опционально: прокси и куки домена для проверки плейлиста
+	Hub   *sse.Hub            // опционально: уведомляет браузер о новых заданиях
 }
 
 func New(jobs repo.JobRepo, tags repo.TagRepo) *Expander {
 	return &Expander{Jobs: jobs, Tags: tags}
 }
 
+// OptsFor применяет к opts правило домена ссылки (прокси, куки), если оно есть.
+func (e *Expander) OptsFor(ctx context.Context, rawURL string, opts downloader.Options) downloader.Options {
+	if e.Rules == nil {
+		return opts
+	}
+	rules, err := e.Rules.List(ctx)
+	if err != nil {
+		slog.Warn("playlist: list domain rules", "err", err)
+		return opts
+	}
+	return opts.WithDomainRule(model.MatchDomainRule(rules, (&model.Job{URL: rawURL}).Domain()))
+}
+
+// FindDuplicate возвращает задание того же владельца с тем же видео или nil
+// (см. repo.JobRepo.FindDuplicate); ошибка поиска не мешает добавлению.
+func (e *Expander) FindDuplicate(ctx context.Context, job *model.Job) *model.Job {
+	dup, err := e.Jobs.FindDuplicate(ctx, job)
+	if err != nil {
+		slog.Warn("playlist: find duplicate", "url", job.URL, "err", err)
+		return nil
+	}
+	return dup
+}
+
 // CreateJobs создаёт одно pending-задание на каждое видео из плейлиста и
-// помечает каждое тегом с названием плейлиста. Возвращает число созданных заданий.
-func (e *Expander) CreateJobs(ctx context.Context, info *downloader.PlaylistInfo, source string, chatID int64) int {
+// помечает каждое тегом с названием плейлиста. Источник, чат, владелец, опции,
+// пресет и приоритет берутся из proto; приоритет понижается на PriorityPlaylist,
+// чтобы большой плейлист не задерживал одиночные ссылки.
+// Видео, которые уже есть в библиотеке или в очереди, пропускаются.
+// Возвращает созданные задания.
+func (e *Expander) CreateJobs(ctx context.Context, info *downloader.PlaylistInfo, proto model.Job) []*model.Job {
 	var tagID string
 	if info.PlaylistTitle != "" && e.Tags != nil {
 		if tag, err := e.Tags.Upsert(ctx, info.PlaylistTitle); err == nil {
@@ -34,14 +63,25 @@ func (e *Expander) CreateJobs(ctx context.Context, info *downloader.PlaylistInfo
 		}
 	}
 
-	created := 0
+	var created []*model.Job
+	skipped := 0
 	for _, entry := range info.Entries {
 		job := &model.Job{
-			URL:    entry.URL,
-			Title:  entry.Title,
-			Status: model.JobPending,
-			Source: source,
-			ChatID: chatID,
+			URL:       entry.URL,
+			Title:     entry.Title,
+			Status:    model.JobPending,
+			Source:    proto.Source,
+			ChatID:    proto.ChatID,
+			OwnerID:   proto.OwnerID,
+			Options:   proto.Options,
+			PresetID:  proto.PresetID,
+			Priority:  proto.Priority + model.PriorityPlaylist,
+			Extractor: entry.Extractor,
+			VideoID:   entry.ID,
+		}
+		if e.FindDuplicate(ctx, job) != nil {
+			skipped++
+			continue
 		}
 		if err := e.Jobs.Create(ctx, job); err != nil {
 			slog.Error("playlist: create job", "url", entry.URL, "err", err)
@@ -50,7 +90,11 @@ func (e *Expander) CreateJobs(ctx context.Context, info *downloader.PlaylistInfo
 		if tagID != "" && e.Tags != nil {
 			e.Tags.AddToJob(ctx, job.ID, tagID) //nolint:errcheck
 		}
-		created++
+		e.Hub.Publish(sse.JobStatus, sse.JobStatusOf(job))
+		created = append(created, job)
+	}
+	if skipped > 0 {
+		slog.Info("playlist: skipped known videos", "playlist", info.PlaylistTitle, "skipped", skipped)
 	}
 	return created
 }
@@ -59,22 +103,40 @@ func (e *Expander) CreateJobs(ctx context.Context, info *downloader.PlaylistInfo
 //   - одиночное видео → переводит placeholder checking → pending (ConfirmSingle);
 //   - плейлист        → удаляет placeholder и создаёт отдельные задания (CreateJobs).
 //
+// Если такое видео уже есть, а force не задан, placeholder остаётся в checking
+// с пометкой дубликата — пользователь решает, что с ним делать.
 // Вызывается асинхронно: placeholder создан в статусе checking, который воркер игнорирует.
-func (e *Expander) ResolvePlaceholder(ctx context.Context, placeholderID, rawURL string, opts downloader.Options, source string, chatID int64) {
-	info := downloader.FetchPlaylist(ctx, rawURL, opts)
+func (e *Expander) ResolvePlaceholder(ctx context.Context, placeholder *model.Job, opts downloader.Options, force bool) {
+	placeholderID := placeholder.ID
+	info, single := downloader.FetchPlaylist(ctx, placeholder.URL, e.OptsFor(ctx, placeholder.URL, opts))
 	if info == nil {
+		if single.ID != "" {
+			placeholder.Extractor, placeholder.VideoID = single.Extractor, single.ID
+			if err := e.Jobs.SetVideoID(ctx, placeholderID, single.Extractor, single.ID); err != nil {
+				slog.Warn("playlist: set video id", "id", placeholderID, "err", err)
+			}
+		}
+		if !force {
+			if dup := e.FindDuplicate(ctx, placeholder); dup != nil {
+				placeholder.MarkDuplicate(dup)
+				if err := e.Jobs.SetDuplicate(ctx, placeholderID, dup.ID, placeholder.Error); err != nil {
+					slog.Error("playlist: mark duplicate", "id", placeholderID, "err", err)
+				}
+				e.Hub.Publish(sse.JobStatus, sse.JobStatusOf(placeholder))
+				return
+			}
+		}
 		// Одиночное видео — переводим checking → pending.
 		if err := e.Jobs.ConfirmSingle(ctx, placeholderID); err != nil {
 			slog.Error("playlist: confirm single", "id", placeholderID, "err", err)
 		}
+		e.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: placeholderID, Status: string(model.JobPending)})
 	} else {
 		// Плейлист: удаляем placeholder и создаём индивидуальные задания.
 		if err := e.Jobs.DeleteChecking(ctx, placeholderID); err != nil {
 			slog.Error("playlist: delete checking placeholder", "id", placeholderID, "err", err)
 		}
-		e.CreateJobs(ctx, info, source, chatID)
-	}
-	if e.Hub != nil {
-		e.Hub.Broadcast()
+		e.Hub.Publish(sse.JobStatus, sse.JobStatusData{ID: placeholderID, Status: "deleted"})
+		e.CreateJobs(ctx, info, *placeholder)
 	}
 }
@@ -0,0 +1,153 @@
+// Package probe читает параметры медиафайлов через ffprobe.
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+type output struct {
+	Streams []struct {
+		Index        int    `json:"index"`
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		Channels     int    `json:"channels"`
+		Duration     string `json:"duration"`
+		Disposition  struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+		Tags struct {
+			Language string `json:"language"`
+		} `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		BitRate  string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// File запускает ffprobe для path. Берутся первые видео- и аудиопотоки;
+// обложка аудиофайла (attached_pic) видео не считается.
+func File(ctx context.Context, ffprobeBin, path string) (model.Probe, error) {
+	out, err := run(ctx, ffprobeBin, "-show_format", "-show_streams", path)
+	if err != nil {
+		return model.Probe{}, err
+	}
+	return parse(out)
+}
+
+// SubtitleStream — текстовая дорожка субтитров в файле.
+type SubtitleStream struct {
+	Index int    // номер потока для ffmpeg -map 0:N
+	Lang  string // уникален в пределах файла: und, eng, eng-2
+	Codec string
+}
+
+// imageSubs — растровые субтитры: в WebVTT их не перевести.
+var imageSubs = map[string]bool{
+	"hdmv_pgs_subtitle": true,
+	"dvd_subtitle":      true,
+	"dvb_subtitle":      true,
+	"xsub":              true,
+}
+
+// Subtitles перечисляет текстовые дорожки субтитров файла (встроенные в MKV/MP4).
+func Subtitles(ctx context.Context, ffprobeBin, path string) ([]SubtitleStream, error) {
+	out, err := run(ctx, ffprobeBin, "-show_streams", "-select_streams", "s", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseSubtitles(out)
+}
+
+func run(ctx context.Context, ffprobeBin string, args ...string) ([]byte, error) {
+	args = append([]string{"-v", "error", "-print_format", "json"}, args...)
+	out, err := exec.CommandContext(ctx, ffprobeBin, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("ffprobe: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+	return out, nil
+}
+
+func parseSubtitles(data []byte) ([]SubtitleStream, error) {
+	var o output
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("ffprobe output: %w", err)
+	}
+	var subs []SubtitleStream
+	seen := make(map[string]int)
+	for _, s := range o.Streams {
+		if s.CodecType != "subtitle" || imageSubs[s.CodecName] {
+			continue
+		}
+		lang := s.Tags.Language
+		if !model.ValidSubLang(lang) {
+			lang = "und"
+		}
+		// Две дорожки одного языка (полные и форсированные) не должны затирать друг друга.
+		seen[lang]++
+		if n := seen[lang]; n > 1 {
+			lang += "-" + strconv.Itoa(n)
+		}
+		subs = append(subs, SubtitleStream{Index: s.Index, Lang: lang, Codec: s.CodecName})
+	}
+	return subs, nil
+}
+
+func parse(data []byte) (model.Probe, error) {
+	var o output
+	if err := json.Unmarshal(data, &o); err != nil {
+		return model.Probe{}, fmt.Errorf("ffprobe output: %w", err)
+	}
+	var p model.Probe
+	duration := seconds(o.Format.Duration)
+	for _, s := range o.Streams {
+		switch {
+		case s.CodecType == "video" && s.Disposition.AttachedPic == 0 && p.VCodec == "":
+			p.VCodec, p.Width, p.Height = s.CodecName, s.Width, s.Height
+			p.FPS = frameRate(s.AvgFrameRate)
+		case s.CodecType == "audio" && p.ACodec == "":
+			p.ACodec, p.Channels = s.CodecName, s.Channels
+		default:
+			continue
+		}
+		if duration == 0 {
+			duration = seconds(s.Duration)
+		}
+	}
+	p.Duration = int(duration + 0.5)
+	p.Bitrate, _ = strconv.ParseInt(o.Format.BitRate, 10, 64)
+	return p, nil
+}
+
+func seconds(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// frameRate разбирает дробь вида "30000/1001"; "0/0" — частота неизвестна.
+func frameRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return seconds(s)
+	}
+	n, d := seconds(num), seconds(den)
+	if d == 0 {
+		return 0
+	}
+	return n / d
+}
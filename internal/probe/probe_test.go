@@ -0,0 +1,58 @@
+package probe
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			name: "video",
+			json: `{"streams":[
+				{"codec_type":"video","codec_name":"h264","width":1920,"height":1080,"avg_frame_rate":"30000/1001"},
+				{"codec_type":"audio","codec_name":"aac","channels":2}],
+				"format":{"duration":"125.6","bit_rate":"2500000"}}`,
+			want: "{Duration:126 Width:1920 Height:1080 VCodec:h264 ACodec:aac Bitrate:2500000 FPS:29.97002997002997 Channels:2}",
+		},
+		{
+			name: "audio with cover",
+			json: `{"streams":[
+				{"codec_type":"audio","codec_name":"mp3","channels":1,"duration":"61.2"},
+				{"codec_type":"video","codec_name":"mjpeg","width":600,"height":600,"avg_frame_rate":"0/0","disposition":{"attached_pic":1}}],
+				"format":{"bit_rate":"128000"}}`,
+			want: "{Duration:61 Width:0 Height:0 VCodec: ACodec:mp3 Bitrate:128000 FPS:0 Channels:1}",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := parse([]byte(c.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%+v", p); got != c.want {
+				t.Errorf("got  %s\nwant %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseSubtitles(t *testing.T) {
+	data := `{"streams":[
+		{"index":2,"codec_type":"subtitle","codec_name":"subrip","tags":{"language":"eng"}},
+		{"index":3,"codec_type":"subtitle","codec_name":"hdmv_pgs_subtitle","tags":{"language":"rus"}},
+		{"index":4,"codec_type":"subtitle","codec_name":"ass","tags":{"language":"eng"}},
+		{"index":5,"codec_type":"subtitle","codec_name":"mov_text"}]}`
+	subs, err := parseSubtitles([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[{Index:2 Lang:eng Codec:subrip} {Index:4 Lang:eng-2 Codec:ass} {Index:5 Lang:und Codec:mov_text}]"
+	if got := fmt.Sprintf("%+v", subs); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
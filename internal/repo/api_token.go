@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/google/uuid"
+)
+
+// apiTokenPrefix — по префиксу токен легко узнать в логах и менеджерах секретов.
+const apiTokenPrefix = "tlm_"
+
+// apiTokenTouchEvery — last_used_at обновляется не чаще этого интервала,
+// чтобы не писать в БД на каждый запрос.
+const apiTokenTouchEvery = time.Minute
+
+const apiTokenSelect = `SELECT id, COALESCE(user_id,''), name, scope, created_at, COALESCE(expires_at,''), COALESCE(last_used_at,'') FROM api_tokens`
+
+type sqliteAPITokenRepo struct {
+	db *sql.DB
+}
+
+func NewAPITokenRepo(db *sql.DB) APITokenRepo {
+	return &sqliteAPITokenRepo{db: db}
+}
+
+// hashSecret — SHA-256 секрета (API-токена или сессии) для хранения в БД.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create генерирует секрет, сохраняет его хэш и возвращает секрет — показать его можно только один раз.
+func (r *sqliteAPITokenRepo) Create(ctx context.Context, t *model.APIToken) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	secret := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	if t.ID == "" {
+		t.ID = uuid.NewString()
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	var expiresAt sql.NullString
+	if t.ExpiresAt != nil {
+		expiresAt = sql.NullString{String: t.ExpiresAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO api_tokens (id, user_id, name, token_hash, scope, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, nullStr(t.UserID), t.Name, hashSecret(secret), string(t.Scope),
+		t.CreatedAt.Format(time.RFC3339Nano), expiresAt,
+	)
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// List возвращает токены пользователя; пустой userID — все токены.
+func (r *sqliteAPITokenRepo) List(ctx context.Context, userID string) ([]*model.APIToken, error) {
+	q := apiTokenSelect
+	var args []any
+	if userID != "" {
+		q += ` WHERE user_id=?`
+		args = append(args, userID)
+	}
+	rows, err := r.db.QueryContext(ctx, q+` ORDER BY created_at DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// GetBySecret находит токен по предъявленному секрету. Срок действия не проверяет.
+func (r *sqliteAPITokenRepo) GetBySecret(ctx context.Context, secret string) (*model.APIToken, error) {
+	row := r.db.QueryRowContext(ctx, apiTokenSelect+` WHERE token_hash=?`, hashSecret(secret))
+	return scanAPIToken(row)
+}
+
+// Touch обновляет last_used_at (с троттлингом apiTokenTouchEvery).
+func (r *sqliteAPITokenRepo) Touch(ctx context.Context, id string) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE api_tokens SET last_used_at=?
+		 WHERE id=? AND (last_used_at IS NULL OR last_used_at < ?)`,
+		now.Format(time.RFC3339Nano), id, now.Add(-apiTokenTouchEvery).Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+func (r *sqliteAPITokenRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id=?`, id)
+	return err
+}
+
+func scanAPIToken(s scanner) (*model.APIToken, error) {
+	var t model.APIToken
+	var createdAt, expiresAt, lastUsedAt string
+	if err := s.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &createdAt, &expiresAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	t.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	if expiresAt != "" {
+		v, _ := time.Parse(time.RFC3339Nano, expiresAt)
+		t.ExpiresAt = &v
+	}
+	if lastUsedAt != "" {
+		v, _ := time.Parse(time.RFC3339Nano, lastUsedAt)
+		t.LastUsedAt = &v
+	}
+	return &t, nil
+}
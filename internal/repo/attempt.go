@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/google/uuid"
+)
+
+// Попытки скачивания хранятся в job_attempts и принадлежат заданию, поэтому
+// их методы — часть JobRepo.
+
+const attemptSelect = `SELECT id, job_id, number, started_at, finished_at, status, exit_code,
+	error, error_class, files, ytdlp_version, args, log FROM job_attempts`
+
+func (r *sqliteJobRepo) StartAttempt(ctx context.Context, a *model.JobAttempt) error {
+	if a.ID == "" {
+		a.ID = uuid.NewString()
+	}
+	if a.StartedAt.IsZero() {
+		a.StartedAt = time.Now().UTC()
+	}
+	a.Status = model.JobRunning
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO job_attempts (id, job_id, number, started_at, status, ytdlp_version, args)
+		 SELECT ?, ?, COALESCE(MAX(number), 0) + 1, ?, ?, ?, ? FROM job_attempts WHERE job_id=?
+		 RETURNING number`,
+		a.ID, a.JobID, a.StartedAt.Format(time.RFC3339Nano), a.Status, a.YtDlpVersion, a.Args, a.JobID,
+	).Scan(&a.Number)
+}
+
+func (r *sqliteJobRepo) FinishAttempt(ctx context.Context, a *model.JobAttempt) error {
+	if a.FinishedAt == nil {
+		now := time.Now().UTC()
+		a.FinishedAt = &now
+	}
+	var exitCode sql.NullInt64
+	if a.ExitCode != nil {
+		exitCode = sql.NullInt64{Int64: int64(*a.ExitCode), Valid: true}
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE job_attempts SET finished_at=?, status=?, exit_code=?, error=?, error_class=?, files=?, log=? WHERE id=?`,
+		a.FinishedAt.Format(time.RFC3339Nano), a.Status, exitCode, a.Error, a.ErrorClass,
+		strings.Join(a.Files, "\n"), a.Log, a.ID,
+	)
+	return err
+}
+
+func (r *sqliteJobRepo) ListAttempts(ctx context.Context, jobID string) ([]*model.JobAttempt, error) {
+	rows, err := r.db.QueryContext(ctx, attemptSelect+` WHERE job_id=? ORDER BY number`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.JobAttempt
+	for rows.Next() {
+		a, err := scanAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqliteJobRepo) ListAttemptOutcomes(ctx context.Context, jobID string) ([]*model.JobAttempt, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT number, status, error_class FROM job_attempts WHERE job_id=? ORDER BY number`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.JobAttempt
+	for rows.Next() {
+		a := &model.JobAttempt{JobID: jobID}
+		if err := rows.Scan(&a.Number, &a.Status, &a.ErrorClass); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func scanAttempt(s scanner) (*model.JobAttempt, error) {
+	var a model.JobAttempt
+	var startedAt, files string
+	var finishedAt sql.NullString
+	var exitCode sql.NullInt64
+	if err := s.Scan(&a.ID, &a.JobID, &a.Number, &startedAt, &finishedAt, &a.Status, &exitCode,
+		&a.Error, &a.ErrorClass, &files, &a.YtDlpVersion, &a.Args, &a.Log); err != nil {
+		return nil, err
+	}
+	a.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+	if finishedAt.Valid {
+		t, _ := time.Parse(time.RFC3339Nano, finishedAt.String)
+		a.FinishedAt = &t
+	}
+	if exitCode.Valid {
+		code := int(exitCode.Int64)
+		a.ExitCode = &code
+	}
+	if files != "" {
+		a.Files = strings.Split(files, "\n")
+	}
+	return &a, nil
+}
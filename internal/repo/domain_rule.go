@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/google/uuid"
+)
+
+type sqliteDomainRuleRepo struct {
+	db *sql.DB
+}
+
+func NewDomainRuleRepo(db *sql.DB) DomainRuleRepo {
+	return &sqliteDomainRuleRepo{db: db}
+}
+
+const domainRuleSelect = `SELECT id, pattern, proxy, max_parallel, min_delay_sec, COALESCE(preset_id,''), cookies, created_at FROM domain_rules`
+
+func (r *sqliteDomainRuleRepo) Create(ctx context.Context, rule *model.DomainRule) error {
+	if rule.ID == "" {
+		rule.ID = uuid.NewString()
+	}
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO domain_rules (id, pattern, proxy, max_parallel, min_delay_sec, preset_id, cookies, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID, rule.Pattern, rule.Proxy, rule.MaxParallel, int64(rule.MinDelay/time.Second),
+		nullStr(rule.PresetID), rule.Cookies, rule.CreatedAt.Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+func (r *sqliteDomainRuleRepo) Update(ctx context.Context, rule *model.DomainRule) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE domain_rules SET pattern=?, proxy=?, max_parallel=?, min_delay_sec=?, preset_id=?, cookies=? WHERE id=?`,
+		rule.Pattern, rule.Proxy, rule.MaxParallel, int64(rule.MinDelay/time.Second),
+		nullStr(rule.PresetID), rule.Cookies, rule.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *sqliteDomainRuleRepo) List(ctx context.Context) ([]*model.DomainRule, error) {
+	rows, err := r.db.QueryContext(ctx, domainRuleSelect+` ORDER BY pattern`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.DomainRule
+	for rows.Next() {
+		rule, err := scanDomainRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqliteDomainRuleRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM domain_rules WHERE id=?`, id)
+	return err
+}
+
+func scanDomainRule(s scanner) (*model.DomainRule, error) {
+	var rule model.DomainRule
+	var delaySec int64
+	var createdAt string
+	if err := s.Scan(&rule.ID, &rule.Pattern, &rule.Proxy, &rule.MaxParallel, &delaySec,
+		&rule.PresetID, &rule.Cookies, &createdAt); err != nil {
+		return nil, err
+	}
+	rule.MinDelay = time.Duration(delaySec) * time.Second
+	rule.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	return &rule, nil
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+// FindDuplicate: видимые скачанные задания важнее ожидающих, скрытые — в последнюю очередь.
+// Совпадение по id требует того же экстрактора: короткие id разных сайтов пересекаются.
+// Если экстрактор у одной из сторон ещё неизвестен, вместо него сравнивается домен.
+func (r *sqliteJobRepo) FindDuplicate(ctx context.Context, job *model.Job) (*model.Job, error) {
+	row := r.db.QueryRowContext(ctx, jobSelect+`
+		WHERE id <> ? AND duplicate_of = '' AND COALESCE(owner_id,'') = ?
+		  AND (status IN ('done','imported') OR (hidden = 0 AND status IN ('checking','pending','running','retrying','paused')))
+		  AND (url = ? OR (? <> '' AND video_id = ? AND (extractor = ? OR ((extractor = '' OR ? = '') AND domain = ?))))
+		ORDER BY hidden, CASE WHEN status IN ('done','imported') THEN 0 ELSE 1 END, created_at DESC
+		LIMIT 1`,
+		job.ID, job.OwnerID,
+		job.URL, job.VideoID, job.VideoID, job.Extractor, job.Extractor, strings.ToLower(job.Domain()),
+	)
+	dup, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return dup, err
+}
+
+func (r *sqliteJobRepo) SetVideoID(ctx context.Context, jobID, extractor, videoID string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE jobs SET extractor=?, video_id=?, updated_at=? WHERE id=?`,
+		extractor, videoID, time.Now().UTC().Format(time.RFC3339Nano), jobID,
+	)
+	return err
+}
+
+func (r *sqliteJobRepo) SetDuplicate(ctx context.Context, jobID, of, note string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE jobs SET duplicate_of=?, error=?, updated_at=? WHERE id=? AND status='checking'`,
+		of, note, time.Now().UTC().Format(time.RFC3339Nano), jobID,
+	)
+	return err
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -21,7 +22,10 @@ func NewItemRepo(db *sql.DB) ItemRepo {
 const itemSelect = `
 	SELECT id, COALESCE(job_id,''), kind, path, name, size, duration,
 	       title, artist, album, year, genre,
-	       created_at, COALESCE(deleted_at,''), COALESCE(lost_at,'')
+	       created_at, COALESCE(deleted_at,''), COALESCE(lost_at,''),
+	       uploader, channel, upload_date, description, width, height,
+	       vcodec, acodec, view_count, webpage_url, chapters, thumb,
+	       bitrate, fps, channels
 	FROM items`
 
 func (r *sqliteItemRepo) Create(ctx context.Context, item *model.Item) error {
@@ -31,18 +35,31 @@ func (r *sqliteItemRepo) Create(ctx context.Context, item *model.Item) error {
 	if item.CreatedAt.IsZero() {
 		item.CreatedAt = time.Now().UTC()
 	}
+	info := item.Info
 	_, err := r.db.ExecContext(ctx,
 		`INSERT INTO items (id, job_id, kind, path, name, size, duration,
-		                    title, artist, album, year, genre, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		                    title, artist, album, year, genre, created_at,
+		                    uploader, channel, upload_date, description, width, height,
+		                    vcodec, acodec, view_count, webpage_url, chapters, thumb,
+		                    bitrate, fps, channels)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(path) DO UPDATE SET
 		     name=excluded.name, size=excluded.size, duration=excluded.duration,
 		     title=excluded.title, artist=excluded.artist, album=excluded.album,
-		     year=excluded.year, genre=excluded.genre`,
+		     year=excluded.year, genre=excluded.genre,
+		     uploader=excluded.uploader, channel=excluded.channel, upload_date=excluded.upload_date,
+		     description=excluded.description, width=excluded.width, height=excluded.height,
+		     vcodec=excluded.vcodec, acodec=excluded.acodec, view_count=excluded.view_count,
+		     webpage_url=excluded.webpage_url, chapters=excluded.chapters,
+		     bitrate=excluded.bitrate, fps=excluded.fps, channels=excluded.channels`,
 		item.ID, nullStr(item.JobID), item.Kind, item.Path, item.Name,
 		item.Size, item.Duration,
 		item.Meta.Title, item.Meta.Artist, item.Meta.Album, item.Meta.Year, item.Meta.Genre,
 		item.CreatedAt.Format(time.RFC3339Nano),
+		info.Uploader, info.Channel, info.UploadDate, info.Description, info.Width, info.Height,
+		info.VCodec, info.ACodec, info.ViewCount, info.WebpageURL, marshalChapters(info.Chapters),
+		item.Thumb,
+		info.Bitrate, info.FPS, info.Channels,
 	)
 	if err != nil {
 		return err
@@ -120,24 +137,15 @@ func (r *sqliteItemRepo) AllPaths(ctx context.Context) (map[string]struct{}, err
 	return paths, rows.Err()
 }
 
-func (r *sqliteItemRepo) PathsForCleanup(ctx context.Context) ([]string, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT i.path FROM items i
-		JOIN jobs j ON j.id = i.job_id
-		WHERE j.hidden=1 OR j.status='failed'`)
+func (r *sqliteItemRepo) ItemsForCleanup(ctx context.Context) ([]*model.Item, error) {
+	rows, err := r.db.QueryContext(ctx, itemSelect+`
+		WHERE job_id IN (SELECT id FROM jobs WHERE hidden=1 OR status='failed')
+		   OR lost_at IS NOT NULL`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var paths []string
-	for rows.Next() {
-		var p string
-		if err := rows.Scan(&p); err != nil {
-			return nil, err
-		}
-		paths = append(paths, p)
-	}
-	return paths, rows.Err()
+	return scanItems(rows)
 }
 
 func (r *sqliteItemRepo) PruneLost(ctx context.Context) (int, error) {
@@ -174,6 +182,21 @@ func (r *sqliteItemRepo) MarkFound(ctx context.Context, id string) error {
 	return err
 }
 
+func (r *sqliteItemRepo) SetThumb(ctx context.Context, id, path string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE items SET thumb=? WHERE id=?`, path, id)
+	return err
+}
+
+// SetProbe записывает параметры файла; длительность остаётся прежней, если ffprobe её не знает.
+func (r *sqliteItemRepo) SetProbe(ctx context.Context, id string, p model.Probe) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE items SET duration=CASE WHEN ?>0 THEN ? ELSE duration END,
+		     width=?, height=?, vcodec=?, acodec=?, bitrate=?, fps=?, channels=?
+		 WHERE id=?`,
+		p.Duration, p.Duration, p.Width, p.Height, p.VCodec, p.ACodec, p.Bitrate, p.FPS, p.Channels, id)
+	return err
+}
+
 func (r *sqliteItemRepo) UpdateMeta(ctx context.Context, id string, meta model.AudioMeta) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE items SET title=?, artist=?, album=?, year=?, genre=? WHERE id=?`,
@@ -211,16 +234,21 @@ func (r *sqliteItemRepo) BulkUpdateMetaFields(ctx context.Context, ids []string,
 
 func scanItem(s scanner) (*model.Item, error) {
 	var item model.Item
-	var createdAt, deletedAt, lostAt string
+	var createdAt, deletedAt, lostAt, chapters string
+	info := &item.Info
 	err := s.Scan(
 		&item.ID, &item.JobID, &item.Kind, &item.Path, &item.Name,
 		&item.Size, &item.Duration,
 		&item.Meta.Title, &item.Meta.Artist, &item.Meta.Album, &item.Meta.Year, &item.Meta.Genre,
 		&createdAt, &deletedAt, &lostAt,
+		&info.Uploader, &info.Channel, &info.UploadDate, &info.Description, &info.Width, &info.Height,
+		&info.VCodec, &info.ACodec, &info.ViewCount, &info.WebpageURL, &chapters, &item.Thumb,
+		&info.Bitrate, &info.FPS, &info.Channels,
 	)
 	if err != nil {
 		return nil, err
 	}
+	info.Chapters = unmarshalChapters(chapters)
 	item.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
 	if deletedAt != "" {
 		t, _ := time.Parse(time.RFC3339Nano, deletedAt)
@@ -244,3 +272,21 @@ func scanItems(rows *sql.Rows) ([]*model.Item, error) {
 	}
 	return out, rows.Err()
 }
+
+// marshalChapters кодирует главы в JSON; без глав — пустая строка.
+func marshalChapters(ch []model.Chapter) string {
+	if len(ch) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(ch)
+	return string(b)
+}
+
+func unmarshalChapters(s string) []model.Chapter {
+	if s == "" {
+		return nil
+	}
+	var ch []model.Chapter
+	json.Unmarshal([]byte(s), &ch) //nolint:errcheck
+	return ch
+}
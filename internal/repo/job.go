@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -11,7 +12,9 @@ import (
 	"github.com/google/uuid"
 )
 
-const jobSelect = `SELECT id, url, status, title, error, source, chat_id, created_at, updated_at, retry_count, next_retry_at, first_failed_at, COALESCE(tg_message_id,0) FROM jobs`
+const jobColumns = `id, url, status, title, error, source, chat_id, created_at, updated_at, retry_count, next_retry_at, first_failed_at, COALESCE(tg_message_id,0), hidden, COALESCE(owner_id,''), options, COALESCE(preset_id,''), priority, error_class, extractor, video_id, duplicate_of`
+
+const jobSelect = `SELECT ` + jobColumns + ` FROM jobs`
 
 type sqliteJobRepo struct {
 	db *sql.DB
@@ -28,17 +31,32 @@ func (r *sqliteJobRepo) Create(ctx context.Context, job *model.Job) error {
 	now := time.Now().UTC()
 	job.CreatedAt = now
 	job.UpdatedAt = now
-	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO jobs (id, url, status, title, error, source, chat_id, created_at, updated_at, retry_count)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0)`,
-		job.ID, job.URL, job.Status, job.Title, job.Error,
+	options, err := marshalJobOptions(job.Options)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO jobs (id, url, domain, status, title, error, source, chat_id, created_at, updated_at, retry_count, owner_id, options, preset_id, priority, extractor, video_id, duplicate_of)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.URL, strings.ToLower(job.Domain()), job.Status, job.Title, job.Error,
 		job.Source, job.ChatID,
 		job.CreatedAt.Format(time.RFC3339Nano),
 		job.UpdatedAt.Format(time.RFC3339Nano),
+		nullStr(job.OwnerID), options, nullStr(job.PresetID), job.Priority,
+		job.Extractor, job.VideoID, job.DuplicateOf,
 	)
 	return err
 }
 
+// marshalJobOptions кодирует опции задания; нулевые хранятся пустой строкой.
+func marshalJobOptions(o model.JobOptions) (string, error) {
+	if o.IsZero() {
+		return "", nil
+	}
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+
 func (r *sqliteJobRepo) GetByID(ctx context.Context, id string) (*model.Job, error) {
 	row := r.db.QueryRowContext(ctx, jobSelect+` WHERE id = ?`, id)
 	return scanJob(row)
@@ -46,6 +64,7 @@ func (r *sqliteJobRepo) GetByID(ctx context.Context, id string) (*model.Job, err
 
 func (r *sqliteJobRepo) List(ctx context.Context, f JobFilter) ([]*model.Job, error) {
 	query := jobSelect
+	var conds []string
 	var args []any
 	if len(f.Statuses) > 0 {
 		placeholders := make([]string, len(f.Statuses))
@@ -53,7 +72,14 @@ func (r *sqliteJobRepo) List(ctx context.Context, f JobFilter) ([]*model.Job, er
 			placeholders[i] = "?"
 			args = append(args, s)
 		}
-		query += " WHERE status IN (" + strings.Join(placeholders, ",") + ")"
+		conds = append(conds, "status IN ("+strings.Join(placeholders, ",")+")")
+	}
+	if f.OwnerID != "" {
+		conds = append(conds, "owner_id = ?")
+		args = append(args, f.OwnerID)
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
 	}
 	query += " ORDER BY created_at DESC"
 
@@ -74,22 +100,66 @@ func (r *sqliteJobRepo) List(ctx context.Context, f JobFilter) ([]*model.Job, er
 	return jobs, rows.Err()
 }
 
-// mediaRowSQL — базовая проекция для всех media-запросов.
-// Все запросы должны возвращать ровно эти колонки в этом порядке.
-const mediaRowSQL = `
+// mediaColumnsSQL — базовая проекция для всех media-запросов без ключа сортировки.
+// Все запросы должны возвращать ровно эти колонки в этом порядке, затем sort_key и row_id.
+const mediaColumnsSQL = `
 	SELECT
 		j.id, j.url, j.status, j.title,
 		j.error, j.source, j.chat_id,
 		j.created_at, j.updated_at, j.retry_count, j.next_retry_at, j.first_failed_at,
-		j.hidden,
+		j.hidden, COALESCE(j.owner_id,''), j.error_class,
 		i.id, i.kind, i.name, i.size, i.path, i.duration,
 		i.title, i.artist, i.album, i.year, i.genre,
 		i.created_at, i.deleted_at, i.lost_at,
+		COALESCE(i.uploader,''), COALESCE(i.channel,''), COALESCE(i.upload_date,''), COALESCE(i.description,''),
+		COALESCE(i.width,0), COALESCE(i.height,0), COALESCE(i.vcodec,''), COALESCE(i.acodec,''),
+		COALESCE(i.view_count,0), COALESCE(i.webpage_url,''), COALESCE(i.chapters,''), COALESCE(i.thumb,''),
+		COALESCE(i.bitrate,0), COALESCE(i.fps,0), COALESCE(i.channels,0),
 		(SELECT GROUP_CONCAT(t2.name,'|')
 		 FROM job_tags jt2 JOIN tags t2 ON t2.id=jt2.tag_id WHERE jt2.job_id=j.id) AS tags,
-		COALESCE(i.created_at, j.created_at) AS sort_ts
+		(SELECT GROUP_CONCAT(s2.lang,'|') FROM subtitles s2 WHERE s2.item_id=i.id) AS subs,
+		COALESCE((SELECT ws.position FROM viewer_watch ws WHERE ws.item_id=i.id),0) AS watch_pos,
+		COALESCE((SELECT ws.watched FROM viewer_watch ws WHERE ws.item_id=i.id),0) AS watched,
 `
 
+// viewerWatchSQL — CTE viewer_watch: прогресс просмотра одного пользователя
+// (параметр — его ID). Media-запросы читают прогресс только через него.
+const viewerWatchSQL = `WITH viewer_watch AS (
+			SELECT item_id, position, watched FROM watch_state WHERE user_id = ?
+		)`
+
+// sortKeys — выражение ключа сортировки для строк обеих частей UNION (у строк
+// без элемента i.* — NULL). Числовые ключи в курсоре — строки, их приводят к cast.
+var sortKeys = map[model.MediaSort]struct {
+	expr string
+	cast string
+}{
+	model.SortDate:      {expr: "COALESCE(i.created_at, j.created_at)"},
+	model.SortName:      {expr: "lower(COALESCE(NULLIF(i.name,''), NULLIF(j.title,''), j.url))"},
+	model.SortSize:      {expr: "COALESCE(i.size, 0)", cast: "INTEGER"},
+	model.SortDuration:  {expr: "COALESCE(i.duration, 0)", cast: "INTEGER"},
+	model.SortDomain:    {expr: "j.domain"},
+	model.SortRelevance: {expr: "h.score", cast: "REAL"}, // только вместе с search_hits h
+}
+
+// rowIDExpr — второй ключ сортировки, чтобы порядок был однозначным.
+const rowIDExpr = "COALESCE(i.id, j.id)"
+
+// mediaRowSQLBy — полная проекция с ключом сортировки s в колонке sort_key.
+func mediaRowSQLBy(s model.MediaSort) string {
+	key, ok := sortKeys[s]
+	if !ok {
+		key = sortKeys[model.SortDate]
+	}
+	return mediaColumnsSQL + `
+		` + key.expr + ` AS sort_key,
+		` + rowIDExpr + ` AS row_id
+`
+}
+
+// mediaRowSQL — проекция с сортировкой по дате добавления.
+var mediaRowSQL = mediaRowSQLBy(model.SortDate)
+
 func (r *sqliteJobRepo) runMediaQuery(ctx context.Context, q string, args ...any) ([]*model.MediaItem, error) {
 	rows, err := r.db.QueryContext(ctx, q, args...)
 	if err != nil {
@@ -111,7 +181,7 @@ func (r *sqliteJobRepo) runMediaQuery(ctx context.Context, q string, args ...any
 //   - одну строку на каждый item (один job → N items → N строк)
 //   - плюс одну строку на job без items (pending/running/retrying/failed/cancelled)
 func (r *sqliteJobRepo) ListMedia(ctx context.Context) ([]*model.MediaItem, error) {
-	q := mediaRowSQL + `
+	q := viewerWatchSQL + mediaRowSQL + `
 		FROM items i
 		JOIN jobs j ON j.id = i.job_id
 		WHERE j.hidden = 0
@@ -122,185 +192,247 @@ func (r *sqliteJobRepo) ListMedia(ctx context.Context) ([]*model.MediaItem, erro
 		FROM jobs j
 		LEFT JOIN items i ON i.id = NULL
 		WHERE j.hidden = 0
-		  AND j.status IN ('checking','pending','running','retrying','failed','cancelled')
+		  AND j.status IN ('checking','pending','running','retrying','paused','failed','cancelled')
 		  AND NOT EXISTS (SELECT 1 FROM items WHERE job_id = j.id)
 
-		ORDER BY sort_ts DESC
+		ORDER BY sort_key DESC
 	`
-	return r.runMediaQuery(ctx, q)
+	return r.runMediaQuery(ctx, q, "")
 }
 
-// SearchMedia ищет по имени, URL, заголовку и тегам (для Telegram-бота).
-func (r *sqliteJobRepo) SearchMedia(ctx context.Context, query string) ([]*model.MediaItem, error) {
-	like := "%" + query + "%"
-	q := mediaRowSQL + `
-		FROM items i
-		JOIN jobs j ON j.id = i.job_id
-		WHERE j.hidden = 0
-		  AND (i.name LIKE ? OR j.url LIKE ? OR j.title LIKE ?
-		       OR j.id IN (SELECT jt2.job_id FROM job_tags jt2
-		                   JOIN tags t2 ON t2.id=jt2.tag_id WHERE t2.name LIKE ?))
-
-		UNION ALL
-
-		` + mediaRowSQL + `
-		FROM jobs j
-		LEFT JOIN items i ON i.id = NULL
-		WHERE j.hidden = 0
-		  AND j.status IN ('checking','pending','running','retrying','failed','cancelled')
-		  AND NOT EXISTS (SELECT 1 FROM items WHERE job_id = j.id)
-		  AND (j.url LIKE ? OR j.title LIKE ?
-		       OR j.id IN (SELECT jt2.job_id FROM job_tags jt2
-		                   JOIN tags t2 ON t2.id=jt2.tag_id WHERE t2.name LIKE ?))
-
-		ORDER BY sort_ts DESC
-		LIMIT 10
-	`
-	return r.runMediaQuery(ctx, q, like, like, like, like, like, like, like)
+// SearchMedia — десять самых подходящих строк по запросу (для Telegram-бота).
+func (r *sqliteJobRepo) SearchMedia(ctx context.Context, query, ownerID string) ([]*model.MediaItem, error) {
+	return r.FilterMedia(ctx, model.MediaFilter{
+		Query: query, OwnerID: ownerID, Sort: model.SortRelevance, Limit: 10,
+	})
 }
 
-// FilterMedia — серверная фильтрация: текст + kind + AND-теги.
+// FilterMedia — серверная фильтрация, сортировка и курсорная пагинация медиатеки.
 func (r *sqliteJobRepo) FilterMedia(ctx context.Context, f model.MediaFilter) ([]*model.MediaItem, error) {
-	if f.Query == "" && f.Kind == "" && len(f.Tags) == 0 && f.Limit == 0 {
-		return r.ListMedia(ctx)
+	f, match, err := applySearch(ctx, r.db, f)
+	if err != nil {
+		return nil, err
+	}
+	if f.Sort == model.SortRelevance && match == "" {
+		f.Sort = model.SortDate
+	}
+	key, ok := sortKeys[f.Sort]
+	if !ok {
+		key = sortKeys[model.SortDate]
+	}
+	dir, cmp := "DESC", "<"
+	if f.Asc {
+		dir, cmp = "ASC", ">"
+	}
+	var extra []string
+	var extraArgs []any
+	if f.After != nil {
+		// Строки строго «после» курсора в порядке sort_key, row_id.
+		param := "?"
+		if key.cast != "" {
+			param = "CAST(? AS " + key.cast + ")"
+		}
+		extra = append(extra, fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND %[4]s %[2]s ?))",
+			key.expr, cmp, param, rowIDExpr))
+		extraArgs = append(extraArgs, f.After.Key, f.After.Key, f.After.RowID)
 	}
 
-	var fileConds []string
-	var fileArgs []any
-	var jobConds []string
-	var jobArgs []any
+	row := mediaRowSQLBy(f.Sort)
+	q, args := mediaUnion(f, match, row, row, extra, extraArgs)
+	q += "\n\t\tORDER BY sort_key " + dir + ", row_id " + dir + "\n"
+	if f.Limit > 0 {
+		q += fmt.Sprintf("LIMIT %d\n", f.Limit)
+	}
+	return r.runMediaQuery(ctx, q, args...)
+}
 
-	if f.Query != "" {
-		like := "%" + f.Query + "%"
-		fileConds = append(fileConds, "(i.name LIKE ? OR j.url LIKE ? OR j.title LIKE ?)")
-		fileArgs = append(fileArgs, like, like, like)
-		jobConds = append(jobConds, "(j.url LIKE ? OR j.title LIKE ?)")
-		jobArgs = append(jobArgs, like, like)
+// mediaUnion собирает UNION строк с элементами (fileSel) и заданий без элементов (jobSel)
+// по фильтру f после applySearch; непустой match оставляет только найденные строки
+// и даёт их релевантность как h.score. extra — дополнительные условия для обеих частей.
+func mediaUnion(f model.MediaFilter, match, fileSel, jobSel string, extra []string, extraArgs []any) (string, []any) {
+	// Условия по заданию и дате действуют на обе части.
+	conds, args := jobFilterConds(f)
+	conds = append(conds, extra...)
+	args = append(args, extraArgs...)
+	if !f.From.IsZero() {
+		conds = append(conds, "COALESCE(i.created_at, j.created_at) >= ?")
+		args = append(args, f.From.UTC().Format(time.RFC3339Nano))
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "COALESCE(i.created_at, j.created_at) < ?")
+		args = append(args, f.To.UTC().Format(time.RFC3339Nano))
 	}
 
+	fileConds := append([]string(nil), conds...)
+	fileArgs := append([]any(nil), args...)
+	jobConds := append([]string(nil), conds...)
+	jobArgs := append([]any(nil), args...)
+
 	if f.Kind != "" {
 		fileConds = append(fileConds, "i.kind=?")
 		fileArgs = append(fileArgs, f.Kind)
 		// для pending-строк kind не применяется (нет item)
 	}
+	fileConds = append(fileConds, watchConds(f)...)
+	if f.MinSize > 0 {
+		fileConds = append(fileConds, "i.size >= ?")
+		fileArgs = append(fileArgs, f.MinSize)
+	}
+	if f.MaxSize > 0 {
+		fileConds = append(fileConds, "i.size <= ?")
+		fileArgs = append(fileArgs, f.MaxSize)
+	}
+	switch f.Status {
+	case model.StatusAvailable:
+		fileConds = append(fileConds, "i.deleted_at IS NULL AND i.lost_at IS NULL")
+	case model.StatusDeleted:
+		fileConds = append(fileConds, "i.deleted_at IS NOT NULL")
+	case model.StatusMissing:
+		fileConds = append(fileConds, "i.lost_at IS NOT NULL")
+	case model.StatusActive:
+		jobConds = append(jobConds, "j.status IN ('checking','pending','running','retrying','paused')")
+	case model.StatusFailed:
+		jobConds = append(jobConds, "j.status IN ('failed','cancelled')")
+	}
+
+	files := withFiles(f)
+	if !files && !withPending(f) {
+		// Фильтры исключают обе части — запрос без строк.
+		files, fileConds = true, append(fileConds, "0")
+	}
+	prefix := viewerWatchSQL
+	allArgs := []any{f.ViewerID}
+	var fileJoin, jobJoin string
+	if match != "" {
+		prefix += ",\n\t\t" + searchHitsSQL
+		fileJoin = "\n\t\tJOIN search_hits h ON h.row_id = i.id"
+		jobJoin = "\n\t\tJOIN search_hits h ON h.row_id = j.id"
+		allArgs = append(allArgs, match)
+	}
+	var parts []string
+	if files {
+		parts = append(parts, fileSel+`
+		FROM items i
+		JOIN jobs j ON j.id = i.job_id`+fileJoin+`
+		WHERE j.hidden = 0`+andConds(fileConds))
+		allArgs = append(allArgs, fileArgs...)
+	}
+	// Если фильтр по kind — показываем только items нужного типа, без pending-строк
+	// (у pending нет items, поэтому kind=audio/video логично исключает их). То же с просмотром.
+	if withPending(f) {
+		parts = append(parts, jobSel+`
+		FROM jobs j
+		LEFT JOIN items i ON i.id = NULL`+jobJoin+`
+		WHERE j.hidden = 0
+		  AND j.status IN ('checking','pending','running','retrying','paused','failed','cancelled')
+		  AND NOT EXISTS (SELECT 1 FROM items WHERE job_id = j.id)`+andConds(jobConds))
+		allArgs = append(allArgs, jobArgs...)
+	}
+	return prefix + "\n" + strings.Join(parts, "\n\n\t\tUNION ALL\n\n"), allArgs
+}
 
+// jobFilterConds — условия фильтра по самому заданию: владелец, теги, домен, источник.
+// Годятся для любого запроса, где задание доступно как j.
+func jobFilterConds(f model.MediaFilter) ([]string, []any) {
+	var conds []string
+	var args []any
+	if f.OwnerID != "" {
+		conds = append(conds, "j.owner_id=?")
+		args = append(args, f.OwnerID)
+	}
+	// tagged — задание с тегом из списка; сразу добавляет имена в args.
+	tagged := func(tags []string) string {
+		placeholders := make([]string, len(tags))
+		for i, tag := range tags {
+			placeholders[i] = "?"
+			args = append(args, tag)
+		}
+		return `j.id IN (SELECT jt.job_id FROM job_tags jt JOIN tags t ON t.id=jt.tag_id WHERE t.name IN (` +
+			strings.Join(placeholders, ",") + `))`
+	}
 	for _, tag := range f.Tags {
-		sub := `j.id IN (SELECT jt.job_id FROM job_tags jt JOIN tags t ON t.id=jt.tag_id WHERE t.name=?)`
-		fileConds = append(fileConds, sub)
-		fileArgs = append(fileArgs, tag)
-		jobConds = append(jobConds, sub)
-		jobArgs = append(jobArgs, tag)
+		conds = append(conds, tagged([]string{tag}))
 	}
-
-	fileWhere := " WHERE j.hidden=0"
-	if len(fileConds) > 0 {
-		fileWhere += " AND " + strings.Join(fileConds, " AND ")
+	if len(f.AnyTags) > 0 {
+		conds = append(conds, tagged(f.AnyTags))
 	}
-	jobAnd := ""
-	if len(jobConds) > 0 {
-		jobAnd = " AND " + strings.Join(jobConds, " AND ")
+	if len(f.NotTags) > 0 {
+		conds = append(conds, "NOT "+tagged(f.NotTags))
 	}
-
-	// Если фильтр по kind — показываем только items нужного типа, без pending-строк
-	// (у pending нет items, поэтому kind=audio/video логично исключает их).
-	pendingPart := ""
-	if f.Kind == "" {
-		pendingPart = `
-			UNION ALL
-
-			` + mediaRowSQL + `
-			FROM jobs j
-			LEFT JOIN items i ON i.id = NULL
-			WHERE j.hidden = 0
-			  AND j.status IN ('checking','pending','running','retrying','failed','cancelled')
-			  AND NOT EXISTS (SELECT 1 FROM items WHERE job_id = j.id)
-			` + jobAnd
-	}
-
-	q := mediaRowSQL + `
-		FROM items i
-		JOIN jobs j ON j.id = i.job_id
-		` + fileWhere + `
-		` + pendingPart + `
-
-		ORDER BY sort_ts DESC
-	`
-	if f.Limit > 0 {
-		q += fmt.Sprintf("LIMIT %d\n", f.Limit)
+	if f.Domain != "" {
+		conds = append(conds, `(j.domain = ? OR j.domain LIKE ? ESCAPE '\')`)
+		args = append(args, f.Domain, "%."+escapeLike(f.Domain))
+	}
+	if f.Source != "" {
+		conds = append(conds, "j.source = ?")
+		args = append(args, f.Source)
 	}
-	allArgs := append(fileArgs, jobArgs...)
-	return r.runMediaQuery(ctx, q, allArgs...)
+	return conds, args
 }
 
-// CountMedia возвращает полное число строк, которые вернул бы FilterMedia без Limit.
-func (r *sqliteJobRepo) CountMedia(ctx context.Context, f model.MediaFilter) (int, error) {
-	var fileConds []string
-	var fileArgs []any
-	var jobConds []string
-	var jobArgs []any
-
-	if f.Query != "" {
-		like := "%" + f.Query + "%"
-		fileConds = append(fileConds, "(i.name LIKE ? OR j.url LIKE ? OR j.title LIKE ?)")
-		fileArgs = append(fileArgs, like, like, like)
-		jobConds = append(jobConds, "(j.url LIKE ? OR j.title LIKE ?)")
-		jobArgs = append(jobArgs, like, like)
-	}
-	if f.Kind != "" {
-		fileConds = append(fileConds, "i.kind=?")
-		fileArgs = append(fileArgs, f.Kind)
+// escapeLike экранирует спецсимволы LIKE для ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// watchConds — условия фильтра по прогрессу просмотра для строк с элементом.
+func watchConds(f model.MediaFilter) []string {
+	var conds []string
+	if f.Unwatched {
+		conds = append(conds, "NOT EXISTS (SELECT 1 FROM viewer_watch ws WHERE ws.item_id=i.id AND ws.watched=1)")
 	}
-	for _, tag := range f.Tags {
-		sub := `j.id IN (SELECT jt.job_id FROM job_tags jt JOIN tags t ON t.id=jt.tag_id WHERE t.name=?)`
-		fileConds = append(fileConds, sub)
-		fileArgs = append(fileArgs, tag)
-		jobConds = append(jobConds, sub)
-		jobArgs = append(jobArgs, tag)
+	if f.InProgress {
+		conds = append(conds, "EXISTS (SELECT 1 FROM viewer_watch ws WHERE ws.item_id=i.id AND ws.watched=0 AND ws.position>0)")
 	}
+	return conds
+}
 
-	fileWhere := " WHERE j.hidden=0"
-	if len(fileConds) > 0 {
-		fileWhere += " AND " + strings.Join(fileConds, " AND ")
-	}
-	jobAnd := ""
-	if len(jobConds) > 0 {
-		jobAnd = " AND " + strings.Join(jobConds, " AND ")
+// withPending — показывать ли строки заданий без элемента: фильтры по самому файлу их исключают.
+func withPending(f model.MediaFilter) bool {
+	switch f.Status {
+	case model.StatusAvailable, model.StatusDeleted, model.StatusMissing:
+		return false
 	}
+	return f.Kind == "" && !f.Unwatched && !f.InProgress && f.MinSize == 0 && f.MaxSize == 0
+}
+
+// withFiles — показывать ли строки с элементами: состояния очереди относятся только к заданиям без них.
+func withFiles(f model.MediaFilter) bool {
+	return f.Status != model.StatusActive && f.Status != model.StatusFailed
+}
 
-	pendingPart := ""
-	if f.Kind == "" {
-		pendingPart = `
-			UNION ALL
-			SELECT j.id AS id FROM jobs j
-			LEFT JOIN items i ON i.id = NULL
-			WHERE j.hidden = 0
-			  AND j.status IN ('checking','pending','running','retrying','failed','cancelled')
-			  AND NOT EXISTS (SELECT 1 FROM items WHERE job_id = j.id)
-			` + jobAnd
+func andConds(conds []string) string {
+	if len(conds) == 0 {
+		return ""
 	}
+	return "\n\t\t  AND " + strings.Join(conds, "\n\t\t  AND ")
+}
 
-	countQ := `SELECT COUNT(*) FROM (
-		SELECT i.id AS id FROM items i
-		JOIN jobs j ON j.id = i.job_id
-		` + fileWhere + `
-		` + pendingPart + `
-	)`
-	allArgs := append(fileArgs, jobArgs...)
+// CountMedia возвращает полное число строк, которые вернул бы FilterMedia без Limit.
+func (r *sqliteJobRepo) CountMedia(ctx context.Context, f model.MediaFilter) (int, error) {
+	f, match, err := applySearch(ctx, r.db, f)
+	if err != nil {
+		return 0, err
+	}
+	q, args := mediaUnion(f, match, "SELECT i.id AS id", "SELECT j.id AS id", nil, nil)
 	var n int
-	err := r.db.QueryRowContext(ctx, countQ, allArgs...).Scan(&n)
+	err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM (\n"+q+"\n)", args...).Scan(&n)
 	return n, err
 }
 
-// LastMedia возвращает последние n доступных элементов.
-func (r *sqliteJobRepo) LastMedia(ctx context.Context, n int) ([]*model.MediaItem, error) {
-	q := mediaRowSQL + `
+// LastMedia возвращает последние n доступных элементов; unwatched пропускает
+// просмотренные пользователем viewerID.
+func (r *sqliteJobRepo) LastMedia(ctx context.Context, n int, ownerID, viewerID string, unwatched bool) ([]*model.MediaItem, error) {
+	q := viewerWatchSQL + mediaRowSQL + `
 		FROM items i
 		JOIN jobs j ON j.id = i.job_id
 		WHERE j.status IN ('done','imported') AND i.deleted_at IS NULL AND i.lost_at IS NULL
-		ORDER BY sort_ts DESC
+		  AND (? = '' OR j.owner_id = ?)
+		  AND (? = 0 OR NOT EXISTS (SELECT 1 FROM viewer_watch ws WHERE ws.item_id=i.id AND ws.watched=1))
+		ORDER BY sort_key DESC
 		LIMIT ?
 	`
-	return r.runMediaQuery(ctx, q, n)
+	return r.runMediaQuery(ctx, q, viewerID, ownerID, ownerID, unwatched, n)
 }
 
 func scanMediaItem(s scanner) (*model.MediaItem, error) {
@@ -314,18 +446,25 @@ func scanMediaItem(s scanner) (*model.MediaItem, error) {
 	var itemDuration sql.NullInt64
 	var itemTitle, itemArtist, itemAlbum, itemYear, itemGenre sql.NullString
 	var itemCreatedAt, itemDeletedAt, itemLostAt sql.NullString
-	var tagNames sql.NullString
-	var sortTS sql.NullString
+	var info model.VideoInfo
+	var chapters, thumb string
+	var tagNames, subLangs sql.NullString
+	var watch model.WatchState
+	var sortKey, rowID sql.NullString
 
 	err := s.Scan(
 		&j.ID, &j.URL, &j.Status, &j.Title,
 		&j.Error, &j.Source, &j.ChatID,
 		&createdAt, &updatedAt, &j.RetryCount, &nextRetryAt, &firstFailedAt,
-		&hidden,
+		&hidden, &j.OwnerID, &j.ErrorClass,
 		&itemID, &itemKind, &itemName, &itemSize, &itemPath, &itemDuration,
 		&itemTitle, &itemArtist, &itemAlbum, &itemYear, &itemGenre,
 		&itemCreatedAt, &itemDeletedAt, &itemLostAt,
-		&tagNames, &sortTS,
+		&info.Uploader, &info.Channel, &info.UploadDate, &info.Description,
+		&info.Width, &info.Height, &info.VCodec, &info.ACodec,
+		&info.ViewCount, &info.WebpageURL, &chapters, &thumb,
+		&info.Bitrate, &info.FPS, &info.Channels,
+		&tagNames, &subLangs, &watch.Position, &watch.Watched, &sortKey, &rowID,
 	)
 	if err != nil {
 		return nil, err
@@ -343,7 +482,7 @@ func scanMediaItem(s scanner) (*model.MediaItem, error) {
 	}
 	j.Hidden = hidden != 0
 
-	media := &model.MediaItem{Job: &j}
+	media := &model.MediaItem{Job: &j, Watch: watch, Pos: model.MediaCursor{Key: sortKey.String, RowID: rowID.String}}
 
 	if itemID.Valid && itemID.String != "" {
 		item := &model.Item{
@@ -361,7 +500,10 @@ func scanMediaItem(s scanner) (*model.MediaItem, error) {
 				Year:   itemYear.String,
 				Genre:  itemGenre.String,
 			},
+			Info:  info,
+			Thumb: thumb,
 		}
+		item.Info.Chapters = unmarshalChapters(chapters)
 		item.CreatedAt, _ = time.Parse(time.RFC3339Nano, itemCreatedAt.String)
 		if itemDeletedAt.Valid && itemDeletedAt.String != "" {
 			t, _ := time.Parse(time.RFC3339Nano, itemDeletedAt.String)
@@ -377,28 +519,163 @@ func scanMediaItem(s scanner) (*model.MediaItem, error) {
 	if tagNames.Valid && tagNames.String != "" {
 		media.Tags = strings.Split(tagNames.String, "|")
 	}
+	if media.Item != nil && subLangs.Valid && subLangs.String != "" {
+		media.Subtitles = strings.Split(subLangs.String, "|")
+	}
 
 	return media, nil
 }
 
-func (r *sqliteJobRepo) ClaimNext(ctx context.Context) (*model.Job, error) {
-	now := time.Now().UTC().Format(time.RFC3339Nano)
-	row := r.db.QueryRowContext(ctx,
-		`UPDATE jobs SET status='running', updated_at=?
-		 WHERE id = (
-		     SELECT id FROM jobs
-		     WHERE status='pending'
-		        OR (status='retrying' AND next_retry_at <= ?)
-		     ORDER BY created_at ASC LIMIT 1
-		 )
-		 RETURNING id, url, status, title, error, source, chat_id, created_at, updated_at, retry_count, next_retry_at, first_failed_at, COALESCE(tg_message_id,0)`,
-		now, now,
+// domainHistoryWindow — за какой срок ClaimNext учитывает запуски при чередовании
+// доменов, если паузы правил не требуют большего.
+const domainHistoryWindow = time.Hour
+
+// ClaimNext выбирает задание в транзакции: соединение с БД одно, поэтому
+// параллельные воркеры не превысят лимиты доменов.
+func (r *sqliteJobRepo) ClaimNext(ctx context.Context, rules []*model.DomainRule) (*model.Job, error) {
+	now := time.Now().UTC()
+	nowStr := now.Format(time.RFC3339Nano)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Хосты под одним правилом (youtube.com, m.youtube.com) делят его лимиты.
+	group := func(host string) (string, *model.DomainRule) {
+		if rule := model.MatchDomainRule(rules, host); rule != nil {
+			return rule.Pattern, rule
+		}
+		return host, nil
+	}
+	type domainState struct {
+		running   int
+		lastStart time.Time
+	}
+	// Запуски старше окна не влияют ни на паузы правил, ни на чередование:
+	// без этого ограничения запрос перебирал бы всю историю заданий.
+	window := domainHistoryWindow
+	for _, rule := range rules {
+		window = max(window, rule.MinDelay)
+	}
+	states := map[string]domainState{}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT domain, SUM(status='running'), COALESCE(MAX(started_at),'') FROM jobs
+		 WHERE status='running' OR started_at >= ?
+		 GROUP BY domain`, now.Add(-window).Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var domain, lastStart string
+		var running int
+		if err := rows.Scan(&domain, &running, &lastStart); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key, _ := group(domain)
+		st := states[key]
+		st.running += running
+		if t, _ := time.Parse(time.RFC3339Nano, lastStart); t.After(st.lastStart) {
+			st.lastStart = t
+		}
+		states[key] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Группы, приостановленные после ответа HTTP 429.
+	pausedUntil := map[string]time.Time{}
+	rows, err = tx.QueryContext(ctx, `SELECT domain, until FROM domain_pauses WHERE until > ?`, nowStr)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var domain, until string
+		if err := rows.Scan(&domain, &until); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key, _ := group(domain)
+		if t, _ := time.Parse(time.RFC3339Nano, until); t.After(pausedUntil[key]) {
+			pausedUntil[key] = t
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Первое в очереди готовое задание каждой группы.
+	rows, err = tx.QueryContext(ctx,
+		`SELECT id, domain, priority FROM jobs
+		 WHERE status='pending'
+		    OR (status='retrying' AND next_retry_at <= ?)
+		 ORDER BY priority DESC, created_at ASC`, nowStr)
+	if err != nil {
+		return nil, err
+	}
+	var candidates, keys []string
+	var candRules []*model.DomainRule
+	var priorities []int
+	seen := map[string]bool{}
+	for rows.Next() {
+		var id, domain string
+		var priority int
+		if err := rows.Scan(&id, &domain, &priority); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if key, rule := group(domain); !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, id)
+			keys = append(keys, key)
+			candRules = append(candRules, rule)
+			priorities = append(priorities, priority)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Из доменов, не упёршихся в лимит и паузу правила, берём задание с наибольшим
+	// приоритетом, а при равном — с домена, с которого дольше всего ничего
+	// не запускалось, — так домены чередуются.
+	pick := -1
+	for i, key := range keys {
+		st := states[key]
+		if now.Before(pausedUntil[key]) {
+			continue
+		}
+		if rule := candRules[i]; rule != nil {
+			if rule.MaxParallel > 0 && st.running >= rule.MaxParallel {
+				continue
+			}
+			if rule.MinDelay > 0 && now.Before(st.lastStart.Add(rule.MinDelay)) {
+				continue
+			}
+		}
+		if pick < 0 || priorities[i] > priorities[pick] ||
+			priorities[i] == priorities[pick] && st.lastStart.Before(states[keys[pick]].lastStart) {
+			pick = i
+		}
+	}
+	if pick < 0 {
+		return nil, nil
+	}
+
+	row := tx.QueryRowContext(ctx,
+		`UPDATE jobs SET status='running', started_at=?, updated_at=? WHERE id=? RETURNING `+jobColumns,
+		nowStr, nowStr, candidates[pick],
 	)
 	j, err := scanJob(row)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	if err != nil {
+		return nil, err
 	}
-	return j, err
+	return j, tx.Commit()
 }
 
 func (r *sqliteJobRepo) Update(ctx context.Context, job *model.Job) error {
@@ -411,8 +688,8 @@ func (r *sqliteJobRepo) Update(ctx context.Context, job *model.Job) error {
 		firstFailed = job.FirstFailedAt.UTC().Format(time.RFC3339Nano)
 	}
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status=?, title=?, error=?, retry_count=?, next_retry_at=?, first_failed_at=?, updated_at=? WHERE id=?`,
-		job.Status, job.Title, job.Error,
+		`UPDATE jobs SET status=?, title=?, error=?, error_class=?, retry_count=?, next_retry_at=?, first_failed_at=?, updated_at=? WHERE id=?`,
+		job.Status, job.Title, job.Error, job.ErrorClass,
 		job.RetryCount, nextRetry, firstFailed,
 		job.UpdatedAt.Format(time.RFC3339Nano), job.ID,
 	)
@@ -421,7 +698,7 @@ func (r *sqliteJobRepo) Update(ctx context.Context, job *model.Job) error {
 
 func (r *sqliteJobRepo) Cancel(ctx context.Context, id string) error {
 	res, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status='cancelled', next_retry_at=NULL, updated_at=? WHERE id=? AND status IN ('checking','pending','retrying')`,
+		`UPDATE jobs SET status='cancelled', next_retry_at=NULL, updated_at=? WHERE id=? AND status IN ('checking','pending','retrying','paused')`,
 		time.Now().UTC().Format(time.RFC3339Nano), id,
 	)
 	if err != nil {
@@ -437,7 +714,7 @@ func (r *sqliteJobRepo) Cancel(ctx context.Context, id string) error {
 func (r *sqliteJobRepo) CancelAll(ctx context.Context) (int64, error) {
 	res, err := r.db.ExecContext(ctx,
 		`UPDATE jobs SET status='cancelled', next_retry_at=NULL, updated_at=?
-		 WHERE status IN ('checking','pending','running','retrying')`,
+		 WHERE status IN ('checking','pending','running','retrying','paused')`,
 		time.Now().UTC().Format(time.RFC3339Nano),
 	)
 	if err != nil {
@@ -462,7 +739,7 @@ func (r *sqliteJobRepo) DeleteChecking(ctx context.Context, id string) error {
 
 func (r *sqliteJobRepo) ResetFailed(ctx context.Context, id string) error {
 	res, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status='pending', error='', retry_count=0, next_retry_at=NULL, first_failed_at=NULL, updated_at=?
+		`UPDATE jobs SET status='pending', error='', error_class='', retry_count=0, next_retry_at=NULL, first_failed_at=NULL, updated_at=?
 		 WHERE id=? AND status IN ('failed','retrying')`,
 		time.Now().UTC().Format(time.RFC3339Nano), id,
 	)
@@ -478,7 +755,7 @@ func (r *sqliteJobRepo) ResetFailed(ctx context.Context, id string) error {
 
 func (r *sqliteJobRepo) Redownload(ctx context.Context, id string) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status='checking', title='', error='',
+		`UPDATE jobs SET status='checking', title='', error='', error_class='',
 		  retry_count=0, next_retry_at=NULL, first_failed_at=NULL, hidden=0, updated_at=?
 		 WHERE id=?`,
 		time.Now().UTC().Format(time.RFC3339Nano), id,
@@ -503,9 +780,18 @@ func (r *sqliteJobRepo) Unhide(ctx context.Context, id string) error {
 }
 
 func (r *sqliteJobRepo) ResetStale(ctx context.Context) error {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	// Попытки, оборванные остановкой процесса, закрываем: задание вернулось в очередь.
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE job_attempts SET status='pending', finished_at=?, error='прервано перезапуском' WHERE status='running'`,
+		now,
+	); err != nil {
+		return err
+	}
+	// Placeholder-дубликаты ждут решения пользователя и после перезапуска.
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE jobs SET status='pending', updated_at=? WHERE status IN ('running','checking')`,
-		time.Now().UTC().Format(time.RFC3339Nano),
+		`UPDATE jobs SET status='pending', updated_at=? WHERE status='running' OR (status='checking' AND duplicate_of='')`,
+		now,
 	)
 	return err
 }
@@ -514,6 +800,9 @@ func (r *sqliteJobRepo) Purge(ctx context.Context, id string) error {
 	if _, err := r.db.ExecContext(ctx, `DELETE FROM items WHERE job_id=?`, id); err != nil {
 		return err
 	}
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM job_attempts WHERE job_id IN (SELECT id FROM jobs WHERE id=? AND hidden=1)`, id); err != nil {
+		return err
+	}
 	_, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE id=? AND hidden=1`, id)
 	return err
 }
@@ -531,6 +820,12 @@ func (r *sqliteJobRepo) CleanupDead(ctx context.Context) (int, error) {
 		)`); err != nil {
 		return 0, err
 	}
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM job_attempts WHERE job_id IN (
+			SELECT id FROM jobs WHERE hidden=1 OR status='failed'
+		)`); err != nil {
+		return 0, err
+	}
 	res, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE hidden=1 OR status='failed'`)
 	if err != nil {
 		return 0, err
@@ -539,11 +834,92 @@ func (r *sqliteJobRepo) CleanupDead(ctx context.Context) (int, error) {
 	return int(n), nil
 }
 
+func (r *sqliteJobRepo) PauseDomain(ctx context.Context, domain string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO domain_pauses (domain, until) VALUES (?, ?)
+		 ON CONFLICT(domain) DO UPDATE SET until=excluded.until`,
+		strings.ToLower(domain), until.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
 func (r *sqliteJobRepo) SetTgMessageID(ctx context.Context, jobID string, msgID int64) error {
 	_, err := r.db.ExecContext(ctx, `UPDATE jobs SET tg_message_id=? WHERE id=?`, msgID, jobID)
 	return err
 }
 
+func (r *sqliteJobRepo) SetPreset(ctx context.Context, jobID, presetID string) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE jobs SET preset_id=?, updated_at=? WHERE id=? AND status IN ('checking','pending','retrying','paused','failed','cancelled')`,
+		nullStr(presetID), time.Now().UTC().Format(time.RFC3339Nano), jobID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("job %s not found or already running", jobID)
+	}
+	return nil
+}
+
+func (r *sqliteJobRepo) Pause(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE jobs SET status='paused', updated_at=? WHERE id=? AND status IN ('pending','retrying')`,
+		time.Now().UTC().Format(time.RFC3339Nano), id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("job %s not found or not pausable", id)
+	}
+	return nil
+}
+
+func (r *sqliteJobRepo) Resume(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE jobs SET status='pending', next_retry_at=NULL, updated_at=? WHERE id=? AND status='paused'`,
+		time.Now().UTC().Format(time.RFC3339Nano), id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("job %s not found or not paused", id)
+	}
+	return nil
+}
+
+func (r *sqliteJobRepo) SetPriority(ctx context.Context, jobID string, priority int) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE jobs SET priority=?, updated_at=? WHERE id=?`,
+		priority, time.Now().UTC().Format(time.RFC3339Nano), jobID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *sqliteJobRepo) MoveToTop(ctx context.Context, jobID string, ceiling int) (int, error) {
+	var priority int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE jobs SET priority = MAX(priority, MIN(?, (
+		     SELECT COALESCE(MAX(priority), 0) + 1 FROM jobs WHERE status IN ('checking','pending','retrying')
+		 ))), updated_at=?
+		 WHERE id=? AND status IN ('checking','pending','retrying')
+		 RETURNING priority`,
+		ceiling, time.Now().UTC().Format(time.RFC3339Nano), jobID,
+	).Scan(&priority)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("job %s not found or not waiting", jobID)
+	}
+	return priority, err
+}
+
 func (r *sqliteJobRepo) SaveLog(ctx context.Context, jobID, log string) error {
 	_, err := r.db.ExecContext(ctx, `UPDATE jobs SET last_log=? WHERE id=?`, log, jobID)
 	return err
@@ -551,7 +927,9 @@ func (r *sqliteJobRepo) SaveLog(ctx context.Context, jobID, log string) error {
 
 func (r *sqliteJobRepo) GetLog(ctx context.Context, jobID string) (string, error) {
 	var v sql.NullString
-	err := r.db.QueryRowContext(ctx, `SELECT last_log FROM jobs WHERE id=?`, jobID).Scan(&v)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE((SELECT log FROM job_attempts WHERE job_id=jobs.id AND log<>'' ORDER BY number DESC LIMIT 1), last_log)
+		FROM jobs WHERE id=?`, jobID).Scan(&v)
 	if err != nil {
 		return "", err
 	}
@@ -566,14 +944,22 @@ func scanJob(s scanner) (*model.Job, error) {
 	var j model.Job
 	var createdAt, updatedAt string
 	var nextRetryAt, firstFailedAt sql.NullString
+	var hidden int
+	var options string
 	err := s.Scan(
 		&j.ID, &j.URL, &j.Status, &j.Title, &j.Error,
 		&j.Source, &j.ChatID, &createdAt, &updatedAt,
 		&j.RetryCount, &nextRetryAt, &firstFailedAt, &j.TgMessageID,
+		&hidden, &j.OwnerID, &options, &j.PresetID, &j.Priority, &j.ErrorClass,
+		&j.Extractor, &j.VideoID, &j.DuplicateOf,
 	)
 	if err != nil {
 		return nil, err
 	}
+	j.Hidden = hidden != 0
+	if options != "" {
+		json.Unmarshal([]byte(options), &j.Options) //nolint:errcheck
+	}
 	j.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
 	j.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
 	if nextRetryAt.Valid && nextRetryAt.String != "" {
@@ -27,9 +27,9 @@ func (r *sqliteOperationRepo) Create(ctx context.Context, op *model.Operation) e
 		op.CreatedAt = time.Now().UTC()
 	}
 	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO operations (id, kind, status, title, payload, created_at)
-		 VALUES (?, ?, 'pending', ?, ?, ?)`,
-		op.ID, op.Kind, op.Title, op.Payload,
+		`INSERT INTO operations (id, kind, status, title, payload, owner_id, created_at)
+		 VALUES (?, ?, 'pending', ?, ?, NULLIF(?, ''), ?)`,
+		op.ID, op.Kind, op.Title, op.Payload, op.OwnerID,
 		op.CreatedAt.Format(time.RFC3339Nano),
 	)
 	return err
@@ -45,8 +45,9 @@ func (r *sqliteOperationRepo) ClaimNext(ctx context.Context) (*model.Operation,
 	var op model.Operation
 	var createdAt string
 	err = tx.QueryRowContext(ctx,
-		`SELECT id, kind, title, payload, created_at FROM operations WHERE status='pending' ORDER BY created_at LIMIT 1`,
-	).Scan(&op.ID, &op.Kind, &op.Title, &op.Payload, &createdAt)
+		`SELECT id, kind, title, payload, COALESCE(owner_id,''), created_at
+		 FROM operations WHERE status='pending' ORDER BY created_at LIMIT 1`,
+	).Scan(&op.ID, &op.Kind, &op.Title, &op.Payload, &op.OwnerID, &createdAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -84,25 +85,31 @@ func (r *sqliteOperationRepo) SetFailed(ctx context.Context, id, errMsg string)
 	return err
 }
 
-func (r *sqliteOperationRepo) List(ctx context.Context, kinds []string) ([]*model.Operation, error) {
-	var rows *sql.Rows
-	var err error
+func (r *sqliteOperationRepo) GetByID(ctx context.Context, id string) (*model.Operation, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+operationCols+` FROM operations WHERE id=?`, id)
+	return scanOperation(row)
+}
 
-	if len(kinds) == 0 {
-		rows, err = r.db.QueryContext(ctx,
-			`SELECT id, kind, status, title, payload, created_at, COALESCE(started_at,''), COALESCE(finished_at,''), COALESCE(error,'')
-			 FROM operations ORDER BY created_at DESC`)
-	} else {
+func (r *sqliteOperationRepo) List(ctx context.Context, kinds []string, ownerID string) ([]*model.Operation, error) {
+	var conds []string
+	var args []any
+	if len(kinds) > 0 {
 		placeholders := make([]string, len(kinds))
-		args := make([]any, len(kinds))
 		for i, k := range kinds {
 			placeholders[i] = "?"
-			args[i] = k
+			args = append(args, k)
 		}
-		q := `SELECT id, kind, status, title, payload, created_at, COALESCE(started_at,''), COALESCE(finished_at,''), COALESCE(error,'')
-			  FROM operations WHERE kind IN (` + strings.Join(placeholders, ",") + `) ORDER BY created_at DESC`
-		rows, err = r.db.QueryContext(ctx, q, args...)
+		conds = append(conds, "kind IN ("+strings.Join(placeholders, ",")+")")
+	}
+	if ownerID != "" {
+		conds = append(conds, "owner_id = ?")
+		args = append(args, ownerID)
 	}
+	q := `SELECT ` + operationCols + ` FROM operations`
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	rows, err := r.db.QueryContext(ctx, q+" ORDER BY created_at DESC", args...)
 	if err != nil {
 		return nil, err
 	}
@@ -124,13 +131,16 @@ func (r *sqliteOperationRepo) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+const operationCols = `id, kind, status, title, payload, COALESCE(owner_id,''), created_at,
+	COALESCE(started_at,''), COALESCE(finished_at,''), COALESCE(error,'')`
+
 func scanOperation(s interface {
 	Scan(...any) error
 }) (*model.Operation, error) {
 	var op model.Operation
 	var createdAt, startedAt, finishedAt string
 	err := s.Scan(
-		&op.ID, &op.Kind, &op.Status, &op.Title, &op.Payload,
+		&op.ID, &op.Kind, &op.Status, &op.Title, &op.Payload, &op.OwnerID,
 		&createdAt, &startedAt, &finishedAt, &op.Error,
 	)
 	if err != nil {
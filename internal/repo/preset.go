@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/google/uuid"
+)
+
+type sqlitePresetRepo struct {
+	db *sql.DB
+}
+
+func NewPresetRepo(db *sql.DB) PresetRepo {
+	return &sqlitePresetRepo{db: db}
+}
+
+const presetSelect = `SELECT id, name, format, output_format, extra_args, proxy, post_process, subfolder, is_default, created_at FROM presets`
+
+// Create сохраняет пресет; если он помечен как пресет по умолчанию, флаг снимается с остальных.
+func (r *sqlitePresetRepo) Create(ctx context.Context, p *model.Preset) error {
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now().UTC()
+	}
+	extra, post := marshalList(p.ExtraArgs), marshalList(p.PostProcess)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO presets (id, name, format, output_format, extra_args, proxy, post_process, subfolder, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.Name, p.Format, p.OutputFormat, extra, p.Proxy, post, p.Subfolder,
+		p.CreatedAt.Format(time.RFC3339Nano),
+	); err != nil {
+		return err
+	}
+	if p.IsDefault {
+		if err := setDefaultPreset(ctx, tx, p.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Update меняет поля пресета; флаг по умолчанию — через SetDefault.
+func (r *sqlitePresetRepo) Update(ctx context.Context, p *model.Preset) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE presets SET name=?, format=?, output_format=?, extra_args=?, proxy=?, post_process=?, subfolder=? WHERE id=?`,
+		p.Name, p.Format, p.OutputFormat, marshalList(p.ExtraArgs), p.Proxy, marshalList(p.PostProcess), p.Subfolder, p.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *sqlitePresetRepo) GetByID(ctx context.Context, id string) (*model.Preset, error) {
+	return scanPreset(r.db.QueryRowContext(ctx, presetSelect+` WHERE id=?`, id))
+}
+
+// Default возвращает пресет по умолчанию или sql.ErrNoRows, если он не выбран.
+func (r *sqlitePresetRepo) Default(ctx context.Context) (*model.Preset, error) {
+	return scanPreset(r.db.QueryRowContext(ctx, presetSelect+` WHERE is_default=1`))
+}
+
+func (r *sqlitePresetRepo) List(ctx context.Context) ([]*model.Preset, error) {
+	rows, err := r.db.QueryContext(ctx, presetSelect+` ORDER BY name COLLATE NOCASE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.Preset
+	for rows.Next() {
+		p, err := scanPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// SetDefault делает пресет пресетом по умолчанию; пустой id снимает выбор.
+func (r *sqlitePresetRepo) SetDefault(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if err := setDefaultPreset(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func setDefaultPreset(ctx context.Context, tx *sql.Tx, id string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE presets SET is_default=0 WHERE is_default=1`); err != nil {
+		return err
+	}
+	if id == "" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE presets SET is_default=1 WHERE id=?`, id)
+	return err
+}
+
+// Delete удаляет пресет; задания и правила доменов с ним переходят на пресет по умолчанию.
+func (r *sqlitePresetRepo) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := tx.ExecContext(ctx, `UPDATE jobs SET preset_id=NULL WHERE preset_id=?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE domain_rules SET preset_id=NULL WHERE preset_id=?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM presets WHERE id=?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// marshalList кодирует список строк в JSON; nil хранится как [].
+func marshalList(v []string) string {
+	if len(v) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func scanPreset(s scanner) (*model.Preset, error) {
+	var p model.Preset
+	var extra, post, createdAt string
+	if err := s.Scan(&p.ID, &p.Name, &p.Format, &p.OutputFormat, &extra, &p.Proxy, &post,
+		&p.Subfolder, &p.IsDefault, &createdAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(extra), &p.ExtraArgs)  //nolint:errcheck
+	json.Unmarshal([]byte(post), &p.PostProcess) //nolint:errcheck
+	p.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	return &p, nil
+}
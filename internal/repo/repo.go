@@ -2,12 +2,14 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"github.com/dr-duke/talmorGo/internal/model"
 )
 
 type JobFilter struct {
 	Statuses []model.JobStatus
+	OwnerID  string // только задания пользователя; "" — все
 }
 
 // ItemRepo — единый репозиторий медиаэлементов (видео + аудио).
@@ -21,8 +23,9 @@ type ItemRepo interface {
 	ListDeleted(ctx context.Context) ([]*model.DeletedItem, error)
 	// AllPaths возвращает множество всех известных путей для сверки при сканировании.
 	AllPaths(ctx context.Context) (map[string]struct{}, error)
-	// PathsForCleanup возвращает пути элементов failed/hidden заданий (для удаления с диска).
-	PathsForCleanup(ctx context.Context) ([]string, error)
+	// ItemsForCleanup возвращает элементы, которые удалит очистка: элементы failed/hidden
+	// заданий и потерянные файлы (для удаления с диска).
+	ItemsForCleanup(ctx context.Context) ([]*model.Item, error)
 	// PruneLost удаляет из БД записи, помеченные как потерянные.
 	PruneLost(ctx context.Context) (int, error)
 	Rename(ctx context.Context, id, newName, newPath string) error
@@ -30,6 +33,24 @@ type ItemRepo interface {
 	MarkLost(ctx context.Context, id string) error
 	MarkFound(ctx context.Context, id string) error
 	UpdateMeta(ctx context.Context, id string, meta model.AudioMeta) error
+	// SetThumb запоминает путь к готовому превью.
+	SetThumb(ctx context.Context, id, path string) error
+	// SetProbe сохраняет параметры файла по ffprobe.
+	SetProbe(ctx context.Context, id string, p model.Probe) error
+	// AddSubtitle сохраняет субтитры элемента; на язык — одна запись.
+	AddSubtitle(ctx context.Context, s *model.Subtitle) error
+	ListSubtitles(ctx context.Context, itemID string) ([]*model.Subtitle, error)
+	// GetSubtitle возвращает sql.ErrNoRows, если субтитров на этом языке нет.
+	GetSubtitle(ctx context.Context, itemID, lang string) (*model.Subtitle, error)
+	// ListSubtitlesWithoutText — субтитры, текст которых ещё не попал в поиск.
+	ListSubtitlesWithoutText(ctx context.Context) ([]*model.Subtitle, error)
+	// SetSubtitleText сохраняет текст реплик для поиска.
+	SetSubtitleText(ctx context.Context, id, text string) error
+	// SaveWatchPosition запоминает позицию плеера пользователя userID; watched только
+	// ставит отметку, не снимает.
+	SaveWatchPosition(ctx context.Context, userID, itemID string, position int, watched bool) error
+	// SetWatched ставит или снимает отметку «просмотрено» и сбрасывает позицию.
+	SetWatched(ctx context.Context, userID, itemID string, watched bool) error
 	// BulkUpdateMetaFields обновляет только указанные поля (title/artist/album/year/genre)
 	// для набора элементов. Ключи, отсутствующие в fields, не затрагиваются.
 	BulkUpdateMetaFields(ctx context.Context, ids []string, fields map[string]string) error
@@ -50,17 +71,29 @@ type JobRepo interface {
 	List(ctx context.Context, f JobFilter) ([]*model.Job, error)
 	// ListMedia возвращает объединённое представление заданий + items + тегов.
 	ListMedia(ctx context.Context) ([]*model.MediaItem, error)
-	// FilterMedia — серверная фильтрация: текст + kind + AND-теги. Уважает f.Limit.
+	// FilterMedia — серверная фильтрация и сортировка по f. Уважает f.Limit и f.After;
+	// f.Query ищется по полнотекстовому индексу (см. model.ParseSearch).
 	FilterMedia(ctx context.Context, f model.MediaFilter) ([]*model.MediaItem, error)
-	// CountMedia — полный счётчик без учёта Limit (для отображения «X из N»).
+	// CountMedia — полный счётчик без учёта Limit и After (для подписи «Найдено N»).
 	CountMedia(ctx context.Context, f model.MediaFilter) (int, error)
-	// SearchMedia ищет по имени файла, URL, домену и тегам (LIKE, для Telegram).
-	SearchMedia(ctx context.Context, query string) ([]*model.MediaItem, error)
-	// LastMedia возвращает последние n доступных элементов (для Telegram-уведомлений).
-	LastMedia(ctx context.Context, n int) ([]*model.MediaItem, error)
-	ClaimNext(ctx context.Context) (*model.Job, error)
+	// SearchMedia — самые подходящие под запрос строки (полнотекстовый поиск, для Telegram).
+	// Пустой ownerID — по всем пользователям.
+	SearchMedia(ctx context.Context, query, ownerID string) ([]*model.MediaItem, error)
+	// LastMedia возвращает последние n доступных элементов (для Telegram-уведомлений)
+	// с прогрессом просмотра пользователя viewerID; unwatched пропускает просмотренные им.
+	LastMedia(ctx context.Context, n int, ownerID, viewerID string, unwatched bool) ([]*model.MediaItem, error)
+	// ClaimNext переводит в running следующее готовое задание или возвращает nil, nil.
+	// Домены чередуются; домены, достигшие MaxParallel или не выждавшие MinDelay
+	// своего правила, а также приостановленные PauseDomain, пропускаются.
+	ClaimNext(ctx context.Context, rules []*model.DomainRule) (*model.Job, error)
+	// PauseDomain не даёт ClaimNext брать задания домена (и доменов его правила) до until.
+	PauseDomain(ctx context.Context, domain string, until time.Time) error
 	Update(ctx context.Context, job *model.Job) error
 	Cancel(ctx context.Context, id string) error
+	// Pause откладывает ожидающее задание: ClaimNext его не берёт до Resume.
+	// Скачиваемое задание приостанавливает пул (Pool.PauseJob).
+	Pause(ctx context.Context, id string) error
+	Resume(ctx context.Context, id string) error
 	CancelAll(ctx context.Context) (int64, error)
 	ConfirmSingle(ctx context.Context, id string) error
 	DeleteChecking(ctx context.Context, id string) error
@@ -72,8 +105,35 @@ type JobRepo interface {
 	ResetStale(ctx context.Context) error
 	Redownload(ctx context.Context, id string) error
 	SetTgMessageID(ctx context.Context, jobID string, msgID int64) error
+	// SetPreset меняет пресет задания, если оно сейчас не скачивается и не готово.
+	SetPreset(ctx context.Context, jobID, presetID string) error
+	SetPriority(ctx context.Context, jobID string, priority int) error
+	// MoveToTop поднимает ожидающее задание выше всех остальных в очереди, но не
+	// выше ceiling (уже более высокий приоритет не снижается), и возвращает новый приоритет.
+	MoveToTop(ctx context.Context, jobID string, ceiling int) (int, error)
+	// SaveLog пишет jobs.last_log — лог заданий, скачанных до появления истории попыток.
 	SaveLog(ctx context.Context, jobID, log string) error
+	// GetLog возвращает лог последней попытки, а если попыток нет — jobs.last_log.
 	GetLog(ctx context.Context, jobID string) (string, error)
+	// RebuildSearch пересобирает поисковый индекс медиатеки.
+	RebuildSearch(ctx context.Context) error
+	// StartAttempt записывает начало попытки и присваивает ей следующий номер.
+	StartAttempt(ctx context.Context, a *model.JobAttempt) error
+	// FinishAttempt сохраняет итог попытки: статус, код выхода, файлы и лог.
+	FinishAttempt(ctx context.Context, a *model.JobAttempt) error
+	// ListAttempts возвращает попытки задания от первой к последней.
+	ListAttempts(ctx context.Context, jobID string) ([]*model.JobAttempt, error)
+	// ListAttemptOutcomes — то же без логов и аргументов: заполнены только
+	// Number, Status и ErrorClass.
+	ListAttemptOutcomes(ctx context.Context, jobID string) ([]*model.JobAttempt, error)
+	// FindDuplicate ищет у того же владельца другое задание с тем же видео: по URL
+	// или по экстрактору и id. Учитываются скачанные (в том числе скрытые) и ожидающие
+	// задания; nil — дубликата нет.
+	FindDuplicate(ctx context.Context, job *model.Job) (*model.Job, error)
+	SetVideoID(ctx context.Context, jobID, extractor, videoID string) error
+	// SetDuplicate помечает placeholder дубликатом задания of с пояснением note;
+	// пустой of снимает пометку.
+	SetDuplicate(ctx context.Context, jobID, of, note string) error
 }
 
 type TokenRepo interface {
@@ -81,6 +141,74 @@ type TokenRepo interface {
 	GetByToken(ctx context.Context, token string) (*model.Token, error)
 }
 
+// UserRepo — учётные записи, их сессии и привязанные Telegram-чаты.
+type UserRepo interface {
+	Create(ctx context.Context, u *model.User) error
+	GetByID(ctx context.Context, id string) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByChatID(ctx context.Context, chatID int64) (*model.User, error)
+	List(ctx context.Context) ([]*model.User, error)
+	Count(ctx context.Context) (int, error)
+	SetRole(ctx context.Context, id string, role model.Role) error
+	SetPassword(ctx context.Context, id, passwordHash string) error
+	// Delete удаляет пользователя; его задания становятся общими.
+	Delete(ctx context.Context, id string) error
+	LinkChat(ctx context.Context, userID string, chatID int64) error
+	UnlinkChat(ctx context.Context, chatID int64) error
+	// CreateSession возвращает секрет для cookie; в БД хранится только его хэш.
+	CreateSession(ctx context.Context, userID string, ttl time.Duration) (string, error)
+	SessionUser(ctx context.Context, secret string) (*model.User, error)
+	DeleteSession(ctx context.Context, secret string) error
+}
+
+// APITokenRepo — именованные API-токены со scope. В БД хранится только SHA-256 секрета.
+type APITokenRepo interface {
+	// Create сохраняет токен и возвращает сгенерированный секрет (в открытом виде — только здесь).
+	Create(ctx context.Context, t *model.APIToken) (string, error)
+	// List возвращает токены пользователя; пустой userID — все.
+	List(ctx context.Context, userID string) ([]*model.APIToken, error)
+	GetBySecret(ctx context.Context, secret string) (*model.APIToken, error)
+	Touch(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SubscriptionRepo — подписки на каналы и плейлисты и уже встреченные в них видео.
+type SubscriptionRepo interface {
+	Create(ctx context.Context, s *model.Subscription) error
+	GetByID(ctx context.Context, id string) (*model.Subscription, error)
+	// List возвращает подписки пользователя; пустой ownerID — все.
+	List(ctx context.Context, ownerID string) ([]*model.Subscription, error)
+	SetEnabled(ctx context.Context, id string, enabled bool) error
+	Delete(ctx context.Context, id string) error
+	// MarkPolled фиксирует результат опроса: время, название и текст ошибки ("" — успех).
+	// Первый успешный опрос отмечает подписку как Synced.
+	MarkPolled(ctx context.Context, id, title, errMsg string) error
+	// SeenURLs возвращает URL видео, уже встречавшихся в подписке.
+	SeenURLs(ctx context.Context, id string) (map[string]struct{}, error)
+	MarkSeen(ctx context.Context, id string, urls []string) error
+}
+
+// PresetRepo — именованные профили загрузки.
+type PresetRepo interface {
+	Create(ctx context.Context, p *model.Preset) error
+	Update(ctx context.Context, p *model.Preset) error
+	GetByID(ctx context.Context, id string) (*model.Preset, error)
+	// Default возвращает пресет по умолчанию или sql.ErrNoRows.
+	Default(ctx context.Context) (*model.Preset, error)
+	List(ctx context.Context) ([]*model.Preset, error)
+	// SetDefault делает пресет пресетом по умолчанию; пустой id снимает выбор.
+	SetDefault(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// DomainRuleRepo — правила загрузки для отдельных доменов.
+type DomainRuleRepo interface {
+	Create(ctx context.Context, r *model.DomainRule) error
+	Update(ctx context.Context, r *model.DomainRule) error
+	List(ctx context.Context) ([]*model.DomainRule, error)
+	Delete(ctx context.Context, id string) error
+}
+
 type TagRepo interface {
 	Upsert(ctx context.Context, name string) (*model.Tag, error)
 	ListAll(ctx context.Context) ([]*model.Tag, error)
@@ -88,9 +216,14 @@ type TagRepo interface {
 	// ListWithCountFiltered возвращает теги с количеством заданий, соответствующих фильтру.
 	// При пустом фильтре эквивалентен ListWithCount.
 	ListWithCountFiltered(ctx context.Context, f model.MediaFilter) ([]*model.TagWithCount, error)
+	ListByJob(ctx context.Context, jobID string) ([]string, error)
 	AddToJob(ctx context.Context, jobID, tagID string) error
 	BulkAddToJobs(ctx context.Context, tagID string, jobIDs []string) error
 	RemoveFromJob(ctx context.Context, jobID, tagName string) error
+	// Rename и Delete работают только с обычными тегами (не коллекциями);
+	// sql.ErrNoRows — тега нет, ErrTagExists — новое имя занято.
+	Rename(ctx context.Context, oldName, newName string) error
+	Delete(ctx context.Context, name string) error
 	// PruneOrphans удаляет оборванные job_tags, пустые теги и пустые коллекции.
 	// Возвращает количество удалённых: привязок, тегов, коллекций.
 	PruneOrphans(ctx context.Context) (nJobTags, nTags, nCollections int, err error)
@@ -103,9 +236,10 @@ type OperationRepo interface {
 	ClaimNext(ctx context.Context) (*model.Operation, error)
 	SetDone(ctx context.Context, id string) error
 	SetFailed(ctx context.Context, id, errMsg string) error
+	GetByID(ctx context.Context, id string) (*model.Operation, error)
 	// List возвращает операции заданных видов (все статусы, кроме deleted).
-	// Пустой kinds — вернуть все.
-	List(ctx context.Context, kinds []string) ([]*model.Operation, error)
+	// Пустой kinds — вернуть все; непустой ownerID — только операции этого пользователя.
+	List(ctx context.Context, kinds []string, ownerID string) ([]*model.Operation, error)
 	Delete(ctx context.Context, id string) error
 }
 
@@ -3,7 +3,13 @@ package repo_test
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dr-duke/talmorGo/internal/db"
 	"github.com/dr-duke/talmorGo/internal/model"
@@ -55,7 +61,7 @@ func TestJobRepo_ClaimNext(t *testing.T) {
 	ctx := context.Background()
 
 	// Нет задач — ClaimNext должен вернуть nil, nil.
-	j, err := r.ClaimNext(ctx)
+	j, err := r.ClaimNext(ctx, nil)
 	if err != nil || j != nil {
 		t.Fatalf("expected nil job, got %v %v", j, err)
 	}
@@ -65,7 +71,7 @@ func TestJobRepo_ClaimNext(t *testing.T) {
 		t.Fatalf("create: %v", err)
 	}
 
-	claimed, err := r.ClaimNext(ctx)
+	claimed, err := r.ClaimNext(ctx, nil)
 	if err != nil {
 		t.Fatalf("claim: %v", err)
 	}
@@ -77,7 +83,7 @@ func TestJobRepo_ClaimNext(t *testing.T) {
 	}
 
 	// Повторный ClaimNext — очередь пуста.
-	j2, err := r.ClaimNext(ctx)
+	j2, err := r.ClaimNext(ctx, nil)
 	if err != nil || j2 != nil {
 		t.Fatalf("expected nil after queue empty, got %v %v", j2, err)
 	}
@@ -288,3 +294,1224 @@ func TestTagRepo_PruneOrphans(t *testing.T) {
 		t.Error("keep-tag should still exist after PruneOrphans")
 	}
 }
+
+func TestTagRepo_RenameDelete(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	tagRepo := repo.NewTagRepo(database)
+	colRepo := repo.NewCollectionRepo(database)
+	ctx := context.Background()
+
+	job := &model.Job{URL: "https://example.com/v", Status: model.JobDone, Source: "web"}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	for _, name := range []string{"music", "live"} {
+		tag, err := tagRepo.Upsert(ctx, name)
+		if err != nil {
+			t.Fatalf("upsert tag: %v", err)
+		}
+		if err := tagRepo.AddToJob(ctx, job.ID, tag.ID); err != nil {
+			t.Fatalf("add tag: %v", err)
+		}
+	}
+	if _, err := colRepo.Create(ctx, "Favourites"); err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+
+	if err := tagRepo.Rename(ctx, "music", "live"); !errors.Is(err, repo.ErrTagExists) {
+		t.Errorf("rename to taken name: %v", err)
+	}
+	if err := tagRepo.Rename(ctx, "Favourites", "fav"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("rename collection: %v", err)
+	}
+	if err := tagRepo.Rename(ctx, "music", "tunes"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := tagRepo.Delete(ctx, "live"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := tagRepo.Delete(ctx, "live"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("delete again: %v", err)
+	}
+	if err := tagRepo.Delete(ctx, "Favourites"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("delete collection: %v", err)
+	}
+	if got, _ := tagRepo.ListByJob(ctx, job.ID); strings.Join(got, ",") != "tunes" {
+		t.Errorf("job tags: %v", got)
+	}
+}
+
+func TestJobRepo_FilterMediaCursor(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	itemRepo := repo.NewItemRepo(database)
+	ctx := context.Background()
+
+	// Три скачанных файла и два задания без файлов — обе ветки UNION.
+	for i := range 3 {
+		job := &model.Job{URL: fmt.Sprintf("https://example.com/%d", i), Status: model.JobDone, Source: "web"}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		item := &model.Item{JobID: job.ID, Kind: "video", Path: fmt.Sprintf("/data/%d.mp4", i), Name: fmt.Sprintf("%d.mp4", i)}
+		if err := itemRepo.Create(ctx, item); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+	for i := range 2 {
+		job := &model.Job{URL: fmt.Sprintf("https://example.com/p%d", i), Status: model.JobPending, Source: "web"}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+	}
+
+	all, err := jobRepo.FilterMedia(ctx, model.MediaFilter{})
+	if err != nil {
+		t.Fatalf("filter: %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("all: got %d rows, want 5", len(all))
+	}
+
+	// Постранично по 2 — должны получить те же строки в том же порядке.
+	var paged []*model.MediaItem
+	f := model.MediaFilter{Limit: 2}
+	for range 5 {
+		page, err := jobRepo.FilterMedia(ctx, f)
+		if err != nil {
+			t.Fatalf("page: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+		c := page[len(page)-1].Pos
+		f.After = &c
+	}
+	if len(paged) != len(all) {
+		t.Fatalf("paged: got %d rows, want %d", len(paged), len(all))
+	}
+	for i := range all {
+		if all[i].Pos != paged[i].Pos {
+			t.Errorf("row %d: got %v, want %v", i, paged[i].Pos, all[i].Pos)
+		}
+	}
+}
+
+func TestJobRepo_FilterMediaSortAndFilters(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	itemRepo := repo.NewItemRepo(database)
+	tagRepo := repo.NewTagRepo(database)
+	ctx := context.Background()
+
+	files := []struct {
+		url, source, name string
+		size              int64
+		duration          int
+		tags              []string
+	}{
+		{"https://www.youtube.com/1", "web", "b.mp4", 300, 10, []string{"music"}},
+		{"https://vimeo.com/2", "telegram", "A.mp4", 100, 30, []string{"music", "live"}},
+		{"https://m.youtube.com/3", "api", "c.mp4", 200, 20, []string{"live"}},
+	}
+	for _, f := range files {
+		job := &model.Job{URL: f.url, Status: model.JobDone, Source: f.source}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		item := &model.Item{JobID: job.ID, Kind: "video", Path: "/data/" + f.name, Name: f.name, Size: f.size, Duration: f.duration}
+		if err := itemRepo.Create(ctx, item); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		for _, name := range f.tags {
+			tag, err := tagRepo.Upsert(ctx, name)
+			if err != nil {
+				t.Fatalf("upsert tag: %v", err)
+			}
+			if err := tagRepo.AddToJob(ctx, job.ID, tag.ID); err != nil {
+				t.Fatalf("add tag: %v", err)
+			}
+		}
+	}
+	pending := &model.Job{URL: "https://youtube.com/p", Status: model.JobPending, Source: "web"}
+	if err := jobRepo.Create(ctx, pending); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+
+	// names выбирает всю выдачу страницами по одной строке.
+	names := func(f model.MediaFilter) string {
+		t.Helper()
+		var out []string
+		f.Limit = 1
+		for range 10 {
+			page, err := jobRepo.FilterMedia(ctx, f)
+			if err != nil {
+				t.Fatalf("filter %+v: %v", f, err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			if page[0].Item != nil {
+				out = append(out, page[0].Item.Name)
+			} else {
+				out = append(out, page[0].Job.URL)
+			}
+			f.After = &page[0].Pos
+		}
+		// CountMedia курсор не учитывает.
+		if n, err := jobRepo.CountMedia(ctx, f); err != nil || n != len(out) {
+			t.Errorf("count: %d, %v, want %d", n, err, len(out))
+		}
+		return strings.Join(out, " ")
+	}
+
+	for _, tc := range []struct {
+		f    model.MediaFilter
+		want string
+	}{
+		{model.MediaFilter{Kind: "video", Sort: model.SortSize, Asc: true}, "A.mp4 c.mp4 b.mp4"},
+		{model.MediaFilter{Kind: "video", Sort: model.SortSize}, "b.mp4 c.mp4 A.mp4"},
+		{model.MediaFilter{Kind: "video", Sort: model.SortDuration}, "A.mp4 c.mp4 b.mp4"},
+		{model.MediaFilter{Sort: model.SortName, Asc: true}, "A.mp4 b.mp4 c.mp4 https://youtube.com/p"},
+		{model.MediaFilter{}, "https://youtube.com/p c.mp4 A.mp4 b.mp4"},
+		{model.MediaFilter{Asc: true}, "b.mp4 A.mp4 c.mp4 https://youtube.com/p"},
+		{model.MediaFilter{Domain: "youtube.com", Sort: model.SortName, Asc: true}, "b.mp4 c.mp4 https://youtube.com/p"},
+		{model.MediaFilter{Domain: "youtube.co_"}, ""}, // «_» — обычный символ, не шаблон LIKE
+		{model.MediaFilter{Source: "telegram"}, "A.mp4"},
+		{model.MediaFilter{Tags: []string{"music", "live"}}, "A.mp4"},
+		{model.MediaFilter{AnyTags: []string{"music", "live"}, Sort: model.SortName, Asc: true}, "A.mp4 b.mp4 c.mp4"},
+		{model.MediaFilter{NotTags: []string{"live"}}, "https://youtube.com/p b.mp4"},
+		{model.MediaFilter{MinSize: 150, Sort: model.SortName, Asc: true}, "b.mp4 c.mp4"},
+		{model.MediaFilter{MaxSize: 150}, "A.mp4"},
+		{model.MediaFilter{Status: model.StatusActive}, "https://youtube.com/p"},
+		{model.MediaFilter{Status: model.StatusActive, Kind: "video"}, ""},
+		{model.MediaFilter{From: time.Now().Add(time.Hour)}, ""},
+		{model.MediaFilter{To: time.Now().Add(time.Hour), Kind: "audio"}, ""},
+	} {
+		if got := names(tc.f); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.f, got, tc.want)
+		}
+	}
+
+	all, _ := jobRepo.FilterMedia(ctx, model.MediaFilter{Sort: model.SortName, Asc: true})
+	if err := itemRepo.SoftDelete(ctx, all[0].Item.ID); err != nil {
+		t.Fatalf("soft delete: %v", err)
+	}
+	if got := names(model.MediaFilter{Status: model.StatusAvailable, Sort: model.SortName, Asc: true}); got != "b.mp4 c.mp4" {
+		t.Errorf("available: got %q", got)
+	}
+	if got := names(model.MediaFilter{Status: model.StatusDeleted}); got != "A.mp4" {
+		t.Errorf("deleted: got %q", got)
+	}
+}
+
+// TestJobRepo_SearchReindexOnlyOnChange проверяет, что смена статуса задания не
+// пересобирает его поисковые документы, а смена названия — пересобирает.
+func TestJobRepo_SearchReindexOnlyOnChange(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	job := &model.Job{URL: "https://example.com/1", Title: "First", Status: model.JobPending, Source: "web"}
+	other := &model.Job{URL: "https://example.com/2", Title: "Second", Status: model.JobPending, Source: "web"}
+	for _, j := range []*model.Job{job, other} {
+		if err := r.Create(ctx, j); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	docID := func() int64 {
+		t.Helper()
+		var id int64
+		if err := database.QueryRowContext(ctx, `SELECT id FROM search_docs WHERE job_id = ?`, job.ID).Scan(&id); err != nil {
+			t.Fatalf("search doc: %v", err)
+		}
+		return id
+	}
+	before := docID()
+
+	job.Status = model.JobRunning
+	if err := r.Update(ctx, job); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := docID(); got != before {
+		t.Errorf("status update reindexed job: doc %d -> %d", before, got)
+	}
+
+	job.Title = "Renamed"
+	if err := r.Update(ctx, job); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := docID(); got == before {
+		t.Error("title update did not reindex job")
+	}
+}
+
+func TestJobRepo_Search(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	itemRepo := repo.NewItemRepo(database)
+	tagRepo := repo.NewTagRepo(database)
+	ctx := context.Background()
+
+	files := []struct {
+		url, title, name, kind string
+		info                   model.VideoInfo
+		tag                    string
+	}{
+		{"https://www.youtube.com/1", "Go programming tutorial", "go_tutorial.mp4", "video",
+			model.VideoInfo{Description: "channels and goroutines"}, "lessons"},
+		{"https://vimeo.com/2", "Cooking pasta", "pasta.mp4", "video",
+			model.VideoInfo{Channel: "Chef Mario"}, ""},
+		{"https://music.example.com/3", "Morning", "song.mp3", "audio",
+			model.VideoInfo{Description: "programming music for focus"}, "music"},
+	}
+	items := map[string]*model.Item{}
+	for _, f := range files {
+		job := &model.Job{URL: f.url, Title: f.title, Status: model.JobDone, Source: "web"}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		item := &model.Item{JobID: job.ID, Kind: f.kind, Path: "/data/" + f.name, Name: f.name, Info: f.info}
+		if err := itemRepo.Create(ctx, item); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		items[f.name] = item
+		if f.tag != "" {
+			tag, err := tagRepo.Upsert(ctx, f.tag)
+			if err != nil {
+				t.Fatalf("upsert tag: %v", err)
+			}
+			if err := tagRepo.AddToJob(ctx, job.ID, tag.ID); err != nil {
+				t.Fatalf("add tag: %v", err)
+			}
+		}
+	}
+	pending := &model.Job{URL: "https://youtube.com/rust", Title: "Programming in Rust", Status: model.JobPending, Source: "web"}
+	if err := jobRepo.Create(ctx, pending); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	sub := &model.Subtitle{ItemID: items["pasta.mp4"].ID, Lang: "ru", Path: "/subs/pasta.ru.vtt", Text: "Сегодня готовим пасту карбонара"}
+	if err := itemRepo.AddSubtitle(ctx, sub); err != nil {
+		t.Fatalf("add subtitle: %v", err)
+	}
+
+	// search возвращает выдачу по запросу (сначала подходящие) страницами по одной строке.
+	search := func(q string) string {
+		t.Helper()
+		f := model.MediaFilter{Query: q, Sort: model.SortRelevance, Limit: 1}
+		var out []string
+		for range 10 {
+			page, err := jobRepo.FilterMedia(ctx, f)
+			if err != nil {
+				t.Fatalf("search %q: %v", q, err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			if page[0].Item != nil {
+				out = append(out, page[0].Item.Name)
+			} else {
+				out = append(out, page[0].Job.URL)
+			}
+			f.After = &page[0].Pos
+		}
+		if n, err := jobRepo.CountMedia(ctx, f); err != nil || n != len(out) {
+			t.Errorf("count %q: %d, %v, want %d", q, n, err, len(out))
+		}
+		return strings.Join(out, " ")
+	}
+
+	for _, tc := range []struct{ q, want string }{
+		{"progr", "https://youtube.com/rust go_tutorial.mp4 song.mp3"}, // заголовок важнее описания
+		{"programming tutorial", "go_tutorial.mp4"},
+		{"карбон", "pasta.mp4"},
+		{"mario", "pasta.mp4"},
+		{`"music for focus"`, "song.mp3"},
+		{`"focus for music"`, ""},
+		{"progr tag:music", "song.mp3"},
+		{"progr kind:audio", "song.mp3"},
+		{"progr domain:youtube.com", "https://youtube.com/rust go_tutorial.mp4"},
+		{"tag:lessons", "go_tutorial.mp4"},
+		{"vimeo", "pasta.mp4"},
+		{"goroutnes", "go_tutorial.mp4"},
+		{"карбанара", "pasta.mp4"},
+		{"prgramm", "https://youtube.com/rust go_tutorial.mp4 song.mp3"},     // опечатка в начале длинного слова
+		{"-", "https://youtube.com/rust song.mp3 pasta.mp4 go_tutorial.mp4"}, // искать нечего — по дате
+	} {
+		got := search(tc.q)
+		if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.q, got, tc.want)
+		}
+	}
+
+	// Индекс следует за изменениями тегов, имён и субтитров.
+	tag, _ := tagRepo.Upsert(ctx, "italian")
+	if err := tagRepo.AddToJob(ctx, items["pasta.mp4"].JobID, tag.ID); err != nil {
+		t.Fatalf("add tag: %v", err)
+	}
+	if err := itemRepo.Rename(ctx, items["song.mp3"].ID, "sunrise.mp3", "/data/sunrise.mp3"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	sub.Text = "buon appetito"
+	if err := itemRepo.AddSubtitle(ctx, sub); err != nil {
+		t.Fatalf("update subtitle: %v", err)
+	}
+	for _, tc := range []struct{ q, want string }{
+		{"italian", "pasta.mp4"},
+		{"sunrise", "sunrise.mp3"},
+		{"song", ""},
+		{"appetito", "pasta.mp4"},
+		{"карбонара", ""},
+	} {
+		if got := search(tc.q); got != tc.want {
+			t.Errorf("after update %q: got %q, want %q", tc.q, got, tc.want)
+		}
+	}
+
+	if err := jobRepo.Purge(ctx, items["pasta.mp4"].JobID); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if err := jobRepo.RebuildSearch(ctx); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if got := search("italian"); got != "" {
+		t.Errorf("purged job still found: %q", got)
+	}
+	found, err := jobRepo.SearchMedia(ctx, "rust", "")
+	if err != nil || len(found) != 1 || found[0].Job.ID != pending.ID {
+		t.Errorf("SearchMedia: %v, %v", found, err)
+	}
+}
+
+func TestAPITokenRepo_Lifecycle(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewAPITokenRepo(database)
+	ctx := context.Background()
+
+	tok := &model.APIToken{Name: "ci", Scope: model.ScopeEnqueue}
+	secret, err := r.Create(ctx, tok)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if secret == "" || tok.ID == "" {
+		t.Fatalf("secret/id not assigned: %q %q", secret, tok.ID)
+	}
+
+	got, err := r.GetBySecret(ctx, secret)
+	if err != nil {
+		t.Fatalf("get by secret: %v", err)
+	}
+	if got.ID != tok.ID || got.Scope != model.ScopeEnqueue || got.LastUsedAt != nil {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := r.GetBySecret(ctx, secret+"x"); err == nil {
+		t.Error("wrong secret accepted")
+	}
+
+	if err := r.Touch(ctx, tok.ID); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	got, _ = r.GetBySecret(ctx, secret)
+	if got.LastUsedAt == nil {
+		t.Error("last_used_at not set after touch")
+	}
+
+	if err := r.Delete(ctx, tok.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := r.GetBySecret(ctx, secret); err == nil {
+		t.Error("revoked token still found")
+	}
+}
+
+func TestUserRepo_SessionsAndChats(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewUserRepo(database)
+	ctx := context.Background()
+
+	u := &model.User{Username: "alice", PasswordHash: "x", Role: model.RoleMember}
+	if err := r.Create(ctx, u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := r.Create(ctx, &model.User{Username: "alice", PasswordHash: "y", Role: model.RoleViewer}); err == nil {
+		t.Error("duplicate username accepted")
+	}
+
+	secret, err := r.CreateSession(ctx, u.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	got, err := r.SessionUser(ctx, secret)
+	if err != nil || got.ID != u.ID {
+		t.Fatalf("session user: %v %+v", err, got)
+	}
+	expired, _ := r.CreateSession(ctx, u.ID, -time.Minute)
+	if _, err := r.SessionUser(ctx, expired); err == nil {
+		t.Error("expired session accepted")
+	}
+	// Смена пароля завершает сессии.
+	if err := r.SetPassword(ctx, u.ID, "z"); err != nil {
+		t.Fatalf("set password: %v", err)
+	}
+	if _, err := r.SessionUser(ctx, secret); err == nil {
+		t.Error("session survived password change")
+	}
+
+	if err := r.LinkChat(ctx, u.ID, 42); err != nil {
+		t.Fatalf("link chat: %v", err)
+	}
+	if got, err := r.GetByChatID(ctx, 42); err != nil || got.ID != u.ID {
+		t.Fatalf("by chat: %v %+v", err, got)
+	}
+	list, _ := r.List(ctx)
+	if len(list) != 1 || len(list[0].ChatIDs) != 1 || list[0].ChatIDs[0] != 42 {
+		t.Errorf("list: %+v", list)
+	}
+	if err := r.UnlinkChat(ctx, 42); err != nil {
+		t.Fatalf("unlink: %v", err)
+	}
+	if _, err := r.GetByChatID(ctx, 42); err == nil {
+		t.Error("chat still linked")
+	}
+}
+
+func TestJobRepo_OwnerScope(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	users := repo.NewUserRepo(database)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", PasswordHash: "x", Role: model.RoleMember}
+	bob := &model.User{Username: "bob", PasswordHash: "x", Role: model.RoleMember}
+	for _, u := range []*model.User{alice, bob} {
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	for i, owner := range []string{alice.ID, alice.ID, bob.ID, ""} {
+		job := &model.Job{URL: fmt.Sprintf("https://example.com/%d", i), Status: model.JobPending, Source: "web", OwnerID: owner}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+	}
+
+	count := func(owner string) int {
+		rows, err := jobRepo.FilterMedia(ctx, model.MediaFilter{OwnerID: owner})
+		if err != nil {
+			t.Fatalf("filter: %v", err)
+		}
+		for _, m := range rows {
+			if owner != "" && m.Job.OwnerID != owner {
+				t.Errorf("foreign job %s in %s's library", m.Job.ID, owner)
+			}
+		}
+		return len(rows)
+	}
+	if n := count(""); n != 4 {
+		t.Errorf("all: got %d, want 4", n)
+	}
+	if n := count(alice.ID); n != 2 {
+		t.Errorf("alice: got %d, want 2", n)
+	}
+
+	// Задания удалённого пользователя становятся общими.
+	if err := users.Delete(ctx, bob.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if n := count(bob.ID); n != 0 {
+		t.Errorf("deleted bob: got %d, want 0", n)
+	}
+	jobs, _ := jobRepo.List(ctx, repo.JobFilter{})
+	shared := 0
+	for _, j := range jobs {
+		if j.OwnerID == "" {
+			shared++
+		}
+	}
+	if shared != 2 {
+		t.Errorf("shared after delete: got %d, want 2", shared)
+	}
+}
+
+func TestJobRepo_OptionsSurviveRedownload(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	opts := model.JobOptions{MaxHeight: 720, Container: "mkv", SubLangs: []string{"en"}}
+	job := &model.Job{URL: "https://example.com/v", Status: model.JobPending, Source: "web", Options: opts}
+	if err := r.Create(ctx, job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	claimed, err := r.ClaimNext(ctx, nil)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if claimed.Options.MaxHeight != 720 || claimed.Options.Container != "mkv" || len(claimed.Options.SubLangs) != 1 {
+		t.Errorf("claimed options: %+v", claimed.Options)
+	}
+	if err := r.Redownload(ctx, job.ID); err != nil {
+		t.Fatalf("redownload: %v", err)
+	}
+	got, err := r.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Options.MaxHeight != 720 {
+		t.Errorf("options lost after redownload: %+v", got.Options)
+	}
+}
+
+func TestPresetRepo_DefaultAndDelete(t *testing.T) {
+	database := openTestDB(t)
+	presets := repo.NewPresetRepo(database)
+	jobs := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	phone := &model.Preset{Name: "Phone 480p", Format: "bv*[height<=480]+ba/b", IsDefault: true}
+	podcast := &model.Preset{Name: "Podcast", OutputFormat: "mp3", PostProcess: []string{"metadata"}}
+	for _, p := range []*model.Preset{phone, podcast} {
+		if err := presets.Create(ctx, p); err != nil {
+			t.Fatalf("create %s: %v", p.Name, err)
+		}
+	}
+	if err := presets.Create(ctx, &model.Preset{Name: "Podcast"}); err == nil {
+		t.Error("duplicate name must fail")
+	}
+
+	def, err := presets.Default(ctx)
+	if err != nil || def.ID != phone.ID {
+		t.Fatalf("default: %v %+v", err, def)
+	}
+	if err := presets.SetDefault(ctx, podcast.ID); err != nil {
+		t.Fatalf("set default: %v", err)
+	}
+	list, _ := presets.List(ctx)
+	defaults := 0
+	for _, p := range list {
+		if p.IsDefault {
+			defaults++
+		}
+	}
+	if defaults != 1 {
+		t.Errorf("defaults: got %d, want 1", defaults)
+	}
+
+	podcast.Subfolder = "podcasts"
+	if err := presets.Update(ctx, podcast); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, _ := presets.GetByID(ctx, podcast.ID)
+	if got.Subfolder != "podcasts" || len(got.PostProcess) != 1 || !got.IsDefault {
+		t.Errorf("after update: %+v", got)
+	}
+
+	job := &model.Job{URL: "https://example.com/v", Status: model.JobPending, Source: "web", PresetID: phone.ID}
+	if err := jobs.Create(ctx, job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if err := presets.Delete(ctx, phone.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	j, _ := jobs.GetByID(ctx, job.ID)
+	if j.PresetID != "" {
+		t.Errorf("preset_id after delete: %q", j.PresetID)
+	}
+	if err := jobs.SetPreset(ctx, job.ID, podcast.ID); err != nil {
+		t.Errorf("set preset on pending job: %v", err)
+	}
+}
+
+func TestDomainRuleRepo_CRUD(t *testing.T) {
+	database := openTestDB(t)
+	rules := repo.NewDomainRuleRepo(database)
+	presets := repo.NewPresetRepo(database)
+	ctx := context.Background()
+
+	p := &model.Preset{Name: "Archive"}
+	if err := presets.Create(ctx, p); err != nil {
+		t.Fatalf("create preset: %v", err)
+	}
+	rule := &model.DomainRule{Pattern: "youtube.com", Proxy: "socks5://vpn:1080", MaxParallel: 1, MinDelay: 30 * time.Second, PresetID: p.ID, Cookies: true}
+	if err := rules.Create(ctx, rule); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := rules.Create(ctx, &model.DomainRule{Pattern: "youtube.com"}); err == nil {
+		t.Error("duplicate pattern must fail")
+	}
+
+	rule.Cookies = false
+	rule.MinDelay = time.Minute
+	if err := rules.Update(ctx, rule); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := presets.Delete(ctx, p.ID); err != nil {
+		t.Fatalf("delete preset: %v", err)
+	}
+	list, err := rules.List(ctx)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list: %v %d", err, len(list))
+	}
+	got := list[0]
+	if got.Cookies || got.MinDelay != time.Minute || got.MaxParallel != 1 || got.PresetID != "" || got.Proxy != rule.Proxy {
+		t.Errorf("after update: %+v", got)
+	}
+
+	if err := rules.Delete(ctx, rule.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if list, _ := rules.List(ctx); len(list) != 0 {
+		t.Errorf("rules left: %d", len(list))
+	}
+}
+
+func TestJobRepo_ClaimNextDomains(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	for _, u := range []string{"https://a.com/1", "https://a.com/2", "https://www.c.com/1", "https://c.com/2", "https://b.com/1"} {
+		if err := r.Create(ctx, &model.Job{URL: u, Status: model.JobPending, Source: "web"}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	rules := []*model.DomainRule{
+		{Pattern: "a.com", MaxParallel: 1},
+		{Pattern: "c.com", MinDelay: time.Hour},
+	}
+	claim := func() string {
+		t.Helper()
+		j, err := r.ClaimNext(ctx, rules)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if j == nil {
+			return ""
+		}
+		return j.URL
+	}
+
+	// a.com упирается в лимит, c.com — в паузу: домены идут по очереди, затем пусто.
+	for _, want := range []string{"https://a.com/1", "https://www.c.com/1", "https://b.com/1", ""} {
+		if got := claim(); got != want {
+			t.Fatalf("claim: got %q, want %q", got, want)
+		}
+	}
+	jobs, _ := r.List(ctx, repo.JobFilter{Statuses: []model.JobStatus{model.JobRunning}})
+	for _, j := range jobs {
+		if j.URL == "https://a.com/1" {
+			j.Status = model.JobDone
+			if err := r.Update(ctx, j); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+		}
+	}
+	if got := claim(); got != "https://a.com/2" {
+		t.Errorf("after a.com freed: got %q", got)
+	}
+
+	// Без правил домены просто чередуются.
+	r = repo.NewJobRepo(openTestDB(t))
+	for _, u := range []string{"https://x.com/1", "https://x.com/2", "https://y.com/1"} {
+		r.Create(ctx, &model.Job{URL: u, Status: model.JobPending, Source: "web"}) //nolint:errcheck
+	}
+	for _, want := range []string{"https://x.com/1", "https://y.com/1", "https://x.com/2"} {
+		j, err := r.ClaimNext(ctx, nil)
+		if err != nil || j == nil || j.URL != want {
+			t.Fatalf("round-robin: got %v %v, want %s", j, err, want)
+		}
+	}
+}
+
+func TestJobRepo_Priority(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	create := func(u string, priority int) *model.Job {
+		t.Helper()
+		j := &model.Job{URL: u, Status: model.JobPending, Source: "web", Priority: priority}
+		if err := r.Create(ctx, j); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return j
+	}
+	create("https://list.com/1", model.PriorityPlaylist)
+	create("https://list.com/2", model.PriorityPlaylist)
+	last := create("https://list.com/3", model.PriorityPlaylist)
+	create("https://single.com/1", model.PriorityNormal)
+	create("https://urgent.com/1", model.PriorityUrgent)
+
+	// Участнику задание поднимается только до срочных: выше уже стоящих не встанет.
+	memberTop := create("https://member.com/1", model.PriorityNormal)
+	if p, err := r.MoveToTop(ctx, memberTop.ID, model.PriorityUrgent); err != nil || p != model.PriorityUrgent {
+		t.Fatalf("capped move to top: %d %v", p, err)
+	}
+
+	p, err := r.MoveToTop(ctx, last.ID, math.MaxInt)
+	if err != nil || p <= model.PriorityUrgent {
+		t.Fatalf("move to top: %d %v", p, err)
+	}
+	for _, want := range []string{"https://list.com/3", "https://urgent.com/1", "https://member.com/1", "https://single.com/1", "https://list.com/1", "https://list.com/2"} {
+		j, err := r.ClaimNext(ctx, nil)
+		if err != nil || j == nil || j.URL != want {
+			t.Fatalf("claim: got %v %v, want %s", j, err, want)
+		}
+	}
+	if _, err := r.MoveToTop(ctx, last.ID, math.MaxInt); err == nil {
+		t.Error("running job must not be moved")
+	}
+}
+
+func TestJobRepo_PauseResume(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	job := &model.Job{URL: "https://example.com/1", Status: model.JobPending, Source: "web"}
+	if err := r.Create(ctx, job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := r.Pause(ctx, job.ID); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if j, err := r.ClaimNext(ctx, nil); err != nil || j != nil {
+		t.Fatalf("paused job must not be claimed: %v %v", j, err)
+	}
+	if err := r.Pause(ctx, job.ID); err == nil {
+		t.Error("pausing a paused job must fail")
+	}
+	if err := r.Resume(ctx, job.ID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	j, err := r.ClaimNext(ctx, nil)
+	if err != nil || j == nil || j.ID != job.ID {
+		t.Fatalf("claim after resume: %v %v", j, err)
+	}
+	if err := r.Resume(ctx, job.ID); err == nil {
+		t.Error("resuming a running job must fail")
+	}
+}
+
+func TestJobRepo_Attempts(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	job := &model.Job{URL: "https://example.com/1", Status: model.JobPending, Source: "web"}
+	if err := r.Create(ctx, job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// Лог задания, скачанного до появления истории попыток.
+	if err := r.SaveLog(ctx, job.ID, "legacy log"); err != nil {
+		t.Fatalf("save log: %v", err)
+	}
+
+	first := &model.JobAttempt{JobID: job.ID, YtDlpVersion: "2025.01.15", Args: "-f best"}
+	if err := r.StartAttempt(ctx, first); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	code := 1
+	first.Status, first.ExitCode, first.Error, first.Log = model.JobRetrying, &code, "ERROR: HTTP Error 429", "first log"
+	if err := r.FinishAttempt(ctx, first); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	second := &model.JobAttempt{JobID: job.ID}
+	if err := r.StartAttempt(ctx, second); err != nil {
+		t.Fatalf("start second: %v", err)
+	}
+	if first.Number != 1 || second.Number != 2 {
+		t.Fatalf("numbers = %d, %d", first.Number, second.Number)
+	}
+	if log, _ := r.GetLog(ctx, job.ID); log != "first log" {
+		t.Errorf("log while second attempt runs = %q", log)
+	}
+
+	// Процесс остановился посреди второй попытки.
+	if err := r.ResetStale(ctx); err != nil {
+		t.Fatalf("reset stale: %v", err)
+	}
+	attempts, err := r.ListAttempts(ctx, job.ID)
+	if err != nil || len(attempts) != 2 {
+		t.Fatalf("list: %v %v", attempts, err)
+	}
+	a := attempts[0]
+	if a.Status != model.JobRetrying || a.ExitCode == nil || *a.ExitCode != 1 || a.FinishedAt == nil ||
+		a.YtDlpVersion != "2025.01.15" || a.Error != "ERROR: HTTP Error 429" {
+		t.Errorf("first attempt = %+v", a)
+	}
+	if a := attempts[1]; a.Status != model.JobPending || a.FinishedAt == nil || a.ExitCode != nil {
+		t.Errorf("interrupted attempt = %+v", a)
+	}
+
+	third := &model.JobAttempt{JobID: job.ID}
+	if err := r.StartAttempt(ctx, third); err != nil {
+		t.Fatalf("start third: %v", err)
+	}
+	third.Status, third.Files, third.Log = model.JobDone, []string{"a.mp4", "b.mp4"}, "third log"
+	if err := r.FinishAttempt(ctx, third); err != nil {
+		t.Fatalf("finish third: %v", err)
+	}
+	attempts, _ = r.ListAttempts(ctx, job.ID)
+	if got := attempts[2]; got.Number != 3 || len(got.Files) != 2 || got.Files[1] != "b.mp4" {
+		t.Errorf("third attempt = %+v", got)
+	}
+	outcomes, err := r.ListAttemptOutcomes(ctx, job.ID)
+	if err != nil || len(outcomes) != 3 || outcomes[2].Number != 3 || outcomes[2].Status != model.JobDone ||
+		outcomes[0].Status != model.JobRetrying || outcomes[2].Log != "" {
+		t.Errorf("outcomes = %+v %v", outcomes, err)
+	}
+	if log, _ := r.GetLog(ctx, job.ID); log != "third log" {
+		t.Errorf("latest log = %q", log)
+	}
+
+	other := &model.Job{URL: "https://example.com/2", Status: model.JobPending, Source: "web"}
+	if err := r.Create(ctx, other); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	r.SaveLog(ctx, other.ID, "legacy log") //nolint:errcheck
+	if log, _ := r.GetLog(ctx, other.ID); log != "legacy log" {
+		t.Errorf("log without attempts = %q", log)
+	}
+}
+
+func TestJobRepo_PauseDomain(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	for _, u := range []string{"https://m.youtube.com/1", "https://vk.com/2"} {
+		if err := r.Create(ctx, &model.Job{URL: u, Status: model.JobPending, Source: "web"}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	// Пауза хоста распространяется на все хосты его правила.
+	rules := []*model.DomainRule{{Pattern: "youtube.com"}}
+	if err := r.PauseDomain(ctx, "www.youtube.com", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("pause domain: %v", err)
+	}
+	j, err := r.ClaimNext(ctx, rules)
+	if err != nil || j == nil || j.Domain() != "vk.com" {
+		t.Fatalf("claim = %v, %v; want vk.com", j, err)
+	}
+	if j, err := r.ClaimNext(ctx, rules); err != nil || j != nil {
+		t.Fatalf("paused domain claimed: %v %v", j, err)
+	}
+	if err := r.PauseDomain(ctx, "www.youtube.com", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("unpause: %v", err)
+	}
+	if j, err := r.ClaimNext(ctx, rules); err != nil || j == nil || j.Domain() != "m.youtube.com" {
+		t.Fatalf("claim after pause = %v, %v", j, err)
+	}
+}
+
+func TestJobRepo_FindDuplicate(t *testing.T) {
+	database := openTestDB(t)
+	r := repo.NewJobRepo(database)
+	ctx := context.Background()
+
+	done := &model.Job{URL: "https://youtu.be/abc123", Status: model.JobDone, Source: "web", Title: "Clip", Extractor: "Youtube", VideoID: "abc123"}
+	imported := &model.Job{URL: "local", Status: model.JobImported, Source: "filesystem", VideoID: "file42"}
+	failed := &model.Job{URL: "https://vk.com/v1", Status: model.JobFailed, Source: "web"}
+	for _, j := range []*model.Job{done, imported, failed} {
+		if err := r.Create(ctx, j); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	find := func(j *model.Job) *model.Job {
+		t.Helper()
+		dup, err := r.FindDuplicate(ctx, j)
+		if err != nil {
+			t.Fatalf("find duplicate: %v", err)
+		}
+		return dup
+	}
+	// Другой URL того же видео находится по экстрактору и id.
+	if dup := find(&model.Job{URL: "https://www.youtube.com/watch?v=abc123", Extractor: "Youtube", VideoID: "abc123"}); dup == nil || dup.ID != done.ID {
+		t.Errorf("by video id: got %+v", dup)
+	}
+	if dup := find(&model.Job{URL: "https://youtu.be/abc123"}); dup == nil || dup.ID != done.ID {
+		t.Errorf("by url: got %+v", dup)
+	}
+	if dup := find(&model.Job{URL: "https://vimeo.com/abc123", Extractor: "Vimeo", VideoID: "abc123"}); dup != nil {
+		t.Errorf("other extractor matched: %+v", dup)
+	}
+	// У найденного сканером файла экстрактор неизвестен, а домен не совпадает:
+	// одного id мало, короткие id разных сайтов пересекаются.
+	if dup := find(&model.Job{URL: "https://youtu.be/file42", Extractor: "Youtube", VideoID: "file42"}); dup != nil {
+		t.Errorf("imported matched by id alone: %+v", dup)
+	}
+	// Экстрактор нового задания ещё неизвестен — хватает id на том же домене.
+	if dup := find(&model.Job{URL: "https://youtu.be/watch?abc123", VideoID: "abc123"}); dup == nil || dup.ID != done.ID {
+		t.Errorf("same domain without extractor: got %+v", dup)
+	}
+	if dup := find(&model.Job{URL: "https://rutube.ru/video/abc123", VideoID: "abc123"}); dup != nil {
+		t.Errorf("other domain without extractor matched: %+v", dup)
+	}
+	if dup := find(&model.Job{URL: "https://vk.com/v1"}); dup != nil {
+		t.Errorf("failed job counted as duplicate: %+v", dup)
+	}
+	// Чужие задания не считаются: пользователь их не видит.
+	if dup := find(&model.Job{URL: "https://youtu.be/abc123", OwnerID: "u2"}); dup != nil {
+		t.Errorf("other owner matched: %+v", dup)
+	}
+
+	// Placeholder-дубликат ждёт решения и после перезапуска, сам дубликатом не считается.
+	ph := &model.Job{URL: "https://youtu.be/abc123", Status: model.JobChecking, Source: "web"}
+	ph.MarkDuplicate(done)
+	if err := r.Create(ctx, ph); err != nil {
+		t.Fatalf("create placeholder: %v", err)
+	}
+	if err := r.ResetStale(ctx); err != nil {
+		t.Fatalf("reset stale: %v", err)
+	}
+	got, _ := r.GetByID(ctx, ph.ID)
+	if !got.IsDuplicate() || got.Error != "Уже скачано: Clip" {
+		t.Errorf("placeholder after restart: %+v", got)
+	}
+	if dup := find(&model.Job{URL: "https://youtu.be/abc123"}); dup == nil || dup.ID != done.ID {
+		t.Errorf("placeholder matched instead of done job: %+v", dup)
+	}
+	if err := r.SetDuplicate(ctx, ph.ID, "", ""); err != nil {
+		t.Fatalf("clear duplicate: %v", err)
+	}
+	if got, _ := r.GetByID(ctx, ph.ID); got.IsDuplicate() || got.Error != "" {
+		t.Errorf("duplicate mark not cleared: %+v", got)
+	}
+
+	// Удалённое из библиотеки задание тоже считается дубликатом.
+	if err := r.DeleteChecking(ctx, ph.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Hide(ctx, done.ID); err != nil {
+		t.Fatal(err)
+	}
+	if dup := find(&model.Job{URL: "https://youtu.be/abc123"}); dup == nil || !dup.Hidden {
+		t.Errorf("hidden: got %+v", dup)
+	}
+}
+
+func TestItemRepo_VideoInfo(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	itemRepo := repo.NewItemRepo(database)
+	ctx := context.Background()
+
+	job := &model.Job{URL: "https://example.com/v", Status: model.JobDone, Source: "web"}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	info := model.VideoInfo{
+		Channel: "Lofi Channel", UploadDate: "20240131", Description: "beats to relax",
+		Width: 1920, Height: 1080, VCodec: "avc1.640028", ViewCount: 1500,
+		FPS: 30, Channels: 6,
+		Chapters: []model.Chapter{{Start: 0, End: 30, Title: "Intro"}},
+	}
+	item := &model.Item{JobID: job.ID, Kind: "video", Path: "/data/v.mp4", Name: "v.mp4", Duration: 90, Info: info}
+	if err := itemRepo.Create(ctx, item); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	got, err := itemRepo.GetByID(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Info.Summary() != "Lofi Channel · 31.01.2024 · 1.5K просмотров" || got.Info.Quality() != "1080p" {
+		t.Errorf("summary: %q, quality %q", got.Info.Summary(), got.Info.Quality())
+	}
+	if len(got.Info.Chapters) != 1 || got.Info.Chapters[0].Title != "Intro" {
+		t.Errorf("chapters: %+v", got.Info.Chapters)
+	}
+	if got.Info.FPS != 30 || got.Info.Channels != 6 {
+		t.Errorf("probe fields: fps %v, channels %d", got.Info.FPS, got.Info.Channels)
+	}
+
+	// Поиск по каналу и описанию; метаданные доходят до строки медиатеки.
+	for _, q := range []string{"lofi", "relax"} {
+		rows, err := jobRepo.FilterMedia(ctx, model.MediaFilter{Query: q})
+		if err != nil {
+			t.Fatalf("filter %q: %v", q, err)
+		}
+		if len(rows) != 1 || rows[0].Item == nil || rows[0].Item.Info.Channel != "Lofi Channel" {
+			t.Errorf("filter %q: got %d rows", q, len(rows))
+		}
+	}
+	if n, _ := jobRepo.CountMedia(ctx, model.MediaFilter{Query: "relax"}); n != 1 {
+		t.Errorf("count: got %d, want 1", n)
+	}
+
+	// ffprobe уточняет параметры файла, но не затирает длительность нулём.
+	if err := itemRepo.SetProbe(ctx, item.ID, model.Probe{Width: 1280, Height: 720, VCodec: "h264", FPS: 25, Channels: 2}); err != nil {
+		t.Fatalf("set probe: %v", err)
+	}
+	got, _ = itemRepo.GetByID(ctx, item.ID)
+	if got.Duration != 90 || got.Info.Quality() != "720p" || got.Info.Technical() != "25 fps · стерео" || got.Info.Channel != "Lofi Channel" {
+		t.Errorf("after probe: duration %d, %+v", got.Duration, got.Info)
+	}
+}
+
+func TestItemRepo_Subtitles(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	itemRepo := repo.NewItemRepo(database)
+	ctx := context.Background()
+
+	job := &model.Job{URL: "https://example.com/s", Status: model.JobDone, Source: "web"}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	item := &model.Item{JobID: job.ID, Kind: "video", Path: "/data/s.mkv", Name: "s.mkv"}
+	if err := itemRepo.Create(ctx, item); err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	for _, s := range []*model.Subtitle{
+		{ItemID: item.ID, Lang: "ru", Path: "/subs/a.ru.vtt"},
+		{ItemID: item.ID, Lang: "en", Path: "/subs/a.en.vtt"},
+		// Извлечённые из файла заменяют скачанные на том же языке.
+		{ItemID: item.ID, Lang: "en", Path: "/subs/b.en.vtt", Source: model.SubsEmbedded},
+	} {
+		if err := itemRepo.AddSubtitle(ctx, s); err != nil {
+			t.Fatalf("add %s: %v", s.Lang, err)
+		}
+	}
+
+	subs, err := itemRepo.ListSubtitles(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(subs) != 2 || subs[0].Lang != "en" || subs[0].Path != "/subs/b.en.vtt" || subs[0].Source != model.SubsEmbedded {
+		t.Fatalf("list: %+v", subs)
+	}
+	if _, err := itemRepo.GetSubtitle(ctx, item.ID, "de"); err != sql.ErrNoRows {
+		t.Errorf("missing lang: got %v, want sql.ErrNoRows", err)
+	}
+
+	rows, err := jobRepo.FilterMedia(ctx, model.MediaFilter{})
+	if err != nil {
+		t.Fatalf("filter: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0].Subtitles) != 2 {
+		t.Errorf("media subtitles: %+v", rows)
+	}
+}
+
+func TestItemRepo_WatchState(t *testing.T) {
+	database := openTestDB(t)
+	jobRepo := repo.NewJobRepo(database)
+	itemRepo := repo.NewItemRepo(database)
+	ctx := context.Background()
+
+	ids := map[string]string{}
+	for _, name := range []string{"new", "started", "seen"} {
+		job := &model.Job{URL: "https://example.com/" + name, Status: model.JobDone, Source: "web"}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("create job: %v", err)
+		}
+		item := &model.Item{JobID: job.ID, Kind: "video", Path: "/data/" + name + ".mp4", Name: name + ".mp4", Duration: 100}
+		if err := itemRepo.Create(ctx, item); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		ids[name] = item.ID
+	}
+	// Задание без файла не проходит ни через один фильтр по просмотру.
+	if err := jobRepo.Create(ctx, &model.Job{URL: "https://example.com/pending", Status: model.JobPending, Source: "web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := itemRepo.SaveWatchPosition(ctx, "u1", ids["started"], 40, false); err != nil {
+		t.Fatalf("save position: %v", err)
+	}
+	if err := itemRepo.SaveWatchPosition(ctx, "u1", ids["seen"], 95, true); err != nil {
+		t.Fatal(err)
+	}
+	// Пересмотр с начала не снимает отметку.
+	if err := itemRepo.SaveWatchPosition(ctx, "u1", ids["seen"], 5, false); err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(f model.MediaFilter) []string {
+		t.Helper()
+		rows, err := jobRepo.FilterMedia(ctx, f)
+		if err != nil {
+			t.Fatalf("filter %+v: %v", f, err)
+		}
+		n, err := jobRepo.CountMedia(ctx, f)
+		if err != nil || n != len(rows) {
+			t.Errorf("count %+v: %d, %v; rows %d", f, n, err, len(rows))
+		}
+		var out []string
+		for _, r := range rows {
+			if r.Item != nil {
+				out = append(out, strings.TrimSuffix(r.Item.Name, ".mp4"))
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+	if got := names(model.MediaFilter{ViewerID: "u1", Unwatched: true}); strings.Join(got, ",") != "new,started" {
+		t.Errorf("unwatched: %v", got)
+	}
+	if got := names(model.MediaFilter{ViewerID: "u1", InProgress: true}); strings.Join(got, ",") != "started" {
+		t.Errorf("in progress: %v", got)
+	}
+
+	rows, _ := jobRepo.FilterMedia(ctx, model.MediaFilter{ViewerID: "u1", InProgress: true})
+	if len(rows) != 1 || rows[0].Watch.Position != 40 || rows[0].WatchPercent() != 40 {
+		t.Errorf("watch state: %+v", rows)
+	}
+	last, err := jobRepo.LastMedia(ctx, 10, "", "u1", true)
+	if err != nil || len(last) != 2 {
+		t.Errorf("last unwatched: %d, %v", len(last), err)
+	}
+	// У другого пользователя прогресс свой.
+	if got := names(model.MediaFilter{ViewerID: "u2", Unwatched: true}); strings.Join(got, ",") != "new,seen,started" {
+		t.Errorf("unwatched for another user: %v", got)
+	}
+	if last, _ := jobRepo.LastMedia(ctx, 10, "", "u2", true); len(last) != 3 {
+		t.Errorf("last unwatched for another user: %d", len(last))
+	}
+
+	if err := itemRepo.SetWatched(ctx, "u1", ids["seen"], false); err != nil {
+		t.Fatalf("unset watched: %v", err)
+	}
+	if err := itemRepo.SetWatched(ctx, "u1", ids["started"], true); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(model.MediaFilter{ViewerID: "u1", Unwatched: true}); strings.Join(got, ",") != "new,seen" {
+		t.Errorf("unwatched after marks: %v", got)
+	}
+	if got := names(model.MediaFilter{ViewerID: "u1", InProgress: true}); len(got) != 0 {
+		t.Errorf("in progress after marks: %v", got)
+	}
+}
+
+func TestOperationRepo_ListOwner(t *testing.T) {
+	ctx := context.Background()
+	ops := repo.NewOperationRepo(openTestDB(t))
+
+	mine := &model.Operation{Kind: "transcode", Title: "mine", Payload: "{}", OwnerID: "u1"}
+	system := &model.Operation{Kind: "cleanup", Title: "system", Payload: "{}"}
+	for _, op := range []*model.Operation{mine, system} {
+		if err := ops.Create(ctx, op); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	if all, _ := ops.List(ctx, nil, ""); len(all) != 2 {
+		t.Fatalf("all: got %d, want 2", len(all))
+	}
+	own, err := ops.List(ctx, nil, "u1")
+	if err != nil || len(own) != 1 || own[0].ID != mine.ID || own[0].OwnerID != "u1" {
+		t.Fatalf("own: %v %+v", err, own)
+	}
+	if none, _ := ops.List(ctx, []string{"cleanup"}, "u1"); len(none) != 0 {
+		t.Errorf("kind+owner: got %d, want 0", len(none))
+	}
+	got, _ := ops.GetByID(ctx, system.ID)
+	if got.OwnerID != "" {
+		t.Errorf("system owner: %q", got.OwnerID)
+	}
+}
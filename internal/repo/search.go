@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+// Полнотекстовый поиск: индекс search_fts (миграция 031) с документом на каждую
+// строку медиатеки; триггеры держат его в согласии с заданиями, элементами,
+// тегами и субтитрами.
+
+// searchHitsSQL — CTE найденных строк медиатеки и их релевантности (больше — лучше).
+// Веса колонок: title, name, tags, channel, description, subtitles, site.
+const searchHitsSQL = `search_hits AS (
+			SELECT d.row_id, -bm25(search_fts, 10.0, 8.0, 6.0, 3.0, 1.0, 0.5, 2.0) AS score
+			FROM search_fts JOIN search_docs d ON d.id = search_fts.rowid
+			WHERE search_fts MATCH ?
+		)`
+
+// applySearch разбирает f.Query: операторы переходят в поля фильтра, а текст —
+// в выражение MATCH (пустое, если искать по тексту нечего). Query в результате пуст.
+func applySearch(ctx context.Context, db *sql.DB, f model.MediaFilter) (model.MediaFilter, string, error) {
+	if f.Query == "" {
+		return f, "", nil
+	}
+	q := model.ParseSearch(f.Query)
+	f.Query = ""
+	f.Tags = slices.Concat(f.Tags, q.Tags)
+	if q.Domain != "" {
+		f.Domain = q.Domain
+	}
+	if q.Kind != "" {
+		f.Kind = q.Kind
+	}
+	match, err := ftsMatch(ctx, db, q)
+	return f, match, err
+}
+
+// ftsMatch собирает выражение MATCH: должны найтись все слова (по началу)
+// и все фразы (целиком). Слово, которого нет в индексе, ищется ещё и в виде
+// похожих слов из словаря — так прощаются опечатки.
+func ftsMatch(ctx context.Context, db *sql.DB, q model.SearchQuery) (string, error) {
+	var terms []string
+	for _, w := range q.Words {
+		if !hasWordChars(w) {
+			continue
+		}
+		term := ftsQuote(w) + "*"
+		alts, err := similarTerms(ctx, db, w)
+		if err != nil {
+			return "", err
+		}
+		if len(alts) > 0 {
+			term = "(" + strings.Join(append([]string{term}, alts...), " OR ") + ")"
+		}
+		terms = append(terms, term)
+	}
+	for _, p := range q.Phrases {
+		if hasWordChars(p) {
+			terms = append(terms, ftsQuote(p))
+		}
+	}
+	return strings.Join(terms, " AND "), nil
+}
+
+// ftsQuote берёт текст в кавычки FTS5: внутри он разбивается на слова тем же
+// токенизатором, что и индекс, а спецсимволы запросов теряют смысл.
+func ftsQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func hasWordChars(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0
+}
+
+// Исправление опечаток: слова короче typoMinLen не исправляются, предлагается
+// не больше typoMaxAlts вариантов из typoMaxScan самых частых кандидатов.
+const (
+	typoMinLen  = 4
+	typoMaxAlts = 5
+	typoMaxScan = 500
+)
+
+// similarTerms возвращает варианты MATCH для слов словаря индекса, близких к w,
+// если w не начало ни одного из них. Кандидаты — слова на ту же букву, у которых
+// какое-нибудь начало отличается от w не больше чем на одну правку (на две для
+// длинных слов). Сравнивать дальше n+maxDist букв незачем, поэтому словарь
+// группируется по началам такой длины прямо в SQL: сколько бы слов в нём ни было,
+// в Go попадает не больше typoMaxScan начал. Усечённое начало ищется как префикс.
+func similarTerms(ctx context.Context, db *sql.DB, w string) ([]string, error) {
+	w = strings.ToLower(w)
+	n := utf8.RuneCountInString(w)
+	if n < typoMinLen || strings.IndexFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) >= 0 {
+		return nil, nil
+	}
+	var found bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM search_vocab WHERE term >= ? AND term < ?)`, w, w+"\U0010FFFF",
+	).Scan(&found)
+	if err != nil || found {
+		return nil, err
+	}
+
+	maxDist := 1
+	if n >= 8 {
+		maxDist = 2
+	}
+	first, _ := utf8.DecodeRuneInString(w)
+	headLen := n + maxDist
+	rows, err := db.QueryContext(ctx,
+		`SELECT substr(term, 1, ?) AS head, SUM(doc) AS docs FROM search_vocab
+		 WHERE term >= ? AND term < ? AND length(term) >= ?
+		 GROUP BY head ORDER BY docs DESC LIMIT ?`,
+		headLen, string(first), string(first)+"\U0010FFFF", n-maxDist, typoMaxScan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type candidate struct {
+		head       string
+		dist, docs int
+	}
+	var cands []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.head, &c.docs); err != nil {
+			return nil, err
+		}
+		if c.dist = prefixDistance(w, c.head); c.dist <= maxDist {
+			cands = append(cands, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].dist != cands[j].dist {
+			return cands[i].dist < cands[j].dist
+		}
+		return cands[i].docs > cands[j].docs
+	})
+	var out []string
+	for _, c := range cands[:min(len(cands), typoMaxAlts)] {
+		alt := ftsQuote(c.head)
+		if utf8.RuneCountInString(c.head) == headLen {
+			alt += "*" // начало более длинных слов
+		}
+		out = append(out, alt)
+	}
+	return out, nil
+}
+
+// prefixDistance — наименьшее расстояние Левенштейна между w и началом term:
+// «прграм» близко к «программирование».
+func prefixDistance(w, term string) int {
+	a, b := []rune(w), []rune(term)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return slices.Min(prev)
+}
+
+// RebuildSearch пересобирает поисковый индекс всех заданий и сжимает его.
+func (r *sqliteJobRepo) RebuildSearch(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT INTO search_reindex SELECT id FROM jobs UNION SELECT job_id FROM search_docs`); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO search_fts(search_fts) VALUES ('optimize')`)
+	return err
+}
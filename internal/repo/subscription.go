@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/google/uuid"
+)
+
+type sqliteSubscriptionRepo struct {
+	db *sql.DB
+}
+
+func NewSubscriptionRepo(db *sql.DB) SubscriptionRepo {
+	return &sqliteSubscriptionRepo{db: db}
+}
+
+const subscriptionSelect = `SELECT id, url, title, interval_sec, tags, COALESCE(collection_id,''), max_age_days,
+	COALESCE(owner_id,''), chat_id, enabled, created_at, COALESCE(last_polled_at,''), synced, last_error FROM subscriptions`
+
+func (r *sqliteSubscriptionRepo) Create(ctx context.Context, s *model.Subscription) error {
+	if s.ID == "" {
+		s.ID = uuid.NewString()
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+	tags, err := json.Marshal(s.Tags)
+	if err != nil {
+		return err
+	}
+	if s.Tags == nil {
+		tags = []byte("[]")
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO subscriptions (id, url, title, interval_sec, tags, collection_id, max_age_days, owner_id, chat_id, enabled, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.URL, s.Title, int64(s.Interval/time.Second), string(tags), nullStr(s.CollectionID),
+		s.MaxAgeDays, nullStr(s.OwnerID), s.ChatID, s.Enabled, s.CreatedAt.Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+func (r *sqliteSubscriptionRepo) GetByID(ctx context.Context, id string) (*model.Subscription, error) {
+	return scanSubscription(r.db.QueryRowContext(ctx, subscriptionSelect+` WHERE id=?`, id))
+}
+
+func (r *sqliteSubscriptionRepo) List(ctx context.Context, ownerID string) ([]*model.Subscription, error) {
+	q := subscriptionSelect
+	var args []any
+	if ownerID != "" {
+		q += ` WHERE owner_id=?`
+		args = append(args, ownerID)
+	}
+	rows, err := r.db.QueryContext(ctx, q+` ORDER BY created_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.Subscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqliteSubscriptionRepo) SetEnabled(ctx context.Context, id string, enabled bool) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE subscriptions SET enabled=? WHERE id=?`, enabled, id)
+	return err
+}
+
+func (r *sqliteSubscriptionRepo) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscription_entries WHERE subscription_id=?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE id=?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteSubscriptionRepo) MarkPolled(ctx context.Context, id, title, errMsg string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE subscriptions SET last_polled_at=?, last_error=?, synced = synced OR ?='',
+		        title=CASE WHEN ?='' THEN title ELSE ? END
+		 WHERE id=?`,
+		time.Now().UTC().Format(time.RFC3339Nano), errMsg, errMsg, title, title, id)
+	return err
+}
+
+func (r *sqliteSubscriptionRepo) SeenURLs(ctx context.Context, id string) (map[string]struct{}, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT url FROM subscription_entries WHERE subscription_id=?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seen := make(map[string]struct{})
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		seen[u] = struct{}{}
+	}
+	return seen, rows.Err()
+}
+
+func (r *sqliteSubscriptionRepo) MarkSeen(ctx context.Context, id string, urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, u := range urls {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO subscription_entries (subscription_id, url, seen_at) VALUES (?, ?, ?)`,
+			id, u, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func scanSubscription(sc scanner) (*model.Subscription, error) {
+	var s model.Subscription
+	var intervalSec int64
+	var tags, createdAt, lastPolledAt string
+	if err := sc.Scan(&s.ID, &s.URL, &s.Title, &intervalSec, &tags, &s.CollectionID, &s.MaxAgeDays,
+		&s.OwnerID, &s.ChatID, &s.Enabled, &createdAt, &lastPolledAt, &s.Synced, &s.LastError); err != nil {
+		return nil, err
+	}
+	s.Interval = time.Duration(intervalSec) * time.Second
+	json.Unmarshal([]byte(tags), &s.Tags) //nolint:errcheck
+	s.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	if lastPolledAt != "" {
+		v, _ := time.Parse(time.RFC3339Nano, lastPolledAt)
+		s.LastPolledAt = &v
+	}
+	return &s, nil
+}
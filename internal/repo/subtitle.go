@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/google/uuid"
+)
+
+// Субтитры принадлежат элементу, поэтому их методы — часть ItemRepo.
+
+const subtitleSelect = `SELECT id, item_id, lang, path, source, created_at FROM subtitles`
+
+// AddSubtitle сохраняет субтитры; повторная запись на тот же язык заменяет файл.
+func (r *sqliteItemRepo) AddSubtitle(ctx context.Context, s *model.Subtitle) error {
+	if s.ID == "" {
+		s.ID = uuid.NewString()
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+	if s.Source == "" {
+		s.Source = model.SubsDownloaded
+	}
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO subtitles (id, item_id, lang, path, source, text, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(item_id, lang) DO UPDATE SET path=excluded.path, source=excluded.source, text=excluded.text
+		 RETURNING id`,
+		s.ID, s.ItemID, s.Lang, s.Path, s.Source, s.Text, s.CreatedAt.Format(time.RFC3339Nano),
+	).Scan(&s.ID)
+}
+
+func (r *sqliteItemRepo) ListSubtitles(ctx context.Context, itemID string) ([]*model.Subtitle, error) {
+	return r.querySubtitles(ctx, subtitleSelect+` WHERE item_id=? ORDER BY lang`, itemID)
+}
+
+func (r *sqliteItemRepo) ListSubtitlesWithoutText(ctx context.Context) ([]*model.Subtitle, error) {
+	return r.querySubtitles(ctx, subtitleSelect+` WHERE text = '' ORDER BY item_id, lang`)
+}
+
+func (r *sqliteItemRepo) SetSubtitleText(ctx context.Context, id, text string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE subtitles SET text=? WHERE id=?`, text, id)
+	return err
+}
+
+func (r *sqliteItemRepo) querySubtitles(ctx context.Context, q string, args ...any) ([]*model.Subtitle, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*model.Subtitle
+	for rows.Next() {
+		s, err := scanSubtitle(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqliteItemRepo) GetSubtitle(ctx context.Context, itemID, lang string) (*model.Subtitle, error) {
+	return scanSubtitle(r.db.QueryRowContext(ctx, subtitleSelect+` WHERE item_id=? AND lang=?`, itemID, lang))
+}
+
+func scanSubtitle(s scanner) (*model.Subtitle, error) {
+	var sub model.Subtitle
+	var createdAt string
+	if err := s.Scan(&sub.ID, &sub.ItemID, &sub.Lang, &sub.Path, &sub.Source, &createdAt); err != nil {
+		return nil, err
+	}
+	sub.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	return &sub, nil
+}
@@ -3,12 +3,16 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strings"
 
 	"github.com/dr-duke/talmorGo/internal/model"
 	"github.com/google/uuid"
 )
 
+// ErrTagExists — тег с таким именем уже есть.
+var ErrTagExists = errors.New("tag already exists")
+
 type sqliteTagRepo struct {
 	db *sql.DB
 }
@@ -50,12 +54,70 @@ func (r *sqliteTagRepo) ListAll(ctx context.Context) ([]*model.Tag, error) {
 	return tags, rows.Err()
 }
 
+// ListByJob возвращает имена тегов задания по алфавиту.
+func (r *sqliteTagRepo) ListByJob(ctx context.Context, jobID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.name FROM tags t
+		JOIN job_tags jt ON jt.tag_id = t.id
+		WHERE jt.job_id = ?
+		ORDER BY t.name`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
 func (r *sqliteTagRepo) AddToJob(ctx context.Context, jobID, tagID string) error {
 	_, err := r.db.ExecContext(ctx,
 		`INSERT OR IGNORE INTO job_tags (job_id, tag_id) VALUES (?, ?)`, jobID, tagID)
 	return err
 }
 
+// Rename переименовывает обычный тег; коллекции переименовывает CollectionRepo.
+// Если такого тега нет — sql.ErrNoRows.
+func (r *sqliteTagRepo) Rename(ctx context.Context, oldName, newName string) error {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tags WHERE name=?)`, newName).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrTagExists
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE tags SET name=? WHERE name=? AND kind='plain'`, newName, oldName)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete удаляет обычный тег вместе с привязками к заданиям. Если такого тега нет — sql.ErrNoRows.
+func (r *sqliteTagRepo) Delete(ctx context.Context, name string) error {
+	if _, err := r.db.ExecContext(ctx,
+		`DELETE FROM job_tags WHERE tag_id=(SELECT id FROM tags WHERE name=? AND kind='plain')`, name); err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tags WHERE name=? AND kind='plain'`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (r *sqliteTagRepo) RemoveFromJob(ctx context.Context, jobID, tagName string) error {
 	if _, err := r.db.ExecContext(ctx,
 		`DELETE FROM job_tags WHERE job_id=? AND tag_id=(SELECT id FROM tags WHERE name=?)`,
@@ -100,21 +162,18 @@ func (r *sqliteTagRepo) ListWithCount(ctx context.Context) ([]*model.TagWithCoun
 }
 
 func (r *sqliteTagRepo) ListWithCountFiltered(ctx context.Context, f model.MediaFilter) ([]*model.TagWithCount, error) {
-	if f.Query == "" && len(f.Tags) == 0 {
-		return r.ListWithCount(ctx)
+	f, match, err := applySearch(ctx, r.db, f)
+	if err != nil {
+		return nil, err
 	}
-
-	var conds []string
-	var args []any
-
-	if f.Query != "" {
-		like := "%" + f.Query + "%"
-		conds = append(conds, "(j.url LIKE ? OR j.title LIKE ?)")
-		args = append(args, like, like)
+	conds, args := jobFilterConds(f)
+	if match != "" {
+		conds = append(conds, `j.id IN (SELECT d.job_id FROM search_fts
+			JOIN search_docs d ON d.id = search_fts.rowid WHERE search_fts MATCH ?)`)
+		args = append(args, match)
 	}
-	for _, tag := range f.Tags {
-		conds = append(conds, `j.id IN (SELECT jt2.job_id FROM job_tags jt2 JOIN tags t2 ON t2.id=jt2.tag_id WHERE t2.name=?)`)
-		args = append(args, tag)
+	if len(conds) == 0 {
+		return r.ListWithCount(ctx)
 	}
 
 	where := "WHERE j.hidden=0"
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/google/uuid"
+)
+
+type sqliteUserRepo struct {
+	db *sql.DB
+}
+
+func NewUserRepo(db *sql.DB) UserRepo {
+	return &sqliteUserRepo{db: db}
+}
+
+const userSelect = `SELECT id, username, password_hash, role, created_at FROM users`
+
+func (r *sqliteUserRepo) Create(ctx context.Context, u *model.User) error {
+	if u.ID == "" {
+		u.ID = uuid.NewString()
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO users (id, username, password_hash, role, created_at) VALUES (?, ?, ?, ?, ?)`,
+		u.ID, u.Username, u.PasswordHash, string(u.Role), u.CreatedAt.Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+func (r *sqliteUserRepo) GetByID(ctx context.Context, id string) (*model.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, userSelect+` WHERE id=?`, id))
+}
+
+func (r *sqliteUserRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, userSelect+` WHERE username=?`, username))
+}
+
+func (r *sqliteUserRepo) GetByChatID(ctx context.Context, chatID int64) (*model.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		userSelect+` WHERE id=(SELECT user_id FROM user_chats WHERE chat_id=?)`, chatID))
+}
+
+// List возвращает всех пользователей с привязанными чатами.
+func (r *sqliteUserRepo) List(ctx context.Context) ([]*model.User, error) {
+	rows, err := r.db.QueryContext(ctx, userSelect+` ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	var users []*model.User
+	byID := map[string]*model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		users = append(users, u)
+		byID[u.ID] = u
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	chats, err := r.db.QueryContext(ctx, `SELECT chat_id, user_id FROM user_chats ORDER BY chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer chats.Close()
+	for chats.Next() {
+		var chatID int64
+		var userID string
+		if err := chats.Scan(&chatID, &userID); err != nil {
+			return nil, err
+		}
+		if u := byID[userID]; u != nil {
+			u.ChatIDs = append(u.ChatIDs, chatID)
+		}
+	}
+	return users, chats.Err()
+}
+
+func (r *sqliteUserRepo) Count(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n)
+	return n, err
+}
+
+func (r *sqliteUserRepo) SetRole(ctx context.Context, id string, role model.Role) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET role=? WHERE id=?`, string(role), id)
+	return err
+}
+
+func (r *sqliteUserRepo) SetPassword(ctx context.Context, id, passwordHash string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE users SET password_hash=? WHERE id=?`, passwordHash, id); err != nil {
+		return err
+	}
+	// Смена пароля завершает все сессии пользователя.
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id=?`, id)
+	return err
+}
+
+// Delete удаляет пользователя вместе с сессиями и привязками чатов.
+// Его задания остаются и становятся общими (owner_id = NULL).
+func (r *sqliteUserRepo) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	for _, q := range []string{
+		`UPDATE jobs SET owner_id=NULL WHERE owner_id=?`,
+		`UPDATE subscriptions SET owner_id=NULL WHERE owner_id=?`,
+		`DELETE FROM api_tokens WHERE user_id=?`,
+		`DELETE FROM sessions WHERE user_id=?`,
+		`DELETE FROM user_chats WHERE user_id=?`,
+		`DELETE FROM users WHERE id=?`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LinkChat привязывает Telegram-чат к пользователю (перепривязывает, если чат был чужим).
+func (r *sqliteUserRepo) LinkChat(ctx context.Context, userID string, chatID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_chats (chat_id, user_id) VALUES (?, ?)
+		 ON CONFLICT(chat_id) DO UPDATE SET user_id=excluded.user_id`, chatID, userID)
+	return err
+}
+
+func (r *sqliteUserRepo) UnlinkChat(ctx context.Context, chatID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_chats WHERE chat_id=?`, chatID)
+	return err
+}
+
+// CreateSession открывает сессию на ttl и возвращает секрет для cookie.
+func (r *sqliteUserRepo) CreateSession(ctx context.Context, userID string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+		hashSecret(secret), userID, now.Format(time.RFC3339Nano), now.Add(ttl).Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// SessionUser возвращает владельца действующей сессии.
+func (r *sqliteUserRepo) SessionUser(ctx context.Context, secret string) (*model.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		userSelect+` WHERE id=(SELECT user_id FROM sessions WHERE token_hash=? AND expires_at > ?)`,
+		hashSecret(secret), time.Now().UTC().Format(time.RFC3339Nano)))
+}
+
+func (r *sqliteUserRepo) DeleteSession(ctx context.Context, secret string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash=?`, hashSecret(secret))
+	return err
+}
+
+func scanUser(s scanner) (*model.User, error) {
+	var u model.User
+	var createdAt string
+	if err := s.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &createdAt); err != nil {
+		return nil, err
+	}
+	u.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	return &u, nil
+}
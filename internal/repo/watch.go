@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// Прогресс просмотра принадлежит элементу (у каждого пользователя свой), поэтому
+// его методы — часть ItemRepo.
+
+// SaveWatchPosition запоминает позицию плеера. Отметка «просмотрено» только
+// ставится: повторный просмотр с начала её не снимает; у просмотренного позиция — 0.
+func (r *sqliteItemRepo) SaveWatchPosition(ctx context.Context, userID, itemID string, position int, watched bool) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO watch_state (user_id, item_id, position, watched, updated_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(user_id, item_id) DO UPDATE SET
+		     position=excluded.position, watched=MAX(watched, excluded.watched), updated_at=excluded.updated_at`,
+		userID, itemID, watchPosition(position, watched), watched, time.Now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// SetWatched явно ставит или снимает отметку «просмотрено» и сбрасывает позицию.
+func (r *sqliteItemRepo) SetWatched(ctx context.Context, userID, itemID string, watched bool) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO watch_state (user_id, item_id, position, watched, updated_at) VALUES (?, ?, 0, ?, ?)
+		 ON CONFLICT(user_id, item_id) DO UPDATE SET
+		     position=0, watched=excluded.watched, updated_at=excluded.updated_at`,
+		userID, itemID, watched, time.Now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+func watchPosition(position int, watched bool) int {
+	if watched {
+		return 0
+	}
+	return position
+}
@@ -0,0 +1,70 @@
+package sse
+
+import "github.com/dr-duke/talmorGo/internal/model"
+
+// JobStatusData — payload события job.status.
+type JobStatusData struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Title  string `json:"title,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func JobStatusOf(j *model.Job) JobStatusData {
+	return JobStatusData{ID: j.ID, Status: string(j.Status), Title: j.Title, Error: j.Error}
+}
+
+// JobProgressData — payload события job.progress.
+type JobProgressData struct {
+	ID         string  `json:"id"`
+	Percent    float64 `json:"percent"`
+	Downloaded int64   `json:"downloaded"`
+	Total      int64   `json:"total"`
+	Speed      float64 `json:"speed"`
+	ETA        int     `json:"eta"`
+}
+
+func JobProgressOf(jobID string, p model.Progress) JobProgressData {
+	return JobProgressData{
+		ID: jobID, Percent: p.Percent, Downloaded: p.Downloaded,
+		Total: p.Total, Speed: p.Speed, ETA: p.ETA,
+	}
+}
+
+// ItemData — payload событий item.created / item.deleted.
+type ItemData struct {
+	ID    string `json:"id"`
+	JobID string `json:"job_id,omitempty"`
+	Kind  string `json:"kind,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+func ItemOf(it *model.Item) ItemData {
+	return ItemData{ID: it.ID, JobID: it.JobID, Kind: it.Kind, Name: it.Name}
+}
+
+// OperationData — payload событий operation.started / operation.finished.
+type OperationData struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// OperationProgressData — payload события operation.progress.
+type OperationProgressData struct {
+	ID      string  `json:"id"`
+	Percent float64 `json:"percent"`
+	Text    string  `json:"text,omitempty"` // напр. «2 из 5» для пакетной операции
+}
+
+// CollectionData — payload события collection.changed.
+type CollectionData struct {
+	ID     string `json:"id,omitempty"`
+	Action string `json:"action"` // created | renamed | deleted | jobs_added
+}
+
+// QueueData — payload события queue.state: вся очередь поставлена на паузу или снята с неё.
+type QueueData struct {
+	Paused bool `json:"paused"`
+}
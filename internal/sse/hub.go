@@ -1,43 +1,96 @@
-// Package sse реализует простой pub/sub-хаб для Server-Sent Events.
-// Воркер и обработчики вызывают Broadcast() при изменении состояния заданий;
-// подключённые браузерные вкладки получают событие и перезапрашивают список.
+// Package sse реализует pub/sub-хаб для Server-Sent Events.
+// Воркер, ops-воркер и обработчики публикуют типизированные события с JSON-payload;
+// подключённые браузерные вкладки обновляют только затронутые фрагменты.
 package sse
 
-import "sync"
+import (
+	"strings"
+	"sync"
+)
 
-// Hub рассылает сигнал об изменениях всем подключённым SSE-клиентам.
+// Типы событий.
+const (
+	JobStatus         = "job.status"
+	JobProgress       = "job.progress"
+	ItemCreated       = "item.created"
+	ItemDeleted       = "item.deleted"
+	OperationStarted  = "operation.started"
+	OperationProgress = "operation.progress"
+	OperationFinished = "operation.finished"
+	CollectionChanged = "collection.changed"
+	QueueState        = "queue.state"
+)
+
+// clientBuffer — сколько событий может накопиться у медленного клиента;
+// дальше события для него отбрасываются.
+const clientBuffer = 32
+
+// Event — типизированное событие; Data сериализуется в JSON.
+type Event struct {
+	Type string
+	Data any
+}
+
+type client struct {
+	ch     chan Event
+	topics []string // пусто — все события
+}
+
+// wants проверяет подписку: топик совпадает с типом целиком ("job.status")
+// или с его группой ("job" → job.status, job.progress).
+func (c *client) wants(typ string) bool {
+	if len(c.topics) == 0 {
+		return true
+	}
+	group, _, _ := strings.Cut(typ, ".")
+	for _, t := range c.topics {
+		if t == typ || t == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Hub рассылает события всем подключённым SSE-клиентам.
 type Hub struct {
 	mu      sync.Mutex
-	clients map[chan struct{}]struct{}
+	clients map[*client]struct{}
 }
 
 func New() *Hub {
-	return &Hub{clients: make(map[chan struct{}]struct{})}
+	return &Hub{clients: make(map[*client]struct{})}
 }
 
-// Subscribe регистрирует нового клиента.
-// Возвращает канал (буферизован на 1) и функцию отписки.
-func (h *Hub) Subscribe() (<-chan struct{}, func()) {
-	ch := make(chan struct{}, 1)
+// Subscribe регистрирует нового клиента, опционально только на указанные топики.
+// Возвращает канал событий и функцию отписки.
+func (h *Hub) Subscribe(topics ...string) (<-chan Event, func()) {
+	c := &client{ch: make(chan Event, clientBuffer), topics: topics}
 	h.mu.Lock()
-	h.clients[ch] = struct{}{}
+	h.clients[c] = struct{}{}
 	h.mu.Unlock()
-	return ch, func() {
+	return c.ch, func() {
 		h.mu.Lock()
-		delete(h.clients, ch)
+		delete(h.clients, c)
 		h.mu.Unlock()
-		close(ch)
+		close(c.ch)
 	}
 }
 
-// Broadcast отправляет сигнал всем подключённым клиентам (non-blocking).
-// Если клиент не успел прочитать предыдущий сигнал — новый не дублируется.
-func (h *Hub) Broadcast() {
+// Publish отправляет событие подписанным клиентам (non-blocking).
+// Безопасен для nil-хаба — тогда событие просто отбрасывается.
+func (h *Hub) Publish(typ string, data any) {
+	if h == nil {
+		return
+	}
+	e := Event{Type: typ, Data: data}
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	for ch := range h.clients {
+	for c := range h.clients {
+		if !c.wants(typ) {
+			continue
+		}
 		select {
-		case ch <- struct{}{}:
+		case c.ch <- e:
 		default:
 		}
 	}
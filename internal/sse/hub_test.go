@@ -0,0 +1,32 @@
+package sse
+
+import "testing"
+
+// TestHub_Topics проверяет фильтрацию событий по топикам подписки.
+func TestHub_Topics(t *testing.T) {
+	h := New()
+	all, unsubAll := h.Subscribe()
+	defer unsubAll()
+	jobs, unsubJobs := h.Subscribe("job")
+	defer unsubJobs()
+	cols, unsubCols := h.Subscribe(CollectionChanged)
+	defer unsubCols()
+
+	h.Publish(JobProgress, JobProgressData{ID: "j1"})
+	h.Publish(CollectionChanged, CollectionData{Action: "created"})
+
+	if n := len(all); n != 2 {
+		t.Errorf("all: got %d events, want 2", n)
+	}
+	if n := len(jobs); n != 1 {
+		t.Errorf("job: got %d events, want 1", n)
+	} else if e := <-jobs; e.Type != JobProgress {
+		t.Errorf("job: got %s, want %s", e.Type, JobProgress)
+	}
+	if n := len(cols); n != 1 {
+		t.Errorf("collection.changed: got %d events, want 1", n)
+	}
+
+	var nilHub *Hub
+	nilHub.Publish(JobStatus, nil) // не должно паниковать
+}
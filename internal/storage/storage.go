@@ -5,16 +5,37 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/subtitle"
+	"github.com/dr-duke/talmorGo/internal/thumb"
 )
 
 type Storage struct {
 	root string
+
+	// Производные файлы элементов — удаляются вместе с ними (DeleteItem).
+	thumbDir string
+	subsDir  string
+	hls      HLSCache
+}
+
+// HLSCache — кеш перекодированных вариантов (*hls.Cache).
+type HLSCache interface {
+	Remove(itemID string)
 }
 
 func New(root string) *Storage {
 	return &Storage{root: root}
 }
 
+// SetItemDirs задаёт каталоги превью и субтитров (Config.ThumbDir, Config.SubsDir).
+func (s *Storage) SetItemDirs(thumbDir, subsDir string) {
+	s.thumbDir, s.subsDir = thumbDir, subsDir
+}
+
+func (s *Storage) SetHLS(c HLSCache) { s.hls = c }
+
 // ErrInvalidName возвращается, когда новое имя файла содержит разделители пути
 // или попытку выхода за пределы каталога (path traversal).
 var ErrInvalidName = fmt.Errorf("invalid file name")
@@ -27,6 +48,34 @@ func (s *Storage) Delete(path string) error {
 	return nil
 }
 
+// DeleteItem удаляет файл элемента вместе с превью, субтитрами и вариантами HLS.
+// Файл удалённого или потерянного элемента не трогается: по его пути мог появиться другой.
+func (s *Storage) DeleteItem(item *model.Item) error {
+	var err error
+	if item.IsAvailable() {
+		err = s.Delete(item.Path)
+	}
+	s.DeleteDerived(item.ID)
+	return err
+}
+
+// DeleteDerived удаляет только производные файлы элемента. Ошибки не возвращаются:
+// оставшееся превью или субтитры ничему не мешают.
+func (s *Storage) DeleteDerived(itemID string) {
+	if itemID == "" {
+		return
+	}
+	if s.thumbDir != "" {
+		s.Delete(thumb.Path(s.thumbDir, itemID)) //nolint:errcheck
+	}
+	if s.subsDir != "" {
+		subtitle.RemoveAll(s.subsDir, itemID) //nolint:errcheck
+	}
+	if s.hls != nil {
+		s.hls.Remove(itemID)
+	}
+}
+
 // Rename переименовывает файл в пределах его текущего каталога. Возвращает новый путь.
 // newName должно быть «чистым» именем файла без разделителей пути — иначе ErrInvalidName.
 // Это защищает от path traversal (напр. "../../etc/passwd").
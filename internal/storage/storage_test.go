@@ -5,6 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/subtitle"
+	"github.com/dr-duke/talmorGo/internal/thumb"
 )
 
 func TestRename_RejectsTraversal(t *testing.T) {
@@ -63,3 +68,64 @@ func TestDelete_MissingFileIsNoError(t *testing.T) {
 		t.Errorf("Delete of missing file should be nil, got %v", err)
 	}
 }
+
+type fakeHLS struct{ removed []string }
+
+func (f *fakeHLS) Remove(itemID string) { f.removed = append(f.removed, itemID) }
+
+// TestDeleteItem_RemovesDerivedFiles проверяет, что при удалении элемента (purge,
+// очистка, мягкое удаление) вместе с файлом уходят превью, субтитры и кеш HLS.
+func TestDeleteItem_RemovesDerivedFiles(t *testing.T) {
+	dir := t.TempDir()
+	thumbDir, subsDir := filepath.Join(dir, ".talmor-thumbs"), filepath.Join(dir, ".talmor-subs")
+	hls := &fakeHLS{}
+	s := New(dir)
+	s.SetItemDirs(thumbDir, subsDir)
+	s.SetHLS(hls)
+
+	item := &model.Item{ID: "item1", Path: filepath.Join(dir, "video.mp4")}
+	other := subtitle.Path(subsDir, "item10", "en")
+	files := []string{
+		item.Path,
+		thumb.Path(thumbDir, item.ID),
+		subtitle.Path(subsDir, item.ID, "en"),
+		subtitle.Path(subsDir, item.ID, "ru"),
+		other,
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.DeleteItem(item); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	for _, f := range files[:4] {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s not removed: %v", f, err)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("subtitles of another item removed: %v", err)
+	}
+	if len(hls.removed) != 1 || hls.removed[0] != item.ID {
+		t.Errorf("HLS removed: %v", hls.removed)
+	}
+
+	// У мягко удалённого элемента файл по старому пути не трогается.
+	now := time.Now()
+	reused := &model.Item{ID: "item2", Path: filepath.Join(dir, "reused.mp4"), DeletedAt: &now}
+	if err := os.WriteFile(reused.Path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteItem(reused); err != nil {
+		t.Fatalf("DeleteItem deleted: %v", err)
+	}
+	if _, err := os.Stat(reused.Path); err != nil {
+		t.Errorf("file of deleted item removed: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package subtitle хранит субтитры элементов в WebVTT — единственном формате,
+// который понимает плеер.
+package subtitle
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Path — файл субтитров элемента на языке lang в каталоге dir (Config.SubsDir).
+func Path(dir, itemID, lang string) string {
+	return filepath.Join(dir, itemID+"."+lang+".vtt")
+}
+
+// RemoveAll удаляет субтитры элемента на всех языках.
+func RemoveAll(dir, itemID string) error {
+	files, err := filepath.Glob(filepath.Join(dir, itemID+".*.vtt"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// srtTime — таймкод SubRip: в WebVTT миллисекунды отделяются точкой, а не запятой.
+var srtTime = regexp.MustCompile(`(\d{2}:\d{2}:\d{2}),(\d{3})`)
+
+// ToVTT конвертирует SubRip в WebVTT. Номера реплик остаются — в WebVTT это идентификаторы.
+func ToVTT(srt []byte) []byte {
+	srt = bytes.TrimPrefix(srt, []byte("\ufeff"))
+	srt = bytes.ReplaceAll(srt, []byte("\r\n"), []byte("\n"))
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n\n")
+	b.Write(srtTime.ReplaceAll(bytes.TrimSpace(srt), []byte("$1.$2")))
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+// Save записывает субтитры src в dst: .srt конвертируется, WebVTT копируется как есть.
+func Save(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(src)) {
+	case ".vtt":
+	case ".srt":
+		data = ToVTT(data)
+	default:
+		return fmt.Errorf("unsupported subtitle format: %s", filepath.Ext(src))
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("create subs dir: %w", err)
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
+
+// Extract извлекает дорожку субтитров (индекс потока в файле) в WebVTT.
+func Extract(ctx context.Context, ffmpegBin, src string, stream int, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("create subs dir: %w", err)
+	}
+	out, err := exec.CommandContext(ctx, ffmpegBin,
+		"-i", src, "-map", "0:"+strconv.Itoa(stream), "-f", "webvtt", "-y", dst,
+	).CombinedOutput()
+	if err != nil {
+		os.Remove(dst) //nolint:errcheck
+		return fmt.Errorf("ffmpeg subtitles: %w: %s", err, tail(string(out), 300))
+	}
+	return nil
+}
+
+// cueTag — разметка внутри реплики: <c.colorE5E5E5>, <00:00:01.500>, <i>.
+var cueTag = regexp.MustCompile(`<[^>]*>`)
+
+// Text — текст реплик WebVTT для поиска, по строке на реплику: без заголовка,
+// таймкодов, идентификаторов и разметки. Строки, повторяющие предыдущую
+// реплику (так устроены автоматические субтитры YouTube), пропускаются.
+func Text(vtt []byte) string {
+	vtt = bytes.ReplaceAll(vtt, []byte("\r\n"), []byte("\n"))
+	var out []string
+	var prev, cur []string
+	for _, block := range strings.Split(string(vtt), "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := slices.IndexFunc(lines, func(l string) bool { return strings.Contains(l, "-->") })
+		if timing < 0 {
+			continue // WEBVTT, NOTE, STYLE, REGION
+		}
+		prev, cur = cur, cur[:0:0]
+		for _, l := range lines[timing+1:] {
+			l = strings.Join(strings.Fields(html.UnescapeString(cueTag.ReplaceAllString(l, ""))), " ")
+			if l == "" {
+				continue
+			}
+			cur = append(cur, l)
+			if !slices.Contains(prev, l) {
+				out = append(out, l)
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// ReadText — Text файла субтитров; непрочитанный файл даёт пустой текст.
+func ReadText(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return Text(data)
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
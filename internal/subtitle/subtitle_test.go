@@ -0,0 +1,53 @@
+package subtitle_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dr-duke/talmorGo/internal/subtitle"
+)
+
+func TestToVTT(t *testing.T) {
+	srt := "\ufeff1\r\n00:00:01,500 --> 00:00:03,000\r\nПривет\r\n\r\n2\r\n00:01:02,000 --> 01:00:00,250\r\nмир, 12,345\r\n"
+	want := "WEBVTT\n\n1\n00:00:01.500 --> 00:00:03.000\nПривет\n\n2\n00:01:02.000 --> 01:00:00.250\nмир, 12,345\n"
+	if got := string(subtitle.ToVTT([]byte(srt))); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "clip.en.srt")
+	if err := os.WriteFile(src, []byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dst := subtitle.Path(filepath.Join(dir, "subs"), "item", "en")
+	if err := subtitle.Save(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nHi\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	ass := filepath.Join(dir, "clip.en.ass")
+	if err := os.WriteFile(ass, []byte("[Script Info]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := subtitle.Save(ass, dst); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestText(t *testing.T) {
+	vtt := "WEBVTT\r\nKind: captions\r\n\r\nNOTE заметка\r\n\r\n" +
+		"1\r\n00:00:01.000 --> 00:00:02.000 align:start\r\n<c.colorE5E5E5>Привет,</c> <i>мир</i>\r\n\r\n" +
+		"00:00:02.000 --> 00:00:04.000\nПривет, мир\nкак  дела &amp; новости\n\n" +
+		"00:00:04.000 --> 00:00:05.000\nкак дела &amp; новости\n<00:00:04.500><c>пока</c>\n"
+	if got, want := subtitle.Text([]byte(vtt)), "Привет, мир\nкак дела & новости\nпока"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
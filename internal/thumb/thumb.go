@@ -0,0 +1,75 @@
+// Package thumb делает превью элементов медиатеки через ffmpeg.
+package thumb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+)
+
+// scale — ширина превью; высота по пропорциям (чётная — требование кодеков).
+const scale = "scale=320:-2"
+
+// Path — файл превью элемента в каталоге dir (Config.ThumbDir).
+func Path(dir, itemID string) string {
+	return filepath.Join(dir, itemID+".jpg")
+}
+
+// FromImage уменьшает картинку (обложку, записанную yt-dlp) до превью.
+func FromImage(ctx context.Context, ffmpegBin, src, dst string) error {
+	return run(ctx, ffmpegBin, dst, "-i", src, "-frames:v", "1", "-vf", scale)
+}
+
+// Make делает превью из самого файла: у видео — кадр на 10% длительности
+// (длительность неизвестна — на 10-й секунде, а у короткого ролика — первый кадр),
+// у аудио — встроенная обложка.
+func Make(ctx context.Context, ffmpegBin string, item *model.Item, dst string) error {
+	if item.IsAudio() {
+		return run(ctx, ffmpegBin, dst, "-i", item.Path, "-an", "-map", "0:v:0", "-frames:v", "1", "-vf", scale)
+	}
+	at := 10.0
+	if item.Duration > 0 {
+		at = float64(item.Duration) / 10
+	}
+	err := frame(ctx, ffmpegBin, item.Path, dst, at)
+	if err != nil && at > 0 {
+		// За пределами файла ffmpeg не пишет кадр — берём первый.
+		err = frame(ctx, ffmpegBin, item.Path, dst, 0)
+	}
+	return err
+}
+
+func frame(ctx context.Context, ffmpegBin, src, dst string, at float64) error {
+	return run(ctx, ffmpegBin, dst,
+		"-ss", strconv.FormatFloat(at, 'f', 1, 64), "-i", src, "-frames:v", "1", "-vf", scale)
+}
+
+// run запускает ffmpeg и проверяет, что превью действительно записано.
+func run(ctx context.Context, ffmpegBin, dst string, args ...string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("create thumb dir: %w", err)
+	}
+	os.Remove(dst) //nolint:errcheck
+	args = append(args, "-q:v", "4", "-y", dst)
+	out, err := exec.CommandContext(ctx, ffmpegBin, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg thumb: %w: %s", err, tail(string(out), 300))
+	}
+	if st, err := os.Stat(dst); err != nil || st.Size() == 0 {
+		os.Remove(dst) //nolint:errcheck
+		return fmt.Errorf("ffmpeg thumb: no frame written")
+	}
+	return nil
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
@@ -0,0 +1,67 @@
+package thumb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/thumb"
+)
+
+// fakeFFmpeg пишет аргументы каждого вызова в лог и создаёт выходной файл (последний
+// аргумент) — кроме кадра на 30-й секунде: так ведёт себя ffmpeg, если ролик короче.
+func fakeFFmpeg(t *testing.T, dir string) (bin, log string) {
+	t.Helper()
+	bin = filepath.Join(dir, "ffmpeg")
+	log = filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+echo "$@" >> ` + log + `
+for a; do out="$a"; done
+case "$*" in *"-ss 30.0 "*) exit 0 ;; esac
+echo jpg > "$out"
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return bin, log
+}
+
+func TestMake(t *testing.T) {
+	dir := t.TempDir()
+	bin, log := fakeFFmpeg(t, dir)
+	ctx := context.Background()
+
+	// Кадр на 10% длительности.
+	dst := thumb.Path(filepath.Join(dir, "thumbs"), "a")
+	if err := thumb.Make(ctx, bin, &model.Item{Kind: "video", Path: "/v.mp4", Duration: 200}, dst); err != nil {
+		t.Fatalf("make: %v", err)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Errorf("thumb not written: %v", err)
+	}
+
+	// Кадр за пределами файла не записан — повтор с первым кадром.
+	if err := thumb.Make(ctx, bin, &model.Item{Kind: "video", Path: "/v.mp4", Duration: 300}, dst); err != nil {
+		t.Fatalf("make fallback: %v", err)
+	}
+
+	// Аудио — встроенная обложка.
+	if err := thumb.Make(ctx, bin, &model.Item{Kind: "audio", Path: "/a.mp3"}, dst); err != nil {
+		t.Fatalf("make audio: %v", err)
+	}
+
+	b, _ := os.ReadFile(log)
+	calls := strings.Split(strings.TrimSpace(string(b)), "\n")
+	want := []string{"-ss 20.0 -i /v.mp4", "-ss 30.0 -i /v.mp4", "-ss 0.0 -i /v.mp4", "-i /a.mp3 -an -map 0:v:0"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls: got %d, want %d:\n%s", len(calls), len(want), b)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(calls[i], w) {
+			t.Errorf("call %d: got %q, want prefix %q", i, calls[i], w)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package transcode перекодирует и перепаковывает файлы медиатеки через ffmpeg.
+package transcode
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Preset — готовый набор параметров ffmpeg.
+type Preset struct {
+	ID     string
+	Title  string
+	Ext    string   // расширение результата
+	Suffix string   // приписка к имени файла: «name (720p).mp4»
+	Args   []string // параметры ffmpeg между входом и выходом
+}
+
+// Presets — доступные пресеты в порядке показа в UI.
+var Presets = []Preset{
+	{
+		ID: "h264_720", Title: "H.264 720p (MP4)", Ext: ".mp4", Suffix: "720p",
+		Args: []string{
+			"-map", "0:v:0", "-map", "0:a?",
+			"-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p",
+			"-vf", "scale=-2:'trunc(min(720,ih)/2)*2'", // высота чётная — требование yuv420p
+			"-c:a", "aac", "-b:a", "160k", "-movflags", "+faststart",
+		},
+	},
+	{
+		ID: "hevc_archive", Title: "HEVC, архив (MKV)", Ext: ".mkv", Suffix: "hevc",
+		Args: []string{
+			"-map", "0:v:0", "-map", "0:a?",
+			"-c:v", "libx265", "-preset", "slow", "-crf", "26", "-c:a", "copy",
+		},
+	},
+	{
+		ID: "remux_mp4", Title: "Сменить контейнер на MP4", Ext: ".mp4", Suffix: "remux",
+		Args: []string{"-map", "0:v:0?", "-map", "0:a?", "-c", "copy", "-movflags", "+faststart"},
+	},
+	{
+		ID: "remux_mkv", Title: "Сменить контейнер на MKV", Ext: ".mkv", Suffix: "remux",
+		Args: []string{"-map", "0", "-c", "copy"},
+	},
+}
+
+// Find ищет пресет по ID.
+func Find(id string) (Preset, bool) {
+	for _, p := range Presets {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// FreePath подбирает в dir ещё не занятое имя stem+ext: «stem (2).ext», «stem (3).ext»…
+func FreePath(dir, stem, ext string) string {
+	path := filepath.Join(dir, stem+ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+	}
+}
+
+// Run перекодирует src в dst по пресету. Файл пишется во временный рядом с dst
+// (с точкой в имени — DirScanner его не видит) и переименовывается по готовности.
+// onProgress получает долю готового от 0 до 1, если известна длительность (секунды).
+func Run(ctx context.Context, ffmpegBin, src, dst string, p Preset, duration int, onProgress func(float64)) error {
+	ext := filepath.Ext(dst)
+	tmp := filepath.Join(filepath.Dir(dst), "."+strings.TrimSuffix(filepath.Base(dst), ext)+".part"+ext)
+
+	args := []string{"-hide_banner", "-nostats", "-progress", "pipe:1", "-i", src}
+	args = append(args, p.Args...)
+	args = append(args, "-y", tmp)
+	cmd := exec.CommandContext(ctx, ffmpegBin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ffmpeg transcode: %w", err)
+	}
+	readProgress(stdout, duration, onProgress)
+	if err := cmd.Wait(); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return fmt.Errorf("ffmpeg transcode: %w: %s", err, tail(stderr.String(), 300))
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return fmt.Errorf("move transcoded file: %w", err)
+	}
+	return nil
+}
+
+// readProgress разбирает вывод -progress: блоки key=value, где out_time_us —
+// позиция в микросекундах (out_time_ms у старых ffmpeg — тоже микросекунды),
+// а progress=end закрывает последний блок.
+func readProgress(r io.Reader, duration int, onProgress func(float64)) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		key, val, _ := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if onProgress == nil || duration <= 0 {
+			continue
+		}
+		switch key {
+		case "out_time_us", "out_time_ms":
+			us, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || us < 0 {
+				continue
+			}
+			onProgress(min(float64(us)/1e6/float64(duration), 1))
+		case "progress":
+			if val == "end" {
+				onProgress(1)
+			}
+		}
+	}
+	io.Copy(io.Discard, r) //nolint:errcheck // не блокировать ffmpeg, если сканер остановился на длинной строке
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
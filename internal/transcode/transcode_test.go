@@ -0,0 +1,71 @@
+package transcode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFFmpeg выводит прогресс в stdout и пишет выходной файл (последний аргумент);
+// для файла "bad.mkv" падает.
+func fakeFFmpeg(t *testing.T, dir string) string {
+	t.Helper()
+	bin := filepath.Join(dir, "ffmpeg")
+	script := `#!/bin/sh
+case "$*" in *bad.mkv*) echo "invalid data" >&2; exit 1 ;; esac
+for a; do out="$a"; done
+printf 'frame=10\nout_time_us=5000000\nprogress=continue\nout_time_us=N/A\nout_time_us=10000000\nprogress=end\n'
+echo video > "$out"
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	bin := fakeFFmpeg(t, dir)
+	p, ok := Find("h264_720")
+	if !ok {
+		t.Fatal("preset not found")
+	}
+
+	dst := filepath.Join(dir, "out.mp4")
+	var got []float64
+	if err := Run(context.Background(), bin, "/v/a.mkv", dst, p, 20, func(f float64) { got = append(got, f) }); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if want := []float64{0.25, 0.5, 1}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("progress %v, want %v", got, want)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "video\n" {
+		t.Errorf("output: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".out.part.mp4")); !os.IsNotExist(err) {
+		t.Errorf("temp file left: %v", err)
+	}
+
+	err := Run(context.Background(), bin, "/v/bad.mkv", filepath.Join(dir, "bad.mp4"), p, 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid data") {
+		t.Errorf("failed transcode: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.mp4")); !os.IsNotExist(err) {
+		t.Errorf("output of failed transcode: %v", err)
+	}
+}
+
+func TestFreePath(t *testing.T) {
+	dir := t.TempDir()
+	if got := FreePath(dir, "a (720p)", ".mp4"); got != filepath.Join(dir, "a (720p).mp4") {
+		t.Errorf("free: %q", got)
+	}
+	for _, name := range []string{"a (720p).mp4", "a (720p) (2).mp4"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0o644) //nolint:errcheck
+	}
+	if got := FreePath(dir, "a (720p)", ".mp4"); got != filepath.Join(dir, "a (720p) (3).mp4") {
+		t.Errorf("taken: %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"io/fs"
 	"log/slog"
 	"os"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/ops"
+	"github.com/dr-duke/talmorGo/internal/probe"
 	"github.com/dr-duke/talmorGo/internal/repo"
 )
 
@@ -39,6 +42,9 @@ type DirScanner struct {
 	dir      string
 	interval time.Duration
 	inFlight *InFlightPaths
+	ops      repo.OperationRepo
+	enqueue  func()
+	ffprobe  string
 }
 
 func NewDirScanner(jobs repo.JobRepo, items repo.ItemRepo, dir string, intervalSec int, inFlight *InFlightPaths) *DirScanner {
@@ -51,6 +57,15 @@ func NewDirScanner(jobs repo.JobRepo, items repo.ItemRepo, dir string, intervalS
 	}
 }
 
+// SetOps включает превью для найденных файлов: после сканирования ставится
+// фоновая операция ops.KindThumbnails, enqueue будит её исполнителя.
+func (s *DirScanner) SetOps(opRepo repo.OperationRepo, enqueue func()) {
+	s.ops, s.enqueue = opRepo, enqueue
+}
+
+// SetFFprobe включает чтение длительности и параметров найденных файлов.
+func (s *DirScanner) SetFFprobe(bin string) { s.ffprobe = bin }
+
 func (s *DirScanner) Start(ctx context.Context) {
 	if s.interval == 0 {
 		return
@@ -75,7 +90,7 @@ func (s *DirScanner) scan(ctx context.Context) {
 		return
 	}
 
-	imported := 0
+	var imported []string
 	walkErr := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
@@ -105,10 +120,10 @@ func (s *DirScanner) scan(ctx context.Context) {
 		if infoErr != nil {
 			return nil
 		}
-		if importErr := s.importFile(ctx, path, d.Name(), info.Size()); importErr != nil {
+		if itemID, importErr := s.importFile(ctx, path, d.Name(), info.Size()); importErr != nil {
 			slog.Error("dir-scanner: import file", "path", path, "err", importErr)
 		} else {
-			imported++
+			imported = append(imported, itemID)
 			known[path] = struct{}{}
 		}
 		return nil
@@ -116,20 +131,41 @@ func (s *DirScanner) scan(ctx context.Context) {
 	if walkErr != nil && !os.IsNotExist(walkErr) {
 		slog.Error("dir-scanner: walk", "dir", s.dir, "err", walkErr)
 	}
-	if imported > 0 {
-		slog.Info("dir-scanner: imported", "files", imported, "dir", s.dir)
+	if len(imported) > 0 {
+		slog.Info("dir-scanner: imported", "files", len(imported), "dir", s.dir)
+		s.queueThumbnails(ctx, imported)
+	}
+}
+
+func (s *DirScanner) queueThumbnails(ctx context.Context, itemIDs []string) {
+	if s.ops == nil {
+		return
+	}
+	payload, _ := json.Marshal(map[string][]string{"item_ids": itemIDs})
+	op := &model.Operation{
+		Kind:    ops.KindThumbnails,
+		Title:   "Превью найденных файлов",
+		Payload: string(payload),
+	}
+	if err := s.ops.Create(ctx, op); err != nil {
+		slog.Error("dir-scanner: create thumbnails op", "err", err)
+		return
 	}
+	s.enqueue()
 }
 
-func (s *DirScanner) importFile(ctx context.Context, path, name string, size int64) error {
+func (s *DirScanner) importFile(ctx context.Context, path, name string, size int64) (string, error) {
 	job := &model.Job{
 		URL:    "local",
 		Title:  name,
 		Status: model.JobImported,
 		Source: "filesystem",
+		// Id из имени («Title [id].mp4»); экстрактор неизвестен, поэтому дубликатом
+		// по нему файл считается только для заданий того же домена.
+		VideoID: model.VideoIDFromName(name),
 	}
 	if err := s.jobs.Create(ctx, job); err != nil {
-		return err
+		return "", err
 	}
 	item := &model.Item{
 		JobID: job.ID,
@@ -138,5 +174,15 @@ func (s *DirScanner) importFile(ctx context.Context, path, name string, size int
 		Name:  name,
 		Size:  size,
 	}
-	return s.items.Create(ctx, item)
+	if s.ffprobe != "" {
+		if pr, err := probe.File(ctx, s.ffprobe, path); err == nil {
+			pr.Apply(item)
+		} else {
+			slog.Warn("dir-scanner: probe", "path", path, "err", err)
+		}
+	}
+	if err := s.items.Create(ctx, item); err != nil {
+		return "", err
+	}
+	return item.ID, nil
 }
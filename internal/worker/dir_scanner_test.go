@@ -4,9 +4,11 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dr-duke/talmorGo/internal/db"
+	"github.com/dr-duke/talmorGo/internal/ops"
 	"github.com/dr-duke/talmorGo/internal/repo"
 )
 
@@ -84,6 +86,42 @@ func TestDirScanner_SkipsInFlight(t *testing.T) {
 	}
 }
 
+// TestDirScanner_QueuesThumbnails проверяет, что найденные файлы уходят
+// в одну фоновую операцию превью, а повторное сканирование её не дублирует.
+func TestDirScanner_QueuesThumbnails(t *testing.T) {
+	tmp := t.TempDir()
+	database, err := db.Open(filepath.Join(tmp, "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+
+	items := repo.NewItemRepo(database)
+	opRepo := repo.NewOperationRepo(database)
+	for _, name := range []string{"a.mp4", "b.mp3"} {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	woken := 0
+	s := NewDirScanner(repo.NewJobRepo(database), items, tmp, 0, NewInFlightPaths())
+	s.SetOps(opRepo, func() { woken++ })
+	s.scan(context.Background())
+	s.scan(context.Background())
+
+	op, err := opRepo.ClaimNext(context.Background())
+	if err != nil || op == nil {
+		t.Fatalf("claim op: %v, %v", op, err)
+	}
+	if op.Kind != ops.KindThumbnails || !strings.Contains(op.Payload, "item_ids") {
+		t.Errorf("op: %s %s", op.Kind, op.Payload)
+	}
+	if next, _ := opRepo.ClaimNext(context.Background()); next != nil || woken != 1 {
+		t.Errorf("expected one op and one wakeup, got extra op %v, woken %d", next, woken)
+	}
+}
+
 func TestMoveFile(t *testing.T) {
 	tmp := t.TempDir()
 	src := filepath.Join(tmp, "src.mp4")
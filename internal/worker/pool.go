@@ -15,8 +15,11 @@ import (
 	"github.com/dr-duke/talmorGo/internal/config"
 	"github.com/dr-duke/talmorGo/internal/downloader"
 	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/probe"
 	"github.com/dr-duke/talmorGo/internal/repo"
 	"github.com/dr-duke/talmorGo/internal/sse"
+	"github.com/dr-duke/talmorGo/internal/subtitle"
+	"github.com/dr-duke/talmorGo/internal/thumb"
 )
 
 type NotifKind uint8
@@ -27,6 +30,16 @@ const (
 	NotifJobDone
 	NotifJobFailed
 	NotifJobRetrying
+	NotifJobProgress
+)
+
+const (
+	// progressBroadcastEvery — как часто прогресс job'а рассылается в SSE.
+	progressBroadcastEvery = time.Second
+	// progressNotifyEvery — как часто редактируется сообщение в Telegram (лимиты API).
+	progressNotifyEvery = 10 * time.Second
+	// scheduleCheckEvery — как часто пул сверяет скорость идущих загрузок с окном расписания.
+	scheduleCheckEvery = time.Minute
 )
 
 type Notification struct {
@@ -39,6 +52,8 @@ type Notification struct {
 	Token     string
 	ErrText   string
 	RetryAt   string
+	ErrClass  model.ErrorClass
+	Progress  *model.Progress
 }
 
 type Notifier interface {
@@ -51,13 +66,27 @@ type Pool struct {
 	itemRepo     repo.ItemRepo
 	tokenRepo    repo.TokenRepo
 	settingsRepo repo.SettingsRepo
+	presetRepo   repo.PresetRepo
+	ruleRepo     repo.DomainRuleRepo
 	notifier     Notifier
 	notify       chan struct{}
 	inFlight     *InFlightPaths
 	hub          *sse.Hub
 
-	mu          sync.Mutex
-	cancelFuncs map[string]context.CancelFunc
+	mu           sync.Mutex
+	ytdlpVersion string // определяется при старте, пишется в историю попыток
+	cancelFuncs  map[string]context.CancelFunc
+	pausing      map[string]bool   // job'ы, прерванные паузой, а не отменой
+	limits       map[string]string // --limit-rate, с которым запущен каждый job
+	relimiting   map[string]bool   // job'ы, прерванные сменой окна: вернутся в очередь
+	progress     map[string]*jobProgress
+}
+
+// jobProgress — последний известный прогресс job'а и время последних рассылок.
+type jobProgress struct {
+	model.Progress
+	broadcastAt time.Time
+	notifiedAt  time.Time
 }
 
 func NewPool(cfg *config.Config, jobRepo repo.JobRepo, itemRepo repo.ItemRepo, tokenRepo repo.TokenRepo, notifier Notifier) *Pool {
@@ -69,17 +98,22 @@ func NewPool(cfg *config.Config, jobRepo repo.JobRepo, itemRepo repo.ItemRepo, t
 		notifier:    notifier,
 		notify:      make(chan struct{}, cfg.WorkerCount),
 		cancelFuncs: make(map[string]context.CancelFunc),
+		pausing:     make(map[string]bool),
+		limits:      make(map[string]string),
+		relimiting:  make(map[string]bool),
+		progress:    make(map[string]*jobProgress),
 		inFlight:    NewInFlightPaths(),
 	}
 }
 
-func (p *Pool) SetHub(h *sse.Hub)                    { p.hub = h }
-func (p *Pool) SetSettingsRepo(sr repo.SettingsRepo) { p.settingsRepo = sr }
+func (p *Pool) SetHub(h *sse.Hub)                       { p.hub = h }
+func (p *Pool) SetSettingsRepo(sr repo.SettingsRepo)    { p.settingsRepo = sr }
+func (p *Pool) SetPresetRepo(pr repo.PresetRepo)        { p.presetRepo = pr }
+func (p *Pool) SetDomainRuleRepo(r repo.DomainRuleRepo) { p.ruleRepo = r }
 
-func (p *Pool) broadcast() {
-	if p.hub != nil {
-		p.hub.Broadcast()
-	}
+// publishStatus сообщает SSE-клиентам текущий статус job'а.
+func (p *Pool) publishStatus(job *model.Job) {
+	p.hub.Publish(sse.JobStatus, sse.JobStatusOf(job))
 }
 
 func (p *Pool) InFlight() *InFlightPaths { return p.inFlight }
@@ -97,6 +131,151 @@ func (p *Pool) CancelJob(jobID string) bool {
 	return ok
 }
 
+// PauseJob прерывает скачиваемый job так, что он переходит в paused, а недокачанный
+// файл остаётся в staging для продолжения. Возвращает true если job был running.
+func (p *Pool) PauseJob(jobID string) bool {
+	p.mu.Lock()
+	fn, ok := p.cancelFuncs[jobID]
+	if ok {
+		delete(p.cancelFuncs, jobID)
+		p.pausing[jobID] = true
+	}
+	p.mu.Unlock()
+	if ok {
+		fn()
+	}
+	return ok
+}
+
+// Paused сообщает, поставлена ли очередь на паузу в настройках: воркеры не берут
+// новые задания, уже скачиваемые докачиваются.
+func (p *Pool) Paused(ctx context.Context) bool {
+	if p.settingsRepo == nil {
+		return false
+	}
+	v, _ := p.settingsRepo.Get(ctx, "queue_paused")
+	return v != ""
+}
+
+// schedule возвращает расписание загрузок из настроек или конфига.
+// Ошибочное расписание не останавливает очередь: оно игнорируется с предупреждением.
+func (p *Pool) schedule(ctx context.Context) model.Schedule {
+	text := p.cfg.DownloadSchedule
+	if p.settingsRepo != nil {
+		if v, _ := p.settingsRepo.Get(ctx, "download_schedule"); v != "" {
+			text = v
+		}
+	}
+	sched, err := model.ParseSchedule(text)
+	if err != nil {
+		slog.Warn("worker: invalid download schedule", "err", err)
+		return nil
+	}
+	return sched
+}
+
+// watchSchedule следит за сменой окон расписания, пока пул работает.
+func (p *Pool) watchSchedule(ctx context.Context) {
+	ticker := time.NewTicker(scheduleCheckEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.relimit(ctx)
+		}
+	}
+}
+
+// relimit перезапускает загрузки, скорость которых не совпадает с активным окном:
+// yt-dlp не меняет --limit-rate на ходу, поэтому задание прерывается и сразу
+// возвращается в очередь, а .part-файлы докачиваются с --continue и новой скоростью.
+// Вне всех окон начатые загрузки докачиваются как есть.
+func (p *Pool) relimit(ctx context.Context) {
+	w, ok := p.schedule(ctx).Active(time.Now())
+	if !ok {
+		return
+	}
+	rate := ""
+	if w != nil {
+		rate = w.RateLimit
+	}
+	var cancels []context.CancelFunc
+	p.mu.Lock()
+	for id, limit := range p.limits {
+		fn, running := p.cancelFuncs[id]
+		if limit == rate || !running {
+			continue
+		}
+		delete(p.cancelFuncs, id)
+		p.relimiting[id] = true
+		cancels = append(cancels, fn)
+		slog.Info("worker: restarting job with new rate limit", "id", id, "from", limit, "to", rate)
+	}
+	p.mu.Unlock()
+	for _, fn := range cancels {
+		fn()
+	}
+}
+
+// canClaim сообщает, можно ли сейчас брать новые задания: очередь не на паузе
+// и текущее время попадает в одно из окон расписания.
+func (p *Pool) canClaim(ctx context.Context) bool {
+	if p.Paused(ctx) {
+		return false
+	}
+	_, ok := p.schedule(ctx).Active(time.Now())
+	return ok
+}
+
+// Progress возвращает снимок прогресса всех скачиваемых сейчас job'ов.
+func (p *Pool) Progress() map[string]model.Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]model.Progress, len(p.progress))
+	for id, jp := range p.progress {
+		out[id] = jp.Progress
+	}
+	return out
+}
+
+// updateProgress запоминает прогресс и с ограничением частоты
+// оповещает SSE-клиентов и Telegram.
+func (p *Pool) updateProgress(ctx context.Context, job *model.Job, pr model.Progress) {
+	now := time.Now()
+	p.mu.Lock()
+	jp, ok := p.progress[job.ID]
+	if !ok {
+		jp = &jobProgress{}
+		p.progress[job.ID] = jp
+	}
+	jp.Progress = pr
+	doBroadcast := now.Sub(jp.broadcastAt) >= progressBroadcastEvery
+	if doBroadcast {
+		jp.broadcastAt = now
+	}
+	doNotify := p.tgJob(job) && job.TgMessageID != 0 && now.Sub(jp.notifiedAt) >= progressNotifyEvery
+	if doNotify {
+		jp.notifiedAt = now
+	}
+	p.mu.Unlock()
+
+	if doBroadcast {
+		p.hub.Publish(sse.JobProgress, sse.JobProgressOf(job.ID, pr))
+	}
+	if doNotify {
+		p.notifier.Notify(ctx, Notification{
+			Kind:      NotifJobProgress,
+			ChatID:    job.ChatID,
+			MessageID: job.TgMessageID,
+			JobID:     job.ID,
+			JobURL:    job.URL,
+			Progress:  &pr,
+		})
+	}
+}
+
 func moveFile(src, dst string) error {
 	if err := os.Rename(src, dst); err == nil {
 		return nil
@@ -134,9 +313,9 @@ func (p *Pool) Start(ctx context.Context) {
 	if err := p.jobRepo.ResetStale(ctx); err != nil {
 		slog.Error("worker: reset stale jobs", "err", err)
 	}
-	if err := os.RemoveAll(p.cfg.StagingDir()); err != nil {
-		slog.Warn("worker: clean staging dir", "dir", p.cfg.StagingDir(), "err", err)
-	}
+	p.cleanStaging(ctx)
+	go p.detectVersion(ctx)
+	go p.watchSchedule(ctx)
 	for i := range p.cfg.WorkerCount {
 		go p.runWorker(ctx, i)
 	}
@@ -146,6 +325,19 @@ func (p *Pool) Start(ctx context.Context) {
 	<-ctx.Done()
 }
 
+// detectVersion запоминает версию yt-dlp для истории попыток.
+func (p *Pool) detectVersion(ctx context.Context) {
+	v, err := downloader.Version(ctx, p.cfg.YtDlpBinary)
+	if err != nil {
+		slog.Warn("worker: yt-dlp version", "err", err)
+		return
+	}
+	slog.Info("worker: yt-dlp version", "version", v)
+	p.mu.Lock()
+	p.ytdlpVersion = v
+	p.mu.Unlock()
+}
+
 func (p *Pool) runWorker(ctx context.Context, id int) {
 	slog.Info("worker: started", "id", id)
 	for {
@@ -156,8 +348,8 @@ func (p *Pool) runWorker(ctx context.Context, id int) {
 		case <-p.notify:
 		case <-time.After(10 * time.Second):
 		}
-		for {
-			job, err := p.jobRepo.ClaimNext(ctx)
+		for p.canClaim(ctx) {
+			job, err := p.jobRepo.ClaimNext(ctx, p.domainRules(ctx))
 			if err != nil {
 				slog.Error("worker: claim next", "err", err)
 				break
@@ -182,13 +374,17 @@ func (p *Pool) process(ctx context.Context, job *model.Job) {
 	defer func() {
 		p.mu.Lock()
 		delete(p.cancelFuncs, job.ID)
+		delete(p.pausing, job.ID)
+		delete(p.limits, job.ID)
+		delete(p.relimiting, job.ID)
+		delete(p.progress, job.ID)
 		p.mu.Unlock()
 		cancel()
-		p.broadcast()
+		p.publishStatus(job)
 	}()
 
 	slog.Info("worker: processing job", "id", job.ID, "url", job.URL, "attempt", job.RetryCount+1)
-	p.broadcast()
+	p.publishStatus(job)
 
 	if p.tgJob(job) {
 		p.notifier.Notify(ctx, Notification{
@@ -200,24 +396,82 @@ func (p *Pool) process(ctx context.Context, job *model.Job) {
 		})
 	}
 
+	// Непустой staging остался от паузы — yt-dlp докачает .part-файлы.
 	jobStaging := filepath.Join(p.cfg.StagingDir(), job.ID)
+	leftovers, _ := os.ReadDir(jobStaging)
 	if err := os.MkdirAll(jobStaging, 0o755); err != nil {
 		slog.Error("worker: create staging dir", "dir", jobStaging, "err", err)
 		p.handleFailure(ctx, job, fmt.Errorf("create staging dir: %w", err))
 		return
 	}
-	defer os.RemoveAll(jobStaging)
+	defer func() {
+		// Пауза и смена скорости оставляют недокачанное для --continue.
+		if job.Status != model.JobPaused && job.Status != model.JobPending {
+			os.RemoveAll(jobStaging)
+		}
+	}()
 
-	opts := p.resolveOpts(ctx, jobStaging)
+	rule := p.domainRule(ctx, job)
+	preset := p.resolvePreset(ctx, job, rule)
+	opts := p.resolveOpts(ctx, jobStaging).WithPreset(preset).WithDomainRule(rule).WithJob(job.Options)
+	opts.Continue = len(leftovers) > 0
+	p.mu.Lock()
+	p.limits[job.ID] = opts.LimitRate
+	p.mu.Unlock()
+	if n := p.geoFailures(ctx, job.ID); n > 0 {
+		if proxies := p.geoProxies(ctx); n <= len(proxies) {
+			opts.Proxy = proxies[n-1]
+		}
+	}
+	destDir := p.cfg.YtDlpOutputDir
+	if preset != nil && preset.Subfolder != "" {
+		destDir = filepath.Join(destDir, preset.Subfolder)
+		if err := os.MkdirAll(destDir, 0o755); err != nil {
+			p.handleFailure(ctx, job, fmt.Errorf("create preset dir: %w", err))
+			return
+		}
+	}
 
 	var firstItem *model.Item
 	var lastErr error
 	fileCount := 0
 
+	p.mu.Lock()
+	attempt := &model.JobAttempt{JobID: job.ID, YtDlpVersion: p.ytdlpVersion, Args: downloader.DisplayArgs(job.URL, opts)}
+	p.mu.Unlock()
+	if err := p.jobRepo.StartAttempt(ctx, attempt); err != nil {
+		slog.Warn("worker: start attempt", "job", job.ID, "err", err)
+	}
+	defer func() {
+		attempt.Status = job.Status
+		if lastErr != nil {
+			attempt.Error = lastErr.Error()
+			attempt.ErrorClass = model.ClassifyError(attempt.Error)
+		}
+		if err := p.jobRepo.FinishAttempt(ctx, attempt); err != nil {
+			slog.Warn("worker: finish attempt", "job", job.ID, "err", err)
+		}
+	}()
+
 	for event := range downloader.Run(jobCtx, job.URL, opts) {
-		if event.Log != "" {
-			if err := p.jobRepo.SaveLog(ctx, job.ID, event.Log); err != nil {
-				slog.Warn("worker: save log", "job", job.ID, "err", err)
+		if event.Progress != nil {
+			p.updateProgress(ctx, job, *event.Progress)
+			continue
+		}
+		if event.VideoID != "" {
+			// Задания из подписок и старые записи узнают своё видео только здесь.
+			if job.VideoID == "" {
+				job.Extractor, job.VideoID = event.Extractor, event.VideoID
+				if err := p.jobRepo.SetVideoID(ctx, job.ID, job.Extractor, job.VideoID); err != nil {
+					slog.Warn("worker: set video id", "job", job.ID, "err", err)
+				}
+			}
+			continue
+		}
+		if event.Done {
+			attempt.Log = event.Log
+			if event.ExitCode >= 0 {
+				attempt.ExitCode = &event.ExitCode
 			}
 			continue
 		}
@@ -231,7 +485,7 @@ func (p *Pool) process(ctx context.Context, job *model.Job) {
 			continue
 		}
 
-		finalPath := filepath.Join(p.cfg.YtDlpOutputDir, event.FileName)
+		finalPath := filepath.Join(destDir, event.FileName)
 		p.inFlight.Add(finalPath)
 		if err := moveFile(event.Path, finalPath); err != nil {
 			p.inFlight.Remove(finalPath)
@@ -248,12 +502,17 @@ func (p *Pool) process(ctx context.Context, job *model.Job) {
 		}
 
 		item := &model.Item{
-			JobID: job.ID,
-			Kind:  "video",
-			Path:  finalPath,
-			Name:  event.FileName,
-			Size:  info.Size(),
+			JobID:    job.ID,
+			Kind:     kindFromExt(event.FileName),
+			Path:     finalPath,
+			Name:     event.FileName,
+			Size:     info.Size(),
+			Duration: event.Duration,
 		}
+		if event.Info != nil {
+			item.Info = *event.Info
+		}
+		p.probe(ctx, item)
 		if err := p.itemRepo.Create(ctx, item); err != nil {
 			p.inFlight.Remove(finalPath)
 			slog.Error("worker: save item record", "err", err)
@@ -261,7 +520,10 @@ func (p *Pool) process(ctx context.Context, job *model.Job) {
 		}
 		p.inFlight.Remove(finalPath)
 		slog.Info("worker: item saved", "name", item.Name, "id", item.ID)
-		p.broadcast()
+		p.saveThumb(ctx, item, event.Thumbnail)
+		p.saveSubtitles(ctx, item, event.Subtitles)
+		p.hub.Publish(sse.ItemCreated, sse.ItemOf(item))
+		attempt.Files = append(attempt.Files, item.Name)
 		fileCount++
 		if firstItem == nil {
 			firstItem = item
@@ -280,6 +542,27 @@ func (p *Pool) process(ctx context.Context, job *model.Job) {
 		}
 	}
 
+	p.mu.Lock()
+	paused := p.pausing[job.ID]
+	relimited := p.relimiting[job.ID]
+	p.mu.Unlock()
+	if jobCtx.Err() != nil && relimited {
+		job.Status = model.JobPending
+		if err := p.jobRepo.Update(ctx, job); err != nil {
+			slog.Error("worker: update job requeued", "err", err)
+		}
+		p.Enqueue()
+		slog.Info("worker: job requeued for new rate limit", "id", job.ID)
+		return
+	}
+	if jobCtx.Err() != nil && paused {
+		job.Status = model.JobPaused
+		if err := p.jobRepo.Update(ctx, job); err != nil {
+			slog.Error("worker: update job paused", "err", err)
+		}
+		slog.Info("worker: job paused", "id", job.ID)
+		return
+	}
 	if jobCtx.Err() != nil {
 		job.Status = model.JobCancelled
 		if err := p.jobRepo.Update(ctx, job); err != nil {
@@ -312,6 +595,12 @@ func (p *Pool) process(ctx context.Context, job *model.Job) {
 	slog.Info("worker: job done", "id", job.ID, "title", job.Title)
 }
 
+// Паузы для ошибок, повтор после которых через обычный backoff бесполезен.
+const (
+	longRetryBackoff = time.Hour        // RetryLong: не меньше часа между попытками
+	rateLimitPause   = 30 * time.Minute // RetryPauseDomain: пауза домена после HTTP 429
+)
+
 func (p *Pool) handleFailure(ctx context.Context, job *model.Job, lastErr error) {
 	maxDuration := time.Duration(p.cfg.RetryMaxDuration) * time.Second
 	base := time.Duration(p.cfg.RetryBackoffBase) * time.Second
@@ -327,16 +616,38 @@ func (p *Pool) handleFailure(ctx context.Context, job *model.Job, lastErr error)
 	backoff := base * time.Duration(1<<uint(shift))
 	maxWindow := firstFailed.Add(maxDuration)
 
-	if now.Add(backoff).After(maxWindow) {
+	class := model.ClassifyError(lastErr.Error())
+	giveUp := false
+	switch class.Policy() {
+	case model.RetryNever:
+		giveUp = true
+	case model.RetryLong:
+		backoff = max(backoff, longRetryBackoff)
+	case model.RetryOtherProxy:
+		// Повторяем сразу, но уже через следующий запасной прокси, пока они не кончатся.
+		backoff = 0
+		giveUp = p.geoFailures(ctx, job.ID)+1 > len(p.geoProxies(ctx))
+	case model.RetryPauseDomain:
+		backoff = rateLimitPause
+		if err := p.jobRepo.PauseDomain(ctx, job.Domain(), now.Add(backoff)); err != nil {
+			slog.Error("worker: pause domain", "domain", job.Domain(), "err", err)
+		}
+		slog.Warn("worker: domain rate-limited, paused", "domain", job.Domain(), "until", now.Add(backoff).Format(time.RFC3339))
+	}
+	giveUp = giveUp || now.Add(backoff).After(maxWindow)
+
+	job.Error = lastErr.Error()
+	job.ErrorClass = class
+	job.RetryCount = retryCount
+	job.FirstFailedAt = &firstFailed
+
+	if giveUp {
 		job.Status = model.JobFailed
-		job.Error = lastErr.Error()
-		job.RetryCount = retryCount
-		job.FirstFailedAt = &firstFailed
 		job.NextRetryAt = nil
 		if err := p.jobRepo.Update(ctx, job); err != nil {
 			slog.Error("worker: update job failed", "err", err)
 		}
-		slog.Warn("worker: job failed permanently", "id", job.ID, "attempts", retryCount)
+		slog.Warn("worker: job failed permanently", "id", job.ID, "attempts", retryCount, "class", class)
 		if p.tgJob(job) {
 			p.notifier.Notify(ctx, Notification{
 				Kind:      NotifJobFailed,
@@ -345,6 +656,7 @@ func (p *Pool) handleFailure(ctx context.Context, job *model.Job, lastErr error)
 				JobID:     job.ID,
 				JobURL:    job.URL,
 				ErrText:   lastErr.Error(),
+				ErrClass:  class,
 			})
 		}
 		return
@@ -352,14 +664,14 @@ func (p *Pool) handleFailure(ctx context.Context, job *model.Job, lastErr error)
 
 	nextRetry := now.Add(backoff)
 	job.Status = model.JobRetrying
-	job.Error = lastErr.Error()
-	job.RetryCount = retryCount
 	job.NextRetryAt = &nextRetry
-	job.FirstFailedAt = &firstFailed
 	if err := p.jobRepo.Update(ctx, job); err != nil {
 		slog.Error("worker: update job retrying", "err", err)
 	}
-	slog.Info("worker: retry scheduled", "id", job.ID, "attempt", retryCount, "next_retry", nextRetry.Format(time.RFC3339))
+	slog.Info("worker: retry scheduled", "id", job.ID, "attempt", retryCount, "class", class, "next_retry", nextRetry.Format(time.RFC3339))
+	if backoff == 0 {
+		p.Enqueue()
+	}
 	if p.tgJob(job) {
 		retryIn := formatDuration(time.Until(nextRetry))
 		p.notifier.Notify(ctx, Notification{
@@ -369,16 +681,90 @@ func (p *Pool) handleFailure(ctx context.Context, job *model.Job, lastErr error)
 			JobID:     job.ID,
 			JobURL:    job.URL,
 			RetryAt:   retryIn,
+			ErrClass:  class,
 		})
 	}
 }
 
+// geoProxies — запасные прокси для повторов после гео-блокировки.
+func (p *Pool) geoProxies(ctx context.Context) []string {
+	proxies := p.cfg.YtDlpGeoProxies
+	if p.settingsRepo != nil {
+		if v, _ := p.settingsRepo.Get(ctx, "yt_dlp_geo_proxies"); v != "" {
+			proxies = strings.Fields(v)
+		}
+	}
+	return proxies
+}
+
+// geoFailures считает завершённые попытки задания, подряд упёршиеся в гео-блокировку.
+func (p *Pool) geoFailures(ctx context.Context, jobID string) int {
+	attempts, err := p.jobRepo.ListAttemptOutcomes(ctx, jobID)
+	if err != nil {
+		slog.Warn("worker: list attempt outcomes", "job", jobID, "err", err)
+		return 0
+	}
+	n := 0
+	for i := len(attempts) - 1; i >= 0; i-- {
+		if attempts[i].Status == model.JobRunning {
+			continue
+		}
+		if attempts[i].ErrorClass != model.ErrGeoBlocked {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func (p *Pool) domainRules(ctx context.Context) []*model.DomainRule {
+	if p.ruleRepo == nil {
+		return nil
+	}
+	rules, err := p.ruleRepo.List(ctx)
+	if err != nil {
+		slog.Warn("worker: list domain rules", "err", err)
+	}
+	return rules
+}
+
+// domainRule возвращает правило для домена задания или nil.
+func (p *Pool) domainRule(ctx context.Context, job *model.Job) *model.DomainRule {
+	return model.MatchDomainRule(p.domainRules(ctx), job.Domain())
+}
+
+// resolvePreset возвращает пресет задания, а если он не выбран или удалён —
+// пресет правила домена или пресет по умолчанию.
+func (p *Pool) resolvePreset(ctx context.Context, job *model.Job, rule *model.DomainRule) *model.Preset {
+	if p.presetRepo == nil {
+		return nil
+	}
+	ids := []string{job.PresetID}
+	if rule != nil {
+		ids = append(ids, rule.PresetID)
+	}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if preset, err := p.presetRepo.GetByID(ctx, id); err == nil {
+			return preset
+		}
+	}
+	preset, err := p.presetRepo.Default(ctx)
+	if err != nil {
+		return nil
+	}
+	return preset
+}
+
 func (p *Pool) resolveOpts(ctx context.Context, outputDir string) downloader.Options {
 	proxy := p.cfg.YtDlpProxy
 	outputFormat := p.cfg.YtDlpOutputFormat
 	maxFiles := p.cfg.YtDlpMaxFilesPerRequest
 	timeout := time.Duration(p.cfg.YtDlpTimeout) * time.Second
 	extraArgs := p.cfg.ExtraArgsList()
+	subLangs := p.cfg.YtDlpSubLangs
 
 	if p.settingsRepo != nil {
 		if v, _ := p.settingsRepo.Get(ctx, "yt_dlp_proxy"); v != "" {
@@ -400,6 +786,15 @@ func (p *Pool) resolveOpts(ctx context.Context, outputDir string) downloader.Opt
 		if v, _ := p.settingsRepo.Get(ctx, "yt_dlp_extra_args"); v != "" {
 			extraArgs = strings.Fields(v)
 		}
+		if v, _ := p.settingsRepo.Get(ctx, "yt_dlp_sub_langs"); v != "" {
+			subLangs = v
+		}
+	}
+
+	// При смене окна начатые загрузки перезапускаются с новой скоростью (см. relimit).
+	limitRate := ""
+	if w, _ := p.schedule(ctx).Active(time.Now()); w != nil {
+		limitRate = w.RateLimit
 	}
 
 	cookiesFile := ""
@@ -416,6 +811,35 @@ func (p *Pool) resolveOpts(ctx context.Context, outputDir string) downloader.Opt
 		MaxFiles:     maxFiles,
 		ExtraArgs:    extraArgs,
 		CookiesFile:  cookiesFile,
+		LimitRate:    limitRate,
+		SaveSubs:     model.ParseSubLangs(subLangs),
+		AutoSubs:     p.cfg.YtDlpAutoSubs,
+	}
+}
+
+// cleanStaging удаляет временные файлы прошлых запусков, кроме недокачанных
+// файлов приостановленных заданий.
+func (p *Pool) cleanStaging(ctx context.Context) {
+	dir := p.cfg.StagingDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	keep := map[string]bool{}
+	if paused, err := p.jobRepo.List(ctx, repo.JobFilter{Statuses: []model.JobStatus{model.JobPaused}}); err == nil {
+		for _, j := range paused {
+			keep[j.ID] = true
+		}
+	} else {
+		slog.Warn("worker: list paused jobs", "err", err)
+	}
+	for _, e := range entries {
+		if keep[e.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			slog.Warn("worker: clean staging dir", "path", filepath.Join(dir, e.Name()), "err", err)
+		}
 	}
 }
 
@@ -433,3 +857,57 @@ func formatDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("через %dh", int(d.Hours()))
 }
+
+// probe уточняет длительность, размер кадра и кодеки нового файла по ffprobe.
+func (p *Pool) probe(ctx context.Context, item *model.Item) {
+	pr, err := probe.File(ctx, p.cfg.FfprobeBinary, item.Path)
+	if err != nil {
+		slog.Warn("worker: probe", "path", item.Path, "err", err)
+		return
+	}
+	pr.Apply(item)
+}
+
+// saveThumb делает превью нового файла: из обложки yt-dlp, а без неё — кадр
+// видео или встроенная обложка аудио. Ошибка превью не мешает скачиванию.
+func (p *Pool) saveThumb(ctx context.Context, item *model.Item, cover string) {
+	dst := thumb.Path(p.cfg.ThumbDir(), item.ID)
+	var err error
+	if cover != "" {
+		err = thumb.FromImage(ctx, p.cfg.FfmpegBinary, cover, dst)
+		os.Remove(cover) //nolint:errcheck
+	}
+	if cover == "" || err != nil {
+		err = thumb.Make(ctx, p.cfg.FfmpegBinary, item, dst)
+	}
+	if err != nil {
+		slog.Warn("worker: thumbnail", "item_id", item.ID, "err", err)
+		return
+	}
+	if err := p.itemRepo.SetThumb(ctx, item.ID, dst); err != nil {
+		slog.Error("worker: save thumbnail", "item_id", item.ID, "err", err)
+		return
+	}
+	item.Thumb = dst
+}
+
+// saveSubtitles переносит субтитры нового файла в SubsDir в формате WebVTT.
+func (p *Pool) saveSubtitles(ctx context.Context, item *model.Item, subs []downloader.Subtitle) {
+	for _, s := range subs {
+		if !model.ValidSubLang(s.Lang) {
+			os.Remove(s.Path) //nolint:errcheck
+			continue
+		}
+		dst := subtitle.Path(p.cfg.SubsDir(), item.ID, s.Lang)
+		err := subtitle.Save(s.Path, dst)
+		os.Remove(s.Path) //nolint:errcheck
+		if err != nil {
+			slog.Warn("worker: subtitles", "item_id", item.ID, "lang", s.Lang, "err", err)
+			continue
+		}
+		sub := &model.Subtitle{ItemID: item.ID, Lang: s.Lang, Path: dst, Text: subtitle.ReadText(dst)}
+		if err := p.itemRepo.AddSubtitle(ctx, sub); err != nil {
+			slog.Error("worker: save subtitles", "item_id", item.ID, "err", err)
+		}
+	}
+}
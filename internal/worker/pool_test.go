@@ -0,0 +1,265 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/config"
+	"github.com/dr-duke/talmorGo/internal/db"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/repo"
+)
+
+// TestPool_PauseKeepsPartial проверяет, что пауза скачиваемого задания оставляет
+// .part-файл в staging, а после возобновления yt-dlp запускается с --continue.
+func TestPool_PauseKeepsPartial(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.Open(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	// Фейковый yt-dlp: запоминает аргументы, пишет .part в каталог -P и «качает» до отмены.
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done > '" + argsFile + "'\n" +
+		"prev=''; for a in \"$@\"; do [ \"$prev\" = '-P' ] && out=\"$a\"; prev=\"$a\"; done\n" +
+		"echo partial > \"$out/video.mp4.part\"\nexec sleep 30\n"
+	bin := filepath.Join(dir, "fake-ytdlp.sh")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := repo.NewJobRepo(database)
+	cfg := &config.Config{WorkerCount: 1, YtDlpBinary: bin, YtDlpOutputDir: filepath.Join(dir, "media"), YtDlpTimeout: 60}
+	pool := NewPool(cfg, jobs, repo.NewItemRepo(database), repo.NewTokenRepo(database), nil)
+
+	job := &model.Job{URL: "https://example.com/v", Status: model.JobPending, Source: "web"}
+	if err := jobs.Create(ctx, job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	claimed, _ := jobs.ClaimNext(ctx, nil)
+	partial := filepath.Join(cfg.StagingDir(), job.ID, "video.mp4.part")
+
+	done := make(chan struct{})
+	go func() {
+		pool.process(ctx, claimed)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(partial); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("fake yt-dlp did not start")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !pool.PauseJob(job.ID) {
+		t.Fatal("PauseJob: job is not running")
+	}
+	<-done
+
+	j, _ := jobs.GetByID(ctx, job.ID)
+	if j.Status != model.JobPaused {
+		t.Fatalf("status after pause: %s", j.Status)
+	}
+	if _, err := os.Stat(partial); err != nil {
+		t.Fatalf("partial file removed: %v", err)
+	}
+	attempts, _ := jobs.ListAttempts(ctx, job.ID)
+	if len(attempts) != 1 || attempts[0].Status != model.JobPaused || attempts[0].FinishedAt == nil ||
+		!strings.Contains(attempts[0].Args, "https://example.com/v") {
+		t.Fatalf("attempts after pause: %+v", attempts)
+	}
+
+	// Перезапуск приложения не трогает staging приостановленных заданий.
+	pool.cleanStaging(ctx)
+	if _, err := os.Stat(partial); err != nil {
+		t.Fatalf("partial file cleaned: %v", err)
+	}
+
+	if err := jobs.Resume(ctx, job.ID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	claimed, _ = jobs.ClaimNext(ctx, nil)
+	resumeCtx, cancel := context.WithCancel(ctx)
+	done = make(chan struct{})
+	go func() {
+		pool.process(resumeCtx, claimed)
+		close(done)
+	}()
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		if b, _ := os.ReadFile(argsFile); strings.Contains(string(b), "--continue") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("resumed download was started without --continue")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+// TestPool_RelimitOnWindowChange проверяет, что при смене окна расписания идущая
+// загрузка возвращается в очередь и докачивается с --continue и новой скоростью.
+func TestPool_RelimitOnWindowChange(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.Open(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nfor a in \"$@\"; do echo \"$a\"; done > '" + argsFile + "'\n" +
+		"prev=''; for a in \"$@\"; do [ \"$prev\" = '-P' ] && out=\"$a\"; prev=\"$a\"; done\n" +
+		"echo partial > \"$out/video.mp4.part\"\nexec sleep 30\n"
+	bin := filepath.Join(dir, "fake-ytdlp.sh")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := repo.NewJobRepo(database)
+	cfg := &config.Config{WorkerCount: 1, YtDlpBinary: bin, YtDlpOutputDir: filepath.Join(dir, "media"), YtDlpTimeout: 60}
+	pool := NewPool(cfg, jobs, repo.NewItemRepo(database), repo.NewTokenRepo(database), nil)
+
+	job := &model.Job{URL: "https://example.com/v", Status: model.JobPending, Source: "web"}
+	if err := jobs.Create(ctx, job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	claimed, _ := jobs.ClaimNext(ctx, nil)
+	partial := filepath.Join(cfg.StagingDir(), job.ID, "video.mp4.part")
+
+	done := make(chan struct{})
+	go func() {
+		pool.process(ctx, claimed)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(partial); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("fake yt-dlp did not start")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Та же скорость — загрузка не трогается.
+	pool.relimit(ctx)
+	select {
+	case <-done:
+		t.Fatal("job restarted without a rate change")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cfg.DownloadSchedule = "00:00-00:00 1M"
+	pool.relimit(ctx)
+	<-done
+
+	j, _ := jobs.GetByID(ctx, job.ID)
+	if j.Status != model.JobPending {
+		t.Fatalf("status after relimit: %s", j.Status)
+	}
+	if _, err := os.Stat(partial); err != nil {
+		t.Fatalf("partial file removed: %v", err)
+	}
+
+	claimed, _ = jobs.ClaimNext(ctx, nil)
+	if claimed == nil || claimed.ID != job.ID {
+		t.Fatalf("requeued job not claimed: %+v", claimed)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	done = make(chan struct{})
+	go func() {
+		pool.process(runCtx, claimed)
+		close(done)
+	}()
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		b, _ := os.ReadFile(argsFile)
+		if strings.Contains(string(b), "--continue") && strings.Contains(string(b), "--limit-rate\n1M") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("restarted download args: %s", b)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+// TestPool_FailurePolicies проверяет, что повтор после ошибки зависит от её класса.
+func TestPool_FailurePolicies(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.Open(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	jobs := repo.NewJobRepo(database)
+	cfg := &config.Config{WorkerCount: 1, RetryBackoffBase: 30, RetryMaxDuration: 86400, YtDlpGeoProxies: []string{"socks5://de:1080"}}
+	pool := NewPool(cfg, jobs, repo.NewItemRepo(database), repo.NewTokenRepo(database), nil)
+
+	fail := func(url, msg string) *model.Job {
+		t.Helper()
+		job := &model.Job{URL: url, Status: model.JobRunning, Source: "web"}
+		if err := jobs.Create(ctx, job); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		pool.handleFailure(ctx, job, errors.New(msg))
+		got, _ := jobs.GetByID(ctx, job.ID)
+		return got
+	}
+
+	if j := fail("https://a.com/1", "ERROR: Private video"); j.Status != model.JobFailed || j.ErrorClass != model.ErrUnavailable {
+		t.Errorf("private video: %s %s", j.Status, j.ErrorClass)
+	}
+	if j := fail("https://a.com/2", "ERROR: [Errno 28] No space left on device"); j.Status != model.JobRetrying ||
+		time.Until(*j.NextRetryAt) < 59*time.Minute {
+		t.Errorf("disk full: %s %v", j.Status, j.NextRetryAt)
+	}
+
+	// HTTP 429 приостанавливает весь домен.
+	if err := jobs.Create(ctx, &model.Job{URL: "https://b.com/2", Status: model.JobPending, Source: "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if j := fail("https://b.com/1", "ERROR: HTTP Error 429: Too Many Requests"); j.Status != model.JobRetrying || j.ErrorClass != model.ErrRateLimited {
+		t.Errorf("rate limit: %s %s", j.Status, j.ErrorClass)
+	}
+	if j, _ := jobs.ClaimNext(ctx, nil); j != nil {
+		t.Errorf("rate-limited domain claimed: %s", j.URL)
+	}
+
+	// Гео-блок: сразу повтор через запасной прокси, а когда прокси кончились — ошибка.
+	geo := fail("https://c.com/1", "ERROR: not available in your country")
+	if geo.Status != model.JobRetrying || geo.NextRetryAt.After(time.Now()) {
+		t.Fatalf("geo block: %s %v", geo.Status, geo.NextRetryAt)
+	}
+	attempt := &model.JobAttempt{JobID: geo.ID}
+	jobs.StartAttempt(ctx, attempt) //nolint:errcheck
+	attempt.Status, attempt.ErrorClass = model.JobRetrying, model.ErrGeoBlocked
+	jobs.FinishAttempt(ctx, attempt) //nolint:errcheck
+	if n := pool.geoFailures(ctx, geo.ID); n != 1 {
+		t.Fatalf("geo failures = %d", n)
+	}
+	pool.handleFailure(ctx, geo, errors.New("ERROR: not available in your country"))
+	if j, _ := jobs.GetByID(ctx, geo.ID); j.Status != model.JobFailed {
+		t.Errorf("geo block without spare proxies: %s", j.Status)
+	}
+}
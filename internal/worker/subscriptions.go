@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/downloader"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/playlist"
+	"github.com/dr-duke/talmorGo/internal/repo"
+)
+
+// subscriptionTick — как часто поллер проверяет, не пора ли опросить какую-то подписку.
+const subscriptionTick = time.Minute
+
+// SubscriptionPoller периодически опрашивает подписки и ставит новые видео в очередь.
+type SubscriptionPoller struct {
+	subs        repo.SubscriptionRepo
+	tags        repo.TagRepo
+	collections repo.CollectionRepo
+	expander    *playlist.Expander
+	pool        *Pool
+	list        func(ctx context.Context, url string, opts downloader.Options) (*downloader.PlaylistInfo, error)
+
+	mu      sync.Mutex
+	polling map[string]struct{} // ID подписок, опрос которых идёт прямо сейчас
+}
+
+func NewSubscriptionPoller(subs repo.SubscriptionRepo, tags repo.TagRepo, collections repo.CollectionRepo, expander *playlist.Expander, pool *Pool) *SubscriptionPoller {
+	return &SubscriptionPoller{
+		subs: subs, tags: tags, collections: collections,
+		expander: expander, pool: pool,
+		list:    downloader.ListEntries,
+		polling: make(map[string]struct{}),
+	}
+}
+
+func (p *SubscriptionPoller) Start(ctx context.Context) {
+	p.pollDue(ctx)
+	ticker := time.NewTicker(subscriptionTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.pollDue(ctx)
+		}
+	}
+}
+
+func (p *SubscriptionPoller) pollDue(ctx context.Context) {
+	subs, err := p.subs.List(ctx, "")
+	if err != nil {
+		slog.Error("subscriptions: list", "err", err)
+		return
+	}
+	now := time.Now()
+	for _, s := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		if s.Due(now) {
+			p.Poll(ctx, s)
+		}
+	}
+}
+
+// Poll опрашивает одну подписку и возвращает число поставленных в очередь видео.
+// Первый опрос только запоминает текущие видео канала, чтобы не выкачивать весь архив;
+// исключение — видео не старше MaxAgeDays, если ограничение задано.
+// Если подписка уже опрашивается (тикер и ручной запуск совпали), вызов ничего не делает.
+func (p *SubscriptionPoller) Poll(ctx context.Context, s *model.Subscription) int {
+	if !p.startPoll(s.ID) {
+		return 0
+	}
+	defer p.finishPoll(s.ID)
+
+	info, err := p.list(ctx, s.URL, p.expander.OptsFor(ctx, s.URL, p.pool.resolveOpts(ctx, "")))
+	if err != nil {
+		slog.Warn("subscriptions: poll", "id", s.ID, "url", s.URL, "err", err)
+		if err := p.subs.MarkPolled(ctx, s.ID, "", err.Error()); err != nil {
+			slog.Error("subscriptions: mark polled", "id", s.ID, "err", err)
+		}
+		return 0
+	}
+	seen, err := p.subs.SeenURLs(ctx, s.ID)
+	if err != nil {
+		slog.Error("subscriptions: seen urls", "id", s.ID, "err", err)
+		return 0
+	}
+
+	// Неудачный опрос обновляет last_polled_at, но архив канала не видел, поэтому
+	// первый — это опрос до первого успешного.
+	first := !s.Synced
+	now := time.Now()
+	var fresh []downloader.PlaylistEntry
+	var newURLs []string
+	for _, e := range info.Entries {
+		if _, ok := seen[e.URL]; ok {
+			continue
+		}
+		newURLs = append(newURLs, e.URL)
+		if first && (s.MaxAgeDays == 0 || e.Uploaded.IsZero()) {
+			continue
+		}
+		if s.TooOld(e.Uploaded, now) {
+			continue
+		}
+		fresh = append(fresh, e)
+	}
+
+	var jobs []*model.Job
+	if len(fresh) > 0 {
+		proto := model.Job{Source: "subscription", ChatID: s.ChatID, OwnerID: s.OwnerID}
+		if s.ChatID != 0 {
+			proto.Source = "telegram" // уведомления о готовности — в чат, откуда подписались
+		}
+		jobs = p.expander.CreateJobs(ctx, &downloader.PlaylistInfo{Entries: fresh, PlaylistTitle: info.PlaylistTitle}, proto)
+		p.applyTargets(ctx, s, jobs)
+		p.pool.Enqueue()
+	}
+	if err := p.subs.MarkSeen(ctx, s.ID, newURLs); err != nil {
+		slog.Error("subscriptions: mark seen", "id", s.ID, "err", err)
+	}
+	if err := p.subs.MarkPolled(ctx, s.ID, info.PlaylistTitle, ""); err != nil {
+		slog.Error("subscriptions: mark polled", "id", s.ID, "err", err)
+	}
+	if len(jobs) > 0 {
+		slog.Info("subscriptions: new uploads", "id", s.ID, "url", s.URL, "enqueued", len(jobs))
+	}
+	return len(jobs)
+}
+
+func (p *SubscriptionPoller) startPoll(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.polling[id]; ok {
+		return false
+	}
+	p.polling[id] = struct{}{}
+	return true
+}
+
+func (p *SubscriptionPoller) finishPoll(id string) {
+	p.mu.Lock()
+	delete(p.polling, id)
+	p.mu.Unlock()
+}
+
+// applyTargets вешает на новые задания теги и коллекцию подписки.
+func (p *SubscriptionPoller) applyTargets(ctx context.Context, s *model.Subscription, jobs []*model.Job) {
+	if len(jobs) == 0 {
+		return
+	}
+	ids := make([]string, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.ID
+	}
+	for _, name := range s.Tags {
+		tag, err := p.tags.Upsert(ctx, name)
+		if err != nil {
+			slog.Error("subscriptions: upsert tag", "tag", name, "err", err)
+			continue
+		}
+		if err := p.tags.BulkAddToJobs(ctx, tag.ID, ids); err != nil {
+			slog.Error("subscriptions: tag jobs", "tag", name, "err", err)
+		}
+	}
+	if s.CollectionID != "" {
+		if err := p.collections.AddJobs(ctx, s.CollectionID, ids); err != nil {
+			slog.Error("subscriptions: add to collection", "collection", s.CollectionID, "err", err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dr-duke/talmorGo/internal/config"
+	"github.com/dr-duke/talmorGo/internal/db"
+	"github.com/dr-duke/talmorGo/internal/downloader"
+	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/playlist"
+	"github.com/dr-duke/talmorGo/internal/repo"
+)
+
+// TestSubscriptionPoller_Diff проверяет, что первый опрос только запоминает архив канала,
+// а следующие ставят в очередь новые видео с тегами подписки, отсекая слишком старые.
+func TestSubscriptionPoller_Diff(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	jobs := repo.NewJobRepo(database)
+	tags := repo.NewTagRepo(database)
+	subs := repo.NewSubscriptionRepo(database)
+	pool := NewPool(&config.Config{WorkerCount: 1}, jobs, repo.NewItemRepo(database), repo.NewTokenRepo(database), nil)
+	p := NewSubscriptionPoller(subs, tags, repo.NewCollectionRepo(database), playlist.New(jobs, tags), pool)
+
+	entries := []downloader.PlaylistEntry{{URL: "https://example.com/old"}}
+	p.list = func(context.Context, string, downloader.Options) (*downloader.PlaylistInfo, error) {
+		return &downloader.PlaylistInfo{Entries: entries, PlaylistTitle: "Channel"}, nil
+	}
+
+	s := &model.Subscription{URL: "https://example.com/ch", Interval: time.Hour, Tags: []string{"sub"}, MaxAgeDays: 30, Enabled: true}
+	if err := subs.Create(ctx, s); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if n := p.Poll(ctx, s); n != 0 {
+		t.Fatalf("first poll enqueued %d, want 0", n)
+	}
+
+	s, _ = subs.GetByID(ctx, s.ID)
+	if s.Title != "Channel" || s.LastPolledAt == nil || s.Due(time.Now()) {
+		t.Fatalf("after poll: %+v", s)
+	}
+	entries = append([]downloader.PlaylistEntry{
+		{URL: "https://example.com/new", Uploaded: time.Now()},
+		{URL: "https://example.com/ancient", Uploaded: time.Now().AddDate(-1, 0, 0)},
+	}, entries...)
+	if n := p.Poll(ctx, s); n != 1 {
+		t.Fatalf("second poll enqueued %d, want 1", n)
+	}
+	if n := p.Poll(ctx, s); n != 0 {
+		t.Fatalf("repeat poll enqueued %d, want 0", n)
+	}
+
+	all, _ := jobs.List(ctx, repo.JobFilter{})
+	if len(all) != 1 || all[0].URL != "https://example.com/new" || all[0].Status != model.JobPending {
+		t.Fatalf("jobs: %+v", all)
+	}
+	jobTags, _ := tags.ListByJob(ctx, all[0].ID)
+	if len(jobTags) != 2 {
+		t.Errorf("tags: got %v, want playlist title and subscription tag", jobTags)
+	}
+}
+
+// TestSubscriptionPoller_FailedFirstPoll проверяет, что после неудачного первого опроса
+// следующий успешный всё ещё считается первым и не выкачивает архив канала.
+func TestSubscriptionPoller_FailedFirstPoll(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	jobs := repo.NewJobRepo(database)
+	tags := repo.NewTagRepo(database)
+	subs := repo.NewSubscriptionRepo(database)
+	pool := NewPool(&config.Config{WorkerCount: 1}, jobs, repo.NewItemRepo(database), repo.NewTokenRepo(database), nil)
+	p := NewSubscriptionPoller(subs, tags, repo.NewCollectionRepo(database), playlist.New(jobs, tags), pool)
+
+	p.list = func(context.Context, string, downloader.Options) (*downloader.PlaylistInfo, error) {
+		return nil, errors.New("network is unreachable")
+	}
+	s := &model.Subscription{URL: "https://example.com/ch", Interval: time.Hour, Enabled: true}
+	if err := subs.Create(ctx, s); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	p.Poll(ctx, s)
+	s, _ = subs.GetByID(ctx, s.ID)
+	if s.LastError == "" {
+		t.Fatalf("error not recorded: %+v", s)
+	}
+
+	p.list = func(context.Context, string, downloader.Options) (*downloader.PlaylistInfo, error) {
+		return &downloader.PlaylistInfo{Entries: []downloader.PlaylistEntry{{URL: "https://example.com/old"}}}, nil
+	}
+	if n := p.Poll(ctx, s); n != 0 {
+		t.Fatalf("poll after failure enqueued %d, want 0", n)
+	}
+}
+
+// TestSubscriptionPoller_SkipsConcurrentPoll проверяет, что подписку, которая уже
+// опрашивается, второй вызов Poll не трогает.
+func TestSubscriptionPoller_SkipsConcurrentPoll(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	jobs := repo.NewJobRepo(database)
+	tags := repo.NewTagRepo(database)
+	subs := repo.NewSubscriptionRepo(database)
+	pool := NewPool(&config.Config{WorkerCount: 1}, jobs, repo.NewItemRepo(database), repo.NewTokenRepo(database), nil)
+	p := NewSubscriptionPoller(subs, tags, repo.NewCollectionRepo(database), playlist.New(jobs, tags), pool)
+
+	var calls atomic.Int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	p.list = func(context.Context, string, downloader.Options) (*downloader.PlaylistInfo, error) {
+		if calls.Add(1) == 1 {
+			close(entered)
+			<-release
+		}
+		return &downloader.PlaylistInfo{}, nil
+	}
+	s := &model.Subscription{URL: "https://example.com/ch", Interval: time.Hour, Enabled: true}
+	if err := subs.Create(ctx, s); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.Poll(ctx, s)
+		close(done)
+	}()
+	<-entered
+	p.Poll(ctx, s)
+	close(release)
+	<-done
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("list called %d times, want 1", n)
+	}
+	p.Poll(ctx, s)
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("list called %d times after first poll finished, want 2", n)
+	}
+}
+
+// TestSubscriptionPoller_EmptyChannel проверяет, что у канала, пустого при первом
+// опросе, первые загрузки ставятся в очередь.
+func TestSubscriptionPoller_EmptyChannel(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	jobs := repo.NewJobRepo(database)
+	tags := repo.NewTagRepo(database)
+	subs := repo.NewSubscriptionRepo(database)
+	pool := NewPool(&config.Config{WorkerCount: 1}, jobs, repo.NewItemRepo(database), repo.NewTokenRepo(database), nil)
+	p := NewSubscriptionPoller(subs, tags, repo.NewCollectionRepo(database), playlist.New(jobs, tags), pool)
+
+	var entries []downloader.PlaylistEntry
+	p.list = func(context.Context, string, downloader.Options) (*downloader.PlaylistInfo, error) {
+		return &downloader.PlaylistInfo{Entries: entries}, nil
+	}
+	s := &model.Subscription{URL: "https://example.com/ch", Interval: time.Hour, Enabled: true}
+	if err := subs.Create(ctx, s); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if n := p.Poll(ctx, s); n != 0 {
+		t.Fatalf("first poll enqueued %d, want 0", n)
+	}
+
+	s, _ = subs.GetByID(ctx, s.ID)
+	if !s.Synced {
+		t.Fatalf("not synced after successful poll: %+v", s)
+	}
+	entries = []downloader.PlaylistEntry{{URL: "https://example.com/first"}}
+	if n := p.Poll(ctx, s); n != 1 {
+		t.Fatalf("poll after empty channel enqueued %d, want 1", n)
+	}
+}
@@ -67,8 +67,9 @@ func newTab(t *testing.T) (context.Context, context.CancelFunc) {
 
 type fakePool struct{}
 
-func (p *fakePool) Enqueue()               {}
-func (p *fakePool) CancelJob(string) bool  { return false }
+func (p *fakePool) Enqueue()              {}
+func (p *fakePool) CancelJob(string) bool { return false }
+func (p *fakePool) PauseJob(string) bool  { return false }
 
 type testEnv struct {
 	URL     string
@@ -99,7 +100,7 @@ func newTestEnv(t *testing.T) *testEnv {
 
 	cfg := &config.Config{BaseURL: "", BasePath: "", SiteName: "TalmorGo"}
 	fp := &fakePool{}
-	srv := api.New(cfg, jobRepo, itemRepo, tokenRepo, tagRepo, cookieRepo, repo.NewSettingsRepo(database), repo.NewCollectionRepo(database), repo.NewOperationRepo(database), storage.New(tmpDir), fp, fp, sse.New())
+	srv := api.New(cfg, jobRepo, itemRepo, tokenRepo, tagRepo, cookieRepo, repo.NewSettingsRepo(database), repo.NewCollectionRepo(database), repo.NewOperationRepo(database), repo.NewAPITokenRepo(database), repo.NewUserRepo(database), repo.NewSubscriptionRepo(database), repo.NewPresetRepo(database), repo.NewDomainRuleRepo(database), storage.New(tmpDir), nil, fp, fp, nil, sse.New())
 	ts := httptest.NewServer(srv.Handler())
 
 	return &testEnv{
@@ -21,6 +21,22 @@ document.body.addEventListener('showToast', (e) => {
   showToast(e.detail?.value || '');
 });
 
+/* Переход к уже добавленному видео (выбор «Открыть» у дубликата в очереди). */
+document.body.addEventListener('openJob', (e) => {
+  const d = e.detail || {};
+  if (d.queue) {
+    setTimeout(() => document.querySelector('#queue-inner .queue-row[data-job-id="' + d.id + '"]')
+      ?.scrollIntoView({ block: 'center' }), 500);
+    return;
+  }
+  filter.q = d.title || ''; filter.kind = ''; clearTags();
+  hideSidebarColl();
+  hidePlayAll();
+  document.querySelectorAll('.sidebar-nav-item[data-kind]').forEach(b => b.classList.remove('active'));
+  _showLib();
+  applyFilter();
+});
+
 /* ── Section switching (lib / queue) ── */
 function _showLib() {
   const lib = document.getElementById('lib-section');
@@ -38,7 +54,7 @@ function _showQueue() {
 }
 
 function setQueue(btn) {
-  filter.q = ''; filter.kind = ''; filter.tag = '';
+  filter.q = ''; filter.kind = ''; clearTags();
   hidePlayAll();
   document.querySelectorAll('.sidebar-nav-item[data-kind]').forEach(b => b.classList.remove('active'));
   document.querySelectorAll('.sidebar-nav-item[data-coll]').forEach(b => b.classList.remove('active'));
@@ -47,15 +63,62 @@ function setQueue(btn) {
 }
 
 /* ── Filter state ── */
-const filter = { q: '', kind: '', tag: '' };
+// tags — выбранные теги: нужны все сразу или, с tagsAny, хотя бы один; notTags — исключённые.
+// more — поля панели «Фильтры» (domain, source, status, min_mb, max_mb, from, to).
+const filter = { q: '', kind: '', tags: [], tagsAny: false, notTags: [], watch: '', sort: '', more: {} };
+
+function clearTags() {
+  filter.tags = [];
+  filter.notTags = [];
+}
+
+// filterParams — параметры запроса по текущему фильтру (список, облако тегов, плейлист).
+function filterParams() {
+  const p = new URLSearchParams();
+  if (filter.q)    p.set('q', filter.q);
+  if (filter.kind) p.set('kind', filter.kind);
+  const tagParam = filter.tagsAny && filter.tags.length > 1 ? 'any_tag' : 'tag';
+  filter.tags.forEach(t => p.append(tagParam, t));
+  filter.notTags.forEach(t => p.append('not_tag', t));
+  if (filter.watch) p.set('watch', filter.watch);
+  if (filter.sort) {
+    const [sort, order] = filter.sort.split(':');
+    p.set('sort', sort);
+    if (order) p.set('order', order);
+  }
+  const m = filter.more;
+  if (m.domain) p.set('domain', m.domain);
+  if (m.source) p.set('source', m.source);
+  if (m.status) p.set('status', m.status);
+  if (m.min_mb) p.set('min_size', Math.round(m.min_mb * 1048576));
+  if (m.max_mb) p.set('max_size', Math.round(m.max_mb * 1048576));
+  if (m.from)   p.set('from', m.from);
+  if (m.to)     p.set('to', m.to);
+  return p;
+}
+
+// syncFilterForm переносит фильтр в скрытую форму, которую подключают HTMX-запросы.
+function syncFilterForm() {
+  const form = document.getElementById('filter-form');
+  if (form) {
+    form.replaceChildren(...[...filterParams()].map(([name, value]) => {
+      const input = document.createElement('input');
+      input.type = 'hidden';
+      input.name = name;
+      input.value = value;
+      return input;
+    }));
+  }
+  const modeBtn = document.getElementById('tag-mode-btn');
+  if (modeBtn) {
+    modeBtn.style.display = filter.tags.length > 1 ? '' : 'none';
+    modeBtn.textContent = filter.tagsAny ? 'любой из тегов' : 'все теги';
+  }
+  document.getElementById('filter-more')?.classList.toggle('active', Object.values(filter.more).some(Boolean));
+}
 
 function applyFilter() {
-  const fq = document.getElementById('filter-q');
-  const fk = document.getElementById('filter-kind');
-  const ft = document.getElementById('filter-tag');
-  if (fq) fq.value = filter.q;
-  if (fk) fk.value = filter.kind;
-  if (ft) ft.value = filter.tag;
+  syncFilterForm();
   const inner = document.getElementById('media-inner');
   if (inner) htmx.trigger(inner, 'mediaRefresh');
   // Обновляем облако тегов с учётом нового фильтра
@@ -64,7 +127,7 @@ function applyFilter() {
 
 function setKind(btn, kind) {
   filter.kind = kind;
-  filter.tag = '';
+  clearTags();
   hideSidebarColl();
   _showLib();
   document.querySelectorAll('.sidebar-nav-item[data-kind]').forEach(b => {
@@ -74,8 +137,34 @@ function setKind(btn, kind) {
   applyFilter();
 }
 
+// setWatch — фильтр по просмотру: '' | 'unwatched' | 'in_progress'; сочетается с остальными.
+function setWatch(value) {
+  filter.watch = value;
+  applyFilter();
+}
+
+// setSort — порядок выдачи: 'ключ' или 'ключ:asc|desc'; '' — по умолчанию
+// (при поиске — сначала подходящие, иначе — новые).
+function setSort(value) {
+  filter.sort = value;
+  applyFilter();
+}
+
+// setFilterField — поле из панели «Фильтры» (name поля — ключ в filter.more).
+function setFilterField(el) {
+  filter.more[el.name] = el.value.trim();
+  applyFilter();
+}
+
+function resetMoreFilters() {
+  filter.more = {};
+  document.querySelectorAll('#filter-more [name]').forEach(el => { el.value = ''; });
+  applyFilter();
+}
+
 function setColl(btn, name) {
-  filter.tag = name;
+  filter.tags = [name];
+  filter.notTags = [];
   filter.kind = '';
   document.querySelectorAll('.sidebar-nav-item[data-kind]').forEach(b => b.classList.remove('active'));
   document.querySelectorAll('.sidebar-nav-item[data-coll]').forEach(b => {
@@ -110,43 +199,65 @@ function hidePlayAll() {
 }
 
 /* ── Tag cloud ── */
-function toggleTag(btn) {
+// toggleTag — клик по чипу выбирает только этот тег (повторный — снимает выбор);
+// с Ctrl/⌘ тег добавляется к выбранным или убирается, с Alt — исключается из выдачи.
+function toggleTag(btn, evt) {
   const tag = btn.dataset.tag;
-  if (filter.tag === tag) {
-    filter.tag = '';
-    btn.classList.remove('active');
-    hideSidebarColl();
-    document.querySelectorAll('.sidebar-nav-item[data-kind=""]').forEach(b => b.classList.add('active'));
-    hidePlayAll();
+  const without = (list) => list.filter(t => t !== tag);
+  if (evt && evt.altKey) {
+    filter.tags = without(filter.tags);
+    filter.notTags = filter.notTags.includes(tag) ? without(filter.notTags) : [...filter.notTags, tag];
+  } else if (evt && (evt.ctrlKey || evt.metaKey)) {
+    filter.notTags = without(filter.notTags);
+    filter.tags = filter.tags.includes(tag) ? without(filter.tags) : [...filter.tags, tag];
+  } else if (filter.tags.length === 1 && filter.tags[0] === tag) {
+    filter.tags = [];
+  } else {
+    filter.tags = [tag];
+    filter.notTags = without(filter.notTags);
+  }
+  document.querySelectorAll('#tag-cloud .chip[data-tag]').forEach(b => {
+    b.classList.toggle('active', filter.tags.includes(b.dataset.tag));
+    b.classList.toggle('excluded', filter.notTags.includes(b.dataset.tag));
+  });
+
+  // Единственная выбранная коллекция — как переход в неё из сайдбара.
+  const only = filter.tags.length === 1 ? filter.tags[0] : '';
+  const isColl = only && document.querySelector(`#tag-cloud .chip.coll[data-tag="${CSS.escape(only)}"]`);
+  hideSidebarColl();
+  if (isColl) {
+    showPlayAll(only);
+    const collBtn = document.querySelector(`.sidebar-nav-item[data-coll="${CSS.escape(only)}"]`);
+    if (collBtn) {
+      document.querySelectorAll('.sidebar-nav-item').forEach(b => b.classList.remove('active'));
+      collBtn.classList.add('active');
+    }
   } else {
-    filter.tag = tag;
-    document.querySelectorAll('#tag-cloud .chip').forEach(b => b.classList.remove('active'));
-    btn.classList.add('active');
-    if (btn.classList.contains('coll')) {
-      showPlayAll(tag);
-      const collBtn = document.querySelector(`.sidebar-nav-item[data-coll="${CSS.escape(tag)}"]`);
-      if (collBtn) {
-        document.querySelectorAll('.sidebar-nav-item').forEach(b => b.classList.remove('active'));
-        collBtn.classList.add('active');
-      }
-    } else {
-      hidePlayAll();
+    hidePlayAll();
+    if (!filter.tags.length) {
+      document.querySelectorAll('.sidebar-nav-item[data-kind=""]').forEach(b => b.classList.add('active'));
     }
   }
-  const ft = document.getElementById('filter-tag');
-  if (ft) ft.value = filter.tag;
+  syncFilterForm();
   const inner = document.getElementById('media-inner');
   if (inner) htmx.trigger(inner, 'mediaRefresh');
 }
 
+// toggleTagMode переключает выбор нескольких тегов между «все сразу» и «любой из них».
+function toggleTagMode() {
+  filter.tagsAny = !filter.tagsAny;
+  applyFilter();
+}
+
 // Фильтрует по тегу при клике на tag-chip в строке медиатеки.
 // Предпочитает делегировать в toggleTag через chip облака тегов (синхронизирует active-состояние).
 function filterByTag(tagName) {
+  const selected = filter.tags.length === 1 && filter.tags[0] === tagName;
   const cloudChip = document.querySelector(`#tag-cloud .chip[data-tag="${CSS.escape(tagName)}"]`);
   if (cloudChip) {
-    if (filter.tag !== tagName) cloudChip.click();
+    if (!selected) cloudChip.click();
   } else {
-    filter.tag = filter.tag === tagName ? '' : tagName;
+    filter.tags = selected ? [] : [tagName];
     applyFilter();
   }
 }
@@ -190,15 +301,18 @@ let playlistIndex = -1;
 function buildPlaylist() {
   return Array.from(
     document.querySelectorAll('#media-inner .media-row[data-stream][data-kind="video"]')
-  ).map(row => ({ stream: row.dataset.stream, title: row.dataset.title }));
+  ).map(row => ({
+    stream: row.dataset.stream,
+    title: row.dataset.title,
+    subs: row.dataset.subs ? row.dataset.subs.split(',') : [],
+    hls: row.dataset.hls || '',
+    position: Number(row.dataset.position) || 0,
+  }));
 }
 
 // fetchPlaylist — серверный плейлист по текущему фильтру (без ограничения страницей).
 async function fetchPlaylist() {
-  const params = new URLSearchParams();
-  if (filter.q)    params.set('q', filter.q);
-  if (filter.kind) params.set('kind', filter.kind);
-  if (filter.tag)  params.set('tag', filter.tag);
+  const params = filterParams();
   const base = document.documentElement.dataset.basePath || '';
   const resp = await fetch(base + '/library/playlist?' + params.toString());
   if (!resp.ok) return [];
@@ -245,6 +359,7 @@ function _teardown() {
       _minimizing = false;
     }
     if (plyrPlayer) { try { plyrPlayer.pause(); plyrPlayer.destroy(); } catch(e) {} plyrPlayer = null; }
+    _hlsDetach();
     const video = document.getElementById('main-player');
     if (video) video.src = '';
   } else if (playerKind === 'audio') {
@@ -255,6 +370,7 @@ function _teardown() {
 
 /* ── Core open ── */
 function openMedia(stream, title, kind) {
+  _watchFlush();
   // Stop the other kind before switching (prevents two players running at once)
   if (playerKind !== null && playerKind !== kind) _teardown();
 
@@ -271,8 +387,11 @@ function openMedia(stream, title, kind) {
     if (!playlist.length) { playlist = buildPlaylist(); }
     const idx = playlist.findIndex(p => p.stream === stream);
     playlistIndex = idx >= 0 ? idx : 0;
-    _openVideo(stream, title);
+    const entry = idx >= 0 ? playlist[idx] : _rowEntry(stream);
+    _watchStart(stream, entry.position);
+    _openVideo(stream, title, entry);
   } else {
+    _watchStart(stream, _rowEntry(stream).position);
     _openAudio(stream, title);
   }
 
@@ -280,13 +399,14 @@ function openMedia(stream, title, kind) {
 }
 
 /* ── Video ── */
-function _openVideo(stream, title) {
+function _openVideo(stream, title, entry) {
   const dlg   = document.getElementById('player-dialog');
   const video = document.getElementById('main-player');
   const titleEl = document.getElementById('player-title');
   if (!dlg || !video) return;
 
   if (titleEl) titleEl.textContent = title || '';
+  _loadPlayerInfo(stream);
 
   // Destroy old Plyr before changing src
   if (plyrPlayer) {
@@ -294,7 +414,9 @@ function _openVideo(stream, title) {
     plyrPlayer = null;
   }
 
-  video.src = stream;
+  _setSubtitleTracks(video, stream, entry.subs);
+  _watchResume(video, stream);
+  _setVideoSource(video, stream, entry.hls);
   // showModal() throws if dialog is already open (e.g. clicking another video while one plays)
   if (!dlg.open) dlg.showModal();
 
@@ -308,16 +430,155 @@ function _openVideo(stream, title) {
   // Call play() immediately within user gesture window (not deferred to 'ready' event)
   plyrPlayer.play().catch(() => {});
 
-  plyrPlayer.on('ended', () => setTimeout(playNext, 600));
+  plyrPlayer.on('ended', () => { _watchFlush(); setTimeout(playNext, 600); });
   plyrPlayer.on('play',  () => _pbPlayIcon(true));
-  plyrPlayer.on('pause', () => _pbPlayIcon(false));
-  plyrPlayer.on('timeupdate', _updateVideoProgress);
+  plyrPlayer.on('pause', () => { _pbPlayIcon(false); _watchFlush(); });
+  plyrPlayer.on('timeupdate', () => { _updateVideoProgress(); _watchSave(_watchCurrent(), false); });
+}
+
+/* Субтитры WebVTT — до создания Plyr: он читает <track> при инициализации. */
+function _setSubtitleTracks(video, stream, langs) {
+  video.querySelectorAll('track').forEach(t => t.remove());
+  (langs || []).forEach(lang => {
+    const track = document.createElement('track');
+    track.kind = 'subtitles';
+    track.src = stream.replace(/\/stream$/, '/subtitles/' + encodeURIComponent(lang) + '.vtt');
+    track.srclang = lang;
+    track.label = lang;
+    video.appendChild(track);
+  });
+}
+
+function _rowEntry(stream) {
+  const row = Array.from(document.querySelectorAll('.media-row[data-stream]'))
+    .find(r => r.dataset.stream === stream);
+  return {
+    subs: row && row.dataset.subs ? row.dataset.subs.split(',') : [],
+    hls: row ? row.dataset.hls || '' : '',
+    position: row ? Number(row.dataset.position) || 0 : 0,
+  };
+}
+
+/* ── Прогресс просмотра: позиция уходит на сервер, с неё продолжается воспроизведение ── */
+const WATCH_SAVE_EVERY = 10;   // секунд воспроизведения между отправками позиции
+let _watchStream = '';          // stream файла в плеере
+let _watchSent   = -1;          // последняя отправленная позиция
+let _watchFrom   = 0;           // позиция, с которой продолжить
+let _watchReady  = false;       // файл загружен и перемотан — до этого позицию не шлём
+const _watchLocal = new Map();  // stream -> позиция, сохранённая без перезагрузки списка
+
+function _watchStart(stream, position) {
+  _watchStream = stream;
+  _watchFrom = _watchLocal.has(stream) ? _watchLocal.get(stream) : (position || 0);
+  _watchSent = _watchFrom;
+  _watchReady = false;
+}
+
+// Перемотка на сохранённую позицию, как только станет известна длительность;
+// почти досмотренный файл начинается сначала.
+function _watchResume(media, stream) {
+  media.addEventListener('loadedmetadata', () => {
+    if (_watchStream !== stream) return;
+    if (_watchFrom && (!media.duration || _watchFrom < media.duration * 0.9)) media.currentTime = _watchFrom;
+    _watchReady = true;
+  }, { once: true });
+}
+
+function _watchCurrent() {
+  if (playerKind === 'video' && plyrPlayer) return plyrPlayer.currentTime;
+  if (playerKind === 'audio') return document.getElementById('audio-player')?.currentTime;
+  return NaN;
+}
+
+// _watchSave отправляет позицию не чаще раза в WATCH_SAVE_EVERY секунд; force — при паузе,
+// конце файла, переключении и уходе со страницы (тогда через sendBeacon).
+function _watchSave(cur, force) {
+  if (!_watchStream || !_watchReady || !isFinite(cur) || cur < 0) return;
+  cur = Math.floor(cur);
+  if (cur === _watchSent || (!force && Math.abs(cur - _watchSent) < WATCH_SAVE_EVERY)) return;
+  _watchSent = cur;
+  _watchLocal.set(_watchStream, cur);
+  const url  = _watchStream.replace(/\/stream$/, '/progress');
+  const body = new URLSearchParams({ position: String(cur) });
+  if (force && document.visibilityState === 'hidden' && navigator.sendBeacon) {
+    navigator.sendBeacon(url, body);
+    return;
+  }
+  fetch(url, { method: 'POST', body }).catch(() => {});
+}
+
+function _watchFlush() { _watchSave(_watchCurrent(), true); }
+
+window.addEventListener('pagehide', _watchFlush);
+document.addEventListener('visibilitychange', () => {
+  if (document.visibilityState === 'hidden') _watchFlush();
+});
+
+/* ── HLS: файлы, которые браузер не играет, сервер перекодирует на лету ── */
+let hlsPlayer = null;   // экземпляр hls.js
+let _hlsLib   = null;   // Promise<Hls|null>
+
+function _hlsDetach() {
+  if (hlsPlayer) { try { hlsPlayer.destroy(); } catch(e) {} hlsPlayer = null; }
+}
+
+// hls.js грузится только при первом HLS-видео; Safari играет HLS сам.
+function _loadHlsLib() {
+  if (!_hlsLib) {
+    _hlsLib = new Promise(resolve => {
+      if (window.Hls) { resolve(window.Hls); return; }
+      const s = document.createElement('script');
+      s.src = 'static/hls.min.js';
+      s.onload  = () => resolve(window.Hls || null);
+      s.onerror = () => resolve(null);
+      document.head.appendChild(s);
+    });
+  }
+  return _hlsLib;
+}
+
+function _setVideoSource(video, stream, hls) {
+  _hlsDetach();
+  if (!hls) { video.src = stream; return; }
+  if (video.canPlayType('application/vnd.apple.mpegurl')) { video.src = hls; return; }
+  _loadHlsLib().then(Hls => {
+    // Без hls.js пробуем файл как есть — вдруг браузер справится.
+    if (!Hls || !Hls.isSupported()) {
+      if (!Hls) console.warn('hls.js не найден: бинарь собран без go generate ./web');
+      video.src = stream;
+      return;
+    }
+    hlsPlayer = new Hls();
+    hlsPlayer.on(Hls.Events.MANIFEST_PARSED, () => {
+      if (plyrPlayer) plyrPlayer.play().catch(() => {});
+    });
+    hlsPlayer.loadSource(hls);
+    hlsPlayer.attachMedia(video);
+  });
+}
+
+/* Описание, главы и ссылка на источник из info.json под видео. */
+function _loadPlayerInfo(stream) {
+  const info = document.getElementById('player-info');
+  if (!info) return;
+  info.innerHTML = '';
+  fetch(stream.replace(/\/stream$/, '/info'))
+    .then(r => r.ok ? r.text() : '')
+    .then(html => { info.innerHTML = html; })
+    .catch(() => {});
+}
+
+function playerSeekTo(sec) {
+  if (!plyrPlayer) return;
+  plyrPlayer.currentTime = Number(sec) || 0;
+  plyrPlayer.play().catch(() => {});
 }
 
 /* ── Audio ── */
 function _openAudio(stream, title) {
   const audio = document.getElementById('audio-player');
   if (!audio) return;
+  _watchResume(audio, stream);
   audio.src = stream;
   audio.play().catch(() => {});
 }
@@ -350,6 +611,8 @@ function playerToggle() {
 
 /* ── Full stop ── */
 function playerClose() {
+  _watchFlush();
+  _watchStream = '';
   const kind = playerKind;
   // Clear playerKind BEFORE dlg.close() — the 'close' event fires asynchronously
   // (browser queues it as a task), so by the time it fires _closing would already
@@ -364,6 +627,7 @@ function playerClose() {
       try { plyrPlayer.pause(); plyrPlayer.destroy(); } catch(e) {}
       plyrPlayer = null;
     }
+    _hlsDetach();
     const video = document.getElementById('main-player');
     if (video) { video.src = ''; }
   } else if (kind === 'audio') {
@@ -479,14 +743,16 @@ function playerSeek(evt) {
   const a = document.getElementById('audio-player');
   if (!a) return;
   a.addEventListener('timeupdate', () => {
-    if (playerKind === 'audio') _setProgress(a.currentTime, a.duration);
+    if (playerKind !== 'audio') return;
+    _setProgress(a.currentTime, a.duration);
+    _watchSave(a.currentTime, false);
   });
   a.addEventListener('durationchange', () => {
     if (playerKind === 'audio') _setProgress(a.currentTime, a.duration);
   });
   a.addEventListener('play',  () => { if (playerKind === 'audio') _pbPlayIcon(true);  });
-  a.addEventListener('pause', () => { if (playerKind === 'audio') _pbPlayIcon(false); });
-  a.addEventListener('ended', () => { if (playerKind === 'audio') _pbPlayIcon(false); });
+  a.addEventListener('pause', () => { if (playerKind === 'audio') { _pbPlayIcon(false); _watchFlush(); } });
+  a.addEventListener('ended', () => { if (playerKind === 'audio') { _pbPlayIcon(false); _watchFlush(); } });
 })();
 
 /* ════════════════════════════════════════════════════
@@ -499,8 +765,28 @@ function openLog(jobId, title) {
   if (!dlg) return;
   if (titleEl) titleEl.textContent = 'Лог: ' + (title || jobId);
   if (content) content.textContent = 'Загрузка…';
+  const attempts = document.getElementById('log-attempts');
+  if (attempts) {
+    attempts.innerHTML = '';
+    fetch(base() + 'jobs/' + jobId + '/attempts')
+      .then(r => r.ok ? r.text() : '')
+      .then(html => { attempts.innerHTML = html; })
+      .catch(() => {});
+  }
   dlg.showModal();
-  fetch(base() + 'jobs/' + jobId + '/log')
+  loadLog(base() + 'jobs/' + jobId + '/log');
+}
+
+/* Лог выбранной попытки из хронологии в диалоге. */
+function showAttemptLog(btn) {
+  document.querySelectorAll('#log-attempts .attempt').forEach(b => b.classList.toggle('attempt-current', b === btn));
+  loadLog(base() + 'jobs/' + btn.dataset.jobId + '/log?attempt=' + btn.dataset.attempt);
+}
+
+function loadLog(url) {
+  const content = document.getElementById('log-content');
+  if (content) content.textContent = 'Загрузка…';
+  fetch(url)
     .then(r => r.text())
     .then(t => { if (content) content.textContent = t || '(пусто)'; })
     .catch(e => { if (content) content.textContent = 'Ошибка: ' + e; });
@@ -602,6 +888,11 @@ function updateActionBar() {
     const allAudio = n > 0 && [...selectedItems.values()].every(e => e.kind === 'audio' && e.itemId);
     metaBtn.style.display = allAudio ? '' : 'none';
   }
+  const transcodeBtn = document.getElementById('action-transcode-btn');
+  if (transcodeBtn) {
+    const allVideo = n > 0 && [...selectedItems.values()].every(e => e.kind === 'video' && e.itemId);
+    transcodeBtn.style.display = allVideo ? '' : 'none';
+  }
 }
 
 function clearSelection() {
@@ -712,6 +1003,51 @@ async function applyMeta() {
   }
 }
 
+/* ── Transcode dialog ── */
+let _transcodeIds = []; // item_id выбранных видео или одного видео из меню строки
+
+function openTranscodeFromMenu(row) {
+  if (openMenu) { openMenu.classList.remove('open'); openMenu = null; }
+  _openTranscode([row.dataset.itemId].filter(Boolean));
+}
+
+function openTranscodeDialog() {
+  _openTranscode([...selectedItems.values()].map(e => e.itemId).filter(Boolean));
+}
+
+function _openTranscode(ids) {
+  _transcodeIds = ids;
+  if (!ids.length) return;
+  const titleEl = document.getElementById('transcode-dialog-title');
+  if (titleEl) titleEl.textContent = ids.length === 1 ? 'Перекодировать' : `Перекодировать (${ids.length} файлов)`;
+  const noteEl = document.getElementById('transcode-count-note');
+  if (noteEl) noteEl.textContent = 'Результат появится рядом с исходным файлом';
+  const replace = document.getElementById('transcode-replace');
+  if (replace) replace.checked = false;
+  document.getElementById('transcode-dialog')?.showModal();
+}
+
+async function applyTranscode() {
+  const dlg = document.getElementById('transcode-dialog');
+  const r = await fetch(base() + 'items/transcode', {
+    method:  'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body:    JSON.stringify({
+      item_ids: _transcodeIds,
+      preset:   document.getElementById('transcode-preset')?.value || '',
+      replace:  !!document.getElementById('transcode-replace')?.checked,
+    }),
+  });
+  dlg?.close();
+  _transcodeIds = [];
+  if (r.ok) {
+    clearSelection();
+    showToast('Перекодирование запущено');
+  } else {
+    r.text().then(t => showToast('Ошибка: ' + t));
+  }
+}
+
 function bulkTag() {
   const name = window.prompt('Тег для всех выбранных:');
   if (!name || !selectedJobs.size) return;
@@ -802,10 +1138,8 @@ async function createAndAddToCollection() {
 
 /* ── Status filter ── */
 function activateStatusFilter(status) {
-  filter.tag = status;
+  filter.tags = [status];
   filter.kind = '';
-  const ft = document.getElementById('filter-tag');
-  if (ft) ft.value = status;
   applyFilter();
 }
 
@@ -814,3 +1148,87 @@ document.body.addEventListener('collectionsRefresh', () => {
   const sidebar = document.getElementById('sidebar');
   if (sidebar) htmx.ajax('GET', 'library/sidebar', { target: '#sidebar', swap: 'outerHTML' });
 });
+
+/* ════════════════════════════════════════════════════
+   LIVE EVENTS (SSE)
+   ════════════════════════════════════════════════════ */
+(function () {
+  const es = new EventSource(base() + 'events');
+
+  // Частые события (пачка job.status при разворачивании плейлиста) схлопываем в один запрос.
+  let refreshTimer;
+  function refreshAll() {
+    clearTimeout(refreshTimer);
+    refreshTimer = setTimeout(() => htmx.trigger(document.body, 'mediaRefresh'), 300);
+  }
+
+  function queueVisible() {
+    const q = document.getElementById('queue-section');
+    return q && q.style.display !== 'none';
+  }
+
+  function fmtBytes(b) {
+    if (b >= 1 << 30) return (b / (1 << 30)).toFixed(1) + ' GB';
+    if (b >= 1 << 20) return (b / (1 << 20)).toFixed(1) + ' MB';
+    if (b >= 1 << 10) return Math.round(b / (1 << 10)) + ' KB';
+    return b + ' B';
+  }
+
+  // job.progress: обновляем полосу на месте, без перезапроса фрагмента.
+  es.addEventListener('job.progress', (e) => {
+    const d = JSON.parse(e.data);
+    const row = document.querySelector('#queue-inner .queue-row[data-job-id="' + d.id + '"]');
+    if (!row) return;
+    const bar  = row.querySelector('.queue-progress');
+    const fill = row.querySelector('.queue-progress-fill');
+    const text = row.querySelector('.queue-progress-text');
+    if (bar)  bar.classList.toggle('indeterminate', !d.total);
+    if (fill) fill.style.width = d.percent.toFixed(1) + '%';
+    if (text) {
+      const parts = d.total
+        ? [Math.round(d.percent) + '%', fmtBytes(d.downloaded) + ' / ' + fmtBytes(d.total)]
+        : [fmtBytes(d.downloaded)];
+      if (d.speed > 0) parts.push(fmtBytes(Math.round(d.speed)) + '/s');
+      if (d.eta >= 0) parts.push('~' + Math.floor(d.eta / 60) + ':' + String(d.eta % 60).padStart(2, '0'));
+      text.textContent = parts.join(' · ');
+    }
+  });
+
+  // job.status: в открытой очереди меняем только строку задания, иначе перезапрашиваем списки.
+  es.addEventListener('job.status', (e) => {
+    const d = JSON.parse(e.data);
+    const row = d.id && document.querySelector('#queue-inner .queue-row[data-job-id="' + d.id + '"]');
+    if (row && queueVisible()) {
+      htmx.ajax('GET', 'queue/jobs/' + d.id, { target: row, swap: 'outerHTML' });
+      return;
+    }
+    refreshAll();
+  });
+
+  // operation.progress: полоса долгой операции (перекодирование) — тоже на месте.
+  es.addEventListener('operation.progress', (e) => {
+    const d = JSON.parse(e.data);
+    const row = document.querySelector('#queue-inner .op-row[data-op-id="' + d.id + '"]');
+    if (!row) return;
+    const bar  = row.querySelector('.queue-progress');
+    const fill = row.querySelector('.queue-progress-fill');
+    const text = row.querySelector('.queue-progress-text');
+    if (bar)  bar.classList.remove('indeterminate');
+    if (fill) fill.style.width = d.percent.toFixed(1) + '%';
+    if (text) text.textContent = [Math.round(d.percent) + '%', d.text].filter(Boolean).join(' · ');
+  });
+
+  es.addEventListener('item.created', refreshAll);
+  es.addEventListener('item.deleted', refreshAll);
+  es.addEventListener('operation.started', refreshAll);
+  // queue.state: очередь поставили на паузу или сняли с неё — перерисовываем кнопку и список.
+  es.addEventListener('queue.state', refreshAll);
+  es.addEventListener('operation.finished', () => {
+    refreshAll();
+    htmx.trigger(document.body, 'tagsRefresh');
+  });
+  es.addEventListener('collection.changed', () => {
+    htmx.trigger(document.body, 'collectionsRefresh');
+    htmx.trigger(document.body, 'tagsRefresh');
+  });
+})();
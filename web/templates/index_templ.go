@@ -10,6 +10,7 @@ import templruntime "github.com/a-h/templ/runtime"
 
 import (
 	"github.com/dr-duke/talmorGo/internal/model"
+	"github.com/dr-duke/talmorGo/internal/transcode"
 	"strconv"
 )
 
@@ -54,7 +55,7 @@ func SidebarNav(cols []*model.Collection) templ.Component {
 				var templ_7745c5c3_Var2 string
 				templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(c.Name)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 32, Col: 23}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 33, Col: 23}
 				}
 				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 				if templ_7745c5c3_Err != nil {
@@ -67,7 +68,7 @@ func SidebarNav(cols []*model.Collection) templ.Component {
 				var templ_7745c5c3_Var3 string
 				templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(c.Name)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 34, Col: 19}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 35, Col: 19}
 				}
 				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 				if templ_7745c5c3_Err != nil {
@@ -80,7 +81,7 @@ func SidebarNav(cols []*model.Collection) templ.Component {
 				var templ_7745c5c3_Var4 string
 				templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(c.Name)
 				if templ_7745c5c3_Err != nil {
-					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 37, Col: 104}
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 38, Col: 104}
 				}
 				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 				if templ_7745c5c3_Err != nil {
@@ -98,7 +99,7 @@ func SidebarNav(cols []*model.Collection) templ.Component {
 					var templ_7745c5c3_Var5 string
 					templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(itoa(c.ItemCount))
 					if templ_7745c5c3_Err != nil {
-						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 39, Col: 53}
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 40, Col: 53}
 					}
 					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
 					if templ_7745c5c3_Err != nil {
@@ -123,7 +124,12 @@ func SidebarNav(cols []*model.Collection) templ.Component {
 	})
 }
 
-func Index(basePath string, siteName string, cols []*model.Collection) templ.Component {
+// searchHint — подсказка к полю поиска о синтаксисе запроса.
+const searchHint = `Слова ищутся по началу: «прог» найдёт «программирование».
+"В кавычках" — точная фраза.
+tag:имя, domain:youtube.com, kind:video или kind:audio — фильтры.`
+
+func Index(basePath string, siteName string, cols []*model.Collection, user *model.User, presets []*model.Preset) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -163,13 +169,173 @@ func Index(basePath string, siteName string, cols []*model.Collection) templ.Com
 			var templ_7745c5c3_Var8 string
 			templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(siteName)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 54, Col: 46}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 60, Col: 46}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</span></a><div class=\"header-spacer\"></div><form class=\"header-add-form\" hx-post=\"queue\" hx-swap=\"none\" hx-on::after-request=\"if(event.detail.successful)this.reset()\"><input type=\"url\" name=\"url\" class=\"header-url-input\" placeholder=\"Вставьте ссылку…\" autocomplete=\"off\" required> <button type=\"submit\" class=\"btn btn-primary btn-sm\"><span class=\"mi\">download</span> <span class=\"header-add-btn-text\">Скачать</span></button></form><a href=\"settings\" class=\"icon-btn\" title=\"Настройки\"><span class=\"mi\">settings</span></a></header><div class=\"app-body\"><!-- ── Sidebar ── -->")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</span></a><div class=\"header-spacer\"></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if user.CanWrite() {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<form class=\"header-add-form\" hx-post=\"queue\" hx-swap=\"none\" hx-on::after-request=\"if(event.detail.successful)this.reset()\"><input type=\"url\" name=\"url\" class=\"header-url-input\" placeholder=\"Вставьте ссылку…\" autocomplete=\"off\" required> <details class=\"header-add-options\"><summary class=\"icon-btn\" title=\"Параметры загрузки\"><span class=\"mi\">tune</span></summary><div class=\"header-add-options-panel\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				if len(presets) > 0 {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<label>Пресет <select name=\"preset\"><option value=\"\">По умолчанию</option> ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					for _, p := range presets {
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "<option value=\"")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						var templ_7745c5c3_Var9 string
+						templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(p.ID)
+						if templ_7745c5c3_Err != nil {
+							return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 87, Col: 32}
+						}
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "\">")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						var templ_7745c5c3_Var10 string
+						templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(p.Name)
+						if templ_7745c5c3_Err != nil {
+							return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 87, Col: 43}
+						}
+						_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+						templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "</option>")
+						if templ_7745c5c3_Err != nil {
+							return templ_7745c5c3_Err
+						}
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "</select></label> ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "<label>Качество <select name=\"quality\"><option value=\"\">Лучшее</option> <option value=\"2160\">2160p</option> <option value=\"1440\">1440p</option> <option value=\"1080\">1080p</option> <option value=\"720\">720p</option> <option value=\"480\">480p</option> <option value=\"360\">360p</option> <option value=\"audio\">Только аудио</option></select></label> <label>Формат <select name=\"container\"><option value=\"\">По умолчанию</option> ")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				for _, c := range model.VideoContainers {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "<option value=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var11 string
+					templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(c)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 110, Col: 28}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "\">")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var12 string
+					templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(c)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 110, Col: 34}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "</option> ")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				for _, c := range model.AudioFormats {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "<option value=\"")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var13 string
+					templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(c)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 113, Col: 28}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "\">")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var14 string
+					templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinStringErrs(c)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 113, Col: 34}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, " (аудио)</option>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "</select></label> <label>Субтитры <input type=\"text\" name=\"sub_langs\" placeholder=\"en,ru\" autocomplete=\"off\"></label> <label class=\"header-add-check\"><input type=\"checkbox\" name=\"embed_thumbnail\" value=\"1\"> Встроить обложку</label></div></details> <button type=\"submit\" class=\"btn btn-primary btn-sm\"><span class=\"mi\">download</span> <span class=\"header-add-btn-text\">Скачать</span></button></form>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "<a href=\"settings\" class=\"icon-btn\" title=\"Настройки\"><span class=\"mi\">settings</span></a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			if user.ID != "" {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "<span class=\"header-user\" title=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var15 string
+				templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(roleLabel(user.Role))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 135, Col: 59}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var16 string
+				templ_7745c5c3_Var16, templ_7745c5c3_Err = templ.JoinStringErrs(user.Username)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 135, Col: 77}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var16))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "</span> <button class=\"icon-btn\" hx-post=\"logout\" title=\"Выйти\"><span class=\"mi\">logout</span></button>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "</header><div class=\"app-body\"><!-- ── Sidebar ── -->")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -177,7 +343,56 @@ func Index(basePath string, siteName string, cols []*model.Collection) templ.Com
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<!-- ── Main ── --><main class=\"main-content\"><!-- Library section --><div id=\"lib-section\" class=\"content-inner\"><div class=\"toolbar\"><input type=\"search\" id=\"media-search\" class=\"search-input\" placeholder=\"Поиск по названию, URL, домену…\" oninput=\"onSearch(this.value)\"><div class=\"toolbar-chips\"><div id=\"tag-cloud\" hx-get=\"library/tags\" hx-trigger=\"load once, tagsRefresh from:body\" hx-swap=\"outerHTML\"></div></div><button class=\"icon-btn\" onclick=\"selectAllVisible()\" title=\"Выбрать все отображаемые\"><span class=\"mi\">checklist</span></button></div><div id=\"play-all-bar\" class=\"play-all-bar\"><span class=\"mi\" style=\"color:var(--accent)\">folder</span> <span class=\"play-all-title\" id=\"play-all-title\"></span> <button class=\"btn btn-primary btn-sm\" onclick=\"playAll()\"><span class=\"mi\">play_arrow</span>Воспроизвести всё</button></div><div id=\"media-inner\" hx-get=\"library/items\" hx-trigger=\"load, mediaRefresh from:body\" hx-swap=\"outerHTML\" hx-include=\"#filter-form\"><div class=\"empty-state\" id=\"media-loading\"><span class=\"mi\">hourglass_empty</span><p>Загрузка…</p></div></div><!-- Скрытая форма фильтров --><form id=\"filter-form\" style=\"display:none\"><input id=\"filter-q\" name=\"q\" type=\"hidden\"> <input id=\"filter-kind\" name=\"kind\" type=\"hidden\"> <input id=\"filter-tag\" name=\"tag\" type=\"hidden\"></form></div><!-- Queue section --><div id=\"queue-section\" class=\"content-inner\" style=\"display:none\"><div class=\"queue-toolbar\"><button class=\"btn btn-ghost btn-sm\" hx-post=\"queue/cancel-all\" hx-swap=\"none\" title=\"Отменить все активные задачи\"><span class=\"mi\">cancel</span>Отменить все активные</button></div><div id=\"queue-inner\" hx-get=\"queue/items\" hx-trigger=\"load, mediaRefresh from:body\" hx-swap=\"outerHTML\"><div class=\"empty-state\"><span class=\"mi\">hourglass_empty</span><p>Загрузка…</p></div></div></div></main></div></div><!-- ── Диалог видеоплеера ── --> <dialog id=\"player-dialog\"><div class=\"dialog-header video-dialog-header\"><span class=\"dialog-title\" id=\"player-title\"></span> <button class=\"icon-btn\" onclick=\"playerMinimize()\" title=\"Свернуть\"><span class=\"mi\">close_fullscreen</span></button> <button class=\"icon-btn player-close\" onclick=\"playerClose()\" title=\"Закрыть\"><span class=\"mi\">close</span></button></div><div id=\"player-wrap\"><video id=\"main-player\" playsinline style=\"width:100%;display:block\"></video></div></dialog><!-- ── Аудио элемент (скрытый, управляется player bar) ── --> <audio id=\"audio-player\" preload=\"auto\" style=\"display:none\"></audio><!-- ── Player bar ── --> <div id=\"player-bar\" class=\"player-bar\"><div class=\"pb-info\"><span class=\"mi pb-kind-icon\" id=\"pb-kind-icon\">play_circle</span> <span class=\"pb-title\" id=\"pb-title\"></span></div><div class=\"pb-center\"><span class=\"pb-time\" id=\"pb-current\">0:00</span><div class=\"pb-track\" id=\"pb-track\" onclick=\"playerSeek(event)\"><div class=\"pb-fill\" id=\"pb-fill\"></div></div><span class=\"pb-time\" id=\"pb-duration\">0:00</span></div><div class=\"pb-controls\"><button class=\"icon-btn\" id=\"pb-expand-btn\" onclick=\"playerExpand()\" title=\"Развернуть\" style=\"display:none\"><span class=\"mi\">open_in_full</span></button> <button class=\"icon-btn pb-play-btn\" id=\"pb-play-btn\" onclick=\"playerToggle()\" title=\"Пауза/Воспроизведение\"><span class=\"mi\" id=\"pb-play-icon\">pause</span></button> <button class=\"icon-btn\" onclick=\"playerClose()\" title=\"Остановить\"><span class=\"mi\">close</span></button></div></div><dialog id=\"log-dialog\"><div class=\"dialog-header\"><span class=\"dialog-title\" id=\"log-title\">Лог скачивания</span> <button class=\"icon-btn player-close\" onclick=\"document.getElementById('log-dialog').close()\"><span class=\"mi\">close</span></button></div><pre id=\"log-content\">Загрузка…</pre></dialog><!-- ── Диалог редактирования аудио-тегов ── --> <dialog id=\"meta-dialog\"><div class=\"dialog-header\"><span class=\"dialog-title\" id=\"meta-dialog-title\">Теги аудио</span> <button class=\"icon-btn\" onclick=\"document.getElementById('meta-dialog').close()\"><span class=\"mi\">close</span></button></div><div class=\"meta-dialog-body\"><table class=\"meta-matrix\"><tbody><tr class=\"meta-row\" data-field=\"title\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Название</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-title\" placeholder=\"Название трека\"></td></tr><tr class=\"meta-row\" data-field=\"artist\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Исполнитель</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-artist\" placeholder=\"Исполнитель\"></td></tr><tr class=\"meta-row\" data-field=\"album\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Альбом</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-album\" placeholder=\"Альбом\"></td></tr><tr class=\"meta-row\" data-field=\"year\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Год</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-year\" placeholder=\"2024\"></td></tr><tr class=\"meta-row\" data-field=\"genre\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Жанр</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-genre\" placeholder=\"Жанр\"></td></tr></tbody></table><div class=\"meta-footer\"><span id=\"meta-count-note\" class=\"meta-count-note\"></span> <button class=\"btn btn-primary btn-sm\" onclick=\"applyMeta()\">Применить</button></div></div></dialog>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "<!-- ── Main ── --><main class=\"main-content\"><!-- Library section --><div id=\"lib-section\" class=\"content-inner\"><div class=\"toolbar\"><input type=\"search\" id=\"media-search\" class=\"search-input\" placeholder=\"Поиск по названию, тегам, описанию, субтитрам…\" title=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var17 string
+			templ_7745c5c3_Var17, templ_7745c5c3_Err = templ.JoinStringErrs(searchHint)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 154, Col: 26}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var17))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 33, "\" oninput=\"onSearch(this.value)\"><div class=\"toolbar-chips\"><div id=\"tag-cloud\" hx-get=\"library/tags\" hx-trigger=\"load once, tagsRefresh from:body\" hx-swap=\"outerHTML\"></div></div><button id=\"tag-mode-btn\" class=\"chip\" style=\"display:none\" onclick=\"toggleTagMode()\" title=\"Показывать файлы со всеми выбранными тегами или хотя бы с одним\">все теги</button> <select id=\"watch-select\" class=\"toolbar-select\" onchange=\"setWatch(this.value)\" title=\"Просмотр\"><option value=\"\">Все</option> <option value=\"unwatched\">Непросмотренные</option> <option value=\"in_progress\">Начатые</option></select> <select id=\"sort-select\" class=\"toolbar-select\" onchange=\"setSort(this.value)\" title=\"Сортировка; по умолчанию при поиске — сначала подходящие, иначе — новые\"><option value=\"\">По умолчанию</option> <option value=\"relevance\">Сначала подходящие</option> <option value=\"date\">Сначала новые</option> <option value=\"date:asc\">Сначала старые</option> <option value=\"name\">По названию</option> <option value=\"size\">Сначала большие</option> <option value=\"size:asc\">Сначала маленькие</option> <option value=\"duration\">Сначала длинные</option> <option value=\"duration:asc\">Сначала короткие</option> <option value=\"domain\">По сайту</option></select> <details id=\"filter-more\" class=\"header-add-options filter-more\"><summary class=\"icon-btn\" title=\"Фильтры\"><span class=\"mi\">filter_list</span></summary><div class=\"header-add-options-panel\"><label>Сайт <input type=\"text\" name=\"domain\" placeholder=\"youtube.com\" autocomplete=\"off\" onchange=\"setFilterField(this)\"></label> <label>Источник <select name=\"source\" onchange=\"setFilterField(this)\"><option value=\"\">Любой</option> <option value=\"web\">Веб-интерфейс</option> <option value=\"telegram\">Telegram</option> <option value=\"api\">API</option> <option value=\"subscription\">Подписки</option> <option value=\"filesystem\">Найдены на диске</option></select></label> <label>Состояние <select name=\"status\" onchange=\"setFilterField(this)\"><option value=\"\">Любое</option> <option value=\"available\">Доступные</option> <option value=\"active\">В очереди</option> <option value=\"failed\">С ошибкой</option> <option value=\"deleted\">Удалённые</option> <option value=\"missing\">Потерянные</option></select></label><div class=\"filter-range\"><label>Размер от, МБ <input type=\"number\" name=\"min_mb\" min=\"0\" onchange=\"setFilterField(this)\"></label> <label>до <input type=\"number\" name=\"max_mb\" min=\"0\" onchange=\"setFilterField(this)\"></label></div><div class=\"filter-range\"><label>Добавлены с <input type=\"date\" name=\"from\" onchange=\"setFilterField(this)\"></label> <label>по <input type=\"date\" name=\"to\" onchange=\"setFilterField(this)\"></label></div><button type=\"button\" class=\"btn btn-ghost btn-sm\" onclick=\"resetMoreFilters()\">Сбросить</button></div></details> <button class=\"icon-btn\" onclick=\"selectAllVisible()\" title=\"Выбрать все отображаемые\"><span class=\"mi\">checklist</span></button></div><div id=\"play-all-bar\" class=\"play-all-bar\"><span class=\"mi\" style=\"color:var(--accent)\">folder</span> <span class=\"play-all-title\" id=\"play-all-title\"></span> <button class=\"btn btn-primary btn-sm\" onclick=\"playAll()\"><span class=\"mi\">play_arrow</span>Воспроизвести всё</button></div><div id=\"media-inner\" hx-get=\"library/items\" hx-trigger=\"load, mediaRefresh from:body\" hx-swap=\"outerHTML\" hx-include=\"#filter-form\"><div class=\"empty-state\" id=\"media-loading\"><span class=\"mi\">hourglass_empty</span><p>Загрузка…</p></div></div><!-- Скрытая форма фильтров: поля заполняет syncFilterForm() в app.js --><form id=\"filter-form\" style=\"display:none\"></form></div><!-- Queue section --><div id=\"queue-section\" class=\"content-inner\" style=\"display:none\"><div class=\"queue-toolbar\"><button class=\"btn btn-ghost btn-sm\" hx-post=\"queue/pause\" hx-swap=\"none\" title=\"Не начинать новые загрузки; текущие докачаются\"><span class=\"mi\">pause</span>Пауза</button> <button class=\"btn btn-ghost btn-sm\" hx-post=\"queue/cancel-all\" hx-swap=\"none\" title=\"Отменить все активные задачи\"><span class=\"mi\">cancel</span>Отменить все активные</button></div><div id=\"queue-inner\" hx-get=\"queue/items\" hx-trigger=\"load, mediaRefresh from:body\" hx-swap=\"outerHTML\"><div class=\"empty-state\"><span class=\"mi\">hourglass_empty</span><p>Загрузка…</p></div></div></div></main></div></div><!-- ── Диалог видеоплеера ── --> <dialog id=\"player-dialog\"><div class=\"dialog-header video-dialog-header\"><span class=\"dialog-title\" id=\"player-title\"></span> <button class=\"icon-btn\" onclick=\"playerMinimize()\" title=\"Свернуть\"><span class=\"mi\">close_fullscreen</span></button> <button class=\"icon-btn player-close\" onclick=\"playerClose()\" title=\"Закрыть\"><span class=\"mi\">close</span></button></div><div id=\"player-wrap\"><video id=\"main-player\" playsinline style=\"width:100%;display:block\"></video></div><div id=\"player-info\"></div></dialog><!-- ── Аудио элемент (скрытый, управляется player bar) ── --> <audio id=\"audio-player\" preload=\"auto\" style=\"display:none\"></audio><!-- ── Player bar ── --> <div id=\"player-bar\" class=\"player-bar\"><div class=\"pb-info\"><span class=\"mi pb-kind-icon\" id=\"pb-kind-icon\">play_circle</span> <span class=\"pb-title\" id=\"pb-title\"></span></div><div class=\"pb-center\"><span class=\"pb-time\" id=\"pb-current\">0:00</span><div class=\"pb-track\" id=\"pb-track\" onclick=\"playerSeek(event)\"><div class=\"pb-fill\" id=\"pb-fill\"></div></div><span class=\"pb-time\" id=\"pb-duration\">0:00</span></div><div class=\"pb-controls\"><button class=\"icon-btn\" id=\"pb-expand-btn\" onclick=\"playerExpand()\" title=\"Развернуть\" style=\"display:none\"><span class=\"mi\">open_in_full</span></button> <button class=\"icon-btn pb-play-btn\" id=\"pb-play-btn\" onclick=\"playerToggle()\" title=\"Пауза/Воспроизведение\"><span class=\"mi\" id=\"pb-play-icon\">pause</span></button> <button class=\"icon-btn\" onclick=\"playerClose()\" title=\"Остановить\"><span class=\"mi\">close</span></button></div></div><dialog id=\"log-dialog\"><div class=\"dialog-header\"><span class=\"dialog-title\" id=\"log-title\">Лог скачивания</span> <button class=\"icon-btn player-close\" onclick=\"document.getElementById('log-dialog').close()\"><span class=\"mi\">close</span></button></div><div id=\"log-attempts\"></div><pre id=\"log-content\">Загрузка…</pre></dialog><!-- ── Диалог редактирования аудио-тегов ── --> <dialog id=\"meta-dialog\"><div class=\"dialog-header\"><span class=\"dialog-title\" id=\"meta-dialog-title\">Теги аудио</span> <button class=\"icon-btn\" onclick=\"document.getElementById('meta-dialog').close()\"><span class=\"mi\">close</span></button></div><div class=\"meta-dialog-body\"><table class=\"meta-matrix\"><tbody><tr class=\"meta-row\" data-field=\"title\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Название</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-title\" placeholder=\"Название трека\"></td></tr><tr class=\"meta-row\" data-field=\"artist\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Исполнитель</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-artist\" placeholder=\"Исполнитель\"></td></tr><tr class=\"meta-row\" data-field=\"album\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Альбом</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-album\" placeholder=\"Альбом\"></td></tr><tr class=\"meta-row\" data-field=\"year\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Год</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-year\" placeholder=\"2024\"></td></tr><tr class=\"meta-row\" data-field=\"genre\"><td><input type=\"checkbox\" class=\"meta-check\" onchange=\"metaCheckChange(this)\"></td><td class=\"meta-label\">Жанр</td><td><input type=\"text\" class=\"meta-input\" id=\"meta-genre\" placeholder=\"Жанр\"></td></tr></tbody></table><div class=\"meta-footer\"><span id=\"meta-count-note\" class=\"meta-count-note\"></span> <button class=\"btn btn-primary btn-sm\" onclick=\"applyMeta()\">Применить</button></div></div></dialog><!-- ── Диалог перекодирования ── --> <dialog id=\"transcode-dialog\"><div class=\"dialog-header\"><span class=\"dialog-title\" id=\"transcode-dialog-title\">Перекодировать</span> <button class=\"icon-btn\" onclick=\"document.getElementById('transcode-dialog').close()\"><span class=\"mi\">close</span></button></div><div class=\"meta-dialog-body\"><select id=\"transcode-preset\" class=\"meta-input\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			for _, p := range transcode.Presets {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 34, "<option value=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var18 string
+				templ_7745c5c3_Var18, templ_7745c5c3_Err = templ.JoinStringErrs(p.ID)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 418, Col: 26}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var18))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 35, "\">")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var19 string
+				templ_7745c5c3_Var19, templ_7745c5c3_Err = templ.JoinStringErrs(p.Title)
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/index.templ`, Line: 418, Col: 38}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var19))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 36, "</option>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 37, "</select> <label class=\"transcode-replace\"><input type=\"checkbox\" id=\"transcode-replace\"> Заменить исходный файл</label><div class=\"meta-footer\"><span id=\"transcode-count-note\" class=\"meta-count-note\"></span> <button class=\"btn btn-primary btn-sm\" onclick=\"applyTranscode()\">Запустить</button></div></div></dialog>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -185,7 +400,7 @@ func Index(basePath string, siteName string, cols []*model.Collection) templ.Com
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, " <!-- ── SSE: dispatches htmx mediaRefresh ── --> <script>\n\t\t\t(function() {\n\t\t\t\tconst base = document.querySelector('base')?.href || '/';\n\t\t\t\tconst es = new EventSource(base + 'events');\n\t\t\t\tfunction dispatchRefresh() {\n\t\t\t\t\thtmx.trigger(document.body, 'mediaRefresh');\n\t\t\t\t}\n\t\t\t\tes.addEventListener('update', dispatchRefresh);\n\t\t\t\tes.addEventListener('message', dispatchRefresh);\n\t\t\t})();\n\t\t</script> <script src=\"static/app.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 38, " <script src=\"static/app.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
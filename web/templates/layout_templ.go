@@ -88,7 +88,7 @@ func Layout(title string, basePath string, siteName string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\"><link rel=\"icon\" type=\"image/svg+xml\" href=\"static/logo.svg\"><link rel=\"stylesheet\" href=\"https://fonts.googleapis.com/css2?family=Material+Symbols+Rounded:opsz,wght,FILL,GRAD@20..48,100..700,0..1,-50..200&display=block\"><link rel=\"stylesheet\" href=\"static/plyr.min.css\"><script src=\"static/htmx.min.js\"></script><script src=\"static/plyr.min.js\"></script><style>\n\t\t\t\t*, *::before, *::after { box-sizing: border-box; margin: 0; padding: 0; }\n\n\t\t\t\t/* ── Tokens ── */\n\t\t\t\t:root {\n\t\t\t\t\t--bg:           #0d0d0f;\n\t\t\t\t\t--surface:      #17171c;\n\t\t\t\t\t--surface-2:    #1f1f26;\n\t\t\t\t\t--surface-3:    #27272f;\n\t\t\t\t\t--border:       #2c2c36;\n\t\t\t\t\t--border-soft:  #222228;\n\t\t\t\t\t--text:         #dcdce8;\n\t\t\t\t\t--text-2:       #8a8a9a;\n\t\t\t\t\t--text-3:       #55555f;\n\t\t\t\t\t--accent:       #7b93c8;\n\t\t\t\t\t--accent-dim:   #1c2c48;\n\t\t\t\t\t--accent-on:    #0d1520;\n\t\t\t\t\t--danger:       #d4665a;\n\t\t\t\t\t--danger-dim:   #3a1a18;\n\t\t\t\t\t--warn-fg:      #d4a054;\n\t\t\t\t\t--warn-dim:     #362810;\n\t\t\t\t\t--ok-fg:        #5aab7a;\n\t\t\t\t\t--ok-dim:       #0e2e1c;\n\t\t\t\t\t--scrim:        rgba(0,0,0,.6);\n\t\t\t\t\t--radius:       10px;\n\t\t\t\t\t--radius-sm:    6px;\n\t\t\t\t\t--mono:         'JetBrains Mono','Fira Code','Cascadia Code',monospace;\n\t\t\t\t}\n\n\t\t\t\thtml, body { height: 100%; background: var(--bg); color: var(--text); }\n\t\t\t\tbody { font-family: system-ui,-apple-system,'Segoe UI',sans-serif; font-size: 14px; line-height: 1.5; }\n\n\t\t\t\t/* ── Icons ── */\n\t\t\t\t.mi {\n\t\t\t\t\tfont-family: 'Material Symbols Rounded';\n\t\t\t\t\tfont-size: 18px; font-weight: 400; line-height: 1;\n\t\t\t\t\tdisplay: inline-block; user-select: none;\n\t\t\t\t\tfont-variation-settings: 'FILL' 0,'wght' 400,'GRAD' 0,'opsz' 20;\n\t\t\t\t\tvertical-align: middle;\n\t\t\t\t}\n\n\t\t\t\t/* ── Icon button ── */\n\t\t\t\t.icon-btn {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; justify-content: center;\n\t\t\t\t\twidth: 32px; height: 32px; border-radius: 50%;\n\t\t\t\t\tborder: none; background: transparent; cursor: pointer;\n\t\t\t\t\tcolor: var(--text-2); transition: background .15s, color .15s;\n\t\t\t\t\tflex-shrink: 0;\n\t\t\t\t}\n\t\t\t\t.icon-btn:hover { background: var(--surface-3); color: var(--text); }\n\t\t\t\t.icon-btn.danger { color: var(--danger); }\n\t\t\t\t.icon-btn.danger:hover { background: var(--danger-dim); }\n\n\t\t\t\t/* ── Buttons ── */\n\t\t\t\t.btn {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; gap: .4rem;\n\t\t\t\t\tborder: none; border-radius: 9999px; cursor: pointer;\n\t\t\t\t\tfont-size: .8125rem; font-weight: 500; padding: .45rem 1.1rem;\n\t\t\t\t\twhite-space: nowrap; transition: filter .15s;\n\t\t\t\t}\n\t\t\t\t.btn-primary { background: var(--accent); color: var(--accent-on); }\n\t\t\t\t.btn-primary:hover { filter: brightness(1.12); }\n\t\t\t\t.btn-ghost {\n\t\t\t\t\tbackground: var(--surface-2); color: var(--text);\n\t\t\t\t\tborder: 1px solid var(--border);\n\t\t\t\t}\n\t\t\t\t.btn-ghost:hover { background: var(--surface-3); }\n\t\t\t\t.btn-danger { background: var(--danger); color: #fff; }\n\t\t\t\t.btn-danger:hover { filter: brightness(1.1); }\n\t\t\t\t.btn-secondary { background: var(--surface-3); color: var(--text); border: 1px solid var(--border); }\n\t\t\t\t.btn-secondary:hover { background: var(--surface-2); }\n\t\t\t\t.btn-sm { padding: .3rem .75rem; font-size: .75rem; }\n\n\t\t\t\t/* ── Chips ── */\n\t\t\t\t.chip {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; gap: .3rem;\n\t\t\t\t\tpadding: .2rem .65rem; border-radius: 9999px;\n\t\t\t\t\tborder: 1px solid var(--border); background: transparent;\n\t\t\t\t\tcolor: var(--text-2); font-size: .75rem; cursor: pointer;\n\t\t\t\t\twhite-space: nowrap; transition: background .12s, color .12s, border-color .12s;\n\t\t\t\t}\n\t\t\t\t.chip:hover { background: var(--surface-2); color: var(--text); }\n\t\t\t\t.chip.active { background: var(--accent); color: var(--accent-on); border-color: transparent; }\n\t\t\t\t.chip-remove {\n\t\t\t\t\tbackground: none; border: none; cursor: pointer; color: inherit;\n\t\t\t\t\tfont-size: .65rem; padding: 0; line-height: 1; opacity: .6;\n\t\t\t\t}\n\t\t\t\t.chip-remove:hover { opacity: 1; }\n\n\t\t\t\t/* ── Status colours ── */\n\t\t\t\t.s-checking,.s-pending,.s-running { background: var(--accent-dim); color: var(--accent); }\n\t\t\t\t.s-done,.s-imported                { background: var(--ok-dim);     color: var(--ok-fg); }\n\t\t\t\t.s-retrying,.s-missing             { background: var(--warn-dim);   color: var(--warn-fg); }\n\t\t\t\t.s-failed,.s-cancelled,.s-deleted  { background: var(--danger-dim); color: var(--danger); }\n\t\t\t\t.s-hidden                          { background: var(--surface-2);  color: var(--text-2); }\n\n\t\t\t\t/* ── Search ── */\n\t\t\t\t.search-input {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 9999px; color: var(--text);\n\t\t\t\t\tfont-size: .875rem; padding: .4rem 1rem; outline: none; min-width: 0;\n\t\t\t\t}\n\t\t\t\t.search-input:focus { border-color: var(--accent); }\n\t\t\t\t.search-input::placeholder { color: var(--text-3); }\n\n\t\t\t\t/* ── App shell ── */\n\t\t\t\t.app-shell { display: flex; flex-direction: column; height: 100vh; }\n\n\t\t\t\t/* ── Header ── */\n\t\t\t\t.app-header {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tpadding: 0 1rem; height: 52px; flex-shrink: 0;\n\t\t\t\t\tbackground: var(--surface); border-bottom: 1px solid var(--border);\n\t\t\t\t\tposition: sticky; top: 0; z-index: 40;\n\t\t\t\t}\n\t\t\t\t.header-logo { display: flex; align-items: center; gap: .5rem; text-decoration: none; }\n\t\t\t\t.header-logo-name { font-size: 1rem; font-weight: 700; color: var(--text); letter-spacing: -.01em; }\n\t\t\t\t.header-spacer { flex: 1; }\n\t\t\t\t.header-add-form { display: flex; gap: .4rem; align-items: center; }\n\t\t\t\t.header-url-input {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 9999px; color: var(--text);\n\t\t\t\t\tfont-size: .8rem; padding: .35rem .875rem; outline: none; width: 260px;\n\t\t\t\t}\n\t\t\t\t.header-url-input:focus { border-color: var(--accent); }\n\t\t\t\t.header-url-input::placeholder { color: var(--text-3); }\n\n\t\t\t\t/* ── Body layout ── */\n\t\t\t\t.app-body { display: flex; flex: 1; min-height: 0; }\n\n\t\t\t\t/* ── Sidebar ── */\n\t\t\t\t.sidebar {\n\t\t\t\t\twidth: 200px; flex-shrink: 0;\n\t\t\t\t\tborder-right: 1px solid var(--border-soft);\n\t\t\t\t\tdisplay: flex; flex-direction: column;\n\t\t\t\t\toverflow-y: auto; padding: .5rem 0;\n\t\t\t\t\tposition: sticky; top: 52px; height: calc(100vh - 52px);\n\t\t\t\t}\n\t\t\t\t.sidebar-nav-item {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tpadding: .45rem .75rem .45rem 1rem;\n\t\t\t\t\tbackground: none; border: none; cursor: pointer;\n\t\t\t\t\tcolor: var(--text-2); font-size: .8125rem;\n\t\t\t\t\tborder-radius: 0 20px 20px 0; margin-right: .5rem;\n\t\t\t\t\ttransition: background .12s, color .12s; text-align: left; width: calc(100% - .5rem);\n\t\t\t\t}\n\t\t\t\t.sidebar-nav-item:hover { background: var(--surface-2); color: var(--text); }\n\t\t\t\t.sidebar-nav-item.active { background: var(--accent-dim); color: var(--accent); font-weight: 600; }\n\t\t\t\t.sidebar-queue-item { color: var(--text); font-weight: 500; }\n\t\t\t\t.sidebar-queue-divider { height: 1px; background: var(--border-soft); margin: .5rem 0; }\n\t\t\t\t.sidebar-section-label {\n\t\t\t\t\tdisplay: block; padding: .75rem 1rem .2rem;\n\t\t\t\t\tfont-size: .65rem; font-weight: 700; text-transform: uppercase;\n\t\t\t\t\tletter-spacing: .09em; color: var(--text-3);\n\t\t\t\t}\n\t\t\t\t.sidebar-divider { height: 1px; background: var(--border-soft); margin: .35rem 0; }\n\t\t\t\t.sidebar-count { font-size: .7rem; opacity: .6; margin-left: auto; }\n\n\t\t\t\t/* ── Main content area ── */\n\t\t\t\t.main-content { flex: 1; min-width: 0; overflow-y: auto; }\n\t\t\t\t.content-inner { padding: .875rem 1.25rem 4rem; max-width: 960px; }\n\n\t\t\t\t/* ── Toolbar (filter bar) ── */\n\t\t\t\t.toolbar {\n\t\t\t\t\tdisplay: flex; flex-wrap: wrap; gap: .5rem;\n\t\t\t\t\talign-items: center; margin-bottom: .75rem;\n\t\t\t\t}\n\t\t\t\t.toolbar-chips { display: flex; flex-wrap: wrap; gap: .3rem; align-items: center; }\n\n\t\t\t\t/* ── Media rows ── */\n\t\t\t\t.media-list { display: flex; flex-direction: column; gap: .3rem; }\n\t\t\t\t.media-row {\n\t\t\t\t\tdisplay: grid; grid-template-columns: 20px 1fr auto;\n\t\t\t\t\tgap: .6rem; align-items: center;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border-soft);\n\t\t\t\t\tborder-radius: var(--radius); padding: .65rem .875rem;\n\t\t\t\t\ttransition: background .12s; cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.media-row:hover { background: var(--surface-2); }\n\t\t\t\t.media-row.sl-running  { border-left: 2px solid var(--accent); }\n\t\t\t\t.media-row.sl-retrying { border-left: 2px solid var(--warn-fg); }\n\t\t\t\t.media-row.sl-failed   { border-left: 2px solid var(--danger); }\n\t\t\t\t.media-row.sl-missing  { border-left: 2px solid var(--warn-fg); opacity: .8; }\n\t\t\t\t.row-check { display: flex; align-items: center; }\n\t\t\t\t.row-checkbox { width: 15px; height: 15px; accent-color: var(--accent); cursor: pointer; }\n\t\t\t\t.media-row:has(.row-checkbox:checked) { background: var(--accent-dim); border-color: var(--accent); }\n\t\t\t\t.row-main { min-width: 0; }\n\t\t\t\t.row-title {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .35rem;\n\t\t\t\t\tfont-size: .875rem; font-weight: 500; color: var(--text);\n\t\t\t\t\toverflow: hidden; margin-bottom: .2rem;\n\t\t\t\t}\n\t\t\t\t.row-title-text { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }\n\t\t\t\t.row-meta { display: flex; flex-wrap: wrap; gap: .35rem; align-items: center; }\n\t\t\t\t.row-domain { font-size: .72rem; color: var(--text-2); }\n\t\t\t\t.row-size { font-size: .72rem; color: var(--text-3); }\n\t\t\t\t.row-retry-note { font-size: .68rem; color: var(--warn-fg); }\n\t\t\t\t.row-tag-chips { display: flex; flex-wrap: wrap; gap: .2rem; align-items: center; }\n\t\t\t\t.row-actions { display: flex; gap: .15rem; align-items: center; flex-shrink: 0; }\n\n\t\t\t\t/* ── Row overflow menu ── */\n\t\t\t\t.row-menu-wrap { position: relative; }\n\t\t\t\t.row-menu {\n\t\t\t\t\tposition: absolute; right: 0; top: calc(100% + 4px);\n\t\t\t\t\tdisplay: none; flex-direction: column;\n\t\t\t\t\tmin-width: 200px; padding: .3rem; z-index: 50;\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius); box-shadow: 0 8px 32px rgba(0,0,0,.5);\n\t\t\t\t\tmax-height: 70vh; overflow-y: auto;\n\t\t\t\t}\n\t\t\t\t.row-menu.open { display: flex; }\n\t\t\t\t.row-menu-item {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .55rem;\n\t\t\t\t\twidth: 100%; padding: .45rem .55rem;\n\t\t\t\t\tbackground: transparent; border: none; border-radius: var(--radius-sm);\n\t\t\t\t\tcolor: var(--text); font-size: .8125rem;\n\t\t\t\t\ttext-align: left; text-decoration: none; white-space: nowrap; cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.row-menu-item:hover { background: var(--surface-3); }\n\t\t\t\t.row-menu-item .mi { font-size: 16px; color: var(--text-2); }\n\t\t\t\t.row-menu-item.danger { color: var(--danger); }\n\t\t\t\t.row-menu-item.danger .mi { color: var(--danger); }\n\t\t\t\t.row-menu-divider { height: 1px; background: var(--border); margin: .2rem .3rem; }\n\n\t\t\t\t/* ── Tag chips on rows ── */\n\t\t\t\t.tag-chip {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; gap: .2rem;\n\t\t\t\t\tpadding: .1rem .5rem; border-radius: 9999px;\n\t\t\t\t\tbackground: var(--surface-3); color: var(--text-2);\n\t\t\t\t\tfont-size: .7rem; border: none; cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.tag-chip:hover { color: var(--text); }\n\t\t\t\t.tag-chip.coll { background: var(--accent-dim); color: var(--accent); }\n\n\t\t\t\t/* ── Tag cloud expand ── */\n\t\t\t\t.tag-extra { display: none !important; }\n\t\t\t\t.tag-cloud-expanded .tag-extra { display: inline-flex !important; }\n\t\t\t\t.tag-cloud-expanded .tag-expand-btn { display: none !important; }\n\t\t\t\t.tag-expand-btn { font-style: italic; opacity: .65; border-style: dashed; }\n\n\t\t\t\t/* ── Play-all bar ── */\n\t\t\t\t.play-all-bar {\n\t\t\t\t\tdisplay: none; align-items: center; gap: .6rem;\n\t\t\t\t\tpadding: .4rem .75rem; margin-bottom: .5rem;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--accent-dim);\n\t\t\t\t\tborder-radius: var(--radius); font-size: .8125rem;\n\t\t\t\t}\n\t\t\t\t.play-all-bar.visible { display: flex; }\n\t\t\t\t.play-all-title { font-weight: 600; color: var(--accent); flex: 1; min-width: 0; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }\n\n\t\t\t\t/* ── Queue section ── */\n\t\t\t\t.queue-toolbar {\n\t\t\t\t\tdisplay: flex; gap: .5rem; align-items: center;\n\t\t\t\t\tmargin-bottom: .75rem; flex-wrap: wrap;\n\t\t\t\t}\n\t\t\t\t.queue-list { display: flex; flex-direction: column; gap: .3rem; }\n\t\t\t\t.queue-row {\n\t\t\t\t\tdisplay: flex; gap: .6rem; align-items: center;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border-soft);\n\t\t\t\t\tborder-radius: var(--radius); padding: .65rem .875rem;\n\t\t\t\t}\n\t\t\t\t.queue-row.ql-running  { border-left: 2px solid var(--accent); }\n\t\t\t\t.queue-row.ql-retrying { border-left: 2px solid var(--warn-fg); }\n\t\t\t\t.queue-row.ql-failed   { border-left: 2px solid var(--danger); }\n\t\t\t\t.queue-row-main { flex: 1; min-width: 0; }\n\t\t\t\t.queue-row-title {\n\t\t\t\t\tfont-size: .875rem; font-weight: 500; color: var(--text);\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap;\n\t\t\t\t\tmargin-bottom: .15rem;\n\t\t\t\t}\n\t\t\t\t.queue-row-meta { display: flex; gap: .4rem; align-items: center; flex-wrap: wrap; }\n\t\t\t\t.queue-domain { font-size: .72rem; color: var(--text-2); }\n\t\t\t\t.queue-retry { font-size: .68rem; color: var(--warn-fg); }\n\t\t\t\t.queue-row-actions { display: flex; gap: .15rem; align-items: center; flex-shrink: 0; }\n\t\t\t\t/* ── Op rows (фоновые операции) ── */\n\t\t\t\t.op-row { border-left: 2px solid transparent; }\n\t\t\t\t.op-row.op-running { border-left-color: var(--accent); }\n\t\t\t\t.op-row.op-failed  { border-left-color: var(--danger); }\n\t\t\t\t.op-row.op-done    { opacity: .7; }\n\t\t\t\t.op-status-icon { flex-shrink: 0; width: 1.4rem; text-align: center; }\n\t\t\t\t.op-error { font-size: .72rem; color: var(--danger); margin-top: .1rem;\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap; }\n\t\t\t\t.queue-section-divider { height: 1px; background: var(--border-soft); margin: .2rem 0; }\n\t\t\t\t@keyframes op-spin { to { transform: rotate(360deg); } }\n\t\t\t\t.op-spin { display: inline-block; animation: op-spin .8s linear infinite; }\n\n\t\t\t\t/* ── Empty state ── */\n\t\t\t\t.empty-state {\n\t\t\t\t\tdisplay: flex; flex-direction: column; align-items: center;\n\t\t\t\t\tgap: .75rem; padding: 3rem 1rem;\n\t\t\t\t\tcolor: var(--text-2); text-align: center;\n\t\t\t\t}\n\t\t\t\t.empty-state .mi { font-size: 48px; opacity: .3; }\n\n\t\t\t\t/* ── Dialogs (base) ── */\n\t\t\t\tdialog { border: none; border-radius: 14px; padding: 0; overflow: hidden; margin: auto; }\n\t\t\t\tdialog::backdrop { background: var(--scrim); }\n\t\t\t\t.dialog-header {\n\t\t\t\t\tdisplay: flex; justify-content: space-between; align-items: center;\n\t\t\t\t\tpadding: .6rem .875rem; border-bottom: 1px solid var(--border); flex-shrink: 0;\n\t\t\t\t\tgap: .35rem;\n\t\t\t\t}\n\t\t\t\t.dialog-title {\n\t\t\t\t\tfont-size: .875rem; font-weight: 600;\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap;\n\t\t\t\t\tflex: 1; min-width: 0;\n\t\t\t\t}\n\n\t\t\t\t/* ── Video dialog ── */\n\t\t\t\tdialog#player-dialog {\n\t\t\t\t\tbackground: #000;\n\t\t\t\t\twidth: min(96vw, 960px);\n\t\t\t\t\tmax-height: 92vh;\n\t\t\t\t\tmargin: auto;\n\t\t\t\t\toverflow: hidden;\n\t\t\t\t\tbox-shadow: 0 8px 48px rgba(0,0,0,.7);\n\t\t\t\t}\n\t\t\t\t.video-dialog-header {\n\t\t\t\t\tbackground: #111; border-color: #2a2a2a;\n\t\t\t\t}\n\t\t\t\t.video-dialog-header .icon-btn { color: #aaa; }\n\t\t\t\t.video-dialog-header .icon-btn:hover { background: rgba(255,255,255,.1); color: #fff; }\n\t\t\t\t#player-wrap { overflow: hidden; background: #000; }\n\t\t\t\t#player-wrap video { display: block; width: 100%; }\n\n\t\t\t\t/* ── Log dialog ── */\n\t\t\t\tdialog#log-dialog {\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border);\n\t\t\t\t\twidth: min(96vw, 820px); min-height: 55vh; max-height: 86vh;\n\t\t\t\t\tbox-shadow: 0 8px 48px rgba(0,0,0,.6);\n\t\t\t\t}\n\t\t\t\tdialog#log-dialog[open] { display: flex; flex-direction: column; }\n\t\t\t\t#log-content {\n\t\t\t\t\tflex: 1; overflow: auto; margin: 0; padding: .75rem 1rem;\n\t\t\t\t\tfont-family: var(--mono); font-size: .75rem; line-height: 1.55;\n\t\t\t\t\tcolor: #c0ccd8; white-space: pre-wrap; word-break: break-all;\n\t\t\t\t\tbackground: #080a0d;\n\t\t\t\t}\n\n\t\t\t\t/* ── Meta dialog ── */\n\t\t\t\tdialog#meta-dialog {\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border);\n\t\t\t\t\twidth: min(96vw, 480px);\n\t\t\t\t\tbox-shadow: 0 8px 48px rgba(0,0,0,.5);\n\t\t\t\t}\n\t\t\t\tdialog#meta-dialog[open] { display: flex; flex-direction: column; }\n\t\t\t\t.meta-dialog-body { padding: .75rem 1rem 1rem; }\n\t\t\t\t.meta-matrix { width: 100%; border-collapse: collapse; }\n\t\t\t\t.meta-matrix td { padding: .3rem .4rem; vertical-align: middle; }\n\t\t\t\t.meta-matrix td:first-child { width: 1.75rem; text-align: center; }\n\t\t\t\t.meta-matrix td:nth-child(2) { width: 8rem; color: var(--text-muted); font-size: .85rem; }\n\t\t\t\t.meta-row { transition: opacity .15s; }\n\t\t\t\t.meta-row.dimmed { opacity: .35; }\n\t\t\t\t.meta-input {\n\t\t\t\t\twidth: 100%; background: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 6px; padding: .3rem .55rem; color: var(--text); font-size: .9rem;\n\t\t\t\t\tbox-sizing: border-box;\n\t\t\t\t}\n\t\t\t\t.meta-input:focus { outline: none; border-color: var(--accent); }\n\t\t\t\t.meta-footer { display: flex; align-items: center; justify-content: flex-end; gap: .75rem; padding: .75rem 0 0; }\n\t\t\t\t.meta-count-note { color: var(--text-muted); font-size: .85rem; flex: 1; }\n\n\t\t\t\t/* ── Player bar ── */\n\t\t\t\t.player-bar {\n\t\t\t\t\tposition: fixed; bottom: 0; left: 0; right: 0; height: 64px;\n\t\t\t\t\tbackground: var(--surface); border-top: 1px solid var(--border);\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tpadding: 0 .875rem;\n\t\t\t\t\tz-index: 70;\n\t\t\t\t\ttransform: translateY(100%);\n\t\t\t\t\ttransition: transform .28s cubic-bezier(.4,0,.2,1);\n\t\t\t\t}\n\t\t\t\t.player-bar.visible { transform: translateY(0); }\n\n\t\t\t\t.pb-info {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tflex: 1; min-width: 0;\n\t\t\t\t}\n\t\t\t\t.pb-kind-icon {\n\t\t\t\t\tcolor: var(--accent); font-size: 20px; flex-shrink: 0;\n\t\t\t\t\tfont-variation-settings: 'FILL' 1,'wght' 400,'GRAD' 0,'opsz' 20;\n\t\t\t\t}\n\t\t\t\t.pb-title {\n\t\t\t\t\tfont-size: .8125rem; font-weight: 500; color: var(--text);\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap;\n\t\t\t\t\tmin-width: 0;\n\t\t\t\t}\n\t\t\t\t.pb-center {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tflex: 2; min-width: 0; max-width: 440px;\n\t\t\t\t}\n\t\t\t\t.pb-time {\n\t\t\t\t\tfont-size: .7rem; color: var(--text-2);\n\t\t\t\t\tfont-variant-numeric: tabular-nums; white-space: nowrap; flex-shrink: 0;\n\t\t\t\t}\n\t\t\t\t.pb-track {\n\t\t\t\t\tflex: 1; height: 4px; background: var(--surface-3);\n\t\t\t\t\tborder-radius: 4px; cursor: pointer; position: relative;\n\t\t\t\t\ttransition: height .15s;\n\t\t\t\t}\n\t\t\t\t.pb-track:hover { height: 7px; }\n\t\t\t\t.pb-fill {\n\t\t\t\t\tposition: absolute; left: 0; top: 0; bottom: 0;\n\t\t\t\t\tbackground: var(--accent); border-radius: 4px;\n\t\t\t\t\tpointer-events: none; width: 0;\n\t\t\t\t\ttransition: width .3s linear;\n\t\t\t\t}\n\t\t\t\t.pb-controls { display: flex; align-items: center; gap: .15rem; flex-shrink: 0; }\n\t\t\t\t.pb-play-btn { color: var(--text); }\n\t\t\t\t.pb-play-btn:hover { background: var(--surface-3); color: var(--text); }\n\n\t\t\t\t/* Offset content when bar is visible */\n\t\t\t\tbody.has-player .content-inner  { padding-bottom: calc(3.5rem + 64px); }\n\t\t\t\tbody.has-player .action-bar      { bottom: calc(64px + .75rem); }\n\t\t\t\tbody.has-player #toast           { bottom: calc(64px + 1.5rem); }\n\n\t\t\t\t/* ── Action bar (bulk) ── */\n\t\t\t\t.action-bar {\n\t\t\t\t\tposition: fixed; bottom: 1.25rem; left: 50%; transform: translateX(-50%);\n\t\t\t\t\tbackground: var(--surface-3); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 14px; padding: .55rem .875rem;\n\t\t\t\t\tdisplay: flex; gap: .5rem; align-items: center; flex-wrap: wrap;\n\t\t\t\t\tbox-shadow: 0 4px 24px rgba(0,0,0,.5); z-index: 80;\n\t\t\t\t\tmax-width: calc(100vw - 2rem);\n\t\t\t\t}\n\t\t\t\t.action-bar.hidden { display: none; }\n\t\t\t\t.action-bar-count { font-size: .8rem; color: var(--text-2); white-space: nowrap; margin-right: .25rem; }\n\t\t\t\t.coll-dropdown-wrap { position: relative; }\n\t\t\t\t.coll-dropdown {\n\t\t\t\t\tposition: absolute; bottom: calc(100% + 8px); left: 0;\n\t\t\t\t\tmin-width: 180px; max-height: 220px; overflow-y: auto;\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius); box-shadow: 0 4px 16px rgba(0,0,0,.4);\n\t\t\t\t\tz-index: 100; padding: .3rem;\n\t\t\t\t}\n\t\t\t\t.coll-dropdown.hidden { display: none; }\n\n\t\t\t\t/* ── Settings ── */\n\t\t\t\t.settings-wrap { padding: 1.25rem; max-width: 760px; }\n\t\t\t\t.settings-section { margin-bottom: 1.75rem; }\n\t\t\t\t.settings-h { font-size: 1rem; font-weight: 700; color: var(--text); margin-bottom: .4rem; }\n\t\t\t\t.settings-h2 { font-size: .875rem; font-weight: 600; color: var(--text); margin-bottom: .5rem; }\n\t\t\t\t.settings-hint { font-size: .8rem; color: var(--text-2); margin-bottom: .875rem; }\n\t\t\t\t.settings-hint code { font-family: var(--mono); background: var(--surface-2); padding: .1em .35em; border-radius: 4px; font-size: .85em; }\n\t\t\t\t.runtime-grid { display: grid; grid-template-columns: 180px 1fr; gap: .5rem 1rem; align-items: start; margin-bottom: .75rem; }\n\t\t\t\t@media (max-width: 500px) { .runtime-grid { grid-template-columns: 1fr; } }\n\t\t\t\t.runtime-label { font-size: .8125rem; font-weight: 500; padding-top: .45rem; }\n\t\t\t\t.runtime-field { display: flex; flex-direction: column; gap: .2rem; }\n\t\t\t\t.runtime-input {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius-sm); color: var(--text);\n\t\t\t\t\tfont-size: .8125rem; padding: .4rem .65rem; outline: none; width: 100%;\n\t\t\t\t}\n\t\t\t\t.runtime-input:focus { border-color: var(--accent); }\n\t\t\t\t.runtime-narrow { max-width: 110px; }\n\t\t\t\t.cookie-textarea {\n\t\t\t\t\twidth: 100%; background: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius-sm); color: var(--text);\n\t\t\t\t\tfont-family: var(--mono); font-size: .75rem; padding: .65rem .875rem;\n\t\t\t\t\tresize: vertical; outline: none;\n\t\t\t\t}\n\t\t\t\t.cookie-textarea:focus { border-color: var(--accent); }\n\t\t\t\t.domain-list { list-style: none; display: flex; flex-direction: column; gap: .35rem; }\n\t\t\t\t.domain-item {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .75rem;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border-soft);\n\t\t\t\t\tborder-radius: var(--radius-sm); padding: .5rem .875rem;\n\t\t\t\t}\n\t\t\t\t.domain-name { font-weight: 500; font-size: .875rem; flex: 1; }\n\t\t\t\t.domain-meta { font-size: .72rem; color: var(--text-2); }\n\t\t\t\t.cleanup-result { font-size: .8rem; color: var(--text-2); margin-top: .5rem; }\n\t\t\t\t.settings-actions { display: flex; gap: .6rem; margin-top: .5rem; }\n\t\t\t\t.settings-empty { font-size: .8125rem; color: var(--text-2); }\n\n\t\t\t\t/* ── Toast ── */\n\t\t\t\t#toast {\n\t\t\t\t\tposition: fixed; bottom: 1.5rem; left: 50%;\n\t\t\t\t\ttransform: translateX(-50%) translateY(140%);\n\t\t\t\t\tbackground: var(--text); color: var(--bg);\n\t\t\t\t\tpadding: .55rem 1.1rem; border-radius: 8px;\n\t\t\t\t\tfont-size: .8125rem; white-space: nowrap;\n\t\t\t\t\tbox-shadow: 0 4px 12px rgba(0,0,0,.3);\n\t\t\t\t\ttransition: transform .22s ease, opacity .22s ease;\n\t\t\t\t\topacity: 0; pointer-events: none; z-index: 9999;\n\t\t\t\t}\n\t\t\t\t#toast.visible { transform: translateX(-50%) translateY(0); opacity: 1; }\n\n\t\t\t\t/* Prevent scrollbar from causing body hscroll */\n\t\t\t\t.app-shell { overflow-x: hidden; }\n\n\t\t\t\t/* ── Mobile (≤767px) ── */\n\t\t\t\t@media (max-width: 767px) {\n\t\t\t\t\t/* Header: logo+tabs+settings on row 1, form full-width on row 2 */\n\t\t\t\t\t.app-header {\n\t\t\t\t\t\tflex-wrap: wrap;\n\t\t\t\t\t\theight: auto;\n\t\t\t\t\t\tpadding: .4rem .75rem;\n\t\t\t\t\t\tgap: .3rem .5rem;\n\t\t\t\t\t}\n\t\t\t\t\t/* Hide logo text so logo icon + tabs + settings fit on one row */\n\t\t\t\t\t.header-logo-name { display: none; }\n\t\t\t\t\t.header-spacer { display: none; }\n\t\t\t\t\t.header-add-form {\n\t\t\t\t\t\torder: 10;\n\t\t\t\t\t\tflex: 0 0 100%;\n\t\t\t\t\t}\n\t\t\t\t\t.header-url-input {\n\t\t\t\t\t\twidth: 0; flex: 1; min-width: 0;\n\t\t\t\t\t}\n\t\t\t\t\t/* Sidebar → horizontal scrollable chip bar */\n\t\t\t\t\t.sidebar {\n\t\t\t\t\t\twidth: 100%; height: auto; position: static;\n\t\t\t\t\t\tborder-right: none; border-bottom: 1px solid var(--border);\n\t\t\t\t\t\tflex-direction: row; overflow-x: auto; overflow-y: hidden;\n\t\t\t\t\t\tpadding: .4rem .75rem; gap: .3rem;\n\t\t\t\t\t\t-webkit-overflow-scrolling: touch;\n\t\t\t\t\t\tscrollbar-width: none;\n\t\t\t\t\t}\n\t\t\t\t\t.sidebar::-webkit-scrollbar { display: none; }\n\t\t\t\t\t.sidebar-section-label { display: none; }\n\t\t\t\t\t.sidebar-divider { display: none; }\n\t\t\t\t\t.sidebar-queue-divider { display: none; }\n\t\t\t\t\t.sidebar-nav-item {\n\t\t\t\t\t\tborder-radius: 9999px; margin-right: 0; width: auto;\n\t\t\t\t\t\tpadding: .3rem .75rem; white-space: nowrap; flex-shrink: 0;\n\t\t\t\t\t}\n\t\t\t\t\t.sidebar-count { display: none; }\n\t\t\t\t\t.app-body { flex-direction: column; }\n\t\t\t\t\t.main-content { overflow-y: visible; }\n\t\t\t\t\t/* Player bar: hide progress on narrow screens, keep controls visible */\n\t\t\t\t\t.pb-center { display: none; }\n\t\t\t\t\t.pb-info { flex: 1; }\n\t\t\t\t\t.player-bar { padding: 0 .6rem; gap: .35rem; }\n\t\t\t\t}\n\t\t\t</style></head><body>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\"><link rel=\"icon\" type=\"image/svg+xml\" href=\"static/logo.svg\"><link rel=\"stylesheet\" href=\"https://fonts.googleapis.com/css2?family=Material+Symbols+Rounded:opsz,wght,FILL,GRAD@20..48,100..700,0..1,-50..200&display=block\"><link rel=\"stylesheet\" href=\"static/plyr.min.css\"><script src=\"static/htmx.min.js\"></script><script src=\"static/plyr.min.js\"></script><style>\n\t\t\t\t*, *::before, *::after { box-sizing: border-box; margin: 0; padding: 0; }\n\n\t\t\t\t/* ── Tokens ── */\n\t\t\t\t:root {\n\t\t\t\t\t--bg:           #0d0d0f;\n\t\t\t\t\t--surface:      #17171c;\n\t\t\t\t\t--surface-2:    #1f1f26;\n\t\t\t\t\t--surface-3:    #27272f;\n\t\t\t\t\t--border:       #2c2c36;\n\t\t\t\t\t--border-soft:  #222228;\n\t\t\t\t\t--text:         #dcdce8;\n\t\t\t\t\t--text-2:       #8a8a9a;\n\t\t\t\t\t--text-3:       #55555f;\n\t\t\t\t\t--accent:       #7b93c8;\n\t\t\t\t\t--accent-dim:   #1c2c48;\n\t\t\t\t\t--accent-on:    #0d1520;\n\t\t\t\t\t--danger:       #d4665a;\n\t\t\t\t\t--danger-dim:   #3a1a18;\n\t\t\t\t\t--warn-fg:      #d4a054;\n\t\t\t\t\t--warn-dim:     #362810;\n\t\t\t\t\t--ok-fg:        #5aab7a;\n\t\t\t\t\t--ok-dim:       #0e2e1c;\n\t\t\t\t\t--scrim:        rgba(0,0,0,.6);\n\t\t\t\t\t--radius:       10px;\n\t\t\t\t\t--radius-sm:    6px;\n\t\t\t\t\t--mono:         'JetBrains Mono','Fira Code','Cascadia Code',monospace;\n\t\t\t\t}\n\n\t\t\t\thtml, body { height: 100%; background: var(--bg); color: var(--text); }\n\t\t\t\tbody { font-family: system-ui,-apple-system,'Segoe UI',sans-serif; font-size: 14px; line-height: 1.5; }\n\n\t\t\t\t/* ── Icons ── */\n\t\t\t\t.mi {\n\t\t\t\t\tfont-family: 'Material Symbols Rounded';\n\t\t\t\t\tfont-size: 18px; font-weight: 400; line-height: 1;\n\t\t\t\t\tdisplay: inline-block; user-select: none;\n\t\t\t\t\tfont-variation-settings: 'FILL' 0,'wght' 400,'GRAD' 0,'opsz' 20;\n\t\t\t\t\tvertical-align: middle;\n\t\t\t\t}\n\n\t\t\t\t/* ── Icon button ── */\n\t\t\t\t.icon-btn {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; justify-content: center;\n\t\t\t\t\twidth: 32px; height: 32px; border-radius: 50%;\n\t\t\t\t\tborder: none; background: transparent; cursor: pointer;\n\t\t\t\t\tcolor: var(--text-2); transition: background .15s, color .15s;\n\t\t\t\t\tflex-shrink: 0;\n\t\t\t\t}\n\t\t\t\t.icon-btn:hover { background: var(--surface-3); color: var(--text); }\n\t\t\t\t.icon-btn.danger { color: var(--danger); }\n\t\t\t\t.icon-btn.danger:hover { background: var(--danger-dim); }\n\n\t\t\t\t/* ── Buttons ── */\n\t\t\t\t.btn {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; gap: .4rem;\n\t\t\t\t\tborder: none; border-radius: 9999px; cursor: pointer;\n\t\t\t\t\tfont-size: .8125rem; font-weight: 500; padding: .45rem 1.1rem;\n\t\t\t\t\twhite-space: nowrap; transition: filter .15s;\n\t\t\t\t}\n\t\t\t\t.btn-primary { background: var(--accent); color: var(--accent-on); }\n\t\t\t\t.btn-primary:hover { filter: brightness(1.12); }\n\t\t\t\t.btn-ghost {\n\t\t\t\t\tbackground: var(--surface-2); color: var(--text);\n\t\t\t\t\tborder: 1px solid var(--border);\n\t\t\t\t}\n\t\t\t\t.btn-ghost:hover { background: var(--surface-3); }\n\t\t\t\t.btn-danger { background: var(--danger); color: #fff; }\n\t\t\t\t.btn-danger:hover { filter: brightness(1.1); }\n\t\t\t\t.btn-secondary { background: var(--surface-3); color: var(--text); border: 1px solid var(--border); }\n\t\t\t\t.btn-secondary:hover { background: var(--surface-2); }\n\t\t\t\t.btn-sm { padding: .3rem .75rem; font-size: .75rem; }\n\n\t\t\t\t/* ── Chips ── */\n\t\t\t\t.chip {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; gap: .3rem;\n\t\t\t\t\tpadding: .2rem .65rem; border-radius: 9999px;\n\t\t\t\t\tborder: 1px solid var(--border); background: transparent;\n\t\t\t\t\tcolor: var(--text-2); font-size: .75rem; cursor: pointer;\n\t\t\t\t\twhite-space: nowrap; transition: background .12s, color .12s, border-color .12s;\n\t\t\t\t}\n\t\t\t\t.chip:hover { background: var(--surface-2); color: var(--text); }\n\t\t\t\t.chip.active { background: var(--accent); color: var(--accent-on); border-color: transparent; }\n\t\t\t\t.chip-remove {\n\t\t\t\t\tbackground: none; border: none; cursor: pointer; color: inherit;\n\t\t\t\t\tfont-size: .65rem; padding: 0; line-height: 1; opacity: .6;\n\t\t\t\t}\n\t\t\t\t.chip-remove:hover { opacity: 1; }\n\n\t\t\t\t/* ── Status colours ── */\n\t\t\t\t.s-checking,.s-pending,.s-running { background: var(--accent-dim); color: var(--accent); }\n\t\t\t\t.s-done,.s-imported                { background: var(--ok-dim);     color: var(--ok-fg); }\n\t\t\t\t.s-retrying,.s-missing             { background: var(--warn-dim);   color: var(--warn-fg); }\n\t\t\t\t.s-failed,.s-cancelled,.s-deleted  { background: var(--danger-dim); color: var(--danger); }\n\t\t\t\t.s-hidden                          { background: var(--surface-2);  color: var(--text-2); }\n\n\t\t\t\t/* ── Search ── */\n\t\t\t\t.search-input {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 9999px; color: var(--text);\n\t\t\t\t\tfont-size: .875rem; padding: .4rem 1rem; outline: none; min-width: 0;\n\t\t\t\t}\n\t\t\t\t.search-input:focus { border-color: var(--accent); }\n\t\t\t\t.search-input::placeholder { color: var(--text-3); }\n\t\t\t\t.toolbar-select {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 9999px; color: var(--text);\n\t\t\t\t\tfont-size: .8rem; padding: .35rem .75rem; outline: none; flex-shrink: 0;\n\t\t\t\t}\n\t\t\t\t.toolbar-select:focus { border-color: var(--accent); }\n\t\t\t\t.chip.excluded { text-decoration: line-through; color: var(--danger); border-color: var(--danger); }\n\t\t\t\t.filter-more.active > summary { color: var(--accent); }\n\t\t\t\t.filter-more .header-add-options-panel { min-width: 240px; }\n\t\t\t\t.filter-more .header-add-options-panel input { width: 100%; box-sizing: border-box; }\n\t\t\t\t.filter-more input[type=number], .filter-more input[type=date] {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 6px; color: var(--text); font-size: .8rem; padding: .25rem .4rem;\n\t\t\t\t}\n\t\t\t\t.filter-range { display: flex; gap: .5rem; }\n\t\t\t\t.filter-range label { flex: 1; }\n\t\t\t\t.page-more {\n\t\t\t\t\tdisplay: flex; align-items: center; justify-content: center; gap: .4rem;\n\t\t\t\t\tpadding: .75rem; color: var(--text-2); font-size: .8rem;\n\t\t\t\t}\n\t\t\t\t.page-footer { padding: .5rem; text-align: center; color: var(--text-2); font-size: .75rem; }\n\n\t\t\t\t/* ── App shell ── */\n\t\t\t\t.app-shell { display: flex; flex-direction: column; height: 100vh; }\n\n\t\t\t\t/* ── Header ── */\n\t\t\t\t.app-header {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tpadding: 0 1rem; height: 52px; flex-shrink: 0;\n\t\t\t\t\tbackground: var(--surface); border-bottom: 1px solid var(--border);\n\t\t\t\t\tposition: sticky; top: 0; z-index: 40;\n\t\t\t\t}\n\t\t\t\t.header-logo { display: flex; align-items: center; gap: .5rem; text-decoration: none; }\n\t\t\t\t.header-logo-name { font-size: 1rem; font-weight: 700; color: var(--text); letter-spacing: -.01em; }\n\t\t\t\t.header-spacer { flex: 1; }\n\t\t\t\t.header-add-form { display: flex; gap: .4rem; align-items: center; }\n\t\t\t\t.header-url-input {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 9999px; color: var(--text);\n\t\t\t\t\tfont-size: .8rem; padding: .35rem .875rem; outline: none; width: 260px;\n\t\t\t\t}\n\t\t\t\t.header-url-input:focus { border-color: var(--accent); }\n\t\t\t\t.header-url-input::placeholder { color: var(--text-3); }\n\t\t\t\t.header-add-options { position: relative; }\n\t\t\t\t.header-add-options > summary { list-style: none; }\n\t\t\t\t.header-add-options > summary::-webkit-details-marker { display: none; }\n\t\t\t\t.header-add-options-panel {\n\t\t\t\t\tposition: absolute; top: calc(100% + .4rem); right: 0; z-index: 20;\n\t\t\t\t\tdisplay: flex; flex-direction: column; gap: .5rem; min-width: 200px;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 8px; padding: .75rem; font-size: .8rem; color: var(--text-2);\n\t\t\t\t\tbox-shadow: 0 4px 12px rgba(0,0,0,.2);\n\t\t\t\t}\n\t\t\t\t.header-add-options-panel label { display: flex; flex-direction: column; gap: .2rem; }\n\t\t\t\t.header-add-options-panel .header-add-check { flex-direction: row; align-items: center; gap: .4rem; }\n\t\t\t\t.header-add-options-panel select,\n\t\t\t\t.header-add-options-panel input[type=text] {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 6px; color: var(--text); font-size: .8rem; padding: .25rem .4rem;\n\t\t\t\t}\n\n\t\t\t\t/* ── Body layout ── */\n\t\t\t\t.app-body { display: flex; flex: 1; min-height: 0; }\n\n\t\t\t\t/* ── Sidebar ── */\n\t\t\t\t.sidebar {\n\t\t\t\t\twidth: 200px; flex-shrink: 0;\n\t\t\t\t\tborder-right: 1px solid var(--border-soft);\n\t\t\t\t\tdisplay: flex; flex-direction: column;\n\t\t\t\t\toverflow-y: auto; padding: .5rem 0;\n\t\t\t\t\tposition: sticky; top: 52px; height: calc(100vh - 52px);\n\t\t\t\t}\n\t\t\t\t.sidebar-nav-item {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tpadding: .45rem .75rem .45rem 1rem;\n\t\t\t\t\tbackground: none; border: none; cursor: pointer;\n\t\t\t\t\tcolor: var(--text-2); font-size: .8125rem;\n\t\t\t\t\tborder-radius: 0 20px 20px 0; margin-right: .5rem;\n\t\t\t\t\ttransition: background .12s, color .12s; text-align: left; width: calc(100% - .5rem);\n\t\t\t\t}\n\t\t\t\t.sidebar-nav-item:hover { background: var(--surface-2); color: var(--text); }\n\t\t\t\t.sidebar-nav-item.active { background: var(--accent-dim); color: var(--accent); font-weight: 600; }\n\t\t\t\t.sidebar-queue-item { color: var(--text); font-weight: 500; }\n\t\t\t\t.sidebar-queue-divider { height: 1px; background: var(--border-soft); margin: .5rem 0; }\n\t\t\t\t.sidebar-section-label {\n\t\t\t\t\tdisplay: block; padding: .75rem 1rem .2rem;\n\t\t\t\t\tfont-size: .65rem; font-weight: 700; text-transform: uppercase;\n\t\t\t\t\tletter-spacing: .09em; color: var(--text-3);\n\t\t\t\t}\n\t\t\t\t.sidebar-divider { height: 1px; background: var(--border-soft); margin: .35rem 0; }\n\t\t\t\t.sidebar-count { font-size: .7rem; opacity: .6; margin-left: auto; }\n\n\t\t\t\t/* ── Main content area ── */\n\t\t\t\t.main-content { flex: 1; min-width: 0; overflow-y: auto; }\n\t\t\t\t.content-inner { padding: .875rem 1.25rem 4rem; max-width: 960px; }\n\n\t\t\t\t/* ── Toolbar (filter bar) ── */\n\t\t\t\t.toolbar {\n\t\t\t\t\tdisplay: flex; flex-wrap: wrap; gap: .5rem;\n\t\t\t\t\talign-items: center; margin-bottom: .75rem;\n\t\t\t\t}\n\t\t\t\t.toolbar-chips { display: flex; flex-wrap: wrap; gap: .3rem; align-items: center; }\n\n\t\t\t\t/* ── Media rows ── */\n\t\t\t\t.media-list { display: flex; flex-direction: column; gap: .3rem; }\n\t\t\t\t.media-row {\n\t\t\t\t\tdisplay: grid; grid-template-columns: 20px 1fr auto;\n\t\t\t\t\tgap: .6rem; align-items: center;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border-soft);\n\t\t\t\t\tborder-radius: var(--radius); padding: .65rem .875rem;\n\t\t\t\t\ttransition: background .12s; cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.media-row:hover { background: var(--surface-2); }\n\t\t\t\t.media-row.sl-running  { border-left: 2px solid var(--accent); }\n\t\t\t\t.media-row.sl-retrying { border-left: 2px solid var(--warn-fg); }\n\t\t\t\t.media-row.sl-failed   { border-left: 2px solid var(--danger); }\n\t\t\t\t.media-row.sl-missing  { border-left: 2px solid var(--warn-fg); opacity: .8; }\n\t\t\t\t.row-check { display: flex; align-items: center; }\n\t\t\t\t.row-checkbox { width: 15px; height: 15px; accent-color: var(--accent); cursor: pointer; }\n\t\t\t\t.media-row:has(.row-checkbox:checked) { background: var(--accent-dim); border-color: var(--accent); }\n\t\t\t\t.media-row:has(.row-thumb) { grid-template-columns: 20px 64px 1fr auto; }\n\t\t\t\t.row-thumb {\n\t\t\t\t\twidth: 64px; height: 36px; object-fit: cover; border-radius: 3px;\n\t\t\t\t\tbackground: var(--surface-2); cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.row-main { min-width: 0; }\n\t\t\t\t.row-title {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .35rem;\n\t\t\t\t\tfont-size: .875rem; font-weight: 500; color: var(--text);\n\t\t\t\t\toverflow: hidden; margin-bottom: .2rem;\n\t\t\t\t}\n\t\t\t\t.row-title-text { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }\n\t\t\t\t.row-meta { display: flex; flex-wrap: wrap; gap: .35rem; align-items: center; }\n\t\t\t\t.row-domain { font-size: .72rem; color: var(--text-2); }\n\t\t\t\t.row-size { font-size: .72rem; color: var(--text-3); }\n\t\t\t\t.row-quality {\n\t\t\t\t\tfont-size: .65rem; font-weight: 600; color: var(--text-2);\n\t\t\t\t\tborder: 1px solid var(--border); border-radius: 3px; padding: 0 .25rem;\n\t\t\t\t}\n\t\t\t\t.row-info { font-size: .72rem; color: var(--text-3); overflow: hidden; text-overflow: ellipsis; white-space: nowrap; min-width: 0; }\n\t\t\t\t.row-retry-note { font-size: .68rem; color: var(--warn-fg); }\n\t\t\t\t.row-watched { display: inline-flex; color: var(--success); }\n\t\t\t\t.row-watched .mi { font-size: 14px; }\n\t\t\t\t.row-watch { height: 3px; margin-top: .35rem; border-radius: 2px; background: var(--surface-2); overflow: hidden; }\n\t\t\t\t.row-watch-fill { height: 100%; background: var(--accent); }\n\t\t\t\t.row-tag-chips { display: flex; flex-wrap: wrap; gap: .2rem; align-items: center; }\n\t\t\t\t.row-actions { display: flex; gap: .15rem; align-items: center; flex-shrink: 0; }\n\n\t\t\t\t/* ── Row overflow menu ── */\n\t\t\t\t.row-menu-wrap { position: relative; }\n\t\t\t\t.row-menu {\n\t\t\t\t\tposition: absolute; right: 0; top: calc(100% + 4px);\n\t\t\t\t\tdisplay: none; flex-direction: column;\n\t\t\t\t\tmin-width: 200px; padding: .3rem; z-index: 50;\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius); box-shadow: 0 8px 32px rgba(0,0,0,.5);\n\t\t\t\t\tmax-height: 70vh; overflow-y: auto;\n\t\t\t\t}\n\t\t\t\t.row-menu.open { display: flex; }\n\t\t\t\t.row-menu-item {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .55rem;\n\t\t\t\t\twidth: 100%; padding: .45rem .55rem;\n\t\t\t\t\tbackground: transparent; border: none; border-radius: var(--radius-sm);\n\t\t\t\t\tcolor: var(--text); font-size: .8125rem;\n\t\t\t\t\ttext-align: left; text-decoration: none; white-space: nowrap; cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.row-menu-item:hover { background: var(--surface-3); }\n\t\t\t\t.row-menu-item .mi { font-size: 16px; color: var(--text-2); }\n\t\t\t\t.row-menu-item.danger { color: var(--danger); }\n\t\t\t\t.row-menu-item.danger .mi { color: var(--danger); }\n\t\t\t\t.row-menu-divider { height: 1px; background: var(--border); margin: .2rem .3rem; }\n\n\t\t\t\t/* ── Tag chips on rows ── */\n\t\t\t\t.tag-chip {\n\t\t\t\t\tdisplay: inline-flex; align-items: center; gap: .2rem;\n\t\t\t\t\tpadding: .1rem .5rem; border-radius: 9999px;\n\t\t\t\t\tbackground: var(--surface-3); color: var(--text-2);\n\t\t\t\t\tfont-size: .7rem; border: none; cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.tag-chip:hover { color: var(--text); }\n\t\t\t\t.tag-chip.coll { background: var(--accent-dim); color: var(--accent); }\n\n\t\t\t\t/* ── Tag cloud expand ── */\n\t\t\t\t.tag-extra { display: none !important; }\n\t\t\t\t.tag-cloud-expanded .tag-extra { display: inline-flex !important; }\n\t\t\t\t.tag-cloud-expanded .tag-expand-btn { display: none !important; }\n\t\t\t\t.tag-expand-btn { font-style: italic; opacity: .65; border-style: dashed; }\n\n\t\t\t\t/* ── Play-all bar ── */\n\t\t\t\t.play-all-bar {\n\t\t\t\t\tdisplay: none; align-items: center; gap: .6rem;\n\t\t\t\t\tpadding: .4rem .75rem; margin-bottom: .5rem;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--accent-dim);\n\t\t\t\t\tborder-radius: var(--radius); font-size: .8125rem;\n\t\t\t\t}\n\t\t\t\t.play-all-bar.visible { display: flex; }\n\t\t\t\t.play-all-title { font-weight: 600; color: var(--accent); flex: 1; min-width: 0; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }\n\n\t\t\t\t/* ── Queue section ── */\n\t\t\t\t.queue-toolbar {\n\t\t\t\t\tdisplay: flex; gap: .5rem; align-items: center;\n\t\t\t\t\tmargin-bottom: .75rem; flex-wrap: wrap;\n\t\t\t\t}\n\t\t\t\t.queue-list { display: flex; flex-direction: column; gap: .3rem; }\n\t\t\t\t.queue-row {\n\t\t\t\t\tdisplay: flex; gap: .6rem; align-items: center;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border-soft);\n\t\t\t\t\tborder-radius: var(--radius); padding: .65rem .875rem;\n\t\t\t\t}\n\t\t\t\t.queue-row.ql-running  { border-left: 2px solid var(--accent); }\n\t\t\t\t.queue-row.ql-retrying { border-left: 2px solid var(--warn-fg); }\n\t\t\t\t.queue-row.ql-failed   { border-left: 2px solid var(--danger); }\n\t\t\t\t.queue-row-main { flex: 1; min-width: 0; }\n\t\t\t\t.queue-row-title {\n\t\t\t\t\tfont-size: .875rem; font-weight: 500; color: var(--text);\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap;\n\t\t\t\t\tmargin-bottom: .15rem;\n\t\t\t\t}\n\t\t\t\t.queue-row-meta { display: flex; gap: .4rem; align-items: center; flex-wrap: wrap; }\n\t\t\t\t.queue-domain { font-size: .72rem; color: var(--text-2); }\n\t\t\t\t.queue-options { font-size: .72rem; color: var(--text-3); }\n\t\t\t\t.queue-priority { display: inline-flex; align-items: center; gap: .15rem; font-size: .72rem; color: var(--accent); }\n\t\t\t\t.queue-priority .mi { font-size: .9rem; }\n\t\t\t\t.error-class { font-size: .72rem; color: var(--danger); cursor: help; }\n\t\t\t\t.queue-duplicate { display: inline-flex; align-items: center; gap: .2rem; font-size: .72rem; color: var(--warn-fg); }\n\t\t\t\t.queue-duplicate .mi { font-size: .9rem; }\n\t\t\t\t.queue-paused {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem; margin-bottom: .75rem;\n\t\t\t\t\tpadding: .5rem .75rem; border-radius: 8px; background: var(--surface-2); font-size: .8125rem;\n\t\t\t\t}\n\t\t\t\t.queue-paused > span:nth-child(2) { flex: 1; }\n\t\t\t\t.queue-retry { font-size: .68rem; color: var(--warn-fg); }\n\t\t\t\t.queue-progress-text { font-size: .68rem; color: var(--text-2); font-variant-numeric: tabular-nums; }\n\t\t\t\t.queue-progress {\n\t\t\t\t\theight: 3px; margin-top: .35rem; border-radius: 2px;\n\t\t\t\t\tbackground: var(--border-soft); overflow: hidden;\n\t\t\t\t}\n\t\t\t\t.queue-progress-fill { height: 100%; background: var(--accent); transition: width .4s ease; }\n\t\t\t\t.queue-progress.indeterminate .queue-progress-fill { width: 30% !important; animation: queue-progress-slide 1.2s ease-in-out infinite; }\n\t\t\t\t@keyframes queue-progress-slide { from { transform: translateX(-100%); } to { transform: translateX(340%); } }\n\t\t\t\t.queue-row-actions { display: flex; gap: .15rem; align-items: center; flex-shrink: 0; }\n\t\t\t\t/* ── Op rows (фоновые операции) ── */\n\t\t\t\t.op-row { border-left: 2px solid transparent; }\n\t\t\t\t.op-row.op-running { border-left-color: var(--accent); }\n\t\t\t\t.op-row.op-failed  { border-left-color: var(--danger); }\n\t\t\t\t.op-row.op-done    { opacity: .7; }\n\t\t\t\t.op-status-icon { flex-shrink: 0; width: 1.4rem; text-align: center; }\n\t\t\t\t.op-error { font-size: .72rem; color: var(--danger); margin-top: .1rem;\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap; }\n\t\t\t\t.queue-section-divider { height: 1px; background: var(--border-soft); margin: .2rem 0; }\n\t\t\t\t@keyframes op-spin { to { transform: rotate(360deg); } }\n\t\t\t\t.op-spin { display: inline-block; animation: op-spin .8s linear infinite; }\n\n\t\t\t\t/* ── Empty state ── */\n\t\t\t\t.empty-state {\n\t\t\t\t\tdisplay: flex; flex-direction: column; align-items: center;\n\t\t\t\t\tgap: .75rem; padding: 3rem 1rem;\n\t\t\t\t\tcolor: var(--text-2); text-align: center;\n\t\t\t\t}\n\t\t\t\t.empty-state .mi { font-size: 48px; opacity: .3; }\n\n\t\t\t\t/* ── Dialogs (base) ── */\n\t\t\t\tdialog { border: none; border-radius: 14px; padding: 0; overflow: hidden; margin: auto; }\n\t\t\t\tdialog::backdrop { background: var(--scrim); }\n\t\t\t\t.dialog-header {\n\t\t\t\t\tdisplay: flex; justify-content: space-between; align-items: center;\n\t\t\t\t\tpadding: .6rem .875rem; border-bottom: 1px solid var(--border); flex-shrink: 0;\n\t\t\t\t\tgap: .35rem;\n\t\t\t\t}\n\t\t\t\t.dialog-title {\n\t\t\t\t\tfont-size: .875rem; font-weight: 600;\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap;\n\t\t\t\t\tflex: 1; min-width: 0;\n\t\t\t\t}\n\n\t\t\t\t/* ── Video dialog ── */\n\t\t\t\tdialog#player-dialog {\n\t\t\t\t\tbackground: #000;\n\t\t\t\t\twidth: min(96vw, 960px);\n\t\t\t\t\tmax-height: 92vh;\n\t\t\t\t\tmargin: auto;\n\t\t\t\t\toverflow: hidden;\n\t\t\t\t\tbox-shadow: 0 8px 48px rgba(0,0,0,.7);\n\t\t\t\t}\n\t\t\t\t.video-dialog-header {\n\t\t\t\t\tbackground: #111; border-color: #2a2a2a;\n\t\t\t\t}\n\t\t\t\t.video-dialog-header .icon-btn { color: #aaa; }\n\t\t\t\t.video-dialog-header .icon-btn:hover { background: rgba(255,255,255,.1); color: #fff; }\n\t\t\t\t#player-wrap { overflow: hidden; background: #000; }\n\t\t\t\t#player-wrap video { display: block; width: 100%; }\n\t\t\t\t#player-info { max-height: 30vh; overflow: auto; background: #111; color: #bbb; font-size: .78rem; }\n\t\t\t\t#player-info:empty { display: none; }\n\t\t\t\t.player-info { padding: .5rem .9rem .6rem; }\n\t\t\t\t.player-info-line { display: flex; flex-wrap: wrap; gap: .25rem .9rem; }\n\t\t\t\t.player-info-line a { color: var(--accent); }\n\t\t\t\t.player-info-codecs { font-family: var(--mono); color: #888; }\n\t\t\t\t.player-chapters { list-style: none; margin: .4rem 0 0; padding: 0; }\n\t\t\t\t.player-chapters button {\n\t\t\t\t\tbackground: none; border: none; color: #ccc; padding: .15rem .3rem;\n\t\t\t\t\tfont-size: .75rem; text-align: left; cursor: pointer; width: 100%;\n\t\t\t\t}\n\t\t\t\t.player-chapters button:hover { background: rgba(255,255,255,.08); color: #fff; }\n\t\t\t\t.chapter-time { font-family: var(--mono); color: #888; margin-right: .5rem; }\n\t\t\t\t.player-description { margin-top: .4rem; }\n\t\t\t\t.player-description summary { cursor: pointer; color: #999; }\n\t\t\t\t.player-description p { margin: .3rem 0 0; white-space: pre-wrap; word-break: break-word; }\n\n\t\t\t\t/* ── Log dialog ── */\n\t\t\t\tdialog#log-dialog {\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border);\n\t\t\t\t\twidth: min(96vw, 820px); min-height: 55vh; max-height: 86vh;\n\t\t\t\t\tbox-shadow: 0 8px 48px rgba(0,0,0,.6);\n\t\t\t\t}\n\t\t\t\tdialog#log-dialog[open] { display: flex; flex-direction: column; }\n\t\t\t\t#log-content {\n\t\t\t\t\tflex: 1; overflow: auto; margin: 0; padding: .75rem 1rem;\n\t\t\t\t\tfont-family: var(--mono); font-size: .75rem; line-height: 1.55;\n\t\t\t\t\tcolor: #c0ccd8; white-space: pre-wrap; word-break: break-all;\n\t\t\t\t\tbackground: #080a0d;\n\t\t\t\t}\n\n\t\t\t\t#log-attempts { max-height: 30vh; overflow: auto; border-bottom: 1px solid var(--border); }\n\t\t\t\t#log-attempts:empty { display: none; }\n\t\t\t\t.attempt-list { list-style: none; margin: 0; padding: .35rem .5rem; }\n\t\t\t\t.attempt {\n\t\t\t\t\tdisplay: flex; flex-wrap: wrap; gap: .25rem .75rem; width: 100%; padding: .3rem .5rem;\n\t\t\t\t\tbackground: none; border: 1px solid transparent; color: var(--text-2);\n\t\t\t\t\tfont-size: .75rem; text-align: left; cursor: pointer;\n\t\t\t\t}\n\t\t\t\t.attempt:hover { background: var(--surface-2); }\n\t\t\t\t.attempt-current { border-color: var(--border); color: var(--text); }\n\t\t\t\t.attempt-num { font-family: var(--mono); color: var(--text-3); }\n\t\t\t\t.attempt-meta { color: var(--text-3); }\n\t\t\t\t.attempt-error { padding: 0 .5rem .3rem 2.2rem; font-family: var(--mono); font-size: .7rem; color: var(--danger); word-break: break-all; }\n\n\t\t\t\t/* ── Meta / transcode dialogs ── */\n\t\t\t\tdialog#meta-dialog, dialog#transcode-dialog {\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border);\n\t\t\t\t\twidth: min(96vw, 480px);\n\t\t\t\t\tbox-shadow: 0 8px 48px rgba(0,0,0,.5);\n\t\t\t\t}\n\t\t\t\tdialog#meta-dialog[open], dialog#transcode-dialog[open] { display: flex; flex-direction: column; }\n\t\t\t\t.meta-dialog-body { padding: .75rem 1rem 1rem; }\n\t\t\t\t.meta-matrix { width: 100%; border-collapse: collapse; }\n\t\t\t\t.meta-matrix td { padding: .3rem .4rem; vertical-align: middle; }\n\t\t\t\t.meta-matrix td:first-child { width: 1.75rem; text-align: center; }\n\t\t\t\t.meta-matrix td:nth-child(2) { width: 8rem; color: var(--text-muted); font-size: .85rem; }\n\t\t\t\t.meta-row { transition: opacity .15s; }\n\t\t\t\t.meta-row.dimmed { opacity: .35; }\n\t\t\t\t.meta-input {\n\t\t\t\t\twidth: 100%; background: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 6px; padding: .3rem .55rem; color: var(--text); font-size: .9rem;\n\t\t\t\t\tbox-sizing: border-box;\n\t\t\t\t}\n\t\t\t\t.meta-input:focus { outline: none; border-color: var(--accent); }\n\t\t\t\t.meta-footer { display: flex; align-items: center; justify-content: flex-end; gap: .75rem; padding: .75rem 0 0; }\n\t\t\t\t.meta-count-note { color: var(--text-muted); font-size: .85rem; flex: 1; }\n\t\t\t\t.transcode-replace { display: flex; align-items: center; gap: .5rem; padding: .6rem 0 0; font-size: .9rem; }\n\n\t\t\t\t/* ── Player bar ── */\n\t\t\t\t.player-bar {\n\t\t\t\t\tposition: fixed; bottom: 0; left: 0; right: 0; height: 64px;\n\t\t\t\t\tbackground: var(--surface); border-top: 1px solid var(--border);\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tpadding: 0 .875rem;\n\t\t\t\t\tz-index: 70;\n\t\t\t\t\ttransform: translateY(100%);\n\t\t\t\t\ttransition: transform .28s cubic-bezier(.4,0,.2,1);\n\t\t\t\t}\n\t\t\t\t.player-bar.visible { transform: translateY(0); }\n\n\t\t\t\t.pb-info {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tflex: 1; min-width: 0;\n\t\t\t\t}\n\t\t\t\t.pb-kind-icon {\n\t\t\t\t\tcolor: var(--accent); font-size: 20px; flex-shrink: 0;\n\t\t\t\t\tfont-variation-settings: 'FILL' 1,'wght' 400,'GRAD' 0,'opsz' 20;\n\t\t\t\t}\n\t\t\t\t.pb-title {\n\t\t\t\t\tfont-size: .8125rem; font-weight: 500; color: var(--text);\n\t\t\t\t\toverflow: hidden; text-overflow: ellipsis; white-space: nowrap;\n\t\t\t\t\tmin-width: 0;\n\t\t\t\t}\n\t\t\t\t.pb-center {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem;\n\t\t\t\t\tflex: 2; min-width: 0; max-width: 440px;\n\t\t\t\t}\n\t\t\t\t.pb-time {\n\t\t\t\t\tfont-size: .7rem; color: var(--text-2);\n\t\t\t\t\tfont-variant-numeric: tabular-nums; white-space: nowrap; flex-shrink: 0;\n\t\t\t\t}\n\t\t\t\t.pb-track {\n\t\t\t\t\tflex: 1; height: 4px; background: var(--surface-3);\n\t\t\t\t\tborder-radius: 4px; cursor: pointer; position: relative;\n\t\t\t\t\ttransition: height .15s;\n\t\t\t\t}\n\t\t\t\t.pb-track:hover { height: 7px; }\n\t\t\t\t.pb-fill {\n\t\t\t\t\tposition: absolute; left: 0; top: 0; bottom: 0;\n\t\t\t\t\tbackground: var(--accent); border-radius: 4px;\n\t\t\t\t\tpointer-events: none; width: 0;\n\t\t\t\t\ttransition: width .3s linear;\n\t\t\t\t}\n\t\t\t\t.pb-controls { display: flex; align-items: center; gap: .15rem; flex-shrink: 0; }\n\t\t\t\t.pb-play-btn { color: var(--text); }\n\t\t\t\t.pb-play-btn:hover { background: var(--surface-3); color: var(--text); }\n\n\t\t\t\t/* Offset content when bar is visible */\n\t\t\t\tbody.has-player .content-inner  { padding-bottom: calc(3.5rem + 64px); }\n\t\t\t\tbody.has-player .action-bar      { bottom: calc(64px + .75rem); }\n\t\t\t\tbody.has-player #toast           { bottom: calc(64px + 1.5rem); }\n\n\t\t\t\t/* ── Action bar (bulk) ── */\n\t\t\t\t.action-bar {\n\t\t\t\t\tposition: fixed; bottom: 1.25rem; left: 50%; transform: translateX(-50%);\n\t\t\t\t\tbackground: var(--surface-3); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: 14px; padding: .55rem .875rem;\n\t\t\t\t\tdisplay: flex; gap: .5rem; align-items: center; flex-wrap: wrap;\n\t\t\t\t\tbox-shadow: 0 4px 24px rgba(0,0,0,.5); z-index: 80;\n\t\t\t\t\tmax-width: calc(100vw - 2rem);\n\t\t\t\t}\n\t\t\t\t.action-bar.hidden { display: none; }\n\t\t\t\t.action-bar-count { font-size: .8rem; color: var(--text-2); white-space: nowrap; margin-right: .25rem; }\n\t\t\t\t.coll-dropdown-wrap { position: relative; }\n\t\t\t\t.coll-dropdown {\n\t\t\t\t\tposition: absolute; bottom: calc(100% + 8px); left: 0;\n\t\t\t\t\tmin-width: 180px; max-height: 220px; overflow-y: auto;\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius); box-shadow: 0 4px 16px rgba(0,0,0,.4);\n\t\t\t\t\tz-index: 100; padding: .3rem;\n\t\t\t\t}\n\t\t\t\t.coll-dropdown.hidden { display: none; }\n\n\t\t\t\t/* ── Settings ── */\n\t\t\t\t.settings-wrap { padding: 1.25rem; max-width: 760px; }\n\t\t\t\t.settings-section { margin-bottom: 1.75rem; }\n\t\t\t\t.settings-h { font-size: 1rem; font-weight: 700; color: var(--text); margin-bottom: .4rem; }\n\t\t\t\t.settings-h2 { font-size: .875rem; font-weight: 600; color: var(--text); margin-bottom: .5rem; }\n\t\t\t\t.settings-hint { font-size: .8rem; color: var(--text-2); margin-bottom: .875rem; }\n\t\t\t\t.settings-hint code { font-family: var(--mono); background: var(--surface-2); padding: .1em .35em; border-radius: 4px; font-size: .85em; }\n\t\t\t\t.runtime-grid { display: grid; grid-template-columns: 180px 1fr; gap: .5rem 1rem; align-items: start; margin-bottom: .75rem; }\n\t\t\t\t@media (max-width: 500px) { .runtime-grid { grid-template-columns: 1fr; } }\n\t\t\t\t.runtime-label { font-size: .8125rem; font-weight: 500; padding-top: .45rem; }\n\t\t\t\t.runtime-field { display: flex; flex-direction: column; gap: .2rem; }\n\t\t\t\t.runtime-input {\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius-sm); color: var(--text);\n\t\t\t\t\tfont-size: .8125rem; padding: .4rem .65rem; outline: none; width: 100%;\n\t\t\t\t}\n\t\t\t\t.runtime-input:focus { border-color: var(--accent); }\n\t\t\t\t.runtime-narrow { max-width: 110px; }\n\t\t\t\t.cookie-textarea {\n\t\t\t\t\twidth: 100%; background: var(--surface-2); border: 1px solid var(--border);\n\t\t\t\t\tborder-radius: var(--radius-sm); color: var(--text);\n\t\t\t\t\tfont-family: var(--mono); font-size: .75rem; padding: .65rem .875rem;\n\t\t\t\t\tresize: vertical; outline: none;\n\t\t\t\t}\n\t\t\t\t.cookie-textarea:focus { border-color: var(--accent); }\n\t\t\t\t.domain-list { list-style: none; display: flex; flex-direction: column; gap: .35rem; }\n\t\t\t\t.domain-item {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .75rem;\n\t\t\t\t\tbackground: var(--surface); border: 1px solid var(--border-soft);\n\t\t\t\t\tborder-radius: var(--radius-sm); padding: .5rem .875rem;\n\t\t\t\t}\n\t\t\t\t.domain-name { font-weight: 500; font-size: .875rem; flex: 1; }\n\t\t\t\t.domain-meta { font-size: .72rem; color: var(--text-2); }\n\t\t\t\t.preset-item { align-items: flex-start; }\n\t\t\t\t.preset-details { flex: 1; min-width: 0; }\n\t\t\t\t.preset-details > summary { cursor: pointer; display: flex; gap: .5rem; align-items: baseline; flex-wrap: wrap; }\n\t\t\t\t.preset-details > form { margin-top: .75rem; }\n\t\t\t\t.preset-default { color: var(--accent); }\n\t\t\t\t.preset-new { margin-top: .75rem; }\n\t\t\t\t.preset-new > summary { list-style: none; display: inline-flex; }\n\t\t\t\t.preset-new > summary::-webkit-details-marker { display: none; }\n\t\t\t\t.preset-new > form { margin-top: .75rem; }\n\t\t\t\t.preset-steps { display: flex; flex-wrap: wrap; gap: .35rem 1rem; padding-top: .4rem; }\n\t\t\t\t.preset-check { display: inline-flex; align-items: center; gap: .35rem; font-size: .8125rem; }\n\t\t\t\t.cleanup-result { font-size: .8rem; color: var(--text-2); margin-top: .5rem; }\n\t\t\t\t.settings-actions { display: flex; gap: .6rem; margin-top: .5rem; }\n\t\t\t\t.settings-empty { font-size: .8125rem; color: var(--text-2); }\n\t\t\t\t.token-secret {\n\t\t\t\t\tdisplay: flex; align-items: center; gap: .5rem; margin: .5rem 0 .875rem;\n\t\t\t\t\tbackground: var(--surface-2); border: 1px solid var(--accent);\n\t\t\t\t\tborder-radius: var(--radius-sm); padding: .5rem .875rem;\n\t\t\t\t}\n\t\t\t\t.token-secret code { font-family: var(--mono); font-size: .8rem; flex: 1; word-break: break-all; user-select: all; }\n\t\t\t\t.token-expired { color: var(--danger); }\n\t\t\t\t.user-role-select { width: auto; padding: .2rem .4rem; font-size: .75rem; }\n\t\t\t\t.user-chat { display: inline-flex; align-items: center; gap: .15rem; font-size: .72rem; color: var(--text-2); }\n\n\t\t\t\t/* ── Login ── */\n\t\t\t\t.login-wrap { min-height: 100%; display: flex; align-items: center; justify-content: center; padding: 1rem; }\n\t\t\t\t.login-form { display: flex; flex-direction: column; gap: .75rem; width: 100%; max-width: 320px; }\n\t\t\t\t.login-logo { display: flex; align-items: center; justify-content: center; gap: .5rem; margin-bottom: .5rem; }\n\t\t\t\t.login-error { font-size: .8125rem; color: var(--danger); }\n\t\t\t\t.header-user { font-size: .8125rem; color: var(--text-2); }\n\n\t\t\t\t/* ── Toast ── */\n\t\t\t\t#toast {\n\t\t\t\t\tposition: fixed; bottom: 1.5rem; left: 50%;\n\t\t\t\t\ttransform: translateX(-50%) translateY(140%);\n\t\t\t\t\tbackground: var(--text); color: var(--bg);\n\t\t\t\t\tpadding: .55rem 1.1rem; border-radius: 8px;\n\t\t\t\t\tfont-size: .8125rem; white-space: nowrap;\n\t\t\t\t\tbox-shadow: 0 4px 12px rgba(0,0,0,.3);\n\t\t\t\t\ttransition: transform .22s ease, opacity .22s ease;\n\t\t\t\t\topacity: 0; pointer-events: none; z-index: 9999;\n\t\t\t\t}\n\t\t\t\t#toast.visible { transform: translateX(-50%) translateY(0); opacity: 1; }\n\n\t\t\t\t/* Prevent scrollbar from causing body hscroll */\n\t\t\t\t.app-shell { overflow-x: hidden; }\n\n\t\t\t\t/* ── Mobile (≤767px) ── */\n\t\t\t\t@media (max-width: 767px) {\n\t\t\t\t\t/* Header: logo+tabs+settings on row 1, form full-width on row 2 */\n\t\t\t\t\t.app-header {\n\t\t\t\t\t\tflex-wrap: wrap;\n\t\t\t\t\t\theight: auto;\n\t\t\t\t\t\tpadding: .4rem .75rem;\n\t\t\t\t\t\tgap: .3rem .5rem;\n\t\t\t\t\t}\n\t\t\t\t\t/* Hide logo text so logo icon + tabs + settings fit on one row */\n\t\t\t\t\t.header-logo-name { display: none; }\n\t\t\t\t\t.header-spacer { display: none; }\n\t\t\t\t\t.header-add-form {\n\t\t\t\t\t\torder: 10;\n\t\t\t\t\t\tflex: 0 0 100%;\n\t\t\t\t\t}\n\t\t\t\t\t.header-url-input {\n\t\t\t\t\t\twidth: 0; flex: 1; min-width: 0;\n\t\t\t\t\t}\n\t\t\t\t\t/* Sidebar → horizontal scrollable chip bar */\n\t\t\t\t\t.sidebar {\n\t\t\t\t\t\twidth: 100%; height: auto; position: static;\n\t\t\t\t\t\tborder-right: none; border-bottom: 1px solid var(--border);\n\t\t\t\t\t\tflex-direction: row; overflow-x: auto; overflow-y: hidden;\n\t\t\t\t\t\tpadding: .4rem .75rem; gap: .3rem;\n\t\t\t\t\t\t-webkit-overflow-scrolling: touch;\n\t\t\t\t\t\tscrollbar-width: none;\n\t\t\t\t\t}\n\t\t\t\t\t.sidebar::-webkit-scrollbar { display: none; }\n\t\t\t\t\t.sidebar-section-label { display: none; }\n\t\t\t\t\t.sidebar-divider { display: none; }\n\t\t\t\t\t.sidebar-queue-divider { display: none; }\n\t\t\t\t\t.sidebar-nav-item {\n\t\t\t\t\t\tborder-radius: 9999px; margin-right: 0; width: auto;\n\t\t\t\t\t\tpadding: .3rem .75rem; white-space: nowrap; flex-shrink: 0;\n\t\t\t\t\t}\n\t\t\t\t\t.sidebar-count { display: none; }\n\t\t\t\t\t.app-body { flex-direction: column; }\n\t\t\t\t\t.main-content { overflow-y: visible; }\n\t\t\t\t\t/* Player bar: hide progress on narrow screens, keep controls visible */\n\t\t\t\t\t.pb-center { display: none; }\n\t\t\t\t\t.pb-info { flex: 1; }\n\t\t\t\t\t.player-bar { padding: 0 .6rem; gap: .35rem; }\n\t\t\t\t}\n\t\t\t</style></head><body>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -2,5 +2,8 @@ package web
 
 import "embed"
 
+// hls.js не хранится в репозитории: go generate скачивает закреплённую версию.
+//go:generate sh -c "test -s static/hls.min.js || curl -fsSL -o static/hls.min.js https://cdn.jsdelivr.net/npm/hls.js@1.5.20/dist/hls.min.js"
+
 //go:embed static
 var StaticFiles embed.FS